@@ -0,0 +1,147 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines a ready made custom tag with an attribute list, so
+ vendor tags may be written and decoded without own CustomTag types.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Attr is an attribute of AttrTag.
+type Attr struct {
+	Key    string
+	Value  string
+	Quoted bool // the value is a quoted-string, other values (numbers, enumerated strings, resolutions) are written as is
+}
+
+// AttrTag is a custom tag with an attribute list, e.g.
+// #EXT-X-VENDOR:ID="ad-1",DURATION=30. Attributes are written in their
+// order. Quoted values can't contain double quotes and line breaks, other
+// values can't contain commas either, such characters are stripped and
+// attributes with invalid names are skipped, see Validate.
+type AttrTag struct {
+	Name  string // tag name with the leading # and the trailing :, e.g. #EXT-X-VENDOR:
+	Attrs []Attr
+}
+
+var unquotedAttrValue = strings.NewReplacer("\"", "", ",", "", "\r", "", "\n", "")
+
+// TagName returns the name of the tag.
+func (t *AttrTag) TagName() string {
+	return t.Name
+}
+
+// Encode writes the tag with its attributes.
+func (t *AttrTag) Encode() *bytes.Buffer {
+	var buf bytes.Buffer
+	buf.WriteString(strings.TrimSuffix(t.Name, ":"))
+	sep := ':'
+	for _, attr := range t.Attrs {
+		if !reAttrName.MatchString(attr.Key) {
+			continue
+		}
+		buf.WriteRune(sep)
+		sep = ','
+		buf.WriteString(attr.Key)
+		buf.WriteRune('=')
+		if attr.Quoted {
+			buf.WriteRune('"')
+			buf.WriteString(quotedString.Replace(attr.Value))
+			buf.WriteRune('"')
+		} else {
+			buf.WriteString(unquotedAttrValue.Replace(attr.Value))
+		}
+	}
+	return &buf
+}
+
+// String returns the encoded tag.
+func (t *AttrTag) String() string {
+	return t.Encode().String()
+}
+
+// Get returns the value of the attribute.
+func (t *AttrTag) Get(key string) (string, bool) {
+	for _, attr := range t.Attrs {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the attribute, a new attribute is appended after
+// the others.
+func (t *AttrTag) Set(key, value string, quoted bool) {
+	for i := range t.Attrs {
+		if t.Attrs[i].Key == key {
+			t.Attrs[i].Value, t.Attrs[i].Quoted = value, quoted
+			return
+		}
+	}
+	t.Attrs = append(t.Attrs, Attr{key, value, quoted})
+}
+
+// Validate checks that the tag is encoded without changes: its name starts
+// with #, attribute names consist of uppercase letters, digits and dashes
+// and values contain no characters stripped by Encode.
+func (t *AttrTag) Validate() error {
+	if !strings.HasPrefix(t.Name, "#") {
+		return fmt.Errorf("custom tag %q doesn't start with #", t.Name)
+	}
+	for _, attr := range t.Attrs {
+		if !reAttrName.MatchString(attr.Key) {
+			return fmt.Errorf("invalid attribute name %q of %s", attr.Key, t.Name)
+		}
+		invalid := "\",\r\n"
+		if attr.Quoted {
+			invalid = "\"\r\n"
+		}
+		if strings.ContainsAny(attr.Value, invalid) {
+			return fmt.Errorf("%s of %s can't be represented: %q", attr.Key, t.Name, attr.Value)
+		}
+	}
+	return nil
+}
+
+// AttrTagDecoder is a CustomDecoder of tags with an attribute list, it
+// decodes them as AttrTag.
+type AttrTagDecoder struct {
+	Name    string // tag name with the leading # and the trailing :, e.g. #EXT-X-VENDOR:
+	Segment bool   // the tag belongs to the segment after it
+}
+
+// TagName returns the name of decoded tags.
+func (d AttrTagDecoder) TagName() string {
+	return d.Name
+}
+
+// Decode parses attributes of the tag in their order.
+func (d AttrTagDecoder) Decode(line string) (CustomTag, error) {
+	name := strings.TrimSuffix(d.Name, ":")
+	if !strings.HasPrefix(line, name) {
+		return nil, fmt.Errorf("%s expected", name)
+	}
+	t := &AttrTag{Name: d.Name}
+	for _, kv := range reKeyValue.FindAllStringSubmatch(line[len(name):], -1) {
+		quoted := strings.HasPrefix(kv[2], `"`)
+		t.Attrs = append(t.Attrs, Attr{kv[1], strings.Trim(kv[2], ` "`), quoted})
+	}
+	return t, nil
+}
+
+// SegmentTag reports whether decoded tags belong to segments.
+func (d AttrTagDecoder) SegmentTag() bool {
+	return d.Segment
+}
@@ -0,0 +1,77 @@
+/*
+ Package m3u8. Attribute list custom tag tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAttrTagEncode(t *testing.T) {
+	tag := &AttrTag{Name: "#EXT-X-VENDOR:", Attrs: []Attr{
+		{Key: "ID", Value: "ad-1", Quoted: true},
+		{Key: "DURATION", Value: "30.5"},
+		{Key: "TYPE", Value: "MIDROLL"},
+	}}
+	if err := tag.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `#EXT-X-VENDOR:ID="ad-1",DURATION=30.5,TYPE=MIDROLL`
+	if tag.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, tag.String())
+	}
+	tag.Set("ID", `ad "2"`, true)
+	tag.Set("URI", "https://example.com/a,b", false)
+	tag.Attrs = append(tag.Attrs, Attr{Key: "lower", Value: "1"})
+	if err := tag.Validate(); err == nil {
+		t.Error("Expected error for unrepresentable values")
+	}
+	expected = `#EXT-X-VENDOR:ID="ad 2",DURATION=30.5,TYPE=MIDROLL,URI=https://example.com/ab`
+	if tag.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, tag.String())
+	}
+	if v, ok := tag.Get("DURATION"); !ok || v != "30.5" {
+		t.Errorf("Expected DURATION 30.5, got %q", v)
+	}
+	empty := &AttrTag{Name: "#EXT-X-VENDOR-FLAG"}
+	if empty.String() != "#EXT-X-VENDOR-FLAG" {
+		t.Errorf("Expected tag without attributes, got %s", empty.String())
+	}
+}
+
+func TestAttrTagDecoder(t *testing.T) {
+	const src = `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-VENDOR:ID="ad-1",DURATION=30,NAME="a, b"
+#EXTINF:10,
+seg0.ts
+`
+	p, _ := NewMediaPlaylist(0, 1)
+	p.WithCustomDecoders([]CustomDecoder{AttrTagDecoder{Name: "#EXT-X-VENDOR:", Segment: true}})
+	if err := p.DecodeFrom(strings.NewReader(src), true); err != nil {
+		t.Fatal(err)
+	}
+	tag, ok := p.Segments[0].Custom["#EXT-X-VENDOR:"].(*AttrTag)
+	if !ok {
+		t.Fatalf("Expected AttrTag of the segment, got %v", p.Segments[0].Custom)
+	}
+	expected := []Attr{{"ID", "ad-1", true}, {"DURATION", "30", false}, {"NAME", "a, b", true}}
+	if len(tag.Attrs) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, tag.Attrs)
+	}
+	for i := range expected {
+		if tag.Attrs[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], tag.Attrs[i])
+		}
+	}
+	if !strings.Contains(p.String(), "#EXT-X-VENDOR:ID=\"ad-1\",DURATION=30,NAME=\"a, b\"\n#EXTINF:10.000,\nseg0.ts\n") {
+		t.Errorf("Expected the tag encoded as decoded, got:\n%s", p.String())
+	}
+}
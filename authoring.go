@@ -0,0 +1,215 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines checks of the rules of Apple's HLS Authoring
+ Specification, so publishers may pre-flight playlists before review.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Guidance of the authoring specification.
+const (
+	// adjacent bitrates of a variant ladder should be 1.5 to 2 times apart
+	minLadderStep = 1.5
+	maxLadderStep = 2.0
+	// recommended target duration of media playlists
+	authoringTargetDuration = 6
+)
+
+// ValidateAuthoring checks the master playlist and its media playlists
+// against Apple's HLS Authoring Specification: spacing of the variant
+// ladder, completeness of audio groups, frame rates of one family, presence
+// of I-frame playlists and the 6 second target duration. Media playlists
+// are keyed by URI of their variants, they may be nil to check the master
+// playlist only. The rules are guidance rather than the protocol, so the
+// problems found don't make the playlists invalid.
+func (p *MasterPlaylist) ValidateAuthoring(media map[string]*MediaPlaylist) []error {
+	var errs []error
+	errs = append(errs, checkLadder(p)...)
+	errs = append(errs, checkAudioGroups(p)...)
+	errs = append(errs, checkFrameRates(p)...)
+	errs = append(errs, checkIframes(p)...)
+	uris := make([]string, 0, len(media))
+	for uri := range media {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		if pl := media[uri]; pl != nil && pl.TargetDuration != authoringTargetDuration {
+			errs = append(errs, fmt.Errorf("%s: target duration %v differs from recommended %d seconds", uri, pl.TargetDuration, authoringTargetDuration))
+		}
+	}
+	return errs
+}
+
+// checkLadder checks the spacing of bitrates of variants with the same
+// video codec and video range.
+func checkLadder(master *MasterPlaylist) []error {
+	var errs []error
+	ladders := make(map[string][]*Variant)
+	var keys []string
+	for _, v := range master.Variants {
+		if v.Iframe || v.Image || v.Bandwidth == 0 {
+			continue
+		}
+		key := videoCodec(v.Codecs) + "/" + v.VideoRange
+		if _, ok := ladders[key]; !ok {
+			keys = append(keys, key)
+		}
+		ladders[key] = append(ladders[key], v)
+	}
+	for _, key := range keys {
+		ladder := ladders[key]
+		sort.SliceStable(ladder, func(i, j int) bool { return ladder[i].Bandwidth < ladder[j].Bandwidth })
+		for i := 1; i < len(ladder); i++ {
+			lower, upper := ladder[i-1], ladder[i]
+			if lower.Bandwidth == upper.Bandwidth {
+				// the same rendition with another audio group
+				continue
+			}
+			step := float64(upper.Bandwidth) / float64(lower.Bandwidth)
+			if step < minLadderStep || step > maxLadderStep {
+				errs = append(errs, fmt.Errorf("bitrates of variants %s and %s are %.2f times apart, recommended %v to %v", lower.URI, upper.URI, step, minLadderStep, maxLadderStep))
+			}
+		}
+	}
+	return errs
+}
+
+// checkAudioGroups checks that audio groups referenced by variants exist
+// and consist of the same renditions, so switching variants doesn't change
+// the audio choice.
+func checkAudioGroups(master *MasterPlaylist) []error {
+	var errs []error
+	groups := make(map[string]map[string]bool)
+	var referenced []string
+	for _, v := range master.Variants {
+		for _, alt := range v.Alternatives {
+			if alt.Type != "AUDIO" {
+				continue
+			}
+			if groups[alt.GroupId] == nil {
+				groups[alt.GroupId] = make(map[string]bool)
+			}
+			groups[alt.GroupId][alt.Language+"/"+alt.Name] = true
+		}
+		if v.Audio != "" {
+			referenced = append(referenced, v.Audio)
+		}
+	}
+	for _, group := range uniqueStrings(referenced) {
+		if len(groups[group]) == 0 {
+			errs = append(errs, fmt.Errorf("audio group %s has no renditions", group))
+		}
+	}
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	for i := 1; i < len(names); i++ {
+		if !sameSet(groups[names[0]], groups[names[i]]) {
+			errs = append(errs, fmt.Errorf("audio groups %s and %s have different renditions", names[0], names[i]))
+		}
+	}
+	return errs
+}
+
+// checkFrameRates checks that video variants declare frame rates of one
+// family, lower frame rates must divide the highest one.
+func checkFrameRates(master *MasterPlaylist) []error {
+	var errs []error
+	var highest float64
+	var video []*Variant
+	for _, v := range master.Variants {
+		if v.Iframe || v.Image || v.Resolution == "" {
+			continue
+		}
+		video = append(video, v)
+		if v.FrameRate == 0 {
+			errs = append(errs, fmt.Errorf("video variant %s has no FRAME-RATE", v.URI))
+		}
+		highest = math.Max(highest, v.FrameRate)
+	}
+	for _, v := range video {
+		if v.FrameRate == 0 {
+			continue
+		}
+		if ratio := highest / v.FrameRate; math.Abs(ratio-math.Round(ratio)) > 0.01 {
+			errs = append(errs, fmt.Errorf("frame rate %v of variant %s is not of the family of %v", v.FrameRate, v.URI, highest))
+		}
+	}
+	return errs
+}
+
+// checkIframes checks that a playlist with video variants offers I-frame
+// playlists for trick play.
+func checkIframes(master *MasterPlaylist) []error {
+	var video, iframes bool
+	for _, v := range master.Variants {
+		switch {
+		case v.Iframe:
+			iframes = true
+		case !v.Image && v.Resolution != "":
+			video = true
+		}
+	}
+	if video && !iframes {
+		return []error{errors.New("no I-frame playlists for video variants")}
+	}
+	return nil
+}
+
+// videoCodec returns the family of the video codec of CODECS attribute,
+// e.g. avc1 or hvc1, empty for audio only variants.
+func videoCodec(codecs string) string {
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		family := codec
+		if i := strings.IndexByte(codec, '.'); i != -1 {
+			family = codec[:i]
+		}
+		switch family {
+		case "avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "av01", "vp09":
+			return family
+		}
+	}
+	return ""
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sameSet(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
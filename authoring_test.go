@@ -0,0 +1,72 @@
+/*
+ Package m3u8. Authoring specification tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func authoringMaster() *MasterPlaylist {
+	en := &Alternative{Type: "AUDIO", GroupId: "aac", Name: "English", Language: "en", URI: "en.m3u8"}
+	m := NewMasterPlaylist()
+	for _, v := range []VariantParams{
+		{Bandwidth: 1000000, Codecs: "avc1.4d401f,mp4a.40.2", Resolution: "640x360", FrameRate: 25, Audio: "aac"},
+		{Bandwidth: 1800000, Codecs: "avc1.4d401f,mp4a.40.2", Resolution: "960x540", FrameRate: 25, Audio: "aac"},
+		{Bandwidth: 3000000, Codecs: "avc1.640028,mp4a.40.2", Resolution: "1280x720", FrameRate: 50, Audio: "aac"},
+	} {
+		v.Alternatives = []*Alternative{en}
+		m.Append(strings.Split(v.Resolution, "x")[1]+".m3u8", nil, v)
+	}
+	m.Append("iframe.m3u8", nil, VariantParams{Bandwidth: 100000, Resolution: "640x360", Iframe: true})
+	return m
+}
+
+func TestValidateAuthoring(t *testing.T) {
+	m := authoringMaster()
+	p, _ := NewMediaPlaylist(0, 1)
+	p.Append("seg0.ts", 6, "")
+	if errs := m.ValidateAuthoring(map[string]*MediaPlaylist{"360.m3u8": p}); len(errs) != 0 {
+		t.Errorf("Unexpected problems %v", errs)
+	}
+}
+
+func TestValidateAuthoringProblems(t *testing.T) {
+	m := authoringMaster()
+	m.Variants[1].Bandwidth = 1100000 // too close to 1000000 and too far from 3000000
+	m.Variants[2].FrameRate = 30
+	m.Variants[2].Alternatives = []*Alternative{{Type: "AUDIO", GroupId: "ac3", Name: "English", Language: "en"}, {Type: "AUDIO", GroupId: "ac3", Name: "Deutsch", Language: "de"}}
+	m.Variants[2].Audio = "ac3"
+	m.Variants = m.Variants[:3]
+	p, _ := NewMediaPlaylist(0, 1)
+	p.Append("seg0.ts", 10, "")
+
+	errs := m.ValidateAuthoring(map[string]*MediaPlaylist{"360.m3u8": p})
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	all := strings.Join(messages, "\n")
+	for _, expected := range []string{
+		"variants 360.m3u8 and 540.m3u8 are 1.10 times apart",
+		"variants 540.m3u8 and 720.m3u8 are 2.73 times apart",
+		"audio groups aac and ac3 have different renditions",
+		"frame rate 25 of variant 360.m3u8 is not of the family of 30",
+		"no I-frame playlists",
+		"360.m3u8: target duration 10 differs",
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("Expected problem %q in:\n%s", expected, all)
+		}
+	}
+	if len(errs) != 7 {
+		t.Errorf("Expected 7 problems, got:\n%s", all)
+	}
+}
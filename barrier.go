@@ -0,0 +1,93 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines the publication barrier of the live edge used by
+ servers for blocking playlist reload of Low-Latency HLS.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPlaylistClosed is returned by WaitForMSN when the playlist is closed
+// before the requested segment or part is published.
+var ErrPlaylistClosed = errors.New("playlist is closed")
+
+// liveEdge keeps the last published position of the playlist. It has its
+// own lock, so waiters don't access the playlist which is being appended
+// concurrently.
+type liveEdge struct {
+	mu      sync.Mutex
+	msn     uint64        // media sequence number of the segment being produced
+	parts   int           // number of published parts of the segment being produced
+	closed  bool          // no more segments will be published
+	changed chan struct{} // closed and replaced on every publication
+}
+
+func newLiveEdge() *liveEdge {
+	return &liveEdge{changed: make(chan struct{})}
+}
+
+// reached reports whether the segment msn or its part is published. A
+// negative part requests the whole segment.
+func (e *liveEdge) reached(msn uint64, part int) bool {
+	if msn < e.msn {
+		return true
+	}
+	return msn == e.msn && part >= 0 && part < e.parts
+}
+
+// publish updates the position and wakes up all the waiters.
+func (e *liveEdge) publish(msn uint64, parts int, closed bool) {
+	e.mu.Lock()
+	e.msn, e.parts, e.closed = msn, parts, closed
+	close(e.changed)
+	e.changed = make(chan struct{})
+	e.mu.Unlock()
+}
+
+// publish makes the appended segments and parts visible to WaitForMSN.
+func (p *MediaPlaylist) publish() {
+	if p.edge != nil {
+		p.edge.publish(p.nextMSN(), len(p.PendingParts), p.Closed)
+	}
+}
+
+// WaitForMSN blocks until the playlist contains the segment with media
+// sequence number msn or, if part is not negative, the part of this
+// segment, as requested by _HLS_msn and _HLS_part delivery directives. It
+// returns the error of the context if it expires first or
+// ErrPlaylistClosed if the playlist is closed without the segment.
+//
+// WaitForMSN may be called concurrently with appending of segments and
+// parts, although the appends must be serialized by the caller.
+func (p *MediaPlaylist) WaitForMSN(ctx context.Context, msn uint64, part int) error {
+	if p.edge == nil {
+		return errors.New("playlist has no live edge")
+	}
+	for {
+		p.edge.mu.Lock()
+		reached, closed, changed := p.edge.reached(msn, part), p.edge.closed, p.edge.changed
+		p.edge.mu.Unlock()
+		switch {
+		case reached:
+			return nil
+		case closed:
+			return ErrPlaylistClosed
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ Package m3u8. Publication barrier tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWaitForMSN(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 10)
+	p.Append("seg0.ts", 4, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.WaitForMSN(ctx, 0, -1); err != nil {
+		t.Fatalf("Published segment must not block: %v", err)
+	}
+
+	requests := []struct {
+		msn  uint64
+		part int
+	}{{1, 0}, {1, 2}, {1, -1}, {2, 0}, {3, -1}}
+	done := make([]chan error, len(requests))
+	for i, r := range requests {
+		done[i] = make(chan error, 1)
+		go func(r struct {
+			msn  uint64
+			part int
+		}, done chan error) {
+			done <- p.WaitForMSN(ctx, r.msn, r.part)
+		}(r, done[i])
+	}
+
+	// the test goroutine is the only producer, waiters run concurrently
+	for i := 0; i < 3; i++ {
+		p.AppendPartial(fmt.Sprintf("seg1.%d.ts", i), 1, i == 0)
+	}
+	if err := <-done[0]; err != nil {
+		t.Errorf("Part 1.0: %v", err)
+	}
+	if err := <-done[1]; err != nil {
+		t.Errorf("Part 1.2: %v", err)
+	}
+	select {
+	case err := <-done[2]:
+		t.Errorf("Segment 1 is not completed yet, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	p.Append("seg1.ts", 3, "")
+	if err := <-done[2]; err != nil {
+		t.Errorf("Segment 1: %v", err)
+	}
+	p.Append("seg2.ts", 4, "")
+	p.Close()
+	if err := <-done[3]; err != nil {
+		t.Errorf("Part 2.0 is within the completed segment: %v", err)
+	}
+	if err := <-done[4]; err != ErrPlaylistClosed {
+		t.Errorf("Expected ErrPlaylistClosed, got %v", err)
+	}
+}
+
+func TestWaitForMSNContext(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 10)
+	p.Append("seg0.ts", 4, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.WaitForMSN(ctx, 1, 0); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines the builder of media segments.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SegmentBuilder builds a media segment and validates combinations of its
+// fields before the segment is added to a playlist.
+//
+//	seg, err := NewSegmentBuilder().URI("seg1.ts").Duration(6).Range(1000, 0).Build()
+type SegmentBuilder struct {
+	seg MediaSegment
+	ver uint8
+}
+
+// NewSegmentBuilder creates the builder of a media segment.
+func NewSegmentBuilder() *SegmentBuilder {
+	return &SegmentBuilder{ver: minver}
+}
+
+// URI sets the URI of the segment.
+func (b *SegmentBuilder) URI(uri string) *SegmentBuilder {
+	b.seg.URI = uri
+	return b
+}
+
+// Duration sets EXTINF duration of the segment.
+func (b *SegmentBuilder) Duration(duration float64) *SegmentBuilder {
+	b.seg.Duration = duration
+	return b
+}
+
+// Title sets EXTINF title of the segment.
+func (b *SegmentBuilder) Title(title string) *SegmentBuilder {
+	b.seg.Title = title
+	return b
+}
+
+// ProgramDateTime sets EXT-X-PROGRAM-DATE-TIME of the segment.
+func (b *SegmentBuilder) ProgramDateTime(t time.Time) *SegmentBuilder {
+	b.seg.ProgramDateTime = t
+	return b
+}
+
+// Key sets EXT-X-KEY of the segment.
+func (b *SegmentBuilder) Key(method, uri, iv, keyformat, keyformatversions string) *SegmentBuilder {
+	b.seg.Key = &Key{method, uri, iv, keyformat, keyformatversions}
+	return b
+}
+
+// Map sets EXT-X-MAP of the segment.
+func (b *SegmentBuilder) Map(uri string, limit, offset int64) *SegmentBuilder {
+	b.seg.Map = &Map{uri, limit, offset, nil}
+	return b
+}
+
+// Range sets EXT-X-BYTERANGE of the segment.
+func (b *SegmentBuilder) Range(limit, offset int64) *SegmentBuilder {
+	b.seg.Limit = limit
+	b.seg.Offset = offset
+	return b
+}
+
+// SCTE35 adds SCTE-35 cue to the segment.
+func (b *SegmentBuilder) SCTE35(scte35 *SCTE) *SegmentBuilder {
+	b.seg.SCTE = append(b.seg.SCTE, scte35)
+	return b
+}
+
+// CustomTag adds the custom tag to the segment.
+func (b *SegmentBuilder) CustomTag(tag CustomTag) *SegmentBuilder {
+	setCustomTag(&b.seg.Custom, &b.seg.customOrder, tag)
+	return b
+}
+
+// Discontinuity marks the segment with EXT-X-DISCONTINUITY.
+func (b *SegmentBuilder) Discontinuity() *SegmentBuilder {
+	b.seg.Discontinuity = true
+	return b
+}
+
+// Gap marks the segment with EXT-X-GAP. URI of a gap segment is never
+// loaded by clients, so it is not required to be a valid URI.
+func (b *SegmentBuilder) Gap() *SegmentBuilder {
+	b.seg.Gap = true
+	return b
+}
+
+// Build validates the segment and returns a new copy of it.
+func (b *SegmentBuilder) Build() (*MediaSegment, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	seg := b.seg
+	if b.seg.Custom != nil {
+		seg.Custom = make(map[string]CustomTag, len(b.seg.Custom))
+		for k, v := range b.seg.Custom {
+			seg.Custom[k] = v
+		}
+		seg.customOrder = append([]string(nil), b.seg.customOrder...)
+	}
+	return &seg, nil
+}
+
+// Version returns the minimal protocol version of a playlist with the
+// segment. It is valid after a successful Build.
+func (b *SegmentBuilder) Version() uint8 {
+	return b.ver
+}
+
+// AppendTo builds the segment and appends it to the playlist raising the
+// version of the playlist when the segment requires it.
+func (b *SegmentBuilder) AppendTo(p *MediaPlaylist) error {
+	seg, err := b.Build()
+	if err != nil {
+		return err
+	}
+	if err = p.AppendSegment(seg); err != nil {
+		return err
+	}
+	version(&p.ver, b.ver)
+	return nil
+}
+
+func (b *SegmentBuilder) validate() error {
+	seg := &b.seg
+	b.ver = minver
+	switch {
+	case seg.URI == "":
+		return errors.New("segment without URI")
+	case strings.ContainsAny(seg.URI, "\r\n"):
+		return errors.New("segment URI contains line break")
+	case seg.Duration < 0:
+		return fmt.Errorf("negative segment duration %v", seg.Duration)
+	}
+	if seg.Gap {
+		version(&b.ver, 8)
+	} else {
+		if _, err := url.Parse(seg.URI); err != nil {
+			return fmt.Errorf("invalid segment URI: %v", err)
+		}
+		if seg.Duration == 0 {
+			return errors.New("segment without duration")
+		}
+	}
+	if seg.Limit != 0 || seg.Offset != 0 {
+		if seg.Limit <= 0 || seg.Offset < 0 {
+			return fmt.Errorf("invalid byte range %d@%d", seg.Limit, seg.Offset)
+		}
+		version(&b.ver, 4) // due section 3.4.1
+	}
+	for _, key := range seg.keys() {
+		if err := key.Validate(); err != nil {
+			return err
+		}
+		version(&b.ver, keyVersion(key))
+	}
+	if seg.Map != nil {
+		if seg.Map.URI == "" {
+			return errors.New("EXT-X-MAP without URI")
+		}
+		if seg.Map.Limit < 0 || seg.Map.Offset < 0 {
+			return fmt.Errorf("invalid EXT-X-MAP byte range %d@%d", seg.Map.Limit, seg.Map.Offset)
+		}
+		version(&b.ver, 5) // due section 4
+	}
+	for _, scte := range seg.SCTE {
+		if scte.Cue == "" && scte.CueType != SCTE35Cue_End {
+			return errors.New("SCTE-35 without cue")
+		}
+	}
+	for name := range seg.Custom {
+		if !strings.HasPrefix(name, "#") {
+			return fmt.Errorf("custom tag %q doesn't start with #", name)
+		}
+	}
+	return nil
+}
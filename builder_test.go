@@ -0,0 +1,63 @@
+/*
+ Package m3u8. Media segment builder tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentBuilder(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	err := NewSegmentBuilder().
+		URI("media.mp4").
+		Duration(6).
+		Range(1000, 2000).
+		Map("init.mp4", 0, 0).
+		Key("AES-128", "key.bin", "", "", "").
+		AppendTo(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != 5 {
+		t.Errorf("Expected version 5 for EXT-X-MAP, got %d", p.Version())
+	}
+	if out := p.String(); !strings.Contains(out, "#EXT-X-BYTERANGE:1000@2000\n") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+}
+
+func TestSegmentBuilderValidation(t *testing.T) {
+	cases := []struct {
+		name    string
+		builder *SegmentBuilder
+	}{
+		{"no URI", NewSegmentBuilder().Duration(6)},
+		{"no duration", NewSegmentBuilder().URI("a.ts")},
+		{"negative duration", NewSegmentBuilder().URI("a.ts").Duration(-1).Gap()},
+		{"empty byte range", NewSegmentBuilder().URI("a.ts").Duration(6).Range(0, 100)},
+		{"key without URI", NewSegmentBuilder().URI("a.ts").Duration(6).Key("AES-128", "", "", "", "")},
+		{"NONE key with URI", NewSegmentBuilder().URI("a.ts").Duration(6).Key("NONE", "key.bin", "", "", "")},
+		{"map without URI", NewSegmentBuilder().URI("a.ts").Duration(6).Map("", 0, 0)},
+		{"URI with line break", NewSegmentBuilder().URI("a.ts\n#EXT-X-ENDLIST").Duration(6)},
+	}
+	for _, c := range cases {
+		if _, err := c.builder.Build(); err == nil {
+			t.Errorf("%s: expected validation error", c.name)
+		}
+	}
+	b := NewSegmentBuilder().URI("missing").Gap()
+	if _, err := b.Build(); err != nil {
+		t.Errorf("Gap segment doesn't require duration: %v", err)
+	}
+	if b.Version() != 8 {
+		t.Errorf("Expected version 8 for gap segment, got %d", b.Version())
+	}
+}
@@ -0,0 +1,353 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines functions for packing a tree of playlists into a single
+ archive or a multipart response and for unpacking it back.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BundleFormat defines the archive format used for playlist bundles.
+type BundleFormat uint
+
+const (
+	BundleZip       BundleFormat = iota // BundleZip stores the bundle as a zip archive
+	BundleTar                           // BundleTar stores the bundle as an uncompressed tar archive
+	BundleMultipart                     // BundleMultipart stores the bundle as a multipart/mixed body delivered in a single response
+)
+
+// BundleMaster is the name of the master playlist entry inside of a bundle.
+const BundleMaster = "master.m3u8"
+
+// BundlePath converts an URI found in a playlist to the name of the bundle
+// entry which keeps the referenced data. Query strings and schemes are
+// dropped, so "http://cdn.example.com/hi/index.m3u8?t=1" is stored as
+// "hi/index.m3u8".
+func BundlePath(uri string) string {
+	if u, err := url.Parse(uri); err == nil {
+		uri = u.Path
+	}
+	uri = path.Clean("/" + uri)
+	return strings.TrimPrefix(uri, "/")
+}
+
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// ExportBundle writes the master playlist with all the media playlists
+// linked to its variants (Variant.Chunklist) and renditions
+// (Alternative.Chunklist) into a single archive. Files
+// holds optional additional entries, such as keys or init segments, keyed by
+// their URIs.
+func ExportBundle(w io.Writer, format BundleFormat, master *MasterPlaylist, files map[string][]byte) error {
+	entries, err := bundleEntries(master, files)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case BundleZip:
+		zw := zip.NewWriter(w)
+		for _, e := range entries {
+			f, err := zw.Create(e.name)
+			if err != nil {
+				return err
+			}
+			if _, err = f.Write(e.data); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	case BundleTar:
+		tw := tar.NewWriter(w)
+		for _, e := range entries {
+			hdr := &tar.Header{
+				Name:    e.name,
+				Mode:    0644,
+				Size:    int64(len(e.data)),
+				ModTime: time.Unix(0, 0),
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(e.data); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	case BundleMultipart:
+		_, err = writeMultipartBundle(w, entries)
+		return err
+	}
+	return errors.New("unknown bundle format")
+}
+
+// ExportMultipartBundle writes the bundle as a multipart/mixed body and
+// returns the Content-Type of the response. The boundary is chosen at
+// random for each bundle. Parts are named by their Content-Location
+// headers, the master playlist comes first.
+func ExportMultipartBundle(w io.Writer, master *MasterPlaylist, files map[string][]byte) (string, error) {
+	entries, err := bundleEntries(master, files)
+	if err != nil {
+		return "", err
+	}
+	return writeMultipartBundle(w, entries)
+}
+
+// bundleEntries collects the master playlist, the linked media playlists
+// and the additional files in the order they are written to a bundle.
+func bundleEntries(master *MasterPlaylist, files map[string][]byte) ([]bundleEntry, error) {
+	if master == nil {
+		return nil, errors.New("master playlist is nil")
+	}
+	entries := []bundleEntry{{BundleMaster, master.Encode().Bytes()}}
+	written := map[string]bool{BundleMaster: true}
+	playlist := func(kind, uri string, p *MediaPlaylist) error {
+		if p == nil {
+			return nil
+		}
+		name := BundlePath(uri)
+		if name == "" || name == "." {
+			return fmt.Errorf("%s URI %q can't be used as bundle entry", kind, uri)
+		}
+		if !written[name] {
+			written[name] = true
+			entries = append(entries, bundleEntry{name, p.Encode().Bytes()})
+		}
+		return nil
+	}
+	for _, v := range master.Variants {
+		if err := playlist("variant", v.URI, v.Chunklist); err != nil {
+			return nil, err
+		}
+	}
+	// renditions of EXT-X-MEDIA go after variants as the encoder writes them
+	for _, v := range master.Variants {
+		for _, alt := range v.Alternatives {
+			if err := playlist("rendition", alt.URI, alt.Chunklist); err != nil {
+				return nil, err
+			}
+		}
+	}
+	names := make([]string, 0, len(files))
+	for uri := range files {
+		names = append(names, uri)
+	}
+	sort.Strings(names)
+	for _, uri := range names {
+		name := BundlePath(uri)
+		if written[name] {
+			return nil, fmt.Errorf("duplicate bundle entry %q", name)
+		}
+		written[name] = true
+		entries = append(entries, bundleEntry{name, files[uri]})
+	}
+	return entries, nil
+}
+
+// writeMultipartBundle writes entries as parts of a multipart/mixed body
+// with a random boundary and returns the Content-Type of the body.
+func writeMultipartBundle(w io.Writer, entries []bundleEntry) (string, error) {
+	mw := multipart.NewWriter(w)
+	for _, e := range entries {
+		contentType := "application/octet-stream"
+		if strings.HasSuffix(e.name, ".m3u8") {
+			contentType = "application/vnd.apple.mpegurl"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Location", e.name)
+		f, err := mw.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err = f.Write(e.data); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	return mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}), nil
+}
+
+// ImportBundle reads an archive created by ExportBundle. It decodes the
+// master playlist, links decoded media playlists to the variants referencing
+// them and returns all other entries keyed by their names in the bundle.
+// For BundleMultipart the boundary is taken from the first line of the body.
+func ImportBundle(r io.Reader, format BundleFormat, strict bool) (*MasterPlaylist, map[string][]byte, error) {
+	files := make(map[string][]byte)
+	switch format {
+	case BundleZip:
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			files[f.Name], err = ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	case BundleTar:
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if files[hdr.Name], err = ioutil.ReadAll(tr); err != nil {
+				return nil, nil, err
+			}
+		}
+	case BundleMultipart:
+		br := bufio.NewReader(r)
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		boundary := strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(boundary, "--") || len(boundary) == 2 {
+			return nil, nil, errors.New("multipart bundle doesn't start with a boundary")
+		}
+		contentType := mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": boundary[2:]})
+		return ImportMultipartBundle(io.MultiReader(strings.NewReader(line), br), contentType, strict)
+	default:
+		return nil, nil, errors.New("unknown bundle format")
+	}
+	return importBundleFiles(files, strict)
+}
+
+// ImportMultipartBundle reads a multipart response which delivers the
+// master playlist together with its media playlists. The boundary is taken
+// from the Content-Type of the response. The first part is the master
+// playlist, the other parts are named by their Content-Location headers
+// relative to the location of the master playlist.
+func ImportMultipartBundle(r io.Reader, contentType string, strict bool) (*MasterPlaylist, map[string][]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return nil, nil, fmt.Errorf("%s is not a multipart content type", mediaType)
+	}
+	files := make(map[string][]byte)
+	first, dir := true, "."
+	mr := multipart.NewReader(r, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		location := BundlePath(part.Header.Get("Content-Location"))
+		name := BundleMaster
+		if first {
+			dir = path.Dir(location)
+			first = false
+		} else if location == "" {
+			return nil, nil, errors.New("part without Content-Location")
+		} else if name = location; dir != "." {
+			name = strings.TrimPrefix(location, dir+"/")
+		}
+		if files[name], err = ioutil.ReadAll(part); err != nil {
+			return nil, nil, err
+		}
+	}
+	return importBundleFiles(files, strict)
+}
+
+// importBundleFiles decodes the master playlist and the media playlists
+// of a bundle and links them.
+func importBundleFiles(files map[string][]byte, strict bool) (*MasterPlaylist, map[string][]byte, error) {
+	data, ok := files[BundleMaster]
+	if !ok {
+		return nil, nil, fmt.Errorf("bundle has no %s entry", BundleMaster)
+	}
+	delete(files, BundleMaster)
+	master := NewMasterPlaylist()
+	if err := master.Decode(*bytes.NewBuffer(data), strict); err != nil {
+		return nil, nil, err
+	}
+
+	decoded := make(map[string]*MediaPlaylist)
+	playlist := func(uri string) (*MediaPlaylist, error) {
+		name := BundlePath(uri)
+		if media, ok := decoded[name]; ok {
+			return media, nil
+		}
+		data, ok := files[name]
+		if !ok {
+			return nil, nil
+		}
+		p, listType, err := Decode(*bytes.NewBuffer(data), strict)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		if listType != MEDIA {
+			return nil, fmt.Errorf("%s: not a media playlist", name)
+		}
+		decoded[name] = p.(*MediaPlaylist)
+		return decoded[name], nil
+	}
+	var err error
+	for _, v := range master.Variants {
+		if v.Chunklist, err = playlist(v.URI); err != nil {
+			return nil, nil, err
+		}
+		for _, alt := range v.Alternatives {
+			if alt.URI == "" {
+				continue
+			}
+			if alt.Chunklist, err = playlist(alt.URI); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for name := range decoded {
+		delete(files, name)
+	}
+	return master, files, nil
+}
@@ -0,0 +1,184 @@
+/*
+ Package m3u8. Playlist bundle tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBundlePath(t *testing.T) {
+	cases := map[string]string{
+		"low/index.m3u8":                       "low/index.m3u8",
+		"/hi.m3u8?token=1":                     "hi.m3u8",
+		"http://cdn.example.com/a/../b/c.m3u8": "b/c.m3u8",
+		"../../etc/passwd":                     "etc/passwd",
+	}
+	for in, expected := range cases {
+		if got := BundlePath(in); got != expected {
+			t.Errorf("BundlePath(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	for _, format := range []BundleFormat{BundleZip, BundleTar, BundleMultipart} {
+		m := NewMasterPlaylist()
+		for i, name := range []string{"low", "hi"} {
+			p, _ := NewMediaPlaylist(0, 3)
+			for j := 0; j < 3; j++ {
+				p.Append(fmt.Sprintf("%s/seg%d.ts", name, j), 6, "")
+			}
+			p.Close()
+			m.Append(name+"/index.m3u8", p, VariantParams{Bandwidth: uint32(1000000 * (i + 1))})
+		}
+		// the same chunklist referenced twice must be stored only once
+		m.Append("hi/index.m3u8", m.Variants[1].Chunklist, VariantParams{Bandwidth: 2500000})
+
+		var buf bytes.Buffer
+		err := ExportBundle(&buf, format, m, map[string][]byte{"keys/key.bin": []byte("0123456789abcdef")})
+		if err != nil {
+			t.Fatalf("Export bundle failed: %s", err)
+		}
+		got, files, err := ImportBundle(&buf, format, true)
+		if err != nil {
+			t.Fatalf("Import bundle failed: %s", err)
+		}
+		if len(got.Variants) != 3 {
+			t.Fatalf("Expected 3 variants, got %d", len(got.Variants))
+		}
+		for i, v := range got.Variants {
+			if v.Chunklist == nil {
+				t.Fatalf("Variant %d has no linked chunklist", i)
+			}
+			if v.Chunklist.String() != m.Variants[i].Chunklist.String() {
+				t.Errorf("Variant %d chunklist differs:\n%s", i, v.Chunklist)
+			}
+		}
+		if got.Variants[1].Chunklist != got.Variants[2].Chunklist {
+			t.Error("Variants sharing an URI must share a chunklist")
+		}
+		if len(files) != 1 || string(files["keys/key.bin"]) != "0123456789abcdef" {
+			t.Errorf("Unexpected extra files: %v", files)
+		}
+	}
+}
+
+func TestBundleRenditions(t *testing.T) {
+	media := func(prefix string) *MediaPlaylist {
+		p, _ := NewMediaPlaylist(0, 2)
+		p.Append(prefix+"/seg0.ts", 6, "")
+		p.Append(prefix+"/seg1.ts", 6, "")
+		p.Close()
+		return p
+	}
+	audio := []*Alternative{
+		{GroupId: "aac", Type: "AUDIO", Name: "English", Language: "en", Default: true, URI: "audio/en/index.m3u8", Chunklist: media("audio/en")},
+		{GroupId: "aac", Type: "AUDIO", Name: "Deutsch", Language: "de", URI: "audio/de/index.m3u8", Chunklist: media("audio/de")},
+	}
+	m := NewMasterPlaylist()
+	m.Append("low/index.m3u8", media("low"), VariantParams{Bandwidth: 1000000, Audio: "aac", Alternatives: audio})
+	m.Append("hi/index.m3u8", media("hi"), VariantParams{Bandwidth: 2000000, Audio: "aac", Alternatives: audio})
+
+	for _, format := range []BundleFormat{BundleZip, BundleTar, BundleMultipart} {
+		var buf bytes.Buffer
+		if err := ExportBundle(&buf, format, m, nil); err != nil {
+			t.Fatalf("Export bundle failed: %s", err)
+		}
+		got, files, err := ImportBundle(&buf, format, true)
+		if err != nil {
+			t.Fatalf("Import bundle failed: %s", err)
+		}
+		if len(files) != 0 {
+			t.Errorf("Unexpected extra files: %v", files)
+		}
+		var alts []*Alternative
+		for _, v := range got.Variants {
+			alts = append(alts, v.Alternatives...)
+		}
+		if len(alts) != 2 {
+			t.Fatalf("Expected 2 renditions, got %d", len(alts))
+		}
+		for i, alt := range alts {
+			if alt.Chunklist == nil {
+				t.Fatalf("Rendition %s has no linked playlist", alt.URI)
+			}
+			if alt.Chunklist.String() != audio[i].Chunklist.String() {
+				t.Errorf("Rendition %s playlist differs:\n%s", alt.URI, alt.Chunklist)
+			}
+		}
+	}
+}
+
+func TestImportBundleWithoutMaster(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMasterPlaylist()
+	if err := ExportBundle(&buf, BundleTar, m, map[string][]byte{BundleMaster: nil}); err == nil {
+		t.Error("Expected error on duplicate master entry")
+	}
+	buf.Reset()
+	if _, _, err := ImportBundle(&buf, BundleZip, false); err == nil {
+		t.Error("Expected error on empty bundle")
+	}
+}
+
+func TestImportMultipartBundle(t *testing.T) {
+	body := "--b\r\n" +
+		"Content-Type: application/vnd.apple.mpegurl\r\n" +
+		"Content-Location: /vod/main.m3u8\r\n\r\n" +
+		"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000\nlow/index.m3u8\n\r\n" +
+		"--b\r\n" +
+		"Content-Location: https://cdn.example.com/vod/low/index.m3u8\r\n\r\n" +
+		"#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6,\nseg0.ts\n#EXT-X-ENDLIST\n\r\n" +
+		"--b--\r\n"
+	m, files, err := ImportMultipartBundle(strings.NewReader(body), `multipart/mixed; boundary="b"`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Variants[0].Chunklist; p == nil || p.Count() != 1 {
+		t.Fatalf("Expected linked media playlist, got %v", p)
+	}
+	if len(files) != 0 {
+		t.Errorf("Unexpected extra files: %v", files)
+	}
+	if _, _, err = ImportMultipartBundle(strings.NewReader(body), "application/vnd.apple.mpegurl", true); err == nil {
+		t.Error("Expected error for content type which is not multipart")
+	}
+}
+
+func TestExportMultipartBundleBoundary(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("low/index.m3u8", nil, VariantParams{Bandwidth: 1000000})
+	files := map[string][]byte{"keys/key.bin": []byte("\r\n--m3u8-bundle\r\n")}
+	var first, second bytes.Buffer
+	ct1, err := ExportMultipartBundle(&first, m, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct2, err := ExportMultipartBundle(&second, m, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct1 == ct2 {
+		t.Errorf("Expected a random boundary per bundle, got %s twice", ct1)
+	}
+	_, got, err := ImportMultipartBundle(&first, ct1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got["keys/key.bin"]) != "\r\n--m3u8-bundle\r\n" {
+		t.Errorf("Unexpected key file: %q", got["keys/key.bin"])
+	}
+	if _, got, err = ImportBundle(&second, BundleMultipart, true); err != nil || string(got["keys/key.bin"]) != "\r\n--m3u8-bundle\r\n" {
+		t.Errorf("Unexpected key file: %q, %v", got["keys/key.bin"], err)
+	}
+}
@@ -0,0 +1,140 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines canonical representation of playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var reDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Tags with attribute lists which attributes are sorted by canonicalization.
+var canonicalAttrTags = map[string]bool{
+	"#EXT-X-KEY":                true,
+	"#EXT-X-SESSION-KEY":        true,
+	"#EXT-X-MAP":                true,
+	"#EXT-X-MEDIA":              true,
+	"#EXT-X-STREAM-INF":         true,
+	"#EXT-X-I-FRAME-STREAM-INF": true,
+	"#EXT-X-IMAGE-STREAM-INF":   true,
+	"#EXT-X-SESSION-DATA":       true,
+	"#EXT-X-DATERANGE":          true,
+	"#EXT-X-START":              true,
+	"#EXT-X-PART":               true,
+	"#EXT-X-PART-INF":           true,
+	"#EXT-X-SERVER-CONTROL":     true,
+	"#EXT-X-PRELOAD-HINT":       true,
+	"#EXT-X-RENDITION-REPORT":   true,
+	"#EXT-X-SKIP":               true,
+	"#EXT-X-DEFINE":             true,
+	"#EXT-X-CONTENT-STEERING":   true,
+	"#EXT-SCTE35":               true,
+	"#EXT-X-CUE-OUT-CONT":       true,
+}
+
+// Tags with a single integer value.
+var canonicalIntTags = map[string]bool{
+	"#EXT-X-VERSION":                true,
+	"#EXT-X-TARGETDURATION":         true,
+	"#EXT-X-MEDIA-SEQUENCE":         true,
+	"#EXT-X-DISCONTINUITY-SEQUENCE": true,
+}
+
+// Canonicalize converts an encoded playlist to its canonical form. Two
+// playlists which differ only cosmetically (line endings, blank lines and
+// comments, order of attributes, formatting of numbers and dates) have the
+// same canonical form, so it is suitable for computing signatures and
+// content-addressed caching of manifests. The order of tags is preserved as
+// it is significant.
+func Canonicalize(data []byte) []byte {
+	var out bytes.Buffer
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	data = bytes.Replace(data, []byte("\r"), []byte("\n"), -1)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case !strings.HasPrefix(line, "#"):
+			out.WriteString(line)
+		case !strings.HasPrefix(line, "#EXT"):
+			continue // comment
+		default:
+			out.WriteString(canonicalTag(line))
+		}
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// Canonicalize returns the canonical form of the encoded master playlist.
+// See Canonicalize function for details.
+func (p *MasterPlaylist) Canonicalize() []byte {
+	return Canonicalize(p.Encode().Bytes())
+}
+
+// Canonicalize returns the canonical form of the encoded media playlist.
+// See Canonicalize function for details.
+func (p *MediaPlaylist) Canonicalize() []byte {
+	return Canonicalize(p.Encode().Bytes())
+}
+
+func canonicalTag(line string) string {
+	sep := strings.IndexByte(line, ':')
+	if sep == -1 {
+		return line
+	}
+	tag, value := line[:sep], line[sep+1:]
+	switch {
+	case tag == "#EXTINF":
+		title := ""
+		if comma := strings.IndexByte(value, ','); comma != -1 {
+			value, title = value[:comma], value[comma+1:]
+		}
+		return tag + ":" + canonicalNumber(strings.TrimSpace(value)) + "," + title
+	case tag == "#EXT-X-PROGRAM-DATE-TIME":
+		if t, err := TimeParse(value); err == nil {
+			return tag + ":" + t.UTC().Format(time.RFC3339Nano)
+		}
+	case canonicalIntTags[tag]:
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return tag + ":" + strconv.FormatUint(n, 10)
+		}
+	case canonicalAttrTags[tag]:
+		attrs := decodeAttributes(value)
+		sort.SliceStable(attrs, func(i, j int) bool { return attrs[i][0] < attrs[j][0] })
+		parts := make([]string, len(attrs))
+		for i, kv := range attrs {
+			parts[i] = kv[0] + "=" + canonicalNumber(kv[1])
+		}
+		return tag + ":" + strings.Join(parts, ",")
+	}
+	return line
+}
+
+// canonicalNumber formats decimal numbers in the shortest form, other
+// values are returned unchanged.
+func canonicalNumber(value string) string {
+	if !reDecimal.MatchString(value) {
+		return value
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,48 @@
+/*
+ Package m3u8. Playlist canonicalization tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	a := "#EXTM3U\r\n#EXT-X-VERSION:03\r\n#EXT-X-TARGETDURATION:6\r\n\r\n" +
+		"# packager comment\r\n" +
+		"#EXT-X-KEY:URI=\"key,1\",METHOD=AES-128\r\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2019-02-14T04:13:36.100+02:00\r\n" +
+		"#EXTINF:6.000,title, with comma\r\nseg0.ts\r\n"
+	b := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key,1\"\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2019-02-14T02:13:36.1Z\n" +
+		"#EXTINF:6,title, with comma\nseg0.ts"
+	ca, cb := Canonicalize([]byte(a)), Canonicalize([]byte(b))
+	if !bytes.Equal(ca, cb) {
+		t.Fatalf("Canonical forms differ:\n%s\n%s", ca, cb)
+	}
+	expected := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key,1\"\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2019-02-14T02:13:36.1Z\n" +
+		"#EXTINF:6,title, with comma\nseg0.ts\n"
+	if string(ca) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, ca)
+	}
+}
+
+func TestCanonicalizeMasterPlaylist(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, Resolution: "1920x1080", FrameRate: 25})
+	expected := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=3000000,FRAME-RATE=25,PROGRAM-ID=0,RESOLUTION=1920x1080\nhi.m3u8\n"
+	if out := string(m.Canonicalize()); out != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
@@ -0,0 +1,117 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines clipping of media playlists by time range for clip
+ and highlight generation.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"time"
+)
+
+// SubPlaylist returns a closed playlist of the segments overlapping the
+// time range counted from the start of the first segment. The result keeps
+// media sequence numbers of the source and starts with the keys and the
+// map in effect for its first segment. If the range starts inside of a
+// segment the offset is signalled with EXT-X-START PRECISE=YES. Segments
+// are shared with the source, except the first one.
+func (p *MediaPlaylist) SubPlaylist(start, end time.Duration) (*MediaPlaylist, error) {
+	if start < 0 || end <= start {
+		return nil, errors.New("invalid time range")
+	}
+	first, last := -1, -1
+	var offset, pos time.Duration
+	for i := uint(0); i < p.count; i++ {
+		d := seconds(p.segmentAt(i).Duration)
+		if pos < end && pos+d > start {
+			if first < 0 {
+				first, offset = int(i), start-pos
+			}
+			last = int(i)
+		}
+		pos += d
+	}
+	if first < 0 {
+		return nil, errors.New("no segments in the time range")
+	}
+
+	s, err := NewMediaPlaylist(0, uint(last-first+1))
+	if err != nil {
+		return nil, err
+	}
+	s.ver = p.ver
+	s.TargetDuration = p.TargetDuration
+	s.MediaType = p.MediaType
+	s.Map = p.Map
+	s.SeqNo = p.segmentAt(uint(first)).SeqId
+	s.DiscontinuitySeq = p.DiscontinuitySeq
+	for i := 1; i <= first; i++ {
+		if p.segmentAt(uint(i)).Discontinuity {
+			s.DiscontinuitySeq++
+		}
+	}
+	if offset > 0 {
+		s.StartTime = offset.Seconds()
+		s.StartTimePrecise = true
+	}
+	for i := first; i <= last; i++ {
+		seg := p.segmentAt(uint(i))
+		if i == first {
+			copied := *seg
+			copied.Discontinuity = false
+			p.inheritStateAt(uint(i), &copied)
+			seg = &copied
+		}
+		if err = s.appendGrow(seg); err != nil {
+			return nil, err
+		}
+	}
+	s.Close()
+	return s, nil
+}
+
+// inheritStateAt sets the keys and the map in effect for the i-th segment
+// to the copy of the segment which begins a derived playlist.
+func (p *MediaPlaylist) inheritStateAt(i uint, seg *MediaSegment) {
+	if keys := p.keysAt(i); len(seg.keys()) == 0 && len(keys) > 0 {
+		seg.Key, seg.Keys = keys[0], nil
+		if len(keys) > 1 {
+			seg.Keys = keys
+		}
+	}
+	if seg.Map == nil && p.Map == nil {
+		seg.Map = p.mapAt(i)
+	}
+}
+
+// keysAt returns the keys in effect for the i-th segment, its own keys or
+// the keys of a previous segment or the default key.
+func (p *MediaPlaylist) keysAt(i uint) []*Key {
+	for j := int(i); j >= 0; j-- {
+		if keys := p.segmentAt(uint(j)).keys(); len(keys) > 0 {
+			return keys
+		}
+	}
+	if p.Key != nil {
+		return []*Key{p.Key}
+	}
+	return nil
+}
+
+// mapAt returns the map in effect for the i-th segment.
+func (p *MediaPlaylist) mapAt(i uint) *Map {
+	for j := int(i); j >= 0; j-- {
+		if m := p.segmentAt(uint(j)).Map; m != nil {
+			return m
+		}
+	}
+	return p.Map
+}
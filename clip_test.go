@@ -0,0 +1,59 @@
+/*
+ Package m3u8. Time range clipping tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSubPlaylist(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 6)
+	p.SeqNo = 10
+	for i := 0; i < 6; i++ {
+		p.Append(fmt.Sprintf("seg%d.ts", i), 4, "")
+		if i == 1 {
+			p.SetKey("AES-128", "key1", "", "", "")
+		}
+	}
+	p.Close()
+
+	s, err := p.SubPlaylist(10*time.Second, 17*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-MEDIA-SEQUENCE:12
+#EXT-X-TARGETDURATION:4
+#EXT-X-START:TIME-OFFSET=2,PRECISE=YES
+#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXTINF:4.000,
+seg2.ts
+#EXTINF:4.000,
+seg3.ts
+#EXTINF:4.000,
+seg4.ts
+#EXT-X-ENDLIST
+`
+	if out := s.String(); out != expected {
+		t.Errorf("Unexpected clip:\n%s", out)
+	}
+	if p.Segments[2].Key != nil {
+		t.Error("Segments of the source must not be changed")
+	}
+
+	for _, r := range [][2]time.Duration{{24 * time.Second, 30 * time.Second}, {5 * time.Second, 5 * time.Second}, {-time.Second, time.Second}} {
+		if _, err = p.SubPlaylist(r[0], r[1]); err == nil {
+			t.Errorf("Expected error for range %v-%v", r[0], r[1])
+		}
+	}
+}
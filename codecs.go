@@ -0,0 +1,194 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines parsing and composing of RFC 6381 codec strings of
+ CODECS attribute.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Codec is a single codec of CODECS attribute, e.g. avc1.64001f or
+// mp4a.40.2. Parameters of AVC, HEVC, AAC, AV1, VP9 and Dolby Vision
+// codecs are decoded into the typed fields, parameters of other codecs
+// (e.g. ec-3 or stpp.ttml.im1t) are kept in Extra as they are.
+type Codec struct {
+	Name        string   // sample entry type, e.g. avc1, hvc1, mp4a, ec-3
+	ObjectType  int      // object type indication of mp4a, e.g. 0x40 for MPEG-4 audio
+	Profile     int      // profile_idc of AVC and HEVC, audio object type of AAC, profile of AV1, VP9 and Dolby Vision
+	Constraints int      // constraint_set flags of AVC
+	Level       int      // level_idc of AVC and HEVC, level of AV1, VP9 and Dolby Vision
+	Tier        string   // tier of HEVC (L or H) and AV1 (M or H)
+	BitDepth    int      // bit depth of AV1 and VP9
+	Extra       []string // other parameters, e.g. compatibility and constraint flags of HEVC
+}
+
+// ParseCodecs parses the comma separated list of CODECS attribute.
+func ParseCodecs(codecs string) ([]Codec, error) {
+	var out []Codec
+	for _, s := range strings.Split(codecs, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		codec, err := ParseCodec(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, codec)
+	}
+	return out, nil
+}
+
+// FormatCodecs composes CODECS attribute from the codecs.
+func FormatCodecs(codecs ...Codec) string {
+	out := make([]string, len(codecs))
+	for i, codec := range codecs {
+		out[i] = codec.String()
+	}
+	return strings.Join(out, ",")
+}
+
+// ParseCodec parses a single codec string.
+func ParseCodec(s string) (Codec, error) {
+	params := strings.Split(strings.TrimSpace(s), ".")
+	c := Codec{Name: params[0]}
+	params = params[1:]
+	if c.Name == "" {
+		return c, fmt.Errorf("invalid codec %q", s)
+	}
+	var err error
+	switch c.Name {
+	case "avc1", "avc3":
+		// avc1.PPCCLL, profile, constraints and level in hex
+		var v uint64
+		if len(params) != 1 || len(params[0]) != 6 {
+			return c, fmt.Errorf("invalid AVC codec %q", s)
+		}
+		if v, err = strconv.ParseUint(params[0], 16, 32); err != nil {
+			return c, fmt.Errorf("invalid AVC codec %q: %s", s, err)
+		}
+		c.Profile, c.Constraints, c.Level = int(v>>16), int(v>>8&0xff), int(v&0xff)
+	case "hvc1", "hev1":
+		// hvc1.P.C.TL[.constraints], profile space prefixes of the profile
+		// are not supported
+		if len(params) < 3 || len(params[2]) < 2 {
+			return c, fmt.Errorf("invalid HEVC codec %q", s)
+		}
+		if c.Profile, err = strconv.Atoi(params[0]); err != nil {
+			return c, fmt.Errorf("invalid HEVC codec %q: %s", s, err)
+		}
+		c.Tier = params[2][:1]
+		if c.Level, err = strconv.Atoi(params[2][1:]); err != nil || (c.Tier != "L" && c.Tier != "H") {
+			return c, fmt.Errorf("invalid HEVC codec %q", s)
+		}
+		c.Extra = append([]string{params[1]}, params[3:]...)
+	case "mp4a":
+		// mp4a.OO[.A], object type in hex, audio object type in decimal
+		var v uint64
+		if len(params) < 1 || len(params) > 2 {
+			return c, fmt.Errorf("invalid MP4A codec %q", s)
+		}
+		if v, err = strconv.ParseUint(params[0], 16, 8); err != nil {
+			return c, fmt.Errorf("invalid MP4A codec %q: %s", s, err)
+		}
+		c.ObjectType = int(v)
+		if len(params) == 2 {
+			if c.Profile, err = strconv.Atoi(params[1]); err != nil {
+				return c, fmt.Errorf("invalid MP4A codec %q: %s", s, err)
+			}
+		}
+	case "av01":
+		// av01.P.LLT.DD[.optional parameters]
+		if len(params) < 3 || len(params[1]) != 3 {
+			return c, fmt.Errorf("invalid AV1 codec %q", s)
+		}
+		c.Tier = params[1][2:]
+		if c.Profile, err = strconv.Atoi(params[0]); err == nil {
+			if c.Level, err = strconv.Atoi(params[1][:2]); err == nil {
+				c.BitDepth, err = strconv.Atoi(params[2])
+			}
+		}
+		if err != nil || (c.Tier != "M" && c.Tier != "H") {
+			return c, fmt.Errorf("invalid AV1 codec %q", s)
+		}
+		c.Extra = params[3:]
+	case "vp09":
+		// vp09.PP.LL.DD[.optional parameters]
+		if len(params) < 3 {
+			return c, fmt.Errorf("invalid VP9 codec %q", s)
+		}
+		if c.Profile, err = strconv.Atoi(params[0]); err == nil {
+			if c.Level, err = strconv.Atoi(params[1]); err == nil {
+				c.BitDepth, err = strconv.Atoi(params[2])
+			}
+		}
+		if err != nil {
+			return c, fmt.Errorf("invalid VP9 codec %q: %s", s, err)
+		}
+		c.Extra = params[3:]
+	case "dvh1", "dvhe", "dva1", "dvav", "dav1":
+		// dvh1.PP.LL
+		if len(params) != 2 {
+			return c, fmt.Errorf("invalid Dolby Vision codec %q", s)
+		}
+		if c.Profile, err = strconv.Atoi(params[0]); err == nil {
+			c.Level, err = strconv.Atoi(params[1])
+		}
+		if err != nil {
+			return c, fmt.Errorf("invalid Dolby Vision codec %q: %s", s, err)
+		}
+	default:
+		c.Extra = params
+	}
+	if len(c.Extra) == 0 {
+		c.Extra = nil
+	}
+	return c, nil
+}
+
+// String composes the codec string.
+func (c Codec) String() string {
+	var s string
+	switch c.Name {
+	case "avc1", "avc3":
+		s = fmt.Sprintf("%s.%02x%02x%02x", c.Name, c.Profile, c.Constraints, c.Level)
+	case "hvc1", "hev1":
+		compatibility := "0"
+		if len(c.Extra) > 0 {
+			compatibility = c.Extra[0]
+		}
+		s = fmt.Sprintf("%s.%d.%s.%s%d", c.Name, c.Profile, compatibility, c.Tier, c.Level)
+		if len(c.Extra) > 1 {
+			s += "." + strings.Join(c.Extra[1:], ".")
+		}
+		return s
+	case "mp4a":
+		s = fmt.Sprintf("%s.%02X", c.Name, c.ObjectType)
+		if c.Profile != 0 {
+			s += "." + strconv.Itoa(c.Profile)
+		}
+		return s
+	case "av01":
+		s = fmt.Sprintf("%s.%d.%02d%s.%02d", c.Name, c.Profile, c.Level, c.Tier, c.BitDepth)
+	case "vp09":
+		s = fmt.Sprintf("%s.%02d.%02d.%02d", c.Name, c.Profile, c.Level, c.BitDepth)
+	case "dvh1", "dvhe", "dva1", "dvav", "dav1":
+		return fmt.Sprintf("%s.%02d.%02d", c.Name, c.Profile, c.Level)
+	default:
+		s = c.Name
+	}
+	if len(c.Extra) > 0 {
+		s += "." + strings.Join(c.Extra, ".")
+	}
+	return s
+}
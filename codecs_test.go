@@ -0,0 +1,55 @@
+/*
+ Package m3u8. Codec strings tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCodecs(t *testing.T) {
+	codecs := "avc1.64001f,hvc1.2.4.L153.B0,mp4a.40.2,mp4a.6B,ec-3,av01.0.04M.10.0.112,vp09.00.10.08,dvh1.05.06,stpp.ttml.im1t"
+	parsed, err := ParseCodecs(codecs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Codec{
+		{Name: "avc1", Profile: 100, Level: 31},
+		{Name: "hvc1", Profile: 2, Tier: "L", Level: 153, Extra: []string{"4", "B0"}},
+		{Name: "mp4a", ObjectType: 0x40, Profile: 2},
+		{Name: "mp4a", ObjectType: 0x6b},
+		{Name: "ec-3"},
+		{Name: "av01", Profile: 0, Level: 4, Tier: "M", BitDepth: 10, Extra: []string{"0", "112"}},
+		{Name: "vp09", Profile: 0, Level: 10, BitDepth: 8},
+		{Name: "dvh1", Profile: 5, Level: 6},
+		{Name: "stpp", Extra: []string{"ttml", "im1t"}},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Unexpected codecs\ngot: %+v\nexp: %+v", parsed, expected)
+	}
+	if out := FormatCodecs(parsed...); out != codecs {
+		t.Errorf("Expected %s, got %s", codecs, out)
+	}
+}
+
+func TestComposeCodecs(t *testing.T) {
+	out := FormatCodecs(Codec{Name: "avc1", Profile: 77, Constraints: 0x40, Level: 30}, Codec{Name: "mp4a", ObjectType: 0x40, Profile: 5})
+	if out != "avc1.4d401e,mp4a.40.5" {
+		t.Errorf("Unexpected codecs %s", out)
+	}
+}
+
+func TestParseInvalidCodecs(t *testing.T) {
+	for _, codec := range []string{"avc1.64001", "avc1.zz001f", "hvc1.2.4.X153", "hvc1.A2.4.L153", "mp4a.40.x", "av01.0.04X.10", "vp09.00", "dvh1.05", ""} {
+		if _, err := ParseCodec(codec); err == nil {
+			t.Errorf("Expected error for %q", codec)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines concatenation of media playlists, e.g. for stitching
+ of programs or ad pods.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"math"
+)
+
+// AppendPlaylist appends all segments of the other playlist after the
+// segments of the playlist. The first appended segment starts with
+// EXT-X-DISCONTINUITY and carries the keys and the map in effect in the
+// other playlist, so encryption and initialization of the playlist don't
+// leak into the appended segments. The default map of the playlist is
+// moved to its first segment if the other playlist uses another map.
+// Segments are copied, the other playlist is not changed. A frozen target
+// duration of the playlist is kept, the other playlist is refused if its
+// segments are longer.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendPlaylist(other *MediaPlaylist) error {
+	segments := make([]*MediaSegment, 0, other.count)
+	for i := uint(0); i < other.count; i++ {
+		seg := *other.segmentAt(i)
+		segments = append(segments, &seg)
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	if p.frozenTarget {
+		// refused before the playlist is changed, see AppendSegment
+		for _, seg := range segments {
+			if math.Round(seg.Duration) > p.TargetDuration {
+				return fmt.Errorf("segment duration %v exceeds frozen target duration %v", seg.Duration, p.TargetDuration)
+			}
+		}
+	}
+
+	// keys in effect after the last segment of the playlist
+	var current []*Key
+	if p.count > 0 {
+		current = p.keysAt(p.count - 1)
+	} else if p.Key != nil {
+		current = []*Key{p.Key}
+	}
+
+	first := segments[0]
+	first.Discontinuity = true
+	if len(first.keys()) == 0 {
+		switch {
+		case other.Key != nil:
+			first.Key = other.Key
+		case len(current) > 0:
+			first.Key = &Key{Method: "NONE"}
+		}
+	}
+	if first.Map == nil {
+		first.Map = other.Map
+	}
+	if p.Map != nil {
+		for _, seg := range segments {
+			if seg.Map != nil && !sameMap(seg.Map, p.Map) {
+				// the encoder ignores maps of segments when the
+				// playlist has the default one
+				if p.count > 0 && p.segmentAt(0).Map == nil {
+					p.segmentAt(0).Map = p.Map
+				}
+				p.Map = nil
+				break
+			}
+		}
+	}
+	if p.Map != nil {
+		first.Map = nil
+	}
+
+	for _, seg := range segments {
+		if err := p.appendGrow(seg); err != nil {
+			return err
+		}
+	}
+	if !p.frozenTarget {
+		p.TargetDuration = math.Max(p.TargetDuration, math.Ceil(other.TargetDuration))
+	}
+	if other.ver > p.ver {
+		p.ver = other.ver
+	}
+	p.buf.Reset()
+	return nil
+}
+
+// sameMap reports whether both maps describe the same EXT-X-MAP tag.
+func sameMap(a, b *Map) bool {
+	return a.URI == b.URI && a.Limit == b.Limit && a.Offset == b.Offset && sameKey(a.Key, b.Key)
+}
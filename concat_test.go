@@ -0,0 +1,87 @@
+/*
+ Package m3u8. Media playlist concatenation tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppendPlaylist(t *testing.T) {
+	program, _ := NewMediaPlaylist(0, 2)
+	program.SetDefaultMap("program-init.mp4", 0, 0)
+	program.Append("program1.m4s", 6, "")
+	program.SetKey("AES-128", "https://example.com/key", "", "", "")
+	program.Append("program2.m4s", 6, "")
+
+	ad, _ := NewMediaPlaylist(0, 2)
+	ad.SetDefaultMap("ad-init.mp4", 0, 0)
+	ad.Append("ad1.m4s", 7.5, "")
+	ad.Append("ad2.m4s", 7.5, "")
+	ad.Close()
+
+	if err := program.AppendPlaylist(ad); err != nil {
+		t.Fatal(err)
+	}
+	if program.Count() != 4 {
+		t.Fatalf("Expected 4 segments, got %d", program.Count())
+	}
+	if ad.Segments[0].Discontinuity || ad.Segments[0].Key != nil {
+		t.Error("The other playlist must not be changed")
+	}
+	expected := `#EXT-X-TARGETDURATION:8
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key"
+#EXT-X-MAP:URI="program-init.mp4"
+#EXTINF:6.000,
+program1.m4s
+#EXTINF:6.000,
+program2.m4s
+#EXT-X-KEY:METHOD=NONE
+#EXT-X-DISCONTINUITY
+#EXT-X-MAP:URI="ad-init.mp4"
+#EXTINF:7.500,
+ad1.m4s
+#EXTINF:7.500,
+ad2.m4s
+`
+	if out := program.String(); !strings.HasSuffix(out, expected) {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+	for i := uint(0); i < program.Count(); i++ {
+		if seg, _ := program.GetSegment(i); seg.SeqId != uint64(i) {
+			t.Errorf("Unexpected sequence number %d of segment %d", seg.SeqId, i)
+		}
+	}
+}
+
+func TestAppendPlaylistFrozenTarget(t *testing.T) {
+	program, _ := NewMediaPlaylist(0, 2)
+	program.Append("prog0.ts", 4, "")
+	program.SetTargetDuration(6)
+	ad, _ := NewMediaPlaylist(0, 2)
+	ad.Append("ad0.ts", 10, "")
+	ad.TargetDuration = 10
+	if err := program.AppendPlaylist(ad); err == nil {
+		t.Error("Expected error for segments longer than frozen target duration")
+	}
+	if program.Count() != 1 || program.TargetDuration != 6 {
+		t.Errorf("Expected the playlist unchanged, got %d segments and target %v", program.Count(), program.TargetDuration)
+	}
+
+	ad, _ = NewMediaPlaylist(0, 2)
+	ad.Append("ad0.ts", 5, "")
+	ad.TargetDuration = 8 // longer than its segments
+	if err := program.AppendPlaylist(ad); err != nil {
+		t.Fatal(err)
+	}
+	if program.Count() != 2 || program.TargetDuration != 6 {
+		t.Errorf("Expected frozen target duration 6, got %v", program.TargetDuration)
+	}
+}
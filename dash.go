@@ -0,0 +1,813 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines conversion of master playlists to MPEG-DASH media
+ presentation descriptions (MPD) and back, so origins may serve both
+ formats from the same source.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timescale of segment timelines of exported MPDs, durations are rounded
+// to milliseconds.
+const mpdTimescale = 1000
+
+// This structure represents the MPD element of a media presentation
+// description, only elements and attributes used by the library are
+// listed.
+type mpd struct {
+	XMLName                   xml.Name    `xml:"urn:mpeg:dash:schema:mpd:2011 MPD"`
+	Profiles                  string      `xml:"profiles,attr"`
+	Type                      string      `xml:"type,attr"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr,omitempty"`
+	AvailabilityStartTime     string      `xml:"availabilityStartTime,attr,omitempty"`
+	PublishTime               string      `xml:"publishTime,attr,omitempty"`
+	MinimumUpdatePeriod       string      `xml:"minimumUpdatePeriod,attr,omitempty"`
+	TimeShiftBufferDepth      string      `xml:"timeShiftBufferDepth,attr,omitempty"`
+	MinBufferTime             string      `xml:"minBufferTime,attr"`
+	BaseURL                   string      `xml:"BaseURL,omitempty"`
+	Periods                   []mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr,omitempty"`
+	Start          string             `xml:"start,attr,omitempty"`
+	Duration       string             `xml:"duration,attr,omitempty"`
+	BaseURL        string             `xml:"BaseURL,omitempty"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ContentType               string              `xml:"contentType,attr,omitempty"`
+	MimeType                  string              `xml:"mimeType,attr,omitempty"`
+	Codecs                    string              `xml:"codecs,attr,omitempty"`
+	Lang                      string              `xml:"lang,attr,omitempty"`
+	SegmentAlignment          bool                `xml:"segmentAlignment,attr,omitempty"`
+	ContentProtection         []mpdDescriptor     `xml:"ContentProtection"`
+	AudioChannelConfiguration []mpdDescriptor     `xml:"AudioChannelConfiguration"`
+	BaseURL                   string              `xml:"BaseURL,omitempty"`
+	SegmentBase               *mpdSegmentBase     `xml:"SegmentBase"`
+	SegmentList               *mpdSegmentList     `xml:"SegmentList"`
+	SegmentTemplate           *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	Representations           []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdDescriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr,omitempty"`
+}
+
+type mpdRepresentation struct {
+	ID                        string              `xml:"id,attr"`
+	Bandwidth                 uint32              `xml:"bandwidth,attr"`
+	MimeType                  string              `xml:"mimeType,attr,omitempty"`
+	Codecs                    string              `xml:"codecs,attr,omitempty"`
+	Width                     int                 `xml:"width,attr,omitempty"`
+	Height                    int                 `xml:"height,attr,omitempty"`
+	FrameRate                 string              `xml:"frameRate,attr,omitempty"`
+	AudioSamplingRate         string              `xml:"audioSamplingRate,attr,omitempty"`
+	ContentProtection         []mpdDescriptor     `xml:"ContentProtection"`
+	AudioChannelConfiguration []mpdDescriptor     `xml:"AudioChannelConfiguration"`
+	BaseURL                   string              `xml:"BaseURL,omitempty"`
+	SegmentBase               *mpdSegmentBase     `xml:"SegmentBase"`
+	SegmentList               *mpdSegmentList     `xml:"SegmentList"`
+	SegmentTemplate           *mpdSegmentTemplate `xml:"SegmentTemplate"`
+}
+
+type mpdSegmentBase struct {
+	IndexRange string `xml:"indexRange,attr,omitempty"`
+}
+
+type mpdSegmentList struct {
+	Timescale      uint64          `xml:"timescale,attr,omitempty"`
+	Duration       uint64          `xml:"duration,attr,omitempty"`
+	Initialization *mpdURL         `xml:"Initialization"`
+	SegmentURLs    []mpdSegmentURL `xml:"SegmentURL"`
+}
+
+type mpdURL struct {
+	SourceURL string `xml:"sourceURL,attr,omitempty"`
+	Range     string `xml:"range,attr,omitempty"`
+}
+
+type mpdSegmentURL struct {
+	Media      string `xml:"media,attr,omitempty"`
+	MediaRange string `xml:"mediaRange,attr,omitempty"`
+}
+
+type mpdSegmentTemplate struct {
+	Timescale       uint64       `xml:"timescale,attr,omitempty"`
+	Media           string       `xml:"media,attr,omitempty"`
+	Initialization  string       `xml:"initialization,attr,omitempty"`
+	StartNumber     *uint64      `xml:"startNumber,attr"`
+	Duration        uint64       `xml:"duration,attr,omitempty"`
+	SegmentTimeline *mpdTimeline `xml:"SegmentTimeline"`
+}
+
+type mpdTimeline struct {
+	S []mpdS `xml:"S"`
+}
+
+type mpdS struct {
+	T *uint64 `xml:"t,attr"`
+	D uint64  `xml:"d,attr"`
+	R int     `xml:"r,attr,omitempty"`
+}
+
+// ExportMPD converts the master playlist to MPEG-DASH media presentation
+// description. Every stream variant with a chunklist becomes
+// a Representation with SegmentTemplate and SegmentTimeline derived from
+// its segments, variants of the same codecs share an AdaptationSet. The
+// presentation is static if all the chunklists are closed, live
+// presentations require EXT-X-PROGRAM-DATE-TIME of their segments.
+//
+// Segment URIs must be numbered (see InferURIPattern) and the chunklists
+// must not contain byte ranges, encryption or discontinuities, which can't
+// be expressed by a single period with a segment template. Renditions of
+// EXT-X-MEDIA are not exported, their playlists are not part of the master
+// playlist. I-frame and image variants are skipped.
+func ExportMPD(master *MasterPlaylist) ([]byte, error) {
+	var (
+		sets      []*mpdAdaptationSet
+		groups    = make(map[string]*mpdAdaptationSet)
+		live      bool
+		duration  float64
+		target    float64
+		start     time.Time
+		end       time.Time
+		variants  []*Variant
+		timelines [][]mpdSegment
+	)
+	for _, v := range master.Variants {
+		if v.Iframe || v.Image || v.Chunklist == nil {
+			continue
+		}
+		segments, err := mpdSegments(v.Chunklist)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %s", v.URI, err)
+		}
+		variants = append(variants, v)
+		timelines = append(timelines, segments)
+		if !v.Chunklist.Closed {
+			live = true
+		}
+		target = math.Max(target, v.Chunklist.TargetDuration)
+	}
+	if len(variants) == 0 {
+		return nil, errors.New("no variants with chunklists")
+	}
+	if live {
+		for i, segments := range timelines {
+			if segments[0].pdt.IsZero() {
+				return nil, fmt.Errorf("variant %s: live chunklist without EXT-X-PROGRAM-DATE-TIME", variants[i].URI)
+			}
+			if start.IsZero() || segments[0].pdt.Before(start) {
+				start = segments[0].pdt
+			}
+		}
+	}
+
+	for i, v := range variants {
+		p, segments := v.Chunklist, timelines[i]
+		template, err := mpdTemplate(p)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %s", v.URI, err)
+		}
+		var offset float64
+		if live {
+			offset = segments[0].pdt.Sub(start).Seconds()
+		}
+		template.SegmentTimeline = mpdSegmentTimeline(segments, offset)
+		total := segments[len(segments)-1].start + segments[len(segments)-1].duration
+		duration = math.Max(duration, total)
+		if live {
+			if last := start.Add(time.Duration((offset + total) * float64(time.Second))); last.After(end) {
+				end = last
+			}
+		}
+
+		r := mpdRepresentation{
+			ID:              v.StableVariantID,
+			Bandwidth:       v.Bandwidth,
+			Codecs:          v.Codecs,
+			FrameRate:       mpdFrameRate(v.FrameRate),
+			SegmentTemplate: template,
+		}
+		if r.ID == "" {
+			r.ID = strconv.Itoa(i)
+		}
+		if res := v.Dimensions(); !res.IsZero() {
+			r.Width, r.Height = res.Width, res.Height
+		}
+		if j := strings.LastIndexByte(v.URI, '/'); j != -1 {
+			r.BaseURL = v.URI[:j+1]
+		}
+
+		contentType, mimeType := "video", "/mp2t"
+		if v.Codecs != "" && audioCodecs(v.Codecs) == v.Codecs && v.Resolution == "" {
+			contentType = "audio"
+		}
+		if p.mapAt(0) != nil {
+			mimeType = "/mp4"
+		}
+		mimeType = contentType + mimeType
+		// representations of a set must be switchable
+		group := codecGroup(v) + "/" + mimeType
+		set, ok := groups[group]
+		if !ok {
+			set = &mpdAdaptationSet{ContentType: contentType, MimeType: mimeType, SegmentAlignment: true}
+			groups[group] = set
+			sets = append(sets, set)
+		}
+		set.Representations = append(set.Representations, r)
+	}
+
+	out := mpd{
+		Profiles:      "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:          "static",
+		MinBufferTime: mpdDuration(target),
+		Periods:       []mpdPeriod{{ID: "0", Start: mpdDuration(0)}},
+	}
+	if live {
+		out.Type = "dynamic"
+		out.AvailabilityStartTime = start.UTC().Format(time.RFC3339Nano)
+		out.PublishTime = end.UTC().Format(time.RFC3339Nano)
+		out.MinimumUpdatePeriod = mpdDuration(target)
+		out.TimeShiftBufferDepth = mpdDuration(duration)
+	} else {
+		out.MediaPresentationDuration = mpdDuration(duration)
+	}
+	for _, set := range sets {
+		out.Periods[0].AdaptationSets = append(out.Periods[0].AdaptationSets, *set)
+	}
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// mpdSegment is a segment of the timeline of a representation.
+type mpdSegment struct {
+	start    float64 // seconds from the first segment of the chunklist
+	duration float64
+	pdt      time.Time
+}
+
+// mpdSegments returns the timeline of the chunklist and checks it for
+// features which the segment template can't express.
+func mpdSegments(p *MediaPlaylist) ([]mpdSegment, error) {
+	if p.count == 0 {
+		return nil, errors.New("playlist is empty")
+	}
+	segments := make([]mpdSegment, 0, p.count)
+	var start float64
+	init := p.mapAt(0)
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		switch {
+		case seg.Limit > 0:
+			return nil, errors.New("byte ranges are not supported")
+		case seg.Discontinuity && i > 0:
+			return nil, errors.New("discontinuities are not supported")
+		}
+		for _, key := range p.keysAt(i) {
+			if key.Method != "NONE" {
+				return nil, errors.New("encrypted segments are not supported")
+			}
+		}
+		if m := p.mapAt(i); m != init && (m == nil || init == nil || !sameMap(m, init)) {
+			return nil, errors.New("changes of EXT-X-MAP are not supported")
+		}
+		segments = append(segments, mpdSegment{start: start, duration: seg.Duration, pdt: seg.ProgramDateTime})
+		start += seg.Duration
+	}
+	if init != nil && init.Limit > 0 {
+		return nil, errors.New("EXT-X-MAP with byte range is not supported")
+	}
+	return segments, nil
+}
+
+// mpdTemplate returns the segment template with numbered media URIs of
+// the chunklist.
+func mpdTemplate(p *MediaPlaylist) (*mpdSegmentTemplate, error) {
+	template := &mpdSegmentTemplate{Timescale: mpdTimescale}
+	if m := p.mapAt(0); m != nil {
+		template.Initialization = strings.Replace(m.URI, "$", "$$", -1)
+	}
+	var number uint64
+	if p.count == 1 {
+		template.Media = strings.Replace(p.segmentAt(0).URI, "$", "$$", -1)
+	} else {
+		pattern, err := p.InferURIPattern()
+		if err != nil {
+			return nil, err
+		}
+		counter := "$Number$"
+		if pattern.Width > 0 {
+			counter = fmt.Sprintf("$Number%%0%dd$", pattern.Width)
+		}
+		template.Media = strings.Replace(pattern.Prefix, "$", "$$", -1) + counter + strings.Replace(pattern.Suffix, "$", "$$", -1)
+		number = uint64(int64(p.segmentAt(0).SeqId) + pattern.Offset)
+	}
+	template.StartNumber = &number
+	return template, nil
+}
+
+// mpdSegmentTimeline returns the timeline of the segments starting at the
+// offset (seconds), segments of the same duration are repeated.
+func mpdSegmentTimeline(segments []mpdSegment, offset float64) *mpdTimeline {
+	timeline := new(mpdTimeline)
+	for i, seg := range segments {
+		d := uint64(math.Round(seg.duration * mpdTimescale))
+		if n := len(timeline.S); n > 0 && timeline.S[n-1].D == d {
+			timeline.S[n-1].R++
+			continue
+		}
+		s := mpdS{D: d}
+		if i == 0 {
+			t := uint64(math.Round((offset + seg.start) * mpdTimescale))
+			s.T = &t
+		}
+		timeline.S = append(timeline.S, s)
+	}
+	return timeline
+}
+
+// mpdDuration formats the duration in seconds as ISO 8601 duration.
+func mpdDuration(seconds float64) string {
+	return "PT" + strconv.FormatFloat(math.Round(seconds*1000)/1000, 'f', -1, 64) + "S"
+}
+
+// mpdFrameRate formats FRAME-RATE as DASH frame rate, which is an integer
+// or a fraction, e.g. 29.97 is 30000/1001.
+func mpdFrameRate(rate float64) string {
+	switch {
+	case rate <= 0:
+		return ""
+	case rate == math.Trunc(rate):
+		return strconv.Itoa(int(rate))
+	}
+	if ntsc := math.Round(rate * 1.001); math.Abs(ntsc/1.001-rate) < 0.005 {
+		return fmt.Sprintf("%d/1001", int(ntsc)*1000)
+	}
+	return fmt.Sprintf("%d/1000", int(math.Round(rate*1000)))
+}
+
+var (
+	reMPDIdentifier = regexp.MustCompile(`\$(RepresentationID|Number|Bandwidth|Time)(%0[0-9]+d)?\$`)
+	reMPDDuration   = regexp.MustCompile(`^P(?:([0-9.]+)D)?(?:T(?:([0-9.]+)H)?(?:([0-9.]+)M)?(?:([0-9.]+)S)?)?$`)
+)
+
+// ImportMPD converts the static MPEG-DASH media presentation description
+// to a master playlist and media playlists of its representations, which
+// are returned by their URIs and linked to the variants as chunklists.
+// Video representations become variants, audio representations become
+// AUDIO renditions of the variants or variants of an audio-only
+// presentation. Segment URIs and URIs of the media playlists are relative
+// to the MPD.
+//
+// Representations with SegmentTemplate or SegmentList are converted.
+// Features which have no equivalent in HLS, e.g. SegmentBase, further
+// periods, text adaptation sets or ContentProtection, are skipped and
+// reported in Warnings of the master playlist.
+func ImportMPD(data []byte) (*MasterPlaylist, map[string]*MediaPlaylist, error) {
+	var in mpd
+	if err := xml.Unmarshal(data, &in); err != nil {
+		return nil, nil, err
+	}
+	if in.Type == "dynamic" {
+		return nil, nil, errors.New("dynamic MPD is not supported")
+	}
+	if len(in.Periods) == 0 {
+		return nil, nil, errors.New("MPD has no periods")
+	}
+	master := NewMasterPlaylist()
+	warn := func(format string, a ...interface{}) {
+		master.Warnings = append(master.Warnings, fmt.Sprintf(format, a...))
+	}
+	if len(in.Periods) > 1 {
+		warn("only the first of %d periods is imported", len(in.Periods))
+	}
+	period := in.Periods[0]
+	total, err := parseMPDDuration(period.Duration)
+	if err == nil && total == 0 {
+		total, err = parseMPDDuration(in.MediaPresentationDuration)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type stream struct {
+		uri      string
+		playlist *MediaPlaylist
+		params   VariantParams
+		alt      *Alternative
+	}
+	var videos, audios []stream
+	playlists := make(map[string]*MediaPlaylist)
+	for i, set := range period.AdaptationSets {
+		kind := set.ContentType
+		if kind == "" {
+			mimeType := set.MimeType
+			if mimeType == "" && len(set.Representations) > 0 {
+				mimeType = set.Representations[0].MimeType
+			}
+			kind = strings.SplitN(mimeType, "/", 2)[0]
+		}
+		if kind != "video" && kind != "audio" {
+			warn("adaptation set %d: %s content is not imported", i, kind)
+			continue
+		}
+		if len(set.ContentProtection) > 0 {
+			warn("adaptation set %d: ContentProtection is not converted to EXT-X-KEY", i)
+		}
+		for j, rep := range set.Representations {
+			name := rep.ID
+			if name == "" {
+				name = fmt.Sprintf("%s%d-%d", kind, i, j)
+			}
+			uri := url.PathEscape(name) + ".m3u8"
+			if _, ok := playlists[uri]; ok {
+				warn("representation %s: duplicate id, skipped", name)
+				continue
+			}
+			if len(rep.ContentProtection) > 0 && len(set.ContentProtection) == 0 {
+				warn("representation %s: ContentProtection is not converted to EXT-X-KEY", name)
+			}
+			base := joinBaseURL(in.BaseURL, period.BaseURL, set.BaseURL, rep.BaseURL)
+			p, err := mpdPlaylist(&set, &rep, base, total)
+			if err != nil {
+				warn("representation %s: %s, skipped", name, err)
+				continue
+			}
+			playlists[uri] = p
+
+			codecs := rep.Codecs
+			if codecs == "" {
+				codecs = set.Codecs
+			}
+			s := stream{uri: uri, playlist: p, params: VariantParams{Bandwidth: rep.Bandwidth, Codecs: codecs}}
+			if kind == "video" {
+				if rep.Width > 0 && rep.Height > 0 {
+					s.params.SetDimensions(Resolution{rep.Width, rep.Height})
+				}
+				s.params.FrameRate = parseMPDFrameRate(rep.FrameRate)
+				videos = append(videos, s)
+				continue
+			}
+			s.alt = &Alternative{
+				GroupId:    "audio",
+				URI:        uri,
+				Type:       "AUDIO",
+				Language:   set.Lang,
+				Name:       name,
+				Autoselect: "YES",
+			}
+			if rate, err := strconv.ParseUint(rep.AudioSamplingRate, 10, 32); err == nil {
+				s.alt.SampleRate = uint(rate)
+			}
+			s.alt.Channels = mpdChannels(rep.AudioChannelConfiguration)
+			if s.alt.Channels == "" {
+				s.alt.Channels = mpdChannels(set.AudioChannelConfiguration)
+			}
+			audios = append(audios, s)
+		}
+	}
+
+	if len(videos) == 0 {
+		for _, s := range audios {
+			master.Append(s.uri, s.playlist, s.params)
+		}
+	} else {
+		var alts []*Alternative
+		var audioBandwidth uint32
+		var audioCodecs string
+		for i, s := range audios {
+			s.alt.Default = i == 0
+			alts = append(alts, s.alt)
+			if s.params.Bandwidth > audioBandwidth {
+				audioBandwidth = s.params.Bandwidth
+			}
+			if audioCodecs == "" {
+				audioCodecs = s.params.Codecs
+			}
+		}
+		for _, s := range videos {
+			if len(alts) > 0 {
+				s.params.Audio = "audio"
+				s.params.Alternatives = alts
+				s.params.Bandwidth += audioBandwidth
+				if s.params.Codecs != "" && audioCodecs != "" {
+					s.params.Codecs += "," + audioCodecs
+				}
+			}
+			master.Append(s.uri, s.playlist, s.params)
+		}
+	}
+	if len(master.Variants) == 0 {
+		return nil, nil, errors.New("MPD has no representations which can be imported")
+	}
+	return master, playlists, nil
+}
+
+// mpdPlaylist builds the closed media playlist of the representation from
+// its segment template or list.
+func mpdPlaylist(set *mpdAdaptationSet, rep *mpdRepresentation, base string, total float64) (*MediaPlaylist, error) {
+	if rep.SegmentBase != nil {
+		return nil, errors.New("SegmentBase is not supported")
+	}
+	list := rep.SegmentList
+	if list == nil {
+		list = set.SegmentList
+	}
+	if list != nil {
+		return mpdListPlaylist(list, base, total)
+	}
+	template := mergeMPDTemplates(set.SegmentTemplate, rep.SegmentTemplate)
+	if template == nil {
+		if set.SegmentBase != nil {
+			return nil, errors.New("SegmentBase is not supported")
+		}
+		return nil, errors.New("no segment information")
+	}
+
+	timescale := template.Timescale
+	if timescale == 0 {
+		timescale = 1
+	}
+	number := uint64(1)
+	if template.StartNumber != nil {
+		number = *template.StartNumber
+	}
+	type slot struct{ t, d uint64 }
+	var slots []slot
+	end := uint64(math.Round(total * float64(timescale)))
+	switch {
+	case template.SegmentTimeline != nil:
+		var t uint64
+		timeline := template.SegmentTimeline.S
+		for i, s := range timeline {
+			if s.T != nil {
+				t = *s.T
+			}
+			if s.D == 0 {
+				return nil, errors.New("segment timeline with zero duration")
+			}
+			if s.R >= 0 {
+				for j := 0; j <= s.R; j++ {
+					slots = append(slots, slot{t, s.D})
+					t += s.D
+				}
+				continue
+			}
+			// repeated until the next S element or the end of the period
+			until := end
+			if i+1 < len(timeline) && timeline[i+1].T != nil {
+				until = *timeline[i+1].T
+			}
+			if until <= t {
+				return nil, errors.New("open-ended segment timeline without duration of the period")
+			}
+			for ; t < until; t += s.D {
+				d := s.D
+				if t+d > until {
+					d = until - t
+				}
+				slots = append(slots, slot{t, d})
+			}
+		}
+	case template.Duration > 0:
+		if end == 0 {
+			return nil, errors.New("duration of the presentation is unknown")
+		}
+		for t := uint64(0); t < end; t += template.Duration {
+			d := template.Duration
+			if t+d > end {
+				d = end - t
+			}
+			slots = append(slots, slot{t, d})
+		}
+	default:
+		return nil, errors.New("segment template without duration or timeline")
+	}
+	if len(slots) == 0 {
+		return nil, errors.New("no segments")
+	}
+
+	p, err := NewMediaPlaylist(0, uint(len(slots)))
+	if err != nil {
+		return nil, err
+	}
+	p.SeqNo = number
+	if template.Initialization != "" {
+		p.SetDefaultMap(base+mpdSubstitute(template.Initialization, rep, 0, 0), 0, 0)
+	}
+	for i, s := range slots {
+		uri := base + mpdSubstitute(template.Media, rep, number+uint64(i), s.t)
+		if err = p.Append(uri, float64(s.d)/float64(timescale), ""); err != nil {
+			return nil, err
+		}
+	}
+	p.Close()
+	return p, nil
+}
+
+// mpdListPlaylist builds the closed media playlist of the segment list.
+func mpdListPlaylist(list *mpdSegmentList, base string, total float64) (*MediaPlaylist, error) {
+	if len(list.SegmentURLs) == 0 {
+		return nil, errors.New("no segments")
+	}
+	if list.Duration == 0 {
+		return nil, errors.New("segment list without duration")
+	}
+	timescale := list.Timescale
+	if timescale == 0 {
+		timescale = 1
+	}
+	p, err := NewMediaPlaylist(0, uint(len(list.SegmentURLs)))
+	if err != nil {
+		return nil, err
+	}
+	if init := list.Initialization; init != nil {
+		uri := base
+		if init.SourceURL != "" {
+			uri = joinBaseURL(base, init.SourceURL)
+		}
+		limit, offset, err := parseMPDRange(init.Range)
+		if err != nil {
+			return nil, err
+		}
+		p.SetDefaultMap(uri, limit, offset)
+	}
+	duration := float64(list.Duration) / float64(timescale)
+	for i, seg := range list.SegmentURLs {
+		d := duration
+		if rest := total - float64(i)*duration; i == len(list.SegmentURLs)-1 && rest > 0 && rest < d {
+			d = rest
+		}
+		uri := base
+		if seg.Media != "" {
+			uri = joinBaseURL(base, seg.Media)
+		}
+		if err = p.Append(uri, d, ""); err != nil {
+			return nil, err
+		}
+		if seg.MediaRange != "" {
+			limit, offset, err := parseMPDRange(seg.MediaRange)
+			if err != nil {
+				return nil, err
+			}
+			p.SetRange(limit, offset)
+		}
+	}
+	p.Close()
+	return p, nil
+}
+
+// mergeMPDTemplates returns the template of the representation completed
+// with attributes of the template of the adaptation set.
+func mergeMPDTemplates(set, rep *mpdSegmentTemplate) *mpdSegmentTemplate {
+	switch {
+	case set == nil:
+		return rep
+	case rep == nil:
+		return set
+	}
+	out := *set
+	if rep.Timescale != 0 {
+		out.Timescale = rep.Timescale
+	}
+	if rep.Media != "" {
+		out.Media = rep.Media
+	}
+	if rep.Initialization != "" {
+		out.Initialization = rep.Initialization
+	}
+	if rep.StartNumber != nil {
+		out.StartNumber = rep.StartNumber
+	}
+	if rep.Duration != 0 {
+		out.Duration = rep.Duration
+	}
+	if rep.SegmentTimeline != nil {
+		out.SegmentTimeline = rep.SegmentTimeline
+	}
+	return &out
+}
+
+// mpdSubstitute replaces identifiers of the URI template, e.g.
+// $Number%05d$, with values of the segment.
+func mpdSubstitute(template string, rep *mpdRepresentation, number, t uint64) string {
+	parts := strings.Split(template, "$$")
+	for i, part := range parts {
+		parts[i] = reMPDIdentifier.ReplaceAllStringFunc(part, func(id string) string {
+			m := reMPDIdentifier.FindStringSubmatch(id)
+			format := "%d"
+			if m[2] != "" {
+				format = m[2]
+			}
+			switch m[1] {
+			case "RepresentationID":
+				return rep.ID
+			case "Number":
+				return fmt.Sprintf(format, number)
+			case "Bandwidth":
+				return fmt.Sprintf(format, rep.Bandwidth)
+			}
+			return fmt.Sprintf(format, t)
+		})
+	}
+	return strings.Join(parts, "$")
+}
+
+// joinBaseURL resolves BaseURL elements of nested MPD elements, absolute
+// URLs replace the outer ones.
+func joinBaseURL(urls ...string) string {
+	var out string
+	for _, u := range urls {
+		switch {
+		case u == "":
+		case strings.Contains(u, "://") || strings.HasPrefix(u, "/"):
+			out = u
+		case out == "" || strings.HasSuffix(out, "/"):
+			out += u
+		default:
+			out = out[:strings.LastIndexByte(out, '/')+1] + u
+		}
+	}
+	return out
+}
+
+// mpdChannels returns the number of audio channels of the channel
+// configuration descriptors.
+func mpdChannels(descriptors []mpdDescriptor) string {
+	for _, d := range descriptors {
+		if d.SchemeIDURI == "urn:mpeg:dash:23003:3:audio_channel_configuration:2011" {
+			return d.Value
+		}
+	}
+	return ""
+}
+
+// parseMPDRange parses the byte range "first-last" as length and offset.
+func parseMPDRange(r string) (limit, offset int64, err error) {
+	if r == "" {
+		return 0, 0, nil
+	}
+	var last int64
+	if _, err = fmt.Sscanf(r, "%d-%d", &offset, &last); err != nil || last < offset {
+		return 0, 0, fmt.Errorf("invalid byte range %q", r)
+	}
+	return last - offset + 1, offset, nil
+}
+
+// parseMPDDuration parses ISO 8601 duration in days, hours, minutes and
+// seconds, e.g. PT1M30.5S, as seconds. Empty duration is zero.
+func parseMPDDuration(d string) (float64, error) {
+	if d == "" {
+		return 0, nil
+	}
+	m := reMPDDuration.FindStringSubmatch(d)
+	if m == nil || d == "P" || d == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", d)
+	}
+	var seconds float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", d)
+		}
+		seconds += v * unit
+	}
+	return seconds, nil
+}
+
+// parseMPDFrameRate parses DASH frame rate, an integer or a fraction.
+func parseMPDFrameRate(rate string) float64 {
+	var num, den float64
+	if n, _ := fmt.Sscanf(rate, "%g/%g", &num, &den); n == 2 && den > 0 {
+		return math.Round(num/den*1000) / 1000
+	}
+	return num
+}
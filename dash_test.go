@@ -0,0 +1,242 @@
+/*
+ Package m3u8. MPEG-DASH conversion tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportMPD(t *testing.T) {
+	m := NewMasterPlaylist()
+	for i, bw := range []uint32{800000, 2400000} {
+		p, _ := NewMediaPlaylist(0, 4)
+		p.SetDefaultMap(fmt.Sprintf("init_%d.mp4", i), 0, 0)
+		for j, d := range []float64{6, 6, 6, 4.5} {
+			p.Append(fmt.Sprintf("seg_%d_%03d.m4s", i, j+1), d, "")
+		}
+		p.Close()
+		m.Append(fmt.Sprintf("v%d/index.m3u8", i), p, VariantParams{Bandwidth: bw, Codecs: "avc1.64001f,mp4a.40.2", Resolution: "1280x720", FrameRate: 29.97})
+	}
+	data, err := ExportMPD(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="static" mediaPresentationDuration="PT22.5S" minBufferTime="PT6S">
+  <Period id="0" start="PT0S">
+    <AdaptationSet contentType="video" mimeType="video/mp4" segmentAlignment="true">
+      <Representation id="0" bandwidth="800000" codecs="avc1.64001f,mp4a.40.2" width="1280" height="720" frameRate="30000/1001">
+        <BaseURL>v0/</BaseURL>
+        <SegmentTemplate timescale="1000" media="seg_0_$Number%03d$.m4s" initialization="init_0.mp4" startNumber="1">
+          <SegmentTimeline>
+            <S t="0" d="6000" r="2"></S>
+            <S d="4500"></S>
+          </SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+      <Representation id="1" bandwidth="2400000" codecs="avc1.64001f,mp4a.40.2" width="1280" height="720" frameRate="30000/1001">
+        <BaseURL>v1/</BaseURL>
+        <SegmentTemplate timescale="1000" media="seg_1_$Number%03d$.m4s" initialization="init_1.mp4" startNumber="1">
+          <SegmentTimeline>
+            <S t="0" d="6000" r="2"></S>
+            <S d="4500"></S>
+          </SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`
+	if string(data) != expected {
+		t.Errorf("Exported MPD differs:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func TestExportLiveMPD(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	for i := 0; i < 5; i++ {
+		p.Slide(fmt.Sprintf("chunk%d.ts", i), 4, "")
+	}
+	start := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	m := NewMasterPlaylist()
+	m.Append("live.m3u8", p, VariantParams{Bandwidth: 500000})
+	if _, err := ExportMPD(m); err == nil || !strings.Contains(err.Error(), "EXT-X-PROGRAM-DATE-TIME") {
+		t.Errorf("Expected error of live chunklist without program date time, got %v", err)
+	}
+	p.Segments[p.head].ProgramDateTime = start
+	data, err := ExportMPD(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`type="dynamic"`,
+		`availabilityStartTime="2020-05-01T10:00:00Z"`,
+		`publishTime="2020-05-01T10:00:12Z"`,
+		`timeShiftBufferDepth="PT12S"`,
+		`mimeType="video/mp2t"`,
+		`media="chunk$Number$.ts" startNumber="2"`,
+		`<S t="0" d="4000" r="2"></S>`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in MPD:\n%s", expected, data)
+		}
+	}
+}
+
+func TestExportMPDUnsupported(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 3)
+	p.Append("a.ts", 6, "")
+	p.Append("b.ts", 6, "")
+	p.Close()
+	m := NewMasterPlaylist()
+	m.Append("index.m3u8", p, VariantParams{Bandwidth: 500000})
+	if _, err := ExportMPD(m); err == nil {
+		t.Error("Expected error of segments without numbering pattern")
+	}
+	p.Segments[0].URI, p.Segments[1].URI = "1.ts", "2.ts"
+	p.Segments[1].Discontinuity = true
+	if _, err := ExportMPD(m); err == nil || !strings.Contains(err.Error(), "discontinuities") {
+		t.Errorf("Expected error of discontinuity, got %v", err)
+	}
+}
+
+func TestImportExportedMPD(t *testing.T) {
+	m := NewMasterPlaylist()
+	p, _ := NewMediaPlaylist(0, 3)
+	p.SetDefaultMap("init.mp4", 0, 0)
+	for i, d := range []float64{4, 4, 2.5} {
+		p.Append(fmt.Sprintf("seg%d.m4s", i+7), d, "")
+	}
+	p.Close()
+	m.Append("hd/index.m3u8", p, VariantParams{Bandwidth: 3000000, Codecs: "avc1.640028", Resolution: "1920x1080", FrameRate: 25})
+	data, err := ExportMPD(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, playlists, err := ImportMPD(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Warnings) > 0 {
+		t.Errorf("Unexpected warnings %v", imported.Warnings)
+	}
+	expected := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,PROGRAM-ID=0,CODECS="avc1.640028",RESOLUTION=1920x1080,FRAME-RATE=25.000
+0.m3u8
+`
+	if imported.String() != expected {
+		t.Errorf("Imported master playlist differs:\n%s\nexpected:\n%s", imported, expected)
+	}
+	chunklist := playlists["0.m3u8"]
+	if chunklist == nil || imported.Variants[0].Chunklist != chunklist {
+		t.Fatal("Expected the chunklist of the variant")
+	}
+	expected = `#EXTM3U
+#EXT-X-VERSION:5
+#EXT-X-MAP:URI="hd/init.mp4"
+#EXT-X-MEDIA-SEQUENCE:7
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.000,
+hd/seg7.m4s
+#EXTINF:4.000,
+hd/seg8.m4s
+#EXTINF:2.500,
+hd/seg9.m4s
+#EXT-X-ENDLIST
+`
+	if chunklist.String() != expected {
+		t.Errorf("Imported media playlist differs:\n%s\nexpected:\n%s", chunklist, expected)
+	}
+}
+
+func TestImportMPD(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT0H0M10.5S" minBufferTime="PT2S">
+  <BaseURL>https://cdn.example.com/vod/</BaseURL>
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <SegmentTemplate timescale="90000" media="$RepresentationID$/t$Time$.m4s" initialization="$RepresentationID$/init.mp4">
+        <SegmentTimeline>
+          <S t="0" d="360000" r="-1"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="sd" bandwidth="1000000" codecs="avc1.4d401f" width="640" height="360" frameRate="30000/1001"/>
+      <Representation id="idx" bandwidth="2000000" codecs="avc1.4d401f">
+        <SegmentBase indexRange="800-1500"/>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio" lang="de">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc"/>
+      <AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="2"/>
+      <Representation id="de" bandwidth="128000" codecs="mp4a.40.2" audioSamplingRate="48000">
+        <BaseURL>audio/</BaseURL>
+        <SegmentList timescale="1000" duration="5000">
+          <Initialization sourceURL="de.mp4" range="0-799"/>
+          <SegmentURL media="de.mp4" mediaRange="800-80799"/>
+          <SegmentURL media="de.mp4" mediaRange="80800-160799"/>
+          <SegmentURL media="de.mp4" mediaRange="160800-170799"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet contentType="text" mimeType="text/vtt">
+      <Representation id="en" bandwidth="1000"><BaseURL>en.vtt</BaseURL></Representation>
+    </AdaptationSet>
+  </Period>
+  <Period id="2"/>
+</MPD>
+`
+	master, playlists, err := ImportMPD([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := strings.Join(master.Warnings, "\n")
+	for _, expected := range []string{"periods", "representation idx: SegmentBase", "adaptation set 1: ContentProtection", "adaptation set 2: text"} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("Expected warning about %s in %v", expected, master.Warnings)
+		}
+	}
+	if len(master.Variants) != 1 || len(playlists) != 2 {
+		t.Fatalf("Expected 1 variant and 2 playlists, got %d/%d", len(master.Variants), len(playlists))
+	}
+	v := master.Variants[0]
+	if v.Bandwidth != 1128000 || v.Codecs != "avc1.4d401f,mp4a.40.2" || v.FrameRate != 29.97 || v.Audio != "audio" {
+		t.Errorf("Unexpected variant %+v", v.VariantParams)
+	}
+	alt := v.Alternatives[0]
+	if alt.URI != "de.m3u8" || alt.Language != "de" || !alt.Default || alt.Channels != "2" || alt.SampleRate != 48000 {
+		t.Errorf("Unexpected rendition %+v", alt)
+	}
+
+	video := playlists["sd.m3u8"]
+	if video.Count() != 3 || video.Map.URI != "https://cdn.example.com/vod/sd/init.mp4" {
+		t.Fatalf("Unexpected video playlist:\n%s", video)
+	}
+	if seg, _ := video.GetSegment(2); seg.URI != "https://cdn.example.com/vod/sd/t720000.m4s" || seg.Duration != 2.5 {
+		t.Errorf("Unexpected last video segment %+v", seg)
+	}
+	audio := playlists["de.m3u8"]
+	if audio.Map.Limit != 800 || audio.Map.Offset != 0 {
+		t.Errorf("Unexpected audio map %+v", audio.Map)
+	}
+	if seg, _ := audio.GetSegment(1); seg.URI != "https://cdn.example.com/vod/audio/de.mp4" || seg.Limit != 80000 || seg.Offset != 80800 || seg.Duration != 5 {
+		t.Errorf("Unexpected audio segment %+v", seg)
+	}
+	if seg, _ := audio.GetSegment(2); seg.Duration != 0.5 {
+		t.Errorf("Expected the last audio segment to end with the presentation, got %+v", seg)
+	}
+
+	if _, _, err = ImportMPD([]byte(strings.Replace(data, `type="static"`, `type="dynamic"`, 1))); err == nil {
+		t.Error("Expected error of dynamic MPD")
+	}
+}
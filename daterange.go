@@ -0,0 +1,339 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines date ranges (EXT-X-DATERANGE) of media playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rkollar/m3u8/scte35"
+)
+
+// This structure represents a range of time with associated attributes,
+// e.g. an ad break.
+//
+// Realizes EXT-X-DATERANGE tag.
+type DateRange struct {
+	ID              string            `json:"id"`
+	Class           string            `json:"class,omitempty"`
+	StartDate       time.Time         `json:"startDate"`
+	EndDate         time.Time         `json:"endDate,omitempty"`         // zero if absent
+	Duration        float64           `json:"duration,omitempty"`        // DURATION in seconds, zero if absent
+	PlannedDuration float64           `json:"plannedDuration,omitempty"` // PLANNED-DURATION in seconds, zero if absent
+	SCTE35Cmd       string            `json:"scte35Cmd,omitempty"`       // SCTE35-CMD as hexadecimal sequence
+	SCTE35Out       string            `json:"scte35Out,omitempty"`       // SCTE35-OUT as hexadecimal sequence
+	SCTE35In        string            `json:"scte35In,omitempty"`        // SCTE35-IN as hexadecimal sequence
+	EndOnNext       bool              `json:"endOnNext,omitempty"`       // END-ON-NEXT=YES ends the range at the start of the next range of the same class
+	X               map[string]string `json:"x,omitempty"`               // client-defined X-<name> attributes
+	XQuoted         map[string]bool   `json:"xQuoted,omitempty"`         // names of X attributes written as quoted strings whatever their values
+}
+
+// SetDateRange attaches the date range to the current media segment, the
+// tag is written in front of the segment.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetDateRange(dr *DateRange) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	seg := p.Segments[p.last()]
+	seg.DateRanges = append(seg.DateRanges, dr)
+	p.buf.Reset()
+	return nil
+}
+
+// SCTE35DateRange converts the SCTE-35 cue (base64 or hexadecimal text of
+// splice_info_section) to the date range starting at start. A cue which
+// starts a break gives SCTE35-OUT and PLANNED-DURATION, a cue which ends
+// it gives SCTE35-IN, other cues give SCTE35-CMD. ID is the splice or
+// segmentation event ID, so OUT and IN cues of a break share it.
+func SCTE35DateRange(cue string, start time.Time) (*DateRange, error) {
+	data, err := scte35.Bytes(cue)
+	if err != nil {
+		return nil, err
+	}
+	info, err := scte35.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	dr := &DateRange{StartDate: start}
+	switch {
+	case info.SpliceInsert != nil:
+		dr.ID = strconv.FormatUint(uint64(info.SpliceInsert.EventID), 10)
+	case len(info.Segmentation) > 0:
+		dr.ID = strconv.FormatUint(uint64(info.Segmentation[0].EventID), 10)
+	default:
+		dr.ID = start.UTC().Format(DATETIME)
+	}
+	value := "0x" + strings.ToUpper(hex.EncodeToString(data))
+	switch {
+	case info.Out():
+		dr.SCTE35Out = value
+		dr.PlannedDuration = info.Duration().Seconds()
+	case info.In():
+		dr.SCTE35In = value
+	default:
+		dr.SCTE35Cmd = value
+	}
+	return dr, nil
+}
+
+// SetSCTE35DateRange attaches the date range of the SCTE-35 cue to the
+// current media segment, it starts at EXT-X-PROGRAM-DATE-TIME of the
+// segment, see SCTE35DateRange. A cue which ends a break repeats the date
+// range of its OUT cue with END-DATE and DURATION.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetSCTE35DateRange(cue string) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	seg := p.Segments[p.last()]
+	if seg.ProgramDateTime.IsZero() {
+		return errors.New("segment has no EXT-X-PROGRAM-DATE-TIME")
+	}
+	dr, err := SCTE35DateRange(cue, seg.ProgramDateTime)
+	if err != nil {
+		return err
+	}
+	if dr.SCTE35In != "" {
+		out := p.findDateRange(dr.ID)
+		if out == nil {
+			return fmt.Errorf("no date range %s for SCTE35-IN", dr.ID)
+		}
+		dr.StartDate, dr.EndDate = out.StartDate, seg.ProgramDateTime
+		dr.Duration = dr.EndDate.Sub(dr.StartDate).Seconds()
+	}
+	return p.SetDateRange(dr)
+}
+
+// findDateRange returns the last date range with the ID or nil.
+func (p *MediaPlaylist) findDateRange(id string) *DateRange {
+	for i := int(p.count) - 1; i >= 0; i-- {
+		ranges := p.segmentAt(uint(i)).DateRanges
+		for j := len(ranges) - 1; j >= 0; j-- {
+			if ranges[j].ID == id {
+				return ranges[j]
+			}
+		}
+	}
+	return nil
+}
+
+// end returns the end of the date range or zero time if it is unknown.
+func (dr *DateRange) end() time.Time {
+	if !dr.EndDate.IsZero() {
+		return dr.EndDate
+	}
+	if dr.Duration > 0 {
+		return dr.StartDate.Add(seconds(dr.Duration))
+	}
+	return time.Time{}
+}
+
+// ValidateDateRanges checks the date ranges of the playlist:
+//   - tags with the same ID may repeat (e.g. to add END-DATE later) but their
+//     common attributes must not differ, otherwise IDs must be unique;
+//   - END-ON-NEXT ranges must have CLASS and must not have END-DATE or DURATION;
+//   - END-DATE must not precede START-DATE and must agree with DURATION;
+//   - ranges of the same class must not overlap.
+func (p *MediaPlaylist) ValidateDateRanges() error {
+	byID := make(map[string]*DateRange)
+	var ranges []*DateRange
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg == nil {
+			continue
+		}
+		for _, dr := range seg.DateRanges {
+			if dr.ID == "" {
+				return errors.New("date range without ID")
+			}
+			if dr.StartDate.IsZero() {
+				return fmt.Errorf("date range %s without START-DATE", dr.ID)
+			}
+			if dr.EndOnNext {
+				if dr.Class == "" {
+					return fmt.Errorf("date range %s: END-ON-NEXT requires CLASS", dr.ID)
+				}
+				if !dr.EndDate.IsZero() || dr.Duration != 0 {
+					return fmt.Errorf("date range %s: END-ON-NEXT excludes END-DATE and DURATION", dr.ID)
+				}
+			}
+			if !dr.EndDate.IsZero() {
+				if dr.EndDate.Before(dr.StartDate) {
+					return fmt.Errorf("date range %s ends before it starts", dr.ID)
+				}
+				if dr.Duration != 0 && !dr.StartDate.Add(seconds(dr.Duration)).Equal(dr.EndDate) {
+					return fmt.Errorf("date range %s: END-DATE doesn't match DURATION", dr.ID)
+				}
+			}
+			if prev, ok := byID[dr.ID]; ok {
+				if err := mergeDateRange(prev, dr); err != nil {
+					return err
+				}
+				continue
+			}
+			merged := *dr
+			byID[dr.ID] = &merged
+			ranges = append(ranges, &merged)
+		}
+	}
+
+	// check overlaps per class in order of start dates
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].StartDate.Before(ranges[j].StartDate) })
+	last := make(map[string]*DateRange)
+	for _, dr := range ranges {
+		if dr.Class == "" {
+			continue
+		}
+		if prev, ok := last[dr.Class]; ok {
+			if end := prev.end(); !end.IsZero() && end.After(dr.StartDate) {
+				return fmt.Errorf("date ranges %s and %s of class %s overlap", prev.ID, dr.ID, dr.Class)
+			}
+		}
+		last[dr.Class] = dr
+	}
+	return nil
+}
+
+// mergeDateRange checks that the repeated tag doesn't change attributes of
+// the range and adds attributes it introduces.
+func mergeDateRange(prev, dr *DateRange) error {
+	conflict := func(name string) error {
+		return fmt.Errorf("date range %s: %s differs between tags with the same ID", dr.ID, name)
+	}
+	switch {
+	case dr.Class != "" && prev.Class != "" && dr.Class != prev.Class:
+		return conflict("CLASS")
+	case !dr.StartDate.Equal(prev.StartDate):
+		return conflict("START-DATE")
+	case !dr.EndDate.IsZero() && !prev.EndDate.IsZero() && !dr.EndDate.Equal(prev.EndDate):
+		return conflict("END-DATE")
+	case dr.Duration != 0 && prev.Duration != 0 && dr.Duration != prev.Duration:
+		return conflict("DURATION")
+	}
+	if prev.Class == "" {
+		prev.Class = dr.Class
+	}
+	if prev.EndDate.IsZero() {
+		prev.EndDate = dr.EndDate
+	}
+	if prev.Duration == 0 {
+		prev.Duration = dr.Duration
+	}
+	return nil
+}
+
+// writeDateRanges writes EXT-X-DATERANGE tags.
+func writeDateRanges(buf *bytes.Buffer, ranges []*DateRange, eol string) {
+	for _, dr := range ranges {
+		buf.WriteString("#EXT-X-DATERANGE:ID=\"")
+		buf.WriteString(quotedString.Replace(dr.ID))
+		buf.WriteRune('"')
+		if dr.Class != "" {
+			buf.WriteString(",CLASS=\"")
+			buf.WriteString(quotedString.Replace(dr.Class))
+			buf.WriteRune('"')
+		}
+		buf.WriteString(",START-DATE=\"")
+		buf.WriteString(dr.StartDate.Format(DATETIME))
+		buf.WriteRune('"')
+		if !dr.EndDate.IsZero() {
+			buf.WriteString(",END-DATE=\"")
+			buf.WriteString(dr.EndDate.Format(DATETIME))
+			buf.WriteRune('"')
+		}
+		if dr.Duration != 0 {
+			buf.WriteString(",DURATION=")
+			buf.WriteString(strconv.FormatFloat(dr.Duration, 'f', -1, 64))
+		}
+		if dr.PlannedDuration != 0 {
+			buf.WriteString(",PLANNED-DURATION=")
+			buf.WriteString(strconv.FormatFloat(dr.PlannedDuration, 'f', -1, 64))
+		}
+		writeExtraAttributes(buf, dr.X, dr.XQuoted)
+		if dr.SCTE35Cmd != "" {
+			buf.WriteString(",SCTE35-CMD=")
+			buf.WriteString(dr.SCTE35Cmd)
+		}
+		if dr.SCTE35Out != "" {
+			buf.WriteString(",SCTE35-OUT=")
+			buf.WriteString(dr.SCTE35Out)
+		}
+		if dr.SCTE35In != "" {
+			buf.WriteString(",SCTE35-IN=")
+			buf.WriteString(dr.SCTE35In)
+		}
+		if dr.EndOnNext {
+			buf.WriteString(",END-ON-NEXT=YES")
+		}
+		buf.WriteString(eol)
+	}
+}
+
+// decodeDateRange parses attributes of EXT-X-DATERANGE tag.
+func decodeDateRange(line string, strict bool) (*DateRange, error) {
+	var err error
+	dr := new(DateRange)
+	quoted := quotedParams(line)
+	for k, v := range decodeParamsLine(line) {
+		switch k {
+		case "ID":
+			dr.ID = v
+		case "CLASS":
+			dr.Class = v
+		case "START-DATE":
+			if dr.StartDate, err = TimeParse(v); strict && err != nil {
+				return nil, err
+			}
+		case "END-DATE":
+			if dr.EndDate, err = TimeParse(v); strict && err != nil {
+				return nil, err
+			}
+		case "DURATION":
+			if dr.Duration, err = strconv.ParseFloat(v, 64); strict && err != nil {
+				return nil, fmt.Errorf("Invalid DURATION: %s: %v", v, err)
+			}
+		case "PLANNED-DURATION":
+			if dr.PlannedDuration, err = strconv.ParseFloat(v, 64); strict && err != nil {
+				return nil, fmt.Errorf("Invalid PLANNED-DURATION: %s: %v", v, err)
+			}
+		case "SCTE35-CMD":
+			dr.SCTE35Cmd = v
+		case "SCTE35-OUT":
+			dr.SCTE35Out = v
+		case "SCTE35-IN":
+			dr.SCTE35In = v
+		case "END-ON-NEXT":
+			dr.EndOnNext = v == "YES"
+		default:
+			if strings.HasPrefix(k, "X-") {
+				if dr.X == nil {
+					dr.X = make(map[string]string)
+				}
+				dr.X[k] = v
+				if quoted[k] {
+					if dr.XQuoted == nil {
+						dr.XQuoted = make(map[string]bool)
+					}
+					dr.XQuoted[k] = true
+				}
+			}
+		}
+	}
+	return dr, nil
+}
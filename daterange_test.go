@@ -0,0 +1,176 @@
+/*
+ Package m3u8. Date range tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeDateRange(t *testing.T) {
+	in := `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="ad1",CLASS="com.example.ad",START-DATE="2020-01-01T00:00:00Z",DURATION=8,X-AD-ID="42",SCTE35-OUT=0xFC30
+#EXTINF:4,
+s0.ts
+#EXTINF:4,
+s1.ts
+`
+	p, _, err := DecodeFrom(bytes.NewBufferString(in), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := p.(*MediaPlaylist)
+	drs := pp.Segments[0].DateRanges
+	if len(drs) != 1 {
+		t.Fatalf("Expected 1 date range, got %d", len(drs))
+	}
+	dr := drs[0]
+	if dr.ID != "ad1" || dr.Class != "com.example.ad" || dr.Duration != 8 || dr.X["X-AD-ID"] != "42" || dr.SCTE35Out != "0xFC30" {
+		t.Errorf("Unexpected date range %+v", dr)
+	}
+	if !dr.StartDate.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected START-DATE %v", dr.StartDate)
+	}
+	if len(pp.Segments[1].DateRanges) != 0 {
+		t.Error("Date range must be attached to the first segment only")
+	}
+	if out := pp.String(); !strings.Contains(out, "#EXT-X-DATERANGE:ID=\"ad1\",CLASS=\"com.example.ad\",START-DATE=\"2020-01-01T00:00:00Z\",DURATION=8,X-AD-ID=\"42\",SCTE35-OUT=0xFC30\n#EXTINF:4.000,\ns0.ts\n") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+}
+
+func TestDecodeDateRangeQuotedX(t *testing.T) {
+	in := `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-DATERANGE:ID="ad1",START-DATE="2020-01-01T00:00:00Z",X-COM-ID="123",X-COUNT=5
+#EXTINF:4.000,
+s0.ts
+`
+	p, _, err := DecodeFrom(bytes.NewBufferString(in), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := p.(*MediaPlaylist).String()
+	if !strings.Contains(out, `#EXT-X-DATERANGE:ID="ad1",START-DATE="2020-01-01T00:00:00Z",X-COM-ID="123",X-COUNT=5`+"\n") {
+		t.Errorf("Quoting of X attributes isn't kept:\n%s", out)
+	}
+}
+
+func TestValidateDateRanges(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		ranges []*DateRange
+		valid  bool
+	}{
+		{"separate classes", []*DateRange{
+			{ID: "a", Class: "ad", StartDate: start, Duration: 10},
+			{ID: "b", Class: "chapter", StartDate: start.Add(5 * time.Second), Duration: 10},
+		}, true},
+		{"repeated ID adds END-DATE", []*DateRange{
+			{ID: "a", Class: "ad", StartDate: start},
+			{ID: "a", StartDate: start, EndDate: start.Add(10 * time.Second)},
+		}, true},
+		{"repeated ID changes START-DATE", []*DateRange{
+			{ID: "a", StartDate: start},
+			{ID: "a", StartDate: start.Add(time.Second)},
+		}, false},
+		{"END-ON-NEXT", []*DateRange{
+			{ID: "a", Class: "ad", StartDate: start, EndOnNext: true},
+			{ID: "b", Class: "ad", StartDate: start.Add(10 * time.Second), EndOnNext: true},
+		}, true},
+		{"END-ON-NEXT without CLASS", []*DateRange{
+			{ID: "a", StartDate: start, EndOnNext: true},
+		}, false},
+		{"END-ON-NEXT with DURATION", []*DateRange{
+			{ID: "a", Class: "ad", StartDate: start, Duration: 5, EndOnNext: true},
+		}, false},
+		{"END-DATE before START-DATE", []*DateRange{
+			{ID: "a", StartDate: start, EndDate: start.Add(-time.Second)},
+		}, false},
+		{"END-DATE and DURATION differ", []*DateRange{
+			{ID: "a", StartDate: start, EndDate: start.Add(time.Second), Duration: 2},
+		}, false},
+		{"overlap of the same class", []*DateRange{
+			{ID: "a", Class: "ad", StartDate: start, Duration: 10},
+			{ID: "b", Class: "ad", StartDate: start.Add(5 * time.Second), Duration: 10},
+		}, false},
+	}
+	for _, tc := range tests {
+		p, _ := NewMediaPlaylist(0, 2)
+		p.Append("s0.ts", 4, "")
+		for _, dr := range tc.ranges {
+			p.SetDateRange(dr)
+		}
+		err := p.ValidateDateRanges()
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestSetDateRangeEmpty(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 1)
+	if err := p.SetDateRange(&DateRange{ID: "a"}); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+}
+
+func TestSCTE35DateRange(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, _ := NewMediaPlaylist(0, 4)
+	p.Append("s0.ts", 6, "")
+	if err := p.SetSCTE35DateRange("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg=="); err == nil {
+		t.Error("Expected error of segment without program date time")
+	}
+	p.SetProgramDateTime(start)
+	if err := p.SetSCTE35DateRange("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg=="); err != nil {
+		t.Fatal(err)
+	}
+	p.Append("s1.ts", 6, "")
+	p.Append("s2.ts", 6, "")
+	p.SetProgramDateTime(start.Add(12 * time.Second))
+	if err := p.SetSCTE35DateRange("/DAvAAAAAAAA///wBQb+dGKQoAAZAhdDVUVJSAAAjn+fCAgAAAAALKChijUCAKnMZ1g="); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ValidateDateRanges(); err != nil {
+		t.Error(err)
+	}
+	out := p.Segments[0].DateRanges[0]
+	if out.ID != "1207959694" || out.PlannedDuration != 307 || !strings.HasPrefix(out.SCTE35Out, "0xFC3034") || out.SCTE35In != "" {
+		t.Errorf("Unexpected OUT date range %+v", out)
+	}
+	in := p.Segments[2].DateRanges[0]
+	if in.ID != out.ID || !in.StartDate.Equal(start) || in.Duration != 12 || !strings.HasPrefix(in.SCTE35In, "0xFC302F") {
+		t.Errorf("Unexpected IN date range %+v", in)
+	}
+	expected := `#EXT-X-DATERANGE:ID="1207959694",START-DATE="2020-01-01T00:00:00Z",END-DATE="2020-01-01T00:00:12Z",DURATION=12,SCTE35-IN=0xFC302F`
+	if !strings.Contains(p.String(), expected) {
+		t.Errorf("Expected %s in playlist:\n%s", expected, p)
+	}
+
+	dr, err := SCTE35DateRange("/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.ID != "1207959695" || dr.SCTE35Out == "" || dr.PlannedDuration < 60.29 || dr.PlannedDuration > 60.3 {
+		t.Errorf("Unexpected date range of splice insert %+v", dr)
+	}
+	if _, err = SCTE35DateRange("not a cue", start); err == nil {
+		t.Error("Expected error of invalid cue")
+	}
+}
@@ -0,0 +1,213 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines variables of playlists (EXT-X-DEFINE) and
+ substitution of variable references.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	reVariableName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	reVariableRef  = regexp.MustCompile(`\{\$[a-zA-Z0-9_-]+\}`)
+)
+
+// DefineType is the kind of EXT-X-DEFINE tag.
+type DefineType uint
+
+const (
+	// DefineValue declares a variable with a value (NAME and VALUE attributes).
+	DefineValue DefineType = iota
+	// DefineImport imports a variable of the master playlist (IMPORT attribute).
+	DefineImport
+	// DefineQueryParam takes the variable from the query of the playlist URI (QUERYPARAM attribute).
+	DefineQueryParam
+)
+
+// This structure represents a variable of a playlist.
+//
+// Realizes EXT-X-DEFINE tag.
+type Define struct {
+	Name  string     `json:"name"`
+	Type  DefineType `json:"type"`
+	Value string     `json:"value,omitempty"` // the value for DefineValue, the resolved value for other types if decoded WithVariables
+}
+
+// Variables are the external values for variable substitution during
+// decoding of a playlist.
+type Variables struct {
+	Imports map[string]string // variables of the master playlist for IMPORT, see MasterPlaylist.Variables
+	Query   url.Values        // query parameters of the playlist URI for QUERYPARAM
+}
+
+// WithVariables enables substitution of variable references ({$name}) in
+// URIs and attribute values when decoding the master playlist.
+func (p *MasterPlaylist) WithVariables(vars *Variables) Playlist {
+	p.vars = vars
+	return p
+}
+
+// WithVariables enables substitution of variable references ({$name}) in
+// URIs and attribute values when decoding the media playlist.
+func (p *MediaPlaylist) WithVariables(vars *Variables) Playlist {
+	p.vars = vars
+	return p
+}
+
+// AppendDefine declares a variable of the master playlist.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) AppendDefine(define Define) error {
+	if err := checkDefine(p.Defines, define); err != nil {
+		return err
+	}
+	if define.Type == DefineImport {
+		return errors.New("IMPORT is not allowed in master playlist")
+	}
+	p.Defines = append(p.Defines, define)
+	version(&p.ver, 8)
+	p.buf.Reset()
+	return nil
+}
+
+// AppendDefine declares a variable of the media playlist.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendDefine(define Define) error {
+	if err := checkDefine(p.Defines, define); err != nil {
+		return err
+	}
+	p.Defines = append(p.Defines, define)
+	version(&p.ver, 8)
+	p.buf.Reset()
+	return nil
+}
+
+// Variables returns values of the variables of the master playlist, they
+// are imported by media playlists with IMPORT attribute.
+func (p *MasterPlaylist) Variables() map[string]string {
+	return defineValues(p.Defines)
+}
+
+// Variables returns values of the variables of the media playlist.
+func (p *MediaPlaylist) Variables() map[string]string {
+	return defineValues(p.Defines)
+}
+
+func defineValues(defines []Define) map[string]string {
+	out := make(map[string]string, len(defines))
+	for _, d := range defines {
+		out[d.Name] = d.Value
+	}
+	return out
+}
+
+func checkDefine(defines []Define, define Define) error {
+	if !reVariableName.MatchString(define.Name) {
+		return fmt.Errorf("invalid variable name %q", define.Name)
+	}
+	for _, d := range defines {
+		if d.Name == define.Name {
+			return fmt.Errorf("variable %s is already defined", define.Name)
+		}
+	}
+	return nil
+}
+
+// writeDefines writes EXT-X-DEFINE tags.
+func writeDefines(buf *bytes.Buffer, defines []Define, eol string) {
+	for _, d := range defines {
+		buf.WriteString("#EXT-X-DEFINE:")
+		switch d.Type {
+		case DefineImport:
+			buf.WriteString("IMPORT=\"")
+			buf.WriteString(quotedString.Replace(d.Name))
+		case DefineQueryParam:
+			buf.WriteString("QUERYPARAM=\"")
+			buf.WriteString(quotedString.Replace(d.Name))
+		default:
+			buf.WriteString("NAME=\"")
+			buf.WriteString(quotedString.Replace(d.Name))
+			buf.WriteString("\",VALUE=\"")
+			buf.WriteString(quotedString.Replace(d.Value))
+		}
+		buf.WriteRune('"')
+		buf.WriteString(eol)
+	}
+}
+
+// decodeDefine parses attributes of EXT-X-DEFINE tag. The value of the
+// variable is resolved and registered for substitution when the playlist
+// is decoded with variables.
+func decodeDefine(line string, state *decodingState, vars *Variables) (Define, error) {
+	var define Define
+	for k, v := range decodeParamsLine(line) {
+		switch k {
+		case "NAME":
+			define.Name, define.Type = v, DefineValue
+		case "VALUE":
+			define.Value = v
+		case "IMPORT":
+			define.Name, define.Type = v, DefineImport
+		case "QUERYPARAM":
+			define.Name, define.Type = v, DefineQueryParam
+		}
+	}
+	if define.Name == "" {
+		return define, errors.New("EXT-X-DEFINE without variable name")
+	}
+	if vars == nil {
+		return define, nil
+	}
+	var ok bool
+	switch define.Type {
+	case DefineImport:
+		if define.Value, ok = vars.Imports[define.Name]; !ok {
+			return define, fmt.Errorf("imported variable %s is not defined", define.Name)
+		}
+	case DefineQueryParam:
+		if _, ok = vars.Query[define.Name]; !ok {
+			return define, fmt.Errorf("query parameter %s is absent", define.Name)
+		}
+		define.Value = vars.Query.Get(define.Name)
+	}
+	if state.vars == nil {
+		state.vars = make(map[string]string)
+	}
+	if _, ok = state.vars[define.Name]; ok {
+		return define, fmt.Errorf("variable %s is already defined", define.Name)
+	}
+	state.vars[define.Name] = define.Value
+	return define, nil
+}
+
+// expandVariables substitutes variable references in the line. Lines are
+// expanded only for playlists decoded with variables.
+func expandVariables(line string, state *decodingState, vars *Variables) (string, error) {
+	if vars == nil || strings.HasPrefix(line, "#EXT-X-DEFINE:") || !strings.Contains(line, "{$") {
+		return line, nil
+	}
+	var err error
+	line = reVariableRef.ReplaceAllStringFunc(line, func(ref string) string {
+		if value, ok := state.vars[ref[2:len(ref)-1]]; ok {
+			return value
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined variable reference %s", ref)
+		}
+		return ref
+	})
+	return line, err
+}
@@ -0,0 +1,99 @@
+/*
+ Package m3u8. Variable substitution tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testDefineMaster = `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-DEFINE:NAME="cdn",VALUE="https://cdn.example.com"
+#EXT-X-DEFINE:QUERYPARAM="token"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,CODECS="avc1.4d401f"
+{$cdn}/hi.m3u8?token={$token}
+`
+
+func TestDecodeMasterPlaylistWithVariables(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.WithVariables(&Variables{Query: url.Values{"token": {"abc"}}})
+	if err := m.DecodeFrom(strings.NewReader(testDefineMaster), true); err != nil {
+		t.Fatal(err)
+	}
+	if uri := m.Variants[0].URI; uri != "https://cdn.example.com/hi.m3u8?token=abc" {
+		t.Errorf("Unexpected expanded URI %s", uri)
+	}
+	vars := m.Variables()
+	if vars["cdn"] != "https://cdn.example.com" || vars["token"] != "abc" {
+		t.Errorf("Unexpected variables %v", vars)
+	}
+
+	media := `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-TARGETDURATION:4
+#EXT-X-DEFINE:IMPORT="cdn"
+#EXT-X-MAP:URI="{$cdn}/init.mp4"
+#EXTINF:4,
+{$cdn}/seg0.mp4
+`
+	p, _ := NewMediaPlaylist(0, 1)
+	p.WithVariables(&Variables{Imports: vars})
+	if err := p.DecodeFrom(strings.NewReader(media), true); err != nil {
+		t.Fatal(err)
+	}
+	if p.Map.URI != "https://cdn.example.com/init.mp4" || p.Segments[0].URI != "https://cdn.example.com/seg0.mp4" {
+		t.Errorf("Unexpected expanded URIs %s %s", p.Map.URI, p.Segments[0].URI)
+	}
+	if out := p.String(); !strings.Contains(out, "#EXT-X-DEFINE:IMPORT=\"cdn\"\n") {
+		t.Errorf("Import must be encoded as is:\n%s", out)
+	}
+}
+
+func TestDecodeUndefinedVariable(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.WithVariables(&Variables{})
+	if err := m.DecodeFrom(strings.NewReader(testDefineMaster), true); err == nil {
+		t.Error("Expected error for absent query parameter")
+	}
+	// references are kept as is without substitution
+	m = NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(testDefineMaster), true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Variants[0].URI != "{$cdn}/hi.m3u8?token={$token}" {
+		t.Errorf("Unexpected URI %s", m.Variants[0].URI)
+	}
+}
+
+func TestAppendDefine(t *testing.T) {
+	m := NewMasterPlaylist()
+	if err := m.AppendDefine(Define{Name: "cdn", Value: "https://cdn.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AppendDefine(Define{Name: "cdn", Value: "x"}); err == nil {
+		t.Error("Expected error for duplicate variable")
+	}
+	if err := m.AppendDefine(Define{Name: "bad name"}); err == nil {
+		t.Error("Expected error for invalid name")
+	}
+	if err := m.AppendDefine(Define{Name: "x", Type: DefineImport}); err == nil {
+		t.Error("Expected error for IMPORT in master playlist")
+	}
+	m.AppendDefine(Define{Name: "token", Type: DefineQueryParam})
+	m.Append("{$cdn}/hi.m3u8", nil, VariantParams{Bandwidth: 1000000})
+	expected := "#EXTM3U\n#EXT-X-VERSION:8\n" +
+		"#EXT-X-DEFINE:NAME=\"cdn\",VALUE=\"https://cdn.example.com\"\n" +
+		"#EXT-X-DEFINE:QUERYPARAM=\"token\"\n"
+	if out := m.String(); !strings.HasPrefix(out, expected) || !strings.Contains(out, "\n{$cdn}/hi.m3u8\n") {
+		t.Errorf("Expected prefix:\n%s\ngot:\n%s", expected, out)
+	}
+}
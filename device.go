@@ -0,0 +1,121 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines per-device adjustments of playlists selected by
+ user agent.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// DeviceProfile describes adjustments of playlists served to a class of
+// client devices.
+type DeviceProfile struct {
+	Name             string
+	UserAgent        *regexp.Regexp        // matches user agents of the devices, nil matches any user agent
+	Filter           func(v *Variant) bool // keeps the variants it returns true for, nil keeps all of them
+	StripCodecs      []string              // removes variants with codecs starting with any of the prefixes, e.g. "hvc1", "hev1"
+	IntegerDurations bool                  // encodes segment durations as integers
+	DropLowLatency   bool                  // removes LL-HLS tags (parts, server control, rendition reports)
+}
+
+// DeviceProfiles is an ordered list of device profiles, the first profile
+// matching the user agent is used.
+type DeviceProfiles []DeviceProfile
+
+// Match returns the first profile matching the user agent or nil if none
+// of them does.
+func (d DeviceProfiles) Match(userAgent string) *DeviceProfile {
+	for i := range d {
+		if d[i].UserAgent == nil || d[i].UserAgent.MatchString(userAgent) {
+			return &d[i]
+		}
+	}
+	return nil
+}
+
+// ApplyMaster derives the master playlist for the user agent. It returns
+// the source playlist and an empty profile name if no profile matches.
+// The source playlist is not changed, see Experiment.Split for the sharing
+// rules of the result.
+func (d DeviceProfiles) ApplyMaster(userAgent string, p *MasterPlaylist) (*MasterPlaylist, string) {
+	profile := d.Match(userAgent)
+	if profile == nil {
+		return p, ""
+	}
+	return profile.Master(p), profile.Name
+}
+
+// ApplyMedia derives the media playlist for the user agent. It returns
+// the source playlist and an empty profile name if no profile matches.
+func (d DeviceProfiles) ApplyMedia(userAgent string, p *MediaPlaylist) (*MediaPlaylist, string) {
+	profile := d.Match(userAgent)
+	if profile == nil {
+		return p, ""
+	}
+	return profile.Media(p), profile.Name
+}
+
+// Master returns a copy of the master playlist with the variants not
+// suitable for the device removed.
+func (d *DeviceProfile) Master(p *MasterPlaylist) *MasterPlaylist {
+	out := p.shallowCopy()
+	for _, v := range p.Variants {
+		if d.Filter != nil && !d.Filter(v) {
+			continue
+		}
+		if d.stripped(v.Codecs) {
+			continue
+		}
+		out.Variants = append(out.Variants, v.copy())
+	}
+	return out
+}
+
+// stripped checks whether any of the comma separated codecs is removed by
+// the profile.
+func (d *DeviceProfile) stripped(codecs string) bool {
+	if len(d.StripCodecs) == 0 || codecs == "" {
+		return false
+	}
+	return hasCodec(codecs, d.StripCodecs)
+}
+
+// Media returns a copy of the media playlist adjusted for the device. The
+// source playlist is not changed, segments are shared with the source
+// unless the profile has to change them.
+func (d *DeviceProfile) Media(p *MediaPlaylist) *MediaPlaylist {
+	out := *p
+	out.buf = bytes.Buffer{}
+	out.Segments = make([]*MediaSegment, len(p.Segments))
+	copy(out.Segments, p.Segments)
+	if d.IntegerDurations {
+		out.durationAsInt = true
+	}
+	if d.DropLowLatency {
+		out.ServerControl = nil
+		out.PartTarget = 0
+		out.PendingParts = nil
+		out.PreloadHints = nil
+		out.RenditionReports = nil
+		out.SkippedSegments = 0
+		out.RecentlyRemoved = nil
+		for i, seg := range out.Segments {
+			if seg != nil && len(seg.Parts) > 0 {
+				nseg := *seg
+				nseg.Parts = nil
+				out.Segments[i] = &nseg
+			}
+		}
+	}
+	return &out
+}
@@ -0,0 +1,71 @@
+/*
+ Package m3u8. Device profile tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testProfiles = DeviceProfiles{
+	{
+		Name:             "old-android",
+		UserAgent:        regexp.MustCompile(`Android [1-4]\.`),
+		StripCodecs:      []string{"hvc1", "hev1"},
+		IntegerDurations: true,
+		DropLowLatency:   true,
+	},
+	{Name: "default"},
+}
+
+func TestDeviceProfilesApplyMaster(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("hevc.m3u8", nil, VariantParams{Bandwidth: 2000000, Codecs: "hvc1.1.6.L93.B0,mp4a.40.2"})
+	m.Append("avc.m3u8", nil, VariantParams{Bandwidth: 3000000, Codecs: "avc1.4d401f,mp4a.40.2"})
+
+	out, name := testProfiles.ApplyMaster("Mozilla/5.0 (Linux; Android 4.4.2; Nexus 5)", m)
+	if name != "old-android" {
+		t.Fatalf("Expected old-android profile, got %q", name)
+	}
+	if len(out.Variants) != 1 || out.Variants[0].URI != "avc.m3u8" {
+		t.Errorf("HEVC variant must be removed: %+v", out.Variants)
+	}
+	if len(m.Variants) != 2 {
+		t.Error("Source playlist must not be changed")
+	}
+
+	out, name = testProfiles.ApplyMaster("Mozilla/5.0 (Linux; Android 10; Pixel 3)", m)
+	if name != "default" || len(out.Variants) != 2 {
+		t.Errorf("Expected all variants for the default profile, got %s %+v", name, out.Variants)
+	}
+}
+
+func TestDeviceProfilesApplyMedia(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.AppendPartial("part0.0.mp4", 1.002, true)
+	p.Append("seg0.mp4", 4.004, "")
+	p.AppendPartial("part1.0.mp4", 1.002, true)
+	p.SetServerControl(&ServerControl{CanBlockReload: true, PartHoldBack: 3})
+	p.SetPreloadHints([]*PreloadHint{{Type: "PART", URI: "part1.1.mp4"}})
+	source := p.String()
+
+	out, _ := testProfiles.ApplyMedia("Android 2.3", p)
+	encoded := out.String()
+	if strings.Contains(encoded, "#EXT-X-PART") || strings.Contains(encoded, "#EXT-X-SERVER-CONTROL") || strings.Contains(encoded, "#EXT-X-PRELOAD-HINT") {
+		t.Errorf("LL-HLS tags must be removed:\n%s", encoded)
+	}
+	if !strings.Contains(encoded, "#EXTINF:5,") {
+		t.Errorf("Durations must be integers:\n%s", encoded)
+	}
+	if p.String() != source || len(p.Segments[0].Parts) != 1 {
+		t.Errorf("Source playlist must not be changed:\n%s", p)
+	}
+}
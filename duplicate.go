@@ -0,0 +1,112 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines handling of header tags repeated in decoded playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy tells the decoder what to do with header tags which
+// must appear once but are repeated in a playlist. Duplicates are recorded
+// in Warnings of the playlist whatever the policy is.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateLastWins keeps the value of the last tag (the default).
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins keeps the value of the first tag and ignores the repeated ones.
+	DuplicateFirstWins
+	// DuplicateError fails decoding in strict mode, repeated tags are ignored otherwise.
+	DuplicateError
+)
+
+// Header tags which must not appear more than once in a playlist.
+var (
+	masterHeaderTags = []string{
+		"#EXT-X-VERSION",
+		"#EXT-X-INDEPENDENT-SEGMENTS",
+		"#EXT-X-START",
+		"#EXT-X-CONTENT-STEERING",
+	}
+	mediaHeaderTags = []string{
+		"#EXT-X-VERSION",
+		"#EXT-X-TARGETDURATION",
+		"#EXT-X-MEDIA-SEQUENCE",
+		"#EXT-X-DISCONTINUITY-SEQUENCE",
+		"#EXT-X-PLAYLIST-TYPE",
+		"#EXT-X-INDEPENDENT-SEGMENTS",
+		"#EXT-X-START",
+		"#EXT-X-I-FRAMES-ONLY",
+		"#EXT-X-SERVER-CONTROL",
+		"#EXT-X-PART-INF",
+		"#EXT-X-ENDLIST",
+	}
+)
+
+// WithDuplicatePolicy sets handling of repeated header tags when decoding
+// the master playlist.
+func (p *MasterPlaylist) WithDuplicatePolicy(policy DuplicatePolicy) Playlist {
+	p.duplicates = policy
+	return p
+}
+
+// WithDuplicatePolicy sets handling of repeated header tags when decoding
+// the media playlist.
+func (p *MediaPlaylist) WithDuplicatePolicy(policy DuplicatePolicy) Playlist {
+	p.duplicates = policy
+	return p
+}
+
+// DecodeWithPolicy detects the type of playlist and decodes it handling
+// repeated header tags according to the policy. It accepts either
+// bytes.Buffer or io.Reader as input.
+func DecodeWithPolicy(input interface{}, strict bool, policy DuplicatePolicy) (Playlist, ListType, error) {
+	opts := DecodeOptions{Strict: strict}
+	buf, err := readInput(input, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decode(buf, opts, nil, policy)
+}
+
+// checkDuplicate registers header tags seen by the decoder. It returns
+// true if the line must be skipped.
+func checkDuplicate(line string, tags []string, seen map[string]bool, policy DuplicatePolicy, warnings *[]string) (bool, error) {
+	if !strings.HasPrefix(line, "#EXT-X-") {
+		return false, nil
+	}
+	name := line
+	if i := strings.IndexByte(line, ':'); i != -1 {
+		name = line[:i]
+	}
+	for _, tag := range tags {
+		if tag != name {
+			continue
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			return false, nil
+		}
+		warning := fmt.Sprintf("duplicate %s tag", tag)
+		*warnings = append(*warnings, warning)
+		switch policy {
+		case DuplicateFirstWins:
+			return true, nil
+		case DuplicateError:
+			return true, errors.New(warning)
+		}
+		return false, nil
+	}
+	return false, nil
+}
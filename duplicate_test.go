@@ -0,0 +1,88 @@
+/*
+ Package m3u8. Duplicate tag tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+const duplicateTargetDuration = `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-TARGETDURATION:6
+#EXTINF:4.000,
+seg1.ts
+`
+
+func TestDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		policy   DuplicatePolicy
+		duration float64
+		fails    bool
+	}{
+		{DuplicateLastWins, 6, false},
+		{DuplicateFirstWins, 4, false},
+		{DuplicateError, 0, true},
+	}
+	for _, tc := range tests {
+		p, _ := NewMediaPlaylist(0, 1)
+		p.WithDuplicatePolicy(tc.policy)
+		err := p.DecodeFrom(strings.NewReader(duplicateTargetDuration), true)
+		if tc.fails {
+			if err == nil {
+				t.Errorf("Policy %d: expected error", tc.policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Policy %d: %v", tc.policy, err)
+		}
+		if p.TargetDuration != tc.duration {
+			t.Errorf("Policy %d: expected target duration %v, got %v", tc.policy, tc.duration, p.TargetDuration)
+		}
+		if len(p.Warnings) != 1 || p.Warnings[0] != "duplicate #EXT-X-TARGETDURATION tag" {
+			t.Errorf("Policy %d: unexpected warnings %v", tc.policy, p.Warnings)
+		}
+	}
+}
+
+func TestDecodeWithPolicy(t *testing.T) {
+	p, listType, err := DecodeWithPolicy(strings.NewReader(duplicateTargetDuration), true, DuplicateFirstWins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listType != MEDIA {
+		t.Fatalf("Expected media playlist, got %v", listType)
+	}
+	if pp := p.(*MediaPlaylist); pp.TargetDuration != 4 || len(pp.Warnings) != 1 {
+		t.Errorf("Unexpected target duration %v and warnings %v", pp.TargetDuration, pp.Warnings)
+	}
+	if _, _, err = DecodeWithPolicy(strings.NewReader(duplicateTargetDuration), true, DuplicateError); err == nil {
+		t.Error("Expected error for duplicate tag")
+	}
+}
+
+func TestDuplicatePolicyMaster(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-VERSION:7
+#EXT-X-STREAM-INF:BANDWIDTH=1000000
+low.m3u8
+`
+	m := NewMasterPlaylist()
+	m.WithDuplicatePolicy(DuplicateFirstWins)
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version() != 6 || len(m.Warnings) != 1 {
+		t.Errorf("Unexpected version %d and warnings %v", m.Version(), m.Warnings)
+	}
+}
@@ -0,0 +1,91 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines errors and warnings of the decoder, so linters may
+ report where a playlist is malformed.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingHeader is reported by strict decoding of playlists which don't
+// start with #EXTM3U.
+var ErrMissingHeader = errors.New("#EXTM3U absent")
+
+// ParseError describes the line of the playlist the decoder failed on. The
+// cause is available to errors.Is and errors.As.
+type ParseError struct {
+	Line int    // number of the line counting from 1
+	Tag  string // tag of the line without attributes, empty for URI lines
+	Err  error  // the cause
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseError wraps the error of the n-th line of the playlist.
+func parseError(n int, line string, err error) error {
+	return &ParseError{Line: n, Tag: lineTag(line), Err: err}
+}
+
+// lineTag returns the tag of the line without attributes.
+func lineTag(line string) string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#") {
+		return ""
+	}
+	if i := strings.IndexByte(line, ':'); i != -1 {
+		return line[:i]
+	}
+	return line
+}
+
+// missingHeader is the error of playlists without #EXTM3U.
+func missingHeader() error {
+	return &ParseError{Line: 1, Tag: "#EXTM3U", Err: ErrMissingHeader}
+}
+
+// Warning describes a problem tolerated by the decoder, e.g. a duplicate
+// header tag or an unknown attribute. Warnings are collected in Warnings
+// of the playlist, see also DecodeOptions.WarningHandler.
+type Warning struct {
+	Line    int    // number of the line counting from 1
+	Tag     string // tag of the line without attributes, empty for URI lines
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// reportWarnings passes warnings added by the n-th line of the playlist
+// to the handler.
+func reportWarnings(handler func(Warning), n int, line string, warnings []string) {
+	if handler == nil {
+		return
+	}
+	for _, message := range warnings {
+		handler(Warning{Line: n, Tag: lineTag(line), Message: message})
+	}
+}
+
+// unknownAttribute is the warning about the attribute the decoder doesn't
+// know and drops.
+func unknownAttribute(tag, attribute string) string {
+	return fmt.Sprintf("unknown attribute %s of %s", attribute, tag)
+}
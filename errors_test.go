@@ -0,0 +1,85 @@
+/*
+ Package m3u8. Parse errors tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:3
+
+#EXT-X-TARGETDURATION:ten
+#EXTINF:10.000,
+seg0.ts
+`
+	_, _, err := DecodeFrom(strings.NewReader(playlist), true)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected ParseError, got %v", err)
+	}
+	if perr.Line != 4 || perr.Tag != "#EXT-X-TARGETDURATION" {
+		t.Errorf("Expected error at line 4 of #EXT-X-TARGETDURATION, got %+v", perr)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Expected syntax error of the value, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "line 4: ") {
+		t.Errorf("Expected line number in %q", err)
+	}
+
+	p, _ := NewMediaPlaylist(0, 1)
+	err = p.DecodeFrom(strings.NewReader("#EXT-X-TARGETDURATION:10\n#EXTINF:10.000,\nseg0.ts\n"), true)
+	if !errors.Is(err, ErrMissingHeader) {
+		t.Errorf("Expected missing header error, got %v", err)
+	}
+}
+
+func TestWarningHandler(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-TARGETDURATION:10
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",X-VENDOR=1
+#EXTINF:10.000,
+#EXT-X-KEY:METHOD=NONE
+seg0.ts
+`
+	var warnings []Warning
+	opts := DecodeOptions{Strict: true, WarningHandler: func(w Warning) { warnings = append(warnings, w) }}
+	p, _, err := DecodeWithOptions(strings.NewReader(playlist), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Warning{
+		{3, "#EXT-X-TARGETDURATION", "duplicate #EXT-X-TARGETDURATION tag"},
+		{4, "#EXT-X-KEY", "unknown attribute X-VENDOR of EXT-X-KEY"},
+		{6, "#EXT-X-KEY", "EXT-X-KEY between EXTINF and segment URI"},
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Unexpected warnings\ngot: %v\nexp: %v", warnings, expected)
+	}
+	if len(p.(*MediaPlaylist).Warnings) != len(expected) {
+		t.Errorf("Expected warnings in the playlist, got %q", p.(*MediaPlaylist).Warnings)
+	}
+
+	warnings = nil
+	m := NewMasterPlaylist()
+	if err = m.DecodeWithOptions(strings.NewReader("#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aud\",NAME=\"en\",X-FOO=1\n"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].String() != "line 2: unknown attribute X-FOO of EXT-X-MEDIA" {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,202 @@
+// Package packager is a reference implementation of a live packager built
+// on the public API of m3u8 library. It watches a directory for new media
+// segments, adds them to a sliding media playlist, persists the playlist
+// atomically and serves it over HTTP with support of blocking playlist
+// reload and delta updates of Low-Latency HLS.
+package packager
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkollar/m3u8"
+)
+
+// Packager maintains the live media playlist of segments in a directory.
+type Packager struct {
+	Dir             string   // directory watched for segments, the playlist is written there too
+	Playlist        string   // file name of the playlist
+	Extensions      []string // extensions of segment files
+	SegmentDuration float64  // duration of every segment, real packagers take it from the encoder
+
+	mu      sync.Mutex
+	pl      *m3u8.MediaPlaylist
+	seen    map[string]bool
+	updated chan struct{} // closed and replaced on every playlist update
+}
+
+// New creates the packager for the directory with the playlist window of
+// winsize segments.
+func New(dir string, winsize uint, segmentDuration float64) (*Packager, error) {
+	pl, err := m3u8.NewMediaPlaylist(winsize, winsize*2)
+	if err != nil {
+		return nil, err
+	}
+	pl.TargetDuration = segmentDuration
+	// delta updates may skip the segments older than half of the window
+	if err = pl.SetServerControl(&m3u8.ServerControl{
+		CanBlockReload: true,
+		CanSkipUntil:   float64(winsize/2) * segmentDuration,
+	}); err != nil {
+		return nil, err
+	}
+	return &Packager{
+		Dir:             dir,
+		Playlist:        "index.m3u8",
+		Extensions:      []string{".ts", ".m4s"},
+		SegmentDuration: segmentDuration,
+		pl:              pl,
+		seen:            make(map[string]bool),
+		updated:         make(chan struct{}),
+	}, nil
+}
+
+// Scan adds new segments of the directory to the playlist and persists it.
+// Segments are ordered by their file names.
+func (p *Packager) Scan() error {
+	files, err := ioutil.ReadDir(p.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && p.isSegment(f.Name()) && !p.seen[f.Name()] {
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, name := range names {
+		p.seen[name] = true
+		p.pl.Slide(name, p.SegmentDuration, "")
+	}
+	if err = p.persist(); err != nil {
+		return err
+	}
+	close(p.updated)
+	p.updated = make(chan struct{})
+	return nil
+}
+
+// Run scans the directory with the interval until the context is done.
+func (p *Packager) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Scan(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Packager) isSegment(name string) bool {
+	for _, ext := range p.Extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// persist writes the playlist to a temporary file and renames it, so
+// readers never see a partially written playlist.
+func (p *Packager) persist() error {
+	tmp, err := ioutil.TempFile(p.Dir, "."+p.Playlist)
+	if err != nil {
+		return err
+	}
+	if _, err = p.pl.Encode().WriteTo(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(p.Dir, p.Playlist))
+}
+
+// nextMSN returns the media sequence number of the next segment.
+func (p *Packager) nextMSN() uint64 {
+	if p.pl.Count() == 0 {
+		return p.pl.SeqNo
+	}
+	return p.pl.SeqNo + uint64(p.pl.Count())
+}
+
+// ServeHTTP serves the playlist. A request with _HLS_msn query parameter
+// blocks until the playlist contains the segment, a request with
+// _HLS_skip=YES gets the delta update of the playlist.
+func (p *Packager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if v := query.Get("_HLS_msn"); v != "" {
+		msn, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid _HLS_msn", http.StatusBadRequest)
+			return
+		}
+		if err = p.wait(r.Context(), msn); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	if query.Get("_HLS_skip") == "YES" {
+		delta, err := p.pl.EncodeDelta(p.pl.ServerControl.CanSkipUntil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		delta.WriteTo(w)
+		return
+	}
+	w.Write(p.pl.Encode().Bytes())
+}
+
+// wait blocks until the playlist contains the segment msn. Requests for
+// segments more than two segments ahead are rejected, the wait is limited
+// by three target durations.
+func (p *Packager) wait(ctx context.Context, msn uint64) error {
+	timeout := time.After(time.Duration(3 * p.SegmentDuration * float64(time.Second)))
+	for {
+		p.mu.Lock()
+		next, updated := p.nextMSN(), p.updated
+		p.mu.Unlock()
+		if msn < next {
+			return nil
+		}
+		if msn > next+1 {
+			return errors.New("_HLS_msn is too far ahead of the playlist")
+		}
+		select {
+		case <-updated:
+		case <-timeout:
+			return errors.New("timeout waiting for the segment")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package packager
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPackager(t *testing.T) (*Packager, string) {
+	dir, err := ioutil.TempDir("", "packager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(dir, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, dir
+}
+
+func addSegment(t *testing.T, dir, name string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("media"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanPersistsPlaylist(t *testing.T) {
+	p, dir := newTestPackager(t)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"seg1.ts", "seg0.ts", "notes.txt"} {
+		addSegment(t, dir, name)
+	}
+	if err := p.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "index.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if !strings.Contains(out, "seg0.ts\n#EXTINF:2.000,\nseg1.ts\n") || strings.Contains(out, "notes.txt") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+	if !strings.Contains(out, "CAN-BLOCK-RELOAD=YES") {
+		t.Errorf("Playlist must advertise blocking reload:\n%s", out)
+	}
+}
+
+func TestBlockingReload(t *testing.T) {
+	p, dir := newTestPackager(t)
+	defer os.RemoveAll(dir)
+	addSegment(t, dir, "seg0.ts")
+	if err := p.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", "/index.m3u8?_HLS_msn=1", nil))
+		done <- w.Body.String()
+	}()
+	select {
+	case <-done:
+		t.Fatal("Request must block until the segment is published")
+	case <-time.After(50 * time.Millisecond):
+	}
+	addSegment(t, dir, "seg1.ts")
+	if err := p.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case out := <-done:
+		if !strings.Contains(out, "seg1.ts") {
+			t.Errorf("Expected playlist with the new segment:\n%s", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request must be released by the update")
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/index.m3u8?_HLS_msn=5", nil))
+	if w.Code != 400 {
+		t.Errorf("Expected 400 for _HLS_msn far ahead, got %d", w.Code)
+	}
+}
+
+func TestDeltaUpdate(t *testing.T) {
+	p, dir := newTestPackager(t)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"seg0.ts", "seg1.ts", "seg2.ts", "seg3.ts"} {
+		addSegment(t, dir, name)
+	}
+	if err := p.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/index.m3u8?_HLS_skip=YES", nil))
+	if out := w.Body.String(); !strings.Contains(out, "#EXT-X-SKIP:SKIPPED-SEGMENTS=2\n") || strings.Contains(out, "seg1.ts") {
+		t.Errorf("Unexpected delta update:\n%s", out)
+	}
+}
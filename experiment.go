@@ -0,0 +1,127 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines splitting of master playlists for server side
+ experiments.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"sort"
+)
+
+// Experiment describes a server side experiment on a master playlist.
+// Sessions are assigned to the arms deterministically by hash of the
+// session key, so the same session always gets the same variation.
+type Experiment struct {
+	Name string // salts the hash so independent experiments split sessions differently
+	Arms []ExperimentArm
+}
+
+// ExperimentArm is one variation of the master playlist.
+type ExperimentArm struct {
+	Name   string
+	Weight uint                     // relative share of sessions assigned to the arm
+	Filter func(v *Variant) bool    // keeps the variants it returns true for, nil keeps all of them
+	Less   func(a, b *Variant) bool // reorders the variants, nil keeps the original order
+	Modify func(v *Variant)         // changes a copy of the variant, e.g. rewrites URI to an alternate pathway
+}
+
+// Arm returns the arm the session is assigned to.
+func (e *Experiment) Arm(sessionKey string) (*ExperimentArm, error) {
+	var total uint64
+	for _, arm := range e.Arms {
+		total += uint64(arm.Weight)
+	}
+	if total == 0 {
+		return nil, errors.New("experiment has no weighted arms")
+	}
+	h := fnv.New64a()
+	h.Write([]byte(e.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(sessionKey))
+	point := h.Sum64() % total
+	for i := range e.Arms {
+		if point < uint64(e.Arms[i].Weight) {
+			return &e.Arms[i], nil
+		}
+		point -= uint64(e.Arms[i].Weight)
+	}
+	return nil, errors.New("experiment has no weighted arms") // not reachable
+}
+
+// Split derives the master playlist for the session. The source playlist
+// is not changed, the result holds copies of its variants, so it is safe to
+// share the source between concurrent requests. Alternatives and
+// chunklists of the variants are shared with the source.
+func (e *Experiment) Split(p *MasterPlaylist, sessionKey string) (*MasterPlaylist, string, error) {
+	arm, err := e.Arm(sessionKey)
+	if err != nil {
+		return nil, "", err
+	}
+	out := p.shallowCopy()
+	for _, v := range p.Variants {
+		if arm.Filter != nil && !arm.Filter(v) {
+			continue
+		}
+		nv := v.copy()
+		if arm.Modify != nil {
+			arm.Modify(nv)
+		}
+		out.Variants = append(out.Variants, nv)
+	}
+	if arm.Less != nil {
+		sort.SliceStable(out.Variants, func(i, j int) bool { return arm.Less(out.Variants[i], out.Variants[j]) })
+	}
+	return out, arm.Name, nil
+}
+
+// shallowCopy returns a copy of the master playlist without variants and
+// with empty encoding cache.
+func (p *MasterPlaylist) shallowCopy() *MasterPlaylist {
+	out := *p
+	out.buf = bytes.Buffer{}
+	out.Variants = nil
+	if p.Custom != nil {
+		out.Custom = make(map[string]CustomTag, len(p.Custom))
+		for k, v := range p.Custom {
+			out.Custom[k] = v
+		}
+		out.customOrder = append([]string(nil), p.customOrder...)
+	}
+	return &out
+}
+
+// copy returns a copy of the variant which may be changed without
+// affecting the source variant.
+func (v *Variant) copy() *Variant {
+	nv := *v
+	if v.ExtraAttributes != nil {
+		nv.ExtraAttributes = make(map[string]string, len(v.ExtraAttributes))
+		for k, val := range v.ExtraAttributes {
+			nv.ExtraAttributes[k] = val
+		}
+	}
+	if v.ExtraQuoted != nil {
+		nv.ExtraQuoted = make(map[string]bool, len(v.ExtraQuoted))
+		for k, quoted := range v.ExtraQuoted {
+			nv.ExtraQuoted[k] = quoted
+		}
+	}
+	if v.AllowedCPC != nil {
+		nv.AllowedCPC = make(map[string][]string, len(v.AllowedCPC))
+		for k, cpc := range v.AllowedCPC {
+			nv.AllowedCPC[k] = append([]string(nil), cpc...)
+		}
+	}
+	return &nv
+}
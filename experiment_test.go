@@ -0,0 +1,72 @@
+/*
+ Package m3u8. Master playlist experiment tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExperimentSplit(t *testing.T) {
+	m := NewMasterPlaylist()
+	for _, bw := range []uint32{3000000, 1000000, 6000000} {
+		m.Append(fmt.Sprintf("http://cdn-a.example.com/%d.m3u8", bw), nil, VariantParams{Bandwidth: bw})
+	}
+	source := m.String()
+	e := &Experiment{
+		Name: "ladder",
+		Arms: []ExperimentArm{
+			{Name: "control", Weight: 1},
+			{
+				Name:   "capped",
+				Weight: 1,
+				Filter: func(v *Variant) bool { return v.Bandwidth <= 3000000 },
+				Less:   func(a, b *Variant) bool { return a.Bandwidth < b.Bandwidth },
+				Modify: func(v *Variant) { v.URI = strings.Replace(v.URI, "cdn-a", "cdn-b", 1) },
+			},
+		},
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 64; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		out, arm, err := e.Split(m, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, again, _ := e.Split(m, key); again != arm {
+			t.Fatalf("Session %s assigned to %s and %s", key, arm, again)
+		}
+		seen[arm] = true
+		switch arm {
+		case "control":
+			if out.String() != source {
+				t.Errorf("Control arm must keep the playlist:\n%s", out)
+			}
+		case "capped":
+			if len(out.Variants) != 2 || out.Variants[0].Bandwidth != 1000000 || out.Variants[0].URI != "http://cdn-b.example.com/1000000.m3u8" {
+				t.Errorf("Unexpected capped arm:\n%s", out)
+			}
+		}
+	}
+	if !seen["control"] || !seen["capped"] {
+		t.Errorf("Sessions must be split between both arms, got %v", seen)
+	}
+	if m.String() != source || m.Variants[0].URI != "http://cdn-a.example.com/3000000.m3u8" {
+		t.Error("Source playlist must not be changed")
+	}
+}
+
+func TestExperimentWithoutArms(t *testing.T) {
+	e := &Experiment{Arms: []ExperimentArm{{Name: "zero"}}}
+	if _, _, err := e.Split(NewMasterPlaylist(), "session"); err == nil {
+		t.Error("Expected error for experiment without weights")
+	}
+}
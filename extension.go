@@ -0,0 +1,140 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines registration of extensions implementing tags out of
+ the library.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ExtensionScope tells where tags of an extension appear.
+type ExtensionScope uint8
+
+const (
+	// MasterScope is the header of master playlists.
+	MasterScope ExtensionScope = 1 << iota
+	// MediaScope is the header of media playlists.
+	MediaScope
+	// SegmentScope is media segments of media playlists.
+	SegmentScope
+)
+
+// Extension implements a tag the library doesn't support yet. Registered
+// extensions are used by all decoders, decoded tags are kept in Custom
+// maps of playlists and segments and encoded as custom tags.
+type Extension struct {
+	Decoder CustomDecoder  // decodes lines starting with Decoder.TagName(), SegmentTag() is ignored in favor of Scope
+	Scope   ExtensionScope // MediaScope and SegmentScope are mutually exclusive
+	Version uint8          // minimal protocol version of playlists with the tag
+}
+
+var extensions struct {
+	sync.RWMutex
+	list []Extension
+}
+
+// RegisterExtension registers the extension for all playlists.
+func RegisterExtension(ext Extension) error {
+	if ext.Decoder == nil || ext.Decoder.TagName() == "" {
+		return errors.New("extension without decoder")
+	}
+	if !strings.HasPrefix(ext.Decoder.TagName(), "#") {
+		return fmt.Errorf("extension tag %q doesn't start with #", ext.Decoder.TagName())
+	}
+	if ext.Scope == 0 || ext.Scope&(MediaScope|SegmentScope) == MediaScope|SegmentScope {
+		return fmt.Errorf("invalid scope of extension %s", ext.Decoder.TagName())
+	}
+	extensions.Lock()
+	defer extensions.Unlock()
+	for _, e := range extensions.list {
+		if e.Decoder.TagName() == ext.Decoder.TagName() {
+			return fmt.Errorf("extension %s is already registered", ext.Decoder.TagName())
+		}
+	}
+	extensions.list = append(extensions.list, ext)
+	return nil
+}
+
+// UnregisterExtension removes the extension of the tag.
+func UnregisterExtension(tagName string) {
+	extensions.Lock()
+	defer extensions.Unlock()
+	for i, e := range extensions.list {
+		if e.Decoder.TagName() == tagName {
+			extensions.list = append(extensions.list[:i], extensions.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// findExtension returns the extension of the scopes for the line.
+func findExtension(line string, scope ExtensionScope) (Extension, bool) {
+	extensions.RLock()
+	defer extensions.RUnlock()
+	for _, e := range extensions.list {
+		if e.Scope&scope != 0 && strings.HasPrefix(line, e.Decoder.TagName()) {
+			return e, true
+		}
+	}
+	return Extension{}, false
+}
+
+// extensionVersion returns the protocol version required by the tag, zero
+// for tags without registered extension.
+func extensionVersion(tagName string) uint8 {
+	extensions.RLock()
+	defer extensions.RUnlock()
+	for _, e := range extensions.list {
+		if e.Decoder.TagName() == tagName {
+			return e.Version
+		}
+	}
+	return 0
+}
+
+// decodeMasterExtension decodes the line with a registered extension, it
+// returns false if no extension handles the line.
+func decodeMasterExtension(p *MasterPlaylist, line string) (bool, error) {
+	ext, ok := findExtension(line, MasterScope)
+	if !ok {
+		return false, nil
+	}
+	t, err := ext.Decoder.Decode(line)
+	if err != nil {
+		return true, err
+	}
+	setCustomTag(&p.Custom, &p.customOrder, t)
+	return true, nil
+}
+
+// decodeMediaExtension decodes the line with a registered extension, it
+// returns false if no extension handles the line.
+func decodeMediaExtension(p *MediaPlaylist, state *decodingState, line string) (bool, error) {
+	ext, ok := findExtension(line, MediaScope|SegmentScope)
+	if !ok {
+		return false, nil
+	}
+	t, err := ext.Decoder.Decode(line)
+	if err != nil {
+		return true, err
+	}
+	if ext.Scope&SegmentScope != 0 {
+		state.tagCustom = true
+		setCustomTag(&state.custom, &state.customOrder, t)
+		return true, nil
+	}
+	setCustomTag(&p.Custom, &p.customOrder, t)
+	return true, nil
+}
@@ -0,0 +1,77 @@
+/*
+ Package m3u8. Extension registration tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testExtensionTag is a tag with a single value, e.g. #EXT-X-FUTURE:value.
+type testExtensionTag struct {
+	name  string
+	value string
+}
+
+func (t *testExtensionTag) TagName() string  { return t.name }
+func (t *testExtensionTag) SegmentTag() bool { return false }
+func (t *testExtensionTag) String() string   { return t.name + t.value }
+func (t *testExtensionTag) Encode() *bytes.Buffer {
+	return bytes.NewBufferString(t.String())
+}
+func (t *testExtensionTag) Decode(line string) (CustomTag, error) {
+	return &testExtensionTag{name: t.name, value: strings.TrimPrefix(line, t.name)}, nil
+}
+
+func TestRegisterExtension(t *testing.T) {
+	header := Extension{Decoder: &testExtensionTag{name: "#EXT-X-FUTURE-HEADER:"}, Scope: MediaScope, Version: 12}
+	segment := Extension{Decoder: &testExtensionTag{name: "#EXT-X-FUTURE-SEGMENT:"}, Scope: SegmentScope}
+	for _, ext := range []Extension{header, segment} {
+		if err := RegisterExtension(ext); err != nil {
+			t.Fatal(err)
+		}
+		defer UnregisterExtension(ext.Decoder.TagName())
+	}
+	if err := RegisterExtension(header); err == nil {
+		t.Error("Expected error for duplicate extension")
+	}
+	if err := RegisterExtension(Extension{Decoder: &testExtensionTag{name: "#EXT-X-BAD:"}, Scope: MediaScope | SegmentScope}); err == nil {
+		t.Error("Expected error for ambiguous scope")
+	}
+
+	playlist := `#EXTM3U
+#EXT-X-VERSION:12
+#EXT-X-TARGETDURATION:4
+#EXT-X-FUTURE-HEADER:abc
+#EXT-X-FUTURE-SEGMENT:1
+#EXTINF:4,
+seg0.ts
+#EXTINF:4,
+seg1.ts
+`
+	p, listType, err := DecodeFrom(strings.NewReader(playlist), true)
+	if err != nil || listType != MEDIA {
+		t.Fatal(listType, err)
+	}
+	media := p.(*MediaPlaylist)
+	if tag, ok := media.Custom["#EXT-X-FUTURE-HEADER:"]; !ok || tag.String() != "#EXT-X-FUTURE-HEADER:abc" {
+		t.Errorf("Header extension tag must be decoded: %v", media.Custom)
+	}
+	if _, ok := media.Segments[0].Custom["#EXT-X-FUTURE-SEGMENT:"]; !ok || media.Segments[1].Custom != nil {
+		t.Errorf("Segment extension tag must be decoded for the first segment only")
+	}
+
+	out, _ := NewMediaPlaylist(1, 1)
+	out.SetCustomTag(&testExtensionTag{name: "#EXT-X-FUTURE-HEADER:", value: "x"})
+	if out.Version() != 12 {
+		t.Errorf("Expected version 12 required by the extension, got %d", out.Version())
+	}
+}
@@ -0,0 +1,188 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines measuring of bandwidth of variants and synthesis of
+ the audio-only fallback variant of master playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Prefixes of codecs of audio streams as used in CODECS attribute.
+var audioCodecPrefixes = []string{"mp4a", "ac-3", "ec-3", "ac-4", "opus", "Opus", "flac", "fLaC", "alac", "mp3", ".mp3"}
+
+// MeasureBandwidth calculates the peak segment bit rate and the average
+// bit rate (bits per second) of the media playlist from the sizes of its
+// segments in bytes, listed in the order of the segments.
+func MeasureBandwidth(p *MediaPlaylist, sizes []int64) (peak, average uint32, err error) {
+	if p.count == 0 {
+		return 0, 0, errors.New("playlist is empty")
+	}
+	if uint(len(sizes)) != p.count {
+		return 0, 0, fmt.Errorf("got sizes of %d segments, playlist has %d", len(sizes), p.count)
+	}
+	var bits, duration, max float64
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg.Duration <= 0 {
+			return 0, 0, fmt.Errorf("segment %s has no duration", seg.URI)
+		}
+		rate := float64(sizes[i]*8) / seg.Duration
+		if rate > max {
+			max = rate
+		}
+		bits += float64(sizes[i] * 8)
+		duration += seg.Duration
+	}
+	return uint32(math.Ceil(max)), uint32(math.Ceil(bits / duration)), nil
+}
+
+// EstimateBandwidth sets BANDWIDTH and AVERAGE-BANDWIDTH of the variant
+// measured from its chunklist, see MeasureBandwidth. Sizes of segments
+// are taken from their byte ranges if sizes are nil. The variant is not
+// incomplete anymore on success.
+func (v *Variant) EstimateBandwidth(sizes []int64) error {
+	if v.Chunklist == nil {
+		return fmt.Errorf("variant %s has no chunklist", v.URI)
+	}
+	if sizes == nil {
+		var err error
+		if sizes, err = byteRangeSizes(v.Chunklist); err != nil {
+			return err
+		}
+	}
+	peak, average, err := MeasureBandwidth(v.Chunklist, sizes)
+	if err != nil {
+		return err
+	}
+	v.Bandwidth = peak
+	if v.AverageBandwidth == 0 {
+		v.AverageBandwidth = average
+	}
+	v.Incomplete = false
+	return nil
+}
+
+// byteRangeSizes returns sizes of segments of the playlist taken from
+// their byte ranges.
+func byteRangeSizes(p *MediaPlaylist) ([]int64, error) {
+	sizes := make([]int64, p.count)
+	for i := uint(0); i < p.count; i++ {
+		if sizes[i] = p.segmentAt(i).Limit; sizes[i] <= 0 {
+			return nil, fmt.Errorf("size of segment %s is unknown", p.segmentAt(i).URI)
+		}
+	}
+	return sizes, nil
+}
+
+// audioCodecs returns the audio codecs of the CODECS attribute.
+func audioCodecs(codecs string) string {
+	var out []string
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		for _, prefix := range audioCodecPrefixes {
+			if strings.HasPrefix(codec, prefix) {
+				out = append(out, codec)
+				break
+			}
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// AppendAudioOnlyVariant synthesizes the audio-only variant recommended as
+// a fallback for cellular networks. The variant points to the default audio
+// rendition of the group (the first one if none is default), its codecs are
+// the audio codecs of the variants using the group and its bandwidth is
+// measured from the rendition playlist and the sizes of its segments, see
+// MeasureBandwidth.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) AppendAudioOnlyVariant(groupID string, rendition *MediaPlaylist, sizes []int64) (*Variant, error) {
+	var alt *Alternative
+	var codecs string
+	for _, v := range p.Variants {
+		for _, a := range v.Alternatives {
+			if a.Type == "AUDIO" && a.GroupId == groupID && a.URI != "" && (alt == nil || a.Default && !alt.Default) {
+				alt = a
+			}
+		}
+		if v.Audio == groupID && codecs == "" {
+			codecs = audioCodecs(v.Codecs)
+		}
+	}
+	if alt == nil {
+		return nil, fmt.Errorf("no audio rendition with URI in group %s", groupID)
+	}
+	for _, v := range p.Variants {
+		if v.URI == alt.URI {
+			return nil, fmt.Errorf("variant %s already exists", alt.URI)
+		}
+	}
+	peak, average, err := MeasureBandwidth(rendition, sizes)
+	if err != nil {
+		return nil, err
+	}
+	p.Append(alt.URI, rendition, VariantParams{Bandwidth: peak, AverageBandwidth: average, Codecs: codecs})
+	return p.Variants[len(p.Variants)-1], nil
+}
+
+// AppendAudioOnlyVariants synthesizes audio-only variants of all AUDIO
+// groups of the master playlist, see AppendAudioOnlyVariant. The function
+// returns the playlist of the rendition and sizes of its segments, nil
+// sizes are taken from byte ranges of the segments. Groups which already
+// have an audio-only variant are skipped.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) AppendAudioOnlyVariants(rendition func(alt *Alternative) (*MediaPlaylist, []int64, error)) ([]*Variant, error) {
+	var groups []string
+	defaults := make(map[string]*Alternative)
+	for _, v := range p.Variants {
+		for _, a := range v.Alternatives {
+			if a.Type != "AUDIO" || a.URI == "" {
+				continue
+			}
+			if alt, ok := defaults[a.GroupId]; !ok {
+				groups = append(groups, a.GroupId)
+				defaults[a.GroupId] = a
+			} else if a.Default && !alt.Default {
+				defaults[a.GroupId] = a
+			}
+		}
+	}
+	uris := make(map[string]bool)
+	for _, v := range p.Variants {
+		uris[v.URI] = true
+	}
+	var added []*Variant
+	for _, group := range groups {
+		alt := defaults[group]
+		if uris[alt.URI] {
+			continue
+		}
+		playlist, sizes, err := rendition(alt)
+		if err != nil {
+			return added, err
+		}
+		if sizes == nil {
+			if sizes, err = byteRangeSizes(playlist); err != nil {
+				return added, err
+			}
+		}
+		v, err := p.AppendAudioOnlyVariant(group, playlist, sizes)
+		if err != nil {
+			return added, err
+		}
+		added = append(added, v)
+	}
+	return added, nil
+}
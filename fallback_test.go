@@ -0,0 +1,114 @@
+/*
+ Package m3u8. Audio-only fallback tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppendAudioOnlyVariant(t *testing.T) {
+	alts := []*Alternative{
+		{Type: "AUDIO", GroupId: "aud", Name: "French", Language: "fr", URI: "audio/fr.m3u8"},
+		{Type: "AUDIO", GroupId: "aud", Name: "English", Language: "en", URI: "audio/en.m3u8", Default: true},
+	}
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, Codecs: "avc1.640028,mp4a.40.2", Audio: "aud", Alternatives: alts})
+
+	audio, _ := NewMediaPlaylist(0, 3)
+	audio.Append("a0.aac", 4, "")
+	audio.Append("a1.aac", 4, "")
+	audio.Append("a2.aac", 2, "")
+	v, err := m.AppendAudioOnlyVariant("aud", audio, []int64{64000, 72000, 16000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// peak 72000*8/4 = 144000, average (152000*8)/10 = 121600
+	if v.URI != "audio/en.m3u8" || v.Codecs != "mp4a.40.2" || v.Bandwidth != 144000 || v.AverageBandwidth != 121600 {
+		t.Errorf("Unexpected audio-only variant %s %+v", v.URI, v.VariantParams)
+	}
+	if out := m.String(); !strings.Contains(out, "#EXT-X-STREAM-INF:BANDWIDTH=144000,PROGRAM-ID=0,AVERAGE-BANDWIDTH=121600,CODECS=\"mp4a.40.2\"\naudio/en.m3u8\n") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+	if _, err = m.AppendAudioOnlyVariant("aud", audio, []int64{1, 1, 1}); err == nil {
+		t.Error("Expected error for duplicate variant")
+	}
+	if _, err = m.AppendAudioOnlyVariant("none", audio, []int64{1, 1, 1}); err == nil {
+		t.Error("Expected error for unknown group")
+	}
+}
+
+func TestEstimateBandwidthOfIncompleteVariant(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-STREAM-INF:RESOLUTION=1280x720
+mid.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1920x1080
+hi.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err == nil {
+		t.Error("Expected error for missing BANDWIDTH in strict mode")
+	}
+	m = NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), false); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Variants) != 2 || !m.Variants[0].Incomplete || m.Variants[1].Incomplete || len(m.Warnings) != 1 {
+		t.Fatalf("Expected the first variant incomplete, warnings %v", m.Warnings)
+	}
+
+	v := m.Variants[0]
+	if err := v.EstimateBandwidth(nil); err == nil {
+		t.Error("Expected error for variant without chunklist")
+	}
+	v.Chunklist, _ = NewMediaPlaylist(0, 2)
+	v.Chunklist.Append("mid.ts", 4, "")
+	v.Chunklist.SetRange(500000, 0)
+	v.Chunklist.Append("mid.ts", 2, "")
+	v.Chunklist.SetRange(400000, 500000)
+	if err := v.EstimateBandwidth(nil); err != nil {
+		t.Fatal(err)
+	}
+	// peak 400000*8/2 = 1600000, average 900000*8/6 = 1200000
+	if v.Incomplete || v.Bandwidth != 1600000 || v.AverageBandwidth != 1200000 {
+		t.Errorf("Unexpected estimate %v %d %d", v.Incomplete, v.Bandwidth, v.AverageBandwidth)
+	}
+}
+
+func TestAppendAudioOnlyVariants(t *testing.T) {
+	aac := []*Alternative{{Type: "AUDIO", GroupId: "aac", Name: "English", Language: "en", URI: "aac/en.m3u8", Default: true}}
+	ac3 := []*Alternative{{Type: "AUDIO", GroupId: "ac3", Name: "English", Language: "en", URI: "ac3/en.m3u8", Default: true}}
+	m := NewMasterPlaylist()
+	m.Append("lo.m3u8", nil, VariantParams{Bandwidth: 1000000, Codecs: "avc1.4d401f,mp4a.40.2", Audio: "aac", Alternatives: aac})
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, Codecs: "avc1.640028,ac-3", Audio: "ac3", Alternatives: ac3})
+
+	rendition := func(alt *Alternative) (*MediaPlaylist, []int64, error) {
+		p, _ := NewMediaPlaylist(0, 2)
+		p.Append("a0", 4, "")
+		p.SetRange(64000, 0)
+		p.Append("a1", 4, "")
+		p.SetRange(32000, 64000)
+		return p, nil, nil
+	}
+	added, err := m.AppendAudioOnlyVariants(rendition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || added[0].URI != "aac/en.m3u8" || added[1].URI != "ac3/en.m3u8" || added[1].Codecs != "ac-3" {
+		t.Fatalf("Unexpected audio-only variants %+v", added)
+	}
+	// peak 64000*8/4 = 128000, average 96000*8/8 = 96000
+	if added[0].Bandwidth != 128000 || added[0].AverageBandwidth != 96000 {
+		t.Errorf("Unexpected bandwidth %+v", added[0].VariantParams)
+	}
+	if added, err = m.AppendAudioOnlyVariants(rendition); err != nil || len(added) != 0 {
+		t.Errorf("Expected existing audio-only variants kept, got %v %v", added, err)
+	}
+}
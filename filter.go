@@ -0,0 +1,128 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines filtering and sorting of variants of master playlists,
+ e.g. for device specific playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"sort"
+	"strings"
+)
+
+// FilterVariants returns a copy of the master playlist with the variants
+// accepted by all the filters. The source playlist is not changed, see
+// Experiment.Split for the sharing rules of the result.
+func (p *MasterPlaylist) FilterVariants(filters ...func(*Variant) bool) *MasterPlaylist {
+	out := p.shallowCopy()
+next:
+	for _, v := range p.Variants {
+		for _, keep := range filters {
+			if !keep(v) {
+				continue next
+			}
+		}
+		out.Variants = append(out.Variants, v.copy())
+	}
+	return out
+}
+
+// MaxBandwidth accepts variants with BANDWIDTH not exceeding the limit.
+func MaxBandwidth(limit uint32) func(*Variant) bool {
+	return func(v *Variant) bool {
+		return v.Bandwidth <= limit
+	}
+}
+
+// CodecFamily accepts variants with any codec starting with one of the
+// prefixes, e.g. "avc1" or "hvc1", "hev1". Variants without CODECS are
+// accepted, their codecs are unknown.
+func CodecFamily(prefixes ...string) func(*Variant) bool {
+	return func(v *Variant) bool {
+		return v.Codecs == "" || hasCodec(v.Codecs, prefixes)
+	}
+}
+
+// MaxResolution accepts variants with RESOLUTION within the limits.
+// Variants without RESOLUTION, e.g. audio only ones, are accepted.
+func MaxResolution(width, height int) func(*Variant) bool {
+	return func(v *Variant) bool {
+		r := v.Dimensions()
+		return r.IsZero() || r.Fits(Resolution{width, height})
+	}
+}
+
+// NoIframes rejects EXT-X-I-FRAME-STREAM-INF variants.
+func NoIframes(v *Variant) bool {
+	return !v.Iframe
+}
+
+// SortVariants orders variants by BANDWIDTH ascending within codec
+// groups, as Apple authoring guidelines recommend. Variants of the same
+// codec families (e.g. avc1 and mp4a) form a group, groups keep the order
+// of their first variants, I-frame and image variants form groups of
+// their own.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SortVariants() {
+	groups := make(map[string]int)
+	for _, v := range p.Variants {
+		if _, ok := groups[codecGroup(v)]; !ok {
+			groups[codecGroup(v)] = len(groups)
+		}
+	}
+	p.SortVariantsFunc(func(a, b *Variant) bool {
+		if ga, gb := groups[codecGroup(a)], groups[codecGroup(b)]; ga != gb {
+			return ga < gb
+		}
+		return a.Bandwidth < b.Bandwidth
+	})
+}
+
+// SortVariantsFunc orders variants with the comparator, the order of
+// equal variants is kept.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SortVariantsFunc(less func(a, b *Variant) bool) {
+	sort.SliceStable(p.Variants, func(i, j int) bool { return less(p.Variants[i], p.Variants[j]) })
+	p.buf.Reset()
+}
+
+// codecGroup returns the sorted codec families of the variant with its
+// kind, e.g. "stream:avc1,mp4a".
+func codecGroup(v *Variant) string {
+	kind := "stream"
+	switch {
+	case v.Iframe:
+		kind = "iframe"
+	case v.Image:
+		kind = "image"
+	}
+	var families []string
+	for _, codec := range strings.Split(v.Codecs, ",") {
+		if codec = strings.TrimSpace(codec); codec != "" {
+			families = append(families, strings.SplitN(codec, ".", 2)[0])
+		}
+	}
+	sort.Strings(families)
+	return kind + ":" + strings.Join(families, ",")
+}
+
+// hasCodec checks whether any of the comma separated codecs starts with
+// any of the prefixes.
+func hasCodec(codecs string, prefixes []string) bool {
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(codec, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+/*
+ Package m3u8. Variant filtering tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterVariants(t *testing.T) {
+	p := NewMasterPlaylist()
+	p.Append("sd.m3u8", nil, VariantParams{Bandwidth: 1000000, Codecs: "avc1.4d401f,mp4a.40.2", Resolution: "640x360"})
+	p.Append("hd.m3u8", nil, VariantParams{Bandwidth: 5000000, Codecs: "avc1.640028,mp4a.40.2", Resolution: "1920x1080"})
+	p.Append("hevc.m3u8", nil, VariantParams{Bandwidth: 3000000, Codecs: "hvc1.2.4.L123.B0,mp4a.40.2", Resolution: "1280x720"})
+	p.Append("audio.m3u8", nil, VariantParams{Bandwidth: 64000, Codecs: "mp4a.40.2"})
+	p.Append("sd-iframes.m3u8", nil, VariantParams{Bandwidth: 100000, Codecs: "avc1.4d401f", Resolution: "640x360", Iframe: true})
+
+	uris := func(m *MasterPlaylist) string {
+		var out []string
+		for _, v := range m.Variants {
+			out = append(out, v.URI)
+		}
+		return strings.Join(out, " ")
+	}
+	for _, v := range []struct {
+		filters  []func(*Variant) bool
+		expected string
+	}{
+		{[]func(*Variant) bool{MaxBandwidth(3000000)}, "sd.m3u8 hevc.m3u8 audio.m3u8 sd-iframes.m3u8"},
+		{[]func(*Variant) bool{CodecFamily("avc1")}, "sd.m3u8 hd.m3u8 sd-iframes.m3u8"},
+		{[]func(*Variant) bool{MaxResolution(1280, 720)}, "sd.m3u8 hevc.m3u8 audio.m3u8 sd-iframes.m3u8"},
+		{[]func(*Variant) bool{NoIframes, MaxResolution(1280, 720), CodecFamily("avc1")}, "sd.m3u8"},
+	} {
+		if out := uris(p.FilterVariants(v.filters...)); out != v.expected {
+			t.Errorf("Expected variants %q, got %q", v.expected, out)
+		}
+	}
+	if len(p.Variants) != 5 {
+		t.Error("The source playlist must not be changed")
+	}
+}
+
+func TestSortVariants(t *testing.T) {
+	p := NewMasterPlaylist()
+	p.Append("avc-hd.m3u8", nil, VariantParams{Bandwidth: 5000000, Codecs: "avc1.640028,mp4a.40.2"})
+	p.Append("hevc-hd.m3u8", nil, VariantParams{Bandwidth: 4000000, Codecs: "hvc1.2.4.L123.B0,mp4a.40.2"})
+	p.Append("avc-sd.m3u8", nil, VariantParams{Bandwidth: 1000000, Codecs: "mp4a.40.5,avc1.4d401f"})
+	p.Append("avc-iframes.m3u8", nil, VariantParams{Bandwidth: 200000, Codecs: "avc1.640028", Iframe: true})
+	p.Append("hevc-sd.m3u8", nil, VariantParams{Bandwidth: 1500000, Codecs: "hvc1.2.4.L93.B0,mp4a.40.2"})
+	p.Append("avc-sd-iframes.m3u8", nil, VariantParams{Bandwidth: 50000, Codecs: "avc1.4d401f", Iframe: true})
+	p.SortVariants()
+
+	var uris []string
+	for _, v := range p.Variants {
+		uris = append(uris, v.URI)
+	}
+	expected := "avc-sd.m3u8 avc-hd.m3u8 hevc-sd.m3u8 hevc-hd.m3u8 avc-sd-iframes.m3u8 avc-iframes.m3u8"
+	if strings.Join(uris, " ") != expected {
+		t.Errorf("Unexpected order %v", uris)
+	}
+
+	p.SortVariantsFunc(func(a, b *Variant) bool { return a.Bandwidth > b.Bandwidth })
+	if p.Variants[0].URI != "avc-hd.m3u8" {
+		t.Errorf("Unexpected first variant %s", p.Variants[0].URI)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build go1.23
+
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines iterators over the content of playlists for
+ range-over-func loops.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"iter"
+)
+
+// SegmentSeq iterates over segments of the playlist from the oldest one.
+// Unlike ranging over Segments it skips unused slots of the internal
+// buffer and follows the order of a sliding playlist.
+func (p *MediaPlaylist) SegmentSeq() iter.Seq[*MediaSegment] {
+	return func(yield func(*MediaSegment) bool) {
+		for i := uint(0); i < p.count; i++ {
+			if !yield(p.segmentAt(i)) {
+				return
+			}
+		}
+	}
+}
+
+// KeySpans iterates over runs of consecutive segments encrypted with the
+// same key. A segment without own key uses the key of the previous one,
+// the first segments use the default key of the playlist which may be nil.
+func (p *MediaPlaylist) KeySpans() iter.Seq2[*Key, []*MediaSegment] {
+	return func(yield func(*Key, []*MediaSegment) bool) {
+		key := p.Key
+		var span []*MediaSegment
+		for seg := range p.SegmentSeq() {
+			if seg.Key != nil && !sameKey(seg.Key, key) {
+				if len(span) > 0 && !yield(key, span) {
+					return
+				}
+				key, span = seg.Key, nil
+			}
+			span = append(span, seg)
+		}
+		if len(span) > 0 {
+			yield(key, span)
+		}
+	}
+}
+
+// Periods iterates over runs of segments between discontinuities, each
+// segment with EXT-X-DISCONTINUITY starts a new period.
+func (p *MediaPlaylist) Periods() iter.Seq[[]*MediaSegment] {
+	return func(yield func([]*MediaSegment) bool) {
+		var period []*MediaSegment
+		for seg := range p.SegmentSeq() {
+			if seg.Discontinuity && len(period) > 0 {
+				if !yield(period) {
+					return
+				}
+				period = nil
+			}
+			period = append(period, seg)
+		}
+		if len(period) > 0 {
+			yield(period)
+		}
+	}
+}
+
+// VariantSeq iterates over variants of the master playlist.
+func (p *MasterPlaylist) VariantSeq() iter.Seq[*Variant] {
+	return func(yield func(*Variant) bool) {
+		for _, v := range p.Variants {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// AlternativeSeq iterates over renditions of the master playlist. The
+// renditions shared by several variants are yielded once, the same way
+// EXT-X-MEDIA tags are encoded.
+func (p *MasterPlaylist) AlternativeSeq() iter.Seq[*Alternative] {
+	return func(yield func(*Alternative) bool) {
+		seen := make(map[string]bool)
+		for _, v := range p.Variants {
+			for _, alt := range v.Alternatives {
+				key := fmt.Sprintf("%s-%s-%s-%s", alt.Type, alt.GroupId, alt.Name, alt.Language)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if !yield(alt) {
+					return
+				}
+			}
+		}
+	}
+}
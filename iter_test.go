@@ -0,0 +1,91 @@
+//go:build go1.23
+
+/*
+ Package m3u8. Iterator tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSegmentSeqOfSlidingPlaylist(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	for i := 0; i < 5; i++ {
+		p.Slide(fmt.Sprintf("seg%d.ts", i), 4, "")
+	}
+	var uris []string
+	for seg := range p.SegmentSeq() {
+		uris = append(uris, seg.URI)
+	}
+	if strings.Join(uris, " ") != "seg2.ts seg3.ts seg4.ts" {
+		t.Errorf("Unexpected segments %v", uris)
+	}
+	for seg := range p.SegmentSeq() {
+		if seg.URI != "seg2.ts" {
+			t.Errorf("Unexpected first segment %s", seg.URI)
+		}
+		break
+	}
+}
+
+func TestKeySpansAndPeriods(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 5)
+	p.Append("seg0.ts", 4, "")
+	p.Append("seg1.ts", 4, "")
+	p.SetKey("AES-128", "key1", "", "", "")
+	p.Append("seg2.ts", 4, "")
+	p.Append("seg3.ts", 4, "")
+	p.SetDiscontinuity()
+	p.SetKey("AES-128", "key1", "", "", "")
+	p.Append("seg4.ts", 4, "")
+	p.SetKey("AES-128", "key2", "", "", "")
+
+	var spans []string
+	for key, segs := range p.KeySpans() {
+		uri := "none"
+		if key != nil {
+			uri = key.URI
+		}
+		spans = append(spans, fmt.Sprintf("%s:%d", uri, len(segs)))
+	}
+	if strings.Join(spans, " ") != "none:1 key1:3 key2:1" {
+		t.Errorf("Unexpected key spans %v", spans)
+	}
+
+	var periods []int
+	for period := range p.Periods() {
+		periods = append(periods, len(period))
+	}
+	if fmt.Sprint(periods) != "[3 2]" {
+		t.Errorf("Unexpected periods %v", periods)
+	}
+}
+
+func TestVariantAndAlternativeSeq(t *testing.T) {
+	alts := []*Alternative{
+		{Type: "AUDIO", GroupId: "aud", Name: "English"},
+		{Type: "AUDIO", GroupId: "aud", Name: "French"},
+	}
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, Alternatives: alts})
+	m.Append("lo.m3u8", nil, VariantParams{Bandwidth: 1000000, Alternatives: alts})
+	var variants, renditions int
+	for range m.VariantSeq() {
+		variants++
+	}
+	for range m.AlternativeSeq() {
+		renditions++
+	}
+	if variants != 2 || renditions != 2 {
+		t.Errorf("Expected 2 variants and 2 renditions, got %d and %d", variants, renditions)
+	}
+}
@@ -0,0 +1,149 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines JSON representation of playlists, so they may be stored
+ in document databases and inspected by tools not written in Go.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarshalJSON returns the JSON representation of the media playlist.
+// Segments are listed in playback order. Custom tags are not marshaled,
+// they can't be restored without their decoders.
+func (p *MediaPlaylist) MarshalJSON() ([]byte, error) {
+	type plain MediaPlaylist
+	segments := make([]*MediaSegment, 0, p.count)
+	for i := uint(0); i < p.count; i++ {
+		segments = append(segments, p.segmentAt(i))
+	}
+	return json.Marshal(struct {
+		*plain
+		Version       uint8           `json:"version"`
+		WinSize       uint            `json:"winSize"`
+		Capacity      uint            `json:"capacity"`
+		DurationAsInt bool            `json:"durationAsInt,omitempty"`
+		Segments      []*MediaSegment `json:"segments"`
+	}{(*plain)(p), p.ver, p.winsize, p.capacity, p.durationAsInt, segments})
+}
+
+// UnmarshalJSON restores the media playlist from its JSON representation,
+// see MarshalJSON. Fields absent in the JSON are left unchanged, the
+// segments of the playlist are replaced.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) UnmarshalJSON(data []byte) error {
+	type plain MediaPlaylist
+	in := struct {
+		*plain
+		Version       uint8           `json:"version"`
+		WinSize       uint            `json:"winSize"`
+		Capacity      uint            `json:"capacity"`
+		DurationAsInt bool            `json:"durationAsInt"`
+		Segments      []*MediaSegment `json:"segments"`
+	}{plain: (*plain)(p), Version: p.ver, WinSize: p.winsize, Capacity: p.capacity, DurationAsInt: p.durationAsInt}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	capacity := in.Capacity
+	if capacity < uint(len(in.Segments)) {
+		capacity = uint(len(in.Segments))
+	}
+	p.capacity = capacity
+	if err := p.SetWinSize(in.WinSize); err != nil {
+		return err
+	}
+	p.Segments = make([]*MediaSegment, capacity)
+	copy(p.Segments, in.Segments)
+	p.head, p.count = 0, uint(len(in.Segments))
+	p.tail = 0
+	if capacity > 0 {
+		p.tail = p.count % capacity
+	}
+	p.ver = in.Version
+	if p.ver == 0 {
+		p.ver = minver
+	}
+	p.durationAsInt = in.DurationAsInt
+	if p.edge == nil {
+		p.edge = newLiveEdge()
+	}
+	p.publish()
+	p.buf.Reset()
+	return nil
+}
+
+// MarshalJSON returns the JSON representation of the master playlist.
+// Renditions are listed with every variant which refers to them and media
+// playlists of variants are marshaled as well. Custom tags are not
+// marshaled.
+func (p *MasterPlaylist) MarshalJSON() ([]byte, error) {
+	type plain MasterPlaylist
+	return json.Marshal(struct {
+		*plain
+		Version             uint8 `json:"version"`
+		IndependentSegments bool  `json:"independentSegments,omitempty"`
+	}{(*plain)(p), p.ver, p.independentSegments})
+}
+
+// UnmarshalJSON restores the master playlist from its JSON representation,
+// see MarshalJSON. Fields absent in the JSON are left unchanged.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) UnmarshalJSON(data []byte) error {
+	type plain MasterPlaylist
+	in := struct {
+		*plain
+		Version             uint8 `json:"version"`
+		IndependentSegments bool  `json:"independentSegments"`
+	}{plain: (*plain)(p), Version: p.ver, IndependentSegments: p.independentSegments}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	p.ver = in.Version
+	if p.ver == 0 {
+		p.ver = minver
+	}
+	p.independentSegments = in.IndependentSegments
+	p.buf.Reset()
+	return nil
+}
+
+// MarshalJSON returns the JSON representation of the media segment. Zero
+// EXT-X-PROGRAM-DATE-TIME is omitted. Custom tags are not marshaled.
+func (seg *MediaSegment) MarshalJSON() ([]byte, error) {
+	type plain MediaSegment
+	out := struct {
+		*plain
+		ProgramDateTime *time.Time `json:"programDateTime,omitempty"`
+	}{plain: (*plain)(seg)}
+	if !seg.ProgramDateTime.IsZero() {
+		out.ProgramDateTime = &seg.ProgramDateTime
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON restores the media segment from its JSON representation,
+// see MarshalJSON.
+func (seg *MediaSegment) UnmarshalJSON(data []byte) error {
+	type plain MediaSegment
+	in := struct {
+		*plain
+		ProgramDateTime *time.Time `json:"programDateTime"`
+	}{plain: (*plain)(seg)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.ProgramDateTime != nil {
+		seg.ProgramDateTime = *in.ProgramDateTime
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+ Package m3u8. JSON representation tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaPlaylistJSON(t *testing.T) {
+	for _, name := range []string{
+		"media-playlist-with-byterange.m3u8",
+		"media-playlist-with-discontinuity.m3u8",
+		"media-playlist-with-program-date-time.m3u8",
+		"media-playlist-with-parts.m3u8",
+		"media-playlist-with-scte35.m3u8",
+		"widevine-bitrate.m3u8",
+	} {
+		f, err := os.Open("sample-playlists/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _, err := DecodeFrom(f, true)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		var restored MediaPlaylist
+		if err = json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if restored.String() != p.String() {
+			t.Errorf("%s: restored playlist differs:\n%s\nexpected:\n%s", name, &restored, p)
+		}
+	}
+}
+
+func TestSlidingMediaPlaylistJSON(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	for i := 0; i < 7; i++ {
+		p.Slide(fmt.Sprintf("seg%d.ts", i), 6, "")
+	}
+	p.SetKey("AES-128", "key.bin", "", "", "")
+	p.SetProgramDateTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"seqNo":4`, `"winSize":3`, `"capacity":5`, `"uri":"seg4.ts"`, `"programDateTime":"2020-01-02T03:04:05Z"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected %s in %s", field, data)
+		}
+	}
+	if strings.Count(string(data), "programDateTime") != 1 {
+		t.Errorf("Expected zero program date times to be omitted in %s", data)
+	}
+
+	var restored MediaPlaylist
+	if err = json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.String() != p.String() {
+		t.Errorf("Restored playlist differs:\n%s\nexpected:\n%s", &restored, p)
+	}
+	p.Slide("seg7.ts", 6, "")
+	restored.Slide("seg7.ts", 6, "")
+	if restored.String() != p.String() {
+		t.Errorf("Restored playlist slides differently:\n%s\nexpected:\n%s", &restored, p)
+	}
+}
+
+func TestMasterPlaylistJSON(t *testing.T) {
+	for _, name := range []string{
+		"master-with-alternatives.m3u8",
+		"master-with-independent-segments.m3u8",
+		"master-with-stream-inf-name.m3u8",
+		"widevine-master.m3u8",
+	} {
+		f, err := os.Open("sample-playlists/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _, err := DecodeFrom(f, true)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		var restored MasterPlaylist
+		if err = json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if restored.String() != p.String() {
+			t.Errorf("%s: restored playlist differs:\n%s\nexpected:\n%s", name, &restored, p)
+		}
+	}
+}
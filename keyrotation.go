@@ -0,0 +1,68 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines rotation of encryption keys of live playlists, so
+ packagers don't have to track when to attach a new EXT-X-KEY.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+// KeyProvider supplies encryption keys of appended segments. Key is called
+// with the media sequence number of every appended segment and returns the
+// key of the segment, nil for a segment without key.
+type KeyProvider interface {
+	Key(seqID uint64) *Key
+}
+
+// KeyFunc is a function used as KeyProvider.
+type KeyFunc func(seqID uint64) *Key
+
+// Key calls the function.
+func (f KeyFunc) Key(seqID uint64) *Key {
+	return f(seqID)
+}
+
+// SetKeyProvider sets the provider of keys of segments appended after it,
+// nil removes it. Segments appended with own keys keep them. EXT-X-KEY is
+// written only when the key changes, so the provider may return the same
+// key for every segment of a rotation period.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetKeyProvider(keys KeyProvider) {
+	p.keys = keys
+	p.buf.Reset()
+}
+
+// RotateKey encrypts the segments appended after it with a new key every
+// given number of segments. The key is used for the first period, next
+// returns keys of the following periods, e.g. with a new URI and IV. Zero
+// period keeps the key for all the segments.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) RotateKey(key *Key, every uint, next func(seqID uint64) *Key) {
+	var appended uint
+	p.SetKeyProvider(KeyFunc(func(seqID uint64) *Key {
+		if appended > 0 && every > 0 && appended%every == 0 && next != nil {
+			key = next(seqID)
+		}
+		appended++
+		return key
+	}))
+}
+
+// applyKeyProvider sets the key of the appended segment from the key
+// provider unless the segment has own keys.
+func (p *MediaPlaylist) applyKeyProvider(seg *MediaSegment) {
+	if p.keys == nil || len(seg.keys()) > 0 {
+		return
+	}
+	key := p.keys.Key(seg.SeqId)
+	if key == nil {
+		return
+	}
+	version(&p.ver, keyVersion(key))
+	seg.Key = key
+}
@@ -0,0 +1,63 @@
+/*
+ Package m3u8. Key rotation tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRotateKey(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 10)
+	first := &Key{Method: "AES-128", URI: "key0"}
+	p.RotateKey(first, 3, func(seqID uint64) *Key {
+		return &Key{Method: "AES-128", URI: fmt.Sprintf("key%d", seqID)}
+	})
+	for i := 0; i < 7; i++ {
+		if err := p.Append(fmt.Sprintf("seg%d.ts", i), 4, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := p.String()
+	if n := strings.Count(out, "#EXT-X-KEY:"); n != 3 {
+		t.Errorf("Expected 3 keys, got %d:\n%s", n, out)
+	}
+	for _, expected := range []string{
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key0\"\n#EXTINF:4.000,\nseg0.ts\n",
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key3\"\n#EXTINF:4.000,\nseg3.ts\n",
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key6\"\n#EXTINF:4.000,\nseg6.ts\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out)
+		}
+	}
+	// segments with own keys keep them
+	p.AppendSegment(&MediaSegment{URI: "own.ts", Duration: 4, Key: &Key{Method: "NONE"}})
+	if key := p.segmentAt(p.Count() - 1).Key; key.Method != "NONE" {
+		t.Errorf("Expected own key kept, got %v", key)
+	}
+}
+
+func TestKeyProviderVersion(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	p.SetKeyProvider(KeyFunc(func(seqID uint64) *Key {
+		return &Key{Method: "SAMPLE-AES", URI: "skd://key", Keyformat: "com.apple.streamingkeydelivery", Keyformatversions: "1"}
+	}))
+	p.Append("seg0.ts", 4, "")
+	p.Append("seg1.ts", 4, "")
+	out := p.String()
+	if !strings.Contains(out, "#EXT-X-VERSION:5\n") {
+		t.Errorf("Expected version 5 for KEYFORMAT, got:\n%s", out)
+	}
+	if n := strings.Count(out, "#EXT-X-KEY:"); n != 1 {
+		t.Errorf("Expected the same key written once, got %d:\n%s", n, out)
+	}
+}
@@ -0,0 +1,175 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines validation of EXT-X-KEY methods and attributes and the
+ protocol versions they require.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Methods of EXT-X-KEY.
+const (
+	KeyMethodNone         = "NONE"           // segments are not encrypted
+	KeyMethodAES128       = "AES-128"        // segments are encrypted entirely with AES-128 in CBC mode
+	KeyMethodSampleAES    = "SAMPLE-AES"     // media samples are encrypted, e.g. with FairPlay
+	KeyMethodSampleAESCTR = "SAMPLE-AES-CTR" // media samples are encrypted with AES-CTR, e.g. with Widevine and PlayReady
+)
+
+// KEYFORMAT of DRM systems.
+const (
+	FairPlayKeyformat  = "com.apple.streamingkeydelivery"                // FairPlay Streaming
+	WidevineKeyformat  = "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed" // Widevine, the system ID of its PSSH boxes
+	PlayReadyKeyformat = "com.microsoft.playready"                       // PlayReady
+)
+
+var reIV = regexp.MustCompile(`^0[xX][0-9a-fA-F]{1,32}$`)
+
+// Validate checks the method of the key and its attributes: URI is
+// required by encryption methods and forbidden with NONE along with other
+// attributes, IV is a hexadecimal 128-bit number.
+func (key *Key) Validate() error {
+	if err := checkKeyMethod(key.Method); err != nil {
+		return err
+	}
+	if key.Method == KeyMethodNone {
+		if key.URI != "" || key.IV != "" || key.Keyformat != "" || key.Keyformatversions != "" {
+			return errors.New("EXT-X-KEY with METHOD=NONE must not have other attributes")
+		}
+		return nil
+	}
+	if key.URI == "" {
+		return fmt.Errorf("EXT-X-KEY with METHOD=%s requires URI", key.Method)
+	}
+	if key.IV != "" && !reIV.MatchString(key.IV) {
+		return fmt.Errorf("invalid EXT-X-KEY IV %q", key.IV)
+	}
+	return nil
+}
+
+// checkKeyMethod returns an error for methods not defined by the
+// specification.
+func checkKeyMethod(method string) error {
+	switch method {
+	case KeyMethodNone, KeyMethodAES128, KeyMethodSampleAES, KeyMethodSampleAESCTR:
+		return nil
+	}
+	return fmt.Errorf("unknown EXT-X-KEY method %q", method)
+}
+
+// keyVersion returns the minimal protocol version of the key due section
+// 7: IV requires version 2, SAMPLE-AES methods and KEYFORMAT and
+// KEYFORMATVERSIONS attributes require version 5.
+func keyVersion(key *Key) uint8 {
+	switch {
+	case key.Method == KeyMethodSampleAES || key.Method == KeyMethodSampleAESCTR:
+		return 5
+	case key.Keyformat != "" || key.Keyformatversions != "":
+		return 5
+	case key.IV != "":
+		return 2
+	}
+	return 1
+}
+
+// NewFairPlayKey returns FairPlay Streaming key with the skd:// URI of the
+// key server: METHOD=SAMPLE-AES, KEYFORMAT="com.apple.streamingkeydelivery"
+// and KEYFORMATVERSIONS="1". Playlists with the key require protocol
+// version 5, it is set when the key is attached by the playlist methods,
+// the key provider or the segment builder.
+func NewFairPlayKey(skdURI string) *Key {
+	return &Key{
+		Method:            KeyMethodSampleAES,
+		URI:               skdURI,
+		Keyformat:         FairPlayKeyformat,
+		Keyformatversions: "1",
+	}
+}
+
+// SetFairPlayKey sets FairPlay Streaming key with the skd:// URI for the
+// current segment of media playlist, see NewFairPlayKey.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetFairPlayKey(skdURI string) error {
+	if skdURI == "" {
+		return errors.New("FairPlay key requires URI")
+	}
+	key := NewFairPlayKey(skdURI)
+	return p.SetKey(key.Method, key.URI, key.IV, key.Keyformat, key.Keyformatversions)
+}
+
+// NewWidevineKey returns Widevine key with the PSSH box passed in the data
+// URI: URI="data:text/plain;base64,<PSSH>", KEYFORMAT is the urn:uuid of
+// Widevine system ID and KEYFORMATVERSIONS="1". The method is SAMPLE-AES
+// for cbcs or SAMPLE-AES-CTR for cenc encrypted segments.
+func NewWidevineKey(method string, pssh []byte) *Key {
+	return &Key{
+		Method:            method,
+		URI:               "data:text/plain;base64," + base64.StdEncoding.EncodeToString(pssh),
+		Keyformat:         WidevineKeyformat,
+		Keyformatversions: "1",
+	}
+}
+
+// NewPlayReadyKey returns PlayReady key with the PlayReady object (the
+// header with the key IDs and the license server URL) passed in the data
+// URI: URI="data:text/plain;charset=UTF-16;base64,<PRO>",
+// KEYFORMAT="com.microsoft.playready" and KEYFORMATVERSIONS="1". The
+// method is SAMPLE-AES for cbcs or SAMPLE-AES-CTR for cenc encrypted
+// segments.
+func NewPlayReadyKey(method string, pro []byte) *Key {
+	return &Key{
+		Method:            method,
+		URI:               "data:text/plain;charset=UTF-16;base64," + base64.StdEncoding.EncodeToString(pro),
+		Keyformat:         PlayReadyKeyformat,
+		Keyformatversions: "1",
+	}
+}
+
+// Key converts #WV-ECM tag of Widevine Live Packager to Widevine key of
+// the method, see NewWidevineKey. The ECM is passed in the data URI as is.
+func (wv *WV) Key(method string) (*Key, error) {
+	if wv.ECM == "" {
+		return nil, errors.New("no #WV-ECM to convert")
+	}
+	ecm, err := base64.StdEncoding.DecodeString(wv.ECM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid #WV-ECM: %s", err)
+	}
+	return NewWidevineKey(method, ecm), nil
+}
+
+// ConvertWVKey replaces #WV-CYPHER-VERSION and #WV-ECM tags of the media
+// playlist by EXT-X-KEY of the playlist with Widevine key of the method,
+// see WV.Key. Other #WV-* tags describe the media and are kept.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) ConvertWVKey(method string) error {
+	if p.WV == nil {
+		return errors.New("no #WV-* tags to convert")
+	}
+	if err := checkKeyMethod(method); err != nil {
+		return err
+	}
+	key, err := p.WV.Key(method)
+	if err != nil {
+		return err
+	}
+	version(&p.ver, keyVersion(key))
+	p.Key = key
+	p.WV.CypherVersion, p.WV.ECM = "", ""
+	if *p.WV == (WV{}) {
+		p.WV = nil
+	}
+	p.buf.Reset()
+	return nil
+}
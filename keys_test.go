@@ -0,0 +1,151 @@
+/*
+ Package m3u8. Encryption key tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeyValidate(t *testing.T) {
+	valid := []Key{
+		{Method: KeyMethodNone},
+		{Method: KeyMethodAES128, URI: "key", IV: "0x0123456789abcdef0123456789ABCDEF"},
+		{Method: KeyMethodSampleAES, URI: "skd://key", Keyformat: "com.apple.streamingkeydelivery", Keyformatversions: "1"},
+		{Method: KeyMethodSampleAESCTR, URI: "data:text/plain;base64,AAAA", Keyformat: "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"},
+	}
+	for _, key := range valid {
+		if err := key.Validate(); err != nil {
+			t.Errorf("Expected valid key %v, got %s", key, err)
+		}
+	}
+	invalid := []Key{
+		{Method: "AES-256", URI: "key"},
+		{Method: KeyMethodNone, URI: "key"},
+		{Method: KeyMethodSampleAES},
+		{Method: KeyMethodAES128, URI: "key", IV: "0123"},
+		{Method: KeyMethodAES128, URI: "key", IV: "0x0123456789abcdef0123456789abcdef00"},
+	}
+	for _, key := range invalid {
+		if err := key.Validate(); err == nil {
+			t.Errorf("Expected error for key %v", key)
+		}
+	}
+}
+
+func TestKeyMethodVersion(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	p.Append("seg0.ts", 4, "")
+	if err := p.SetKey("AES-256", "key", "", "", ""); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+	if err := p.SetKey(KeyMethodSampleAESCTR, "key", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(p.String(), "#EXTM3U\n#EXT-X-VERSION:5\n") {
+		t.Errorf("Expected version 5 for SAMPLE-AES-CTR, got:\n%s", p.String())
+	}
+}
+
+func TestDecodeUnknownKeyMethod(t *testing.T) {
+	const src = "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-256,URI=\"key\"\n#EXTINF:10,\nseg0.ts\n"
+	p, _ := NewMediaPlaylist(0, 1)
+	err := p.DecodeFrom(strings.NewReader(src), true)
+	if err == nil || !strings.Contains(err.Error(), "line 3: unknown EXT-X-KEY method") {
+		t.Errorf("Expected error for unknown method, got %v", err)
+	}
+	p, _ = NewMediaPlaylist(0, 1)
+	if err = p.DecodeFrom(strings.NewReader(src), false); err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[0].Key == nil || p.Segments[0].Key.Method != "AES-256" {
+		t.Errorf("Expected the key decoded leniently, got %v", p.Segments[0].Key)
+	}
+}
+
+func TestFairPlayKey(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	if err := p.SetFairPlayKey("skd://key"); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("seg0.ts", 4, "")
+	if err := p.SetFairPlayKey(""); err == nil {
+		t.Error("Expected error for empty URI")
+	}
+	if err := p.SetFairPlayKey("skd://key-1"); err != nil {
+		t.Fatal(err)
+	}
+	out := p.String()
+	if !strings.HasPrefix(out, "#EXTM3U\n#EXT-X-VERSION:5\n") {
+		t.Errorf("Expected version 5, got:\n%s", out)
+	}
+	expected := `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key-1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"`
+	if !strings.Contains(out, expected) {
+		t.Errorf("Expected %s, got:\n%s", expected, out)
+	}
+	if err := NewFairPlayKey("skd://key-1").Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWidevineAndPlayReadyKeys(t *testing.T) {
+	key := NewWidevineKey(KeyMethodSampleAESCTR, []byte("pssh"))
+	if key.URI != "data:text/plain;base64,cHNzaA==" || key.Keyformat != WidevineKeyformat || key.Keyformatversions != "1" {
+		t.Errorf("Unexpected Widevine key %v", key)
+	}
+	if err := key.Validate(); err != nil {
+		t.Error(err)
+	}
+	key = NewPlayReadyKey(KeyMethodSampleAES, []byte("pro"))
+	if key.URI != "data:text/plain;charset=UTF-16;base64,cHJv" || key.Keyformat != PlayReadyKeyformat {
+		t.Errorf("Unexpected PlayReady key %v", key)
+	}
+	if keyVersion(key) != 5 {
+		t.Errorf("Expected version 5, got %d", keyVersion(key))
+	}
+}
+
+func TestConvertWVKey(t *testing.T) {
+	f, err := os.Open("sample-playlists/widevine-bitrate.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, _ := NewMediaPlaylist(0, 16)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.ConvertWVKey("AES-256"); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+	if err = p.ConvertWVKey(KeyMethodSampleAES); err != nil {
+		t.Fatal(err)
+	}
+	if p.WV == nil || p.WV.ECM != "" || p.WV.CypherVersion != "" || p.WV.AudioChannels != 2 {
+		t.Errorf("Expected media tags kept and key tags removed, got %+v", p.WV)
+	}
+	out := p.String()
+	if strings.Contains(out, "#WV-ECM") || strings.Contains(out, "#WV-CYPHER-VERSION") {
+		t.Errorf("Expected no #WV-* key tags, got:\n%s", out)
+	}
+	expected := `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAAAQAAOpgCAAHFYAaVFH6QrFv2wYU1lEaO2L3fGQB1/R3oaD9auWtXNAmcVLxgRTvRlHpqHgXX1YY00/pdUiOlgONVbViqou2/ItyDOWc=",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed",KEYFORMATVERSIONS="1"`
+	if !strings.Contains(out, expected) || !strings.HasPrefix(out, "#EXTM3U\n#EXT-X-VERSION:5\n") {
+		t.Errorf("Expected Widevine key and version 5, got:\n%s", out)
+	}
+	if err = p.ConvertWVKey(KeyMethodSampleAES); err == nil {
+		t.Error("Expected error for converted playlist")
+	}
+	wv := &WV{ECM: "not base64!"}
+	if _, err = wv.Key(KeyMethodSampleAES); err == nil {
+		t.Error("Expected error for invalid ECM")
+	}
+}
@@ -0,0 +1,131 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines latency estimation for followed live playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"time"
+)
+
+// Number of segment arrivals kept by LatencyEstimator for jitter and drift
+// calculation.
+const latencyWindow = 32
+
+// LatencyReport holds the numbers calculated by LatencyEstimator on each
+// playlist reload.
+type LatencyReport struct {
+	Latency   time.Duration // wall clock minus the end of the last segment (its PDT plus duration)
+	Jitter    time.Duration // mean deviation of segment arrival intervals from segment durations
+	Drift     time.Duration // wall clock time elapsed minus media time produced over the observed window
+	KeepingUp bool          // origin produces media at least as fast as real time
+}
+
+// LatencyEstimator follows reloads of a live media playlist and estimates
+// the end-to-end latency, the segment arrival jitter and whether the origin
+// keeps up with real time. Segments must carry EXT-X-PROGRAM-DATE-TIME, at
+// least one of them, the others are interpolated from durations.
+type LatencyEstimator struct {
+	Now      func() time.Time // clock used for measurements, time.Now if nil
+	seen     bool
+	lastSeq  uint64
+	arrivals []latencyArrival
+}
+
+type latencyArrival struct {
+	at  time.Time // wall clock when the segment was seen first
+	end time.Time // PDT of the segment plus its duration
+}
+
+// Update must be called each time the followed playlist is reloaded. The
+// first call is a baseline which reports the latency only, jitter and
+// drift are measured on segments appearing in later reloads.
+func (e *LatencyEstimator) Update(p *MediaPlaylist) (LatencyReport, error) {
+	var report LatencyReport
+	now := time.Now()
+	if e.Now != nil {
+		now = e.Now()
+	}
+	ends, err := segmentEnds(p)
+	if err != nil {
+		return report, err
+	}
+	// the first reload is a baseline, its segments were published at
+	// unknown times before it, only later arrivals are sampled
+	baseline := !e.seen
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg == nil || (e.seen && seg.SeqId <= e.lastSeq) {
+			continue
+		}
+		if !baseline {
+			e.arrivals = append(e.arrivals, latencyArrival{now, ends[i]})
+		}
+		e.lastSeq, e.seen = seg.SeqId, true
+	}
+	if len(e.arrivals) > latencyWindow {
+		e.arrivals = e.arrivals[len(e.arrivals)-latencyWindow:]
+	}
+
+	report.Latency = now.Sub(ends[len(ends)-1])
+	report.KeepingUp = true
+	if n := len(e.arrivals); n > 1 {
+		first, last := e.arrivals[0], e.arrivals[n-1]
+		var deviation time.Duration
+		for i := 1; i < n; i++ {
+			d := e.arrivals[i].at.Sub(e.arrivals[i-1].at) - e.arrivals[i].end.Sub(e.arrivals[i-1].end)
+			if d < 0 {
+				d = -d
+			}
+			deviation += d
+		}
+		report.Jitter = deviation / time.Duration(n-1)
+		report.Drift = last.at.Sub(first.at) - last.end.Sub(first.end)
+		report.KeepingUp = report.Drift <= time.Duration(p.TargetDuration*float64(time.Second))
+	}
+	return report, nil
+}
+
+// segmentEnds calculates the end time of each segment of the playlist from
+// the nearest known PROGRAM-DATE-TIME.
+func segmentEnds(p *MediaPlaylist) ([]time.Time, error) {
+	if p.count == 0 {
+		return nil, errors.New("playlist is empty")
+	}
+	ends := make([]time.Time, p.count)
+	known := -1
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg != nil && !seg.ProgramDateTime.IsZero() {
+			ends[i] = seg.ProgramDateTime.Add(seconds(seg.Duration))
+			known = int(i)
+		} else if known >= 0 && seg != nil {
+			ends[i] = ends[i-1].Add(seconds(seg.Duration))
+		}
+	}
+	if known < 0 {
+		return nil, errors.New("no EXT-X-PROGRAM-DATE-TIME in playlist")
+	}
+	// interpolate backwards for the segments before the first known PDT
+	for i := len(ends) - 2; i >= 0; i-- {
+		if ends[i].IsZero() {
+			if next := p.segmentAt(uint(i + 1)); next != nil {
+				ends[i] = ends[i+1].Add(-seconds(next.Duration))
+			}
+		}
+	}
+	return ends, nil
+}
+
+// seconds converts duration in seconds as used in M3U8 to time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,92 @@
+/*
+ Package m3u8. Live latency estimation tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLatencyEstimator(t *testing.T) {
+	start := time.Date(2019, 9, 20, 12, 0, 0, 0, time.UTC)
+	now := start
+	e := &LatencyEstimator{Now: func() time.Time { return now }}
+	p, _ := NewMediaPlaylist(3, 10)
+	p.Append("seg0.ts", 4, "")
+	p.SetProgramDateTime(start.Add(-10 * time.Second))
+
+	r, err := e.Update(p)
+	if err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if r.Latency != 6*time.Second {
+		t.Errorf("Expected latency 6s, got %s", r.Latency)
+	}
+	// segments arrive in real time with 1 second jitter
+	for i := 1; i <= 4; i++ {
+		now = now.Add(4 * time.Second)
+		if i%2 == 0 {
+			now = now.Add(time.Second)
+		} else {
+			now = now.Add(-time.Second)
+		}
+		p.Slide(fmt.Sprintf("seg%d.ts", i), 4, "")
+		p.SetProgramDateTime(start.Add(time.Duration(4*i-10) * time.Second))
+		if r, err = e.Update(p); err != nil {
+			t.Fatalf("Update failed: %s", err)
+		}
+	}
+	if r.Jitter != time.Second {
+		t.Errorf("Expected jitter 1s, got %s", r.Jitter)
+	}
+	if !r.KeepingUp {
+		t.Errorf("Origin expected to keep up, got drift %s", r.Drift)
+	}
+	// no new segments for a long time
+	now = now.Add(20 * time.Second)
+	p.Slide("seg5.ts", 4, "")
+	if r, _ = e.Update(p); r.KeepingUp {
+		t.Errorf("Origin expected to fall behind, got drift %s", r.Drift)
+	}
+}
+
+func TestLatencyEstimatorInitialWindow(t *testing.T) {
+	start := time.Date(2019, 9, 20, 12, 0, 0, 0, time.UTC)
+	now := start
+	e := &LatencyEstimator{Now: func() time.Time { return now }}
+	p, _ := NewMediaPlaylist(3, 10)
+	for i := 0; i < 3; i++ {
+		p.Append(fmt.Sprintf("seg%d.ts", i), 4, "")
+	}
+	p.Segments[0].ProgramDateTime = start.Add(-12 * time.Second)
+
+	// the origin publishes exactly on time
+	for i := 3; i < 8; i++ {
+		r, err := e.Update(p)
+		if err != nil {
+			t.Fatalf("Update failed: %s", err)
+		}
+		if r.Jitter != 0 || r.Drift != 0 || !r.KeepingUp {
+			t.Errorf("Expected no jitter and drift after %d segments, got %s and %s", i, r.Jitter, r.Drift)
+		}
+		now = now.Add(4 * time.Second)
+		p.Slide(fmt.Sprintf("seg%d.ts", i), 4, "")
+		p.SetProgramDateTime(start.Add(time.Duration(4*i-12) * time.Second))
+	}
+}
+
+func TestLatencyEstimatorWithoutPDT(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 10)
+	p.Append("seg0.ts", 4, "")
+	if _, err := new(LatencyEstimator).Update(p); err == nil {
+		t.Error("Expected error for playlist without PDT")
+	}
+}
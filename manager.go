@@ -0,0 +1,172 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines the manager of live media playlists of many channels.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// PlaylistManager owns live media playlists keyed by channel ID. Every
+// channel has its own lock, so updates of one channel don't block readers
+// of others. Buffers for serving playlists are shared between channels.
+type PlaylistManager struct {
+	winsize  uint
+	capacity uint
+
+	mu       sync.RWMutex
+	channels map[string]*channel
+	buffers  sync.Pool
+}
+
+type channel struct {
+	mu       sync.Mutex
+	playlist *MediaPlaylist
+}
+
+// NewPlaylistManager creates the manager, playlists of its channels are
+// created with the window and capacity as by NewMediaPlaylist.
+func NewPlaylistManager(winsize, capacity uint) *PlaylistManager {
+	m := &PlaylistManager{
+		winsize:  winsize,
+		capacity: capacity,
+		channels: make(map[string]*channel),
+	}
+	m.buffers.New = func() interface{} { return new(bytes.Buffer) }
+	return m
+}
+
+// channel returns the channel and optionally creates it.
+func (m *PlaylistManager) channel(id string, create bool) (*channel, error) {
+	m.mu.RLock()
+	ch, ok := m.channels[id]
+	m.mu.RUnlock()
+	if ok {
+		return ch, nil
+	}
+	if !create {
+		return nil, fmt.Errorf("unknown channel %s", id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok = m.channels[id]; ok {
+		return ch, nil
+	}
+	p, err := NewMediaPlaylist(m.winsize, m.capacity)
+	if err != nil {
+		return nil, err
+	}
+	ch = &channel{playlist: p}
+	m.channels[id] = ch
+	return ch, nil
+}
+
+// Update calls the function with the playlist of the channel under the
+// channel lock. The channel is created on the first update.
+func (m *PlaylistManager) Update(id string, f func(p *MediaPlaylist) error) error {
+	ch, err := m.channel(id, true)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return f(ch.playlist)
+}
+
+// View calls the function with the playlist of the channel under the
+// channel lock. The function must not keep the playlist.
+func (m *PlaylistManager) View(id string, f func(p *MediaPlaylist) error) error {
+	ch, err := m.channel(id, false)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return f(ch.playlist)
+}
+
+// WriteTo writes the encoded playlist of the channel. The playlist is
+// copied to a pooled buffer, so slow writers don't hold the channel lock.
+func (m *PlaylistManager) WriteTo(id string, w io.Writer) (int64, error) {
+	buf := m.buffers.Get().(*bytes.Buffer)
+	defer m.buffers.Put(buf)
+	buf.Reset()
+	err := m.View(id, func(p *MediaPlaylist) error {
+		_, err := buf.Write(p.Encode().Bytes())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
+
+// Remove removes the channel.
+func (m *PlaylistManager) Remove(id string) {
+	m.mu.Lock()
+	delete(m.channels, id)
+	m.mu.Unlock()
+}
+
+// Channels returns sorted IDs of the channels.
+func (m *PlaylistManager) Channels() []string {
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.channels))
+	for id := range m.channels {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// Snapshot returns encoded playlists of all the channels. Each playlist is
+// consistent, the channels are not locked all at once.
+func (m *PlaylistManager) Snapshot() map[string][]byte {
+	out := make(map[string][]byte)
+	for _, id := range m.Channels() {
+		m.View(id, func(p *MediaPlaylist) error {
+			out[id] = append([]byte(nil), p.Encode().Bytes()...)
+			return nil
+		})
+	}
+	return out
+}
+
+// Restore decodes the playlists of the snapshot and replaces the playlists
+// of their channels. Nothing is replaced if any playlist fails to decode.
+func (m *PlaylistManager) Restore(snapshot map[string][]byte) error {
+	decoded := make(map[string]*MediaPlaylist, len(snapshot))
+	for id, data := range snapshot {
+		p, err := NewMediaPlaylist(m.winsize, m.capacity)
+		if err != nil {
+			return err
+		}
+		if err = p.Decode(*bytes.NewBuffer(data), false); err != nil {
+			return fmt.Errorf("channel %s: %v", id, err)
+		}
+		decoded[id] = p
+	}
+	for id, p := range decoded {
+		ch, err := m.channel(id, true)
+		if err != nil {
+			return err
+		}
+		ch.mu.Lock()
+		ch.playlist = p
+		ch.mu.Unlock()
+	}
+	return nil
+}
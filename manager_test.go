@@ -0,0 +1,66 @@
+/*
+ Package m3u8. Playlist manager tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPlaylistManager(t *testing.T) {
+	m := NewPlaylistManager(3, 5)
+	var wg sync.WaitGroup
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				m.Update(fmt.Sprintf("ch%d", c), func(p *MediaPlaylist) error {
+					p.Slide(fmt.Sprintf("ch%d/seg%d.ts", c, i), 4, "")
+					return nil
+				})
+			}
+		}(c)
+	}
+	wg.Wait()
+	if ids := m.Channels(); strings.Join(ids, ",") != "ch0,ch1,ch2,ch3" {
+		t.Fatalf("Unexpected channels %v", ids)
+	}
+	var buf bytes.Buffer
+	if _, err := m.WriteTo("ch2", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "#EXT-X-MEDIA-SEQUENCE:2\n") || !strings.Contains(out, "ch2/seg4.ts") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+	if _, err := m.WriteTo("unknown", &buf); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+
+	snapshot := m.Snapshot()
+	restored := NewPlaylistManager(3, 5)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	for id, data := range snapshot {
+		var out bytes.Buffer
+		restored.WriteTo(id, &out)
+		if out.String() != string(data) {
+			t.Errorf("Channel %s differs after restore:\n%s\nexpected:\n%s", id, out.String(), data)
+		}
+	}
+	m.Remove("ch0")
+	if len(m.Channels()) != 3 {
+		t.Error("Channel must be removed")
+	}
+}
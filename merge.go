@@ -0,0 +1,148 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines merging of master playlists, e.g. of outputs of
+ separate audio and video packagers.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergeMasterPlaylists returns the union of variants and renditions of the
+// master playlists. Identical variants and EXT-X-MEDIA renditions are
+// included once. A rendition group (GROUP-ID of a TYPE) present in both
+// playlists must have the same renditions, otherwise variants of one
+// playlist would play renditions of the other one, so the conflict is
+// reported. Header tags of a are preferred, INDEPENDENT-SEGMENTS is kept
+// only if both playlists have it. The source playlists are not changed,
+// the result holds copies of their variants.
+func MergeMasterPlaylists(a, b *MasterPlaylist) (*MasterPlaylist, error) {
+	groupsA, groupsB := renditionGroups(a), renditionGroups(b)
+	for group, alts := range groupsB {
+		if other, ok := groupsA[group]; ok && !sameRenditions(alts, other) {
+			return nil, fmt.Errorf("renditions of %s group %s differ between playlists", alts[0].Type, alts[0].GroupId)
+		}
+	}
+
+	out := a.shallowCopy()
+	out.independentSegments = a.independentSegments && b.independentSegments
+	if b.ver > out.ver {
+		out.ver = b.ver
+	}
+	if out.StartTime == 0 {
+		out.StartTime, out.StartTimePrecise = b.StartTime, b.StartTimePrecise
+	}
+	if out.ContentSteering == nil {
+		out.ContentSteering = b.ContentSteering
+	}
+	out.Defines = append([]Define(nil), a.Defines...)
+	for _, define := range b.Defines {
+		if prev, ok := findDefine(out.Defines, define.Name); ok {
+			if prev != define {
+				return nil, fmt.Errorf("EXT-X-DEFINE %s differs between playlists", define.Name)
+			}
+			continue
+		}
+		out.Defines = append(out.Defines, define)
+	}
+	for _, k := range customTagNames(b.Custom, b.customOrder) {
+		if _, ok := out.Custom[k]; !ok {
+			setCustomTag(&out.Custom, &out.customOrder, b.Custom[k])
+		}
+	}
+
+	variants := make(map[string]*Variant)
+	for _, v := range append(append([]*Variant(nil), a.Variants...), b.Variants...) {
+		nv := v.copy()
+		// renditions of the same group are shared, so they are encoded once
+		if len(v.Alternatives) > 0 {
+			nv.Alternatives = make([]*Alternative, len(v.Alternatives))
+			for i, alt := range v.Alternatives {
+				nv.Alternatives[i] = alt
+				if alts, ok := groupsA[alt.Type+"/"+alt.GroupId]; ok {
+					nv.Alternatives[i] = findRendition(alts, alt)
+				}
+			}
+		}
+		key := fmt.Sprintf("%t-%t-%s", v.Iframe, v.Image, v.URI)
+		if prev, ok := variants[key]; ok {
+			if !reflect.DeepEqual(prev.VariantParams, nv.VariantParams) {
+				return nil, fmt.Errorf("variant %s differs between playlists", v.URI)
+			}
+			continue
+		}
+		variants[key] = nv
+		out.Variants = append(out.Variants, nv)
+	}
+	return out, nil
+}
+
+// renditionGroups returns unique renditions of the playlist by TYPE and
+// GROUP-ID, renditions are compared the same way the encoder does.
+func renditionGroups(p *MasterPlaylist) map[string][]*Alternative {
+	groups := make(map[string][]*Alternative)
+	seen := make(map[string]bool)
+	for _, v := range p.Variants {
+		for _, alt := range v.Alternatives {
+			key := fmt.Sprintf("%s-%s-%s-%s", alt.Type, alt.GroupId, alt.Name, alt.Language)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			group := alt.Type + "/" + alt.GroupId
+			groups[group] = append(groups[group], alt)
+		}
+	}
+	return groups
+}
+
+// sameRenditions reports whether the groups consist of identical
+// renditions regardless of their order.
+func sameRenditions(a, b []*Alternative) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, alt := range a {
+		other := findRendition(b, alt)
+		if other == nil {
+			return false
+		}
+		// renditions are compared by their attributes only
+		x, y := *other, *alt
+		x.Chunklist, y.Chunklist = nil, nil
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// findRendition returns the rendition of the group with the same NAME and
+// LANGUAGE as alt or nil.
+func findRendition(alts []*Alternative, alt *Alternative) *Alternative {
+	for _, other := range alts {
+		if other.Name == alt.Name && other.Language == alt.Language {
+			return other
+		}
+	}
+	return nil
+}
+
+// findDefine returns the variable definition of the name.
+func findDefine(defines []Define, name string) (Define, bool) {
+	for _, define := range defines {
+		if define.Name == name {
+			return define, true
+		}
+	}
+	return Define{}, false
+}
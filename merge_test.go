@@ -0,0 +1,67 @@
+/*
+ Package m3u8. Master playlist merging tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeMasterPlaylists(t *testing.T) {
+	audio := []*Alternative{
+		{GroupId: "aac", Type: "AUDIO", Name: "English", Language: "en", Default: true, URI: "en.m3u8"},
+		{GroupId: "aac", Type: "AUDIO", Name: "Deutsch", Language: "de", URI: "de.m3u8"},
+	}
+	a := NewMasterPlaylist()
+	a.Append("low.m3u8", nil, VariantParams{Bandwidth: 1000000, Audio: "aac", Alternatives: audio})
+	a.SetIndependentSegments(true)
+	b := NewMasterPlaylist()
+	// the same group decoded from another playlist
+	audioCopy := []*Alternative{}
+	for _, alt := range audio {
+		c := *alt
+		audioCopy = append(audioCopy, &c)
+	}
+	b.Append("high.m3u8", nil, VariantParams{Bandwidth: 5000000, Audio: "aac", Alternatives: audioCopy})
+	b.Append("low.m3u8", nil, VariantParams{Bandwidth: 1000000, Audio: "aac", Alternatives: audioCopy})
+
+	m, err := MergeMasterPlaylists(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Variants) != 2 || m.Variants[0].URI != "low.m3u8" || m.Variants[1].URI != "high.m3u8" {
+		t.Fatalf("Unexpected variants %+v", m.Variants)
+	}
+	out := m.String()
+	if strings.Count(out, "#EXT-X-MEDIA:") != 2 {
+		t.Errorf("Expected deduplicated renditions:\n%s", out)
+	}
+	if strings.Contains(out, "#EXT-X-INDEPENDENT-SEGMENTS") {
+		t.Errorf("INDEPENDENT-SEGMENTS must be kept only if both playlists have it:\n%s", out)
+	}
+	if len(a.Variants) != 1 || len(b.Variants) != 2 {
+		t.Error("Source playlists must not be changed")
+	}
+
+	// the group of b has another rendition
+	c := NewMasterPlaylist()
+	c.Append("other.m3u8", nil, VariantParams{Bandwidth: 2000000, Audio: "aac", Alternatives: []*Alternative{
+		{GroupId: "aac", Type: "AUDIO", Name: "Français", Language: "fr", URI: "fr.m3u8"},
+	}})
+	if _, err = MergeMasterPlaylists(a, c); err == nil {
+		t.Error("Expected GROUP-ID conflict")
+	}
+
+	d := NewMasterPlaylist()
+	d.Append("low.m3u8", nil, VariantParams{Bandwidth: 2000000})
+	if _, err = MergeMasterPlaylists(a, d); err == nil {
+		t.Error("Expected conflict of variants with the same URI")
+	}
+}
@@ -0,0 +1,161 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines options of the decoder, so real world playlists may be
+ parsed leniently while validation pipelines enforce the specification,
+ and byte level options of the encoder for picky devices.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UnknownTagPolicy tells the decoder what to do with tags unknown to the
+// library and to the custom decoders.
+type UnknownTagPolicy uint8
+
+const (
+	// UnknownTagsIgnore drops unknown tags (the default) unless the
+	// playlist keeps them, see WithUnknownTags.
+	UnknownTagsIgnore UnknownTagPolicy = iota
+	// UnknownTagsKeep keeps unknown tags in Unknown fields of the playlist.
+	UnknownTagsKeep
+	// UnknownTagsError fails decoding in strict mode, unknown tags are dropped otherwise.
+	UnknownTagsError
+)
+
+// DecodeOptions control the decoder, the zero value decodes leniently.
+// CRLF line ends are accepted in any mode.
+type DecodeOptions struct {
+	Strict             bool             // return the first syntax error
+	AllowMissingHeader bool             // accept playlists without #EXTM3U in strict mode
+	AllowBOM           bool             // skip UTF-8 byte order mark at the start of the playlist
+	MaxSize            int64            // maximum accepted size of the playlist in bytes, 0 for no limit
+	UnknownTags        UnknownTagPolicy // handling of unknown tags
+	WarningHandler     func(Warning)    // called with every problem tolerated by the decoder
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DecodeWithOptions detects the type of playlist and decodes it according
+// to the options. It accepts either bytes.Buffer or io.Reader as input.
+func DecodeWithOptions(input interface{}, opts DecodeOptions) (Playlist, ListType, error) {
+	buf, err := readInput(input, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decode(buf, opts, nil, DuplicateLastWins)
+}
+
+// DecodeWithOptions parses a master playlist from the io.Reader stream
+// according to the options.
+func (p *MasterPlaylist) DecodeWithOptions(reader io.Reader, opts DecodeOptions) error {
+	buf, err := readInput(reader, opts)
+	if err != nil {
+		return err
+	}
+	return p.decode(buf, opts)
+}
+
+// DecodeWithOptions parses a media playlist from the io.Reader stream
+// according to the options.
+func (p *MediaPlaylist) DecodeWithOptions(reader io.Reader, opts DecodeOptions) error {
+	buf, err := readInput(reader, opts)
+	if err != nil {
+		return err
+	}
+	return p.decode(buf, opts)
+}
+
+// readInput reads the playlist from bytes.Buffer or io.Reader input and
+// checks its size. The byte order mark is skipped if the options allow it.
+func readInput(input interface{}, opts DecodeOptions) (*bytes.Buffer, error) {
+	var buf *bytes.Buffer
+	switch v := input.(type) {
+	case bytes.Buffer:
+		buf = &v
+	case io.Reader:
+		if opts.MaxSize > 0 {
+			v = io.LimitReader(v, opts.MaxSize+1)
+		}
+		buf = new(bytes.Buffer)
+		if _, err := buf.ReadFrom(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("input must be bytes.Buffer or io.Reader type")
+	}
+	if opts.MaxSize > 0 && int64(buf.Len()) > opts.MaxSize {
+		return nil, fmt.Errorf("playlist exceeds maximum size of %d bytes", opts.MaxSize)
+	}
+	if opts.AllowBOM && bytes.HasPrefix(buf.Bytes(), utf8BOM) {
+		buf.Next(len(utf8BOM))
+	}
+	return buf, nil
+}
+
+// checkUnknownTag returns an error for unknown tags if the options reject
+// them.
+func checkUnknownTag(line string, opts DecodeOptions, customDecoders []CustomDecoder) error {
+	if !opts.Strict || opts.UnknownTags != UnknownTagsError {
+		return nil
+	}
+	line = strings.TrimSpace(line)
+	if !isUnknownTag(line, customDecoders) {
+		return nil
+	}
+	if i := strings.IndexByte(line, ':'); i != -1 {
+		line = line[:i]
+	}
+	return fmt.Errorf("unknown tag %s", line)
+}
+
+// EncodeOptions control the bytes of the encoded playlist, the zero value
+// encodes according to the specification. Some legacy set-top boxes
+// require CRLF line ends or EXTINF tags without the comma.
+type EncodeOptions struct {
+	CRLF                bool // terminate lines with CR LF instead of LF
+	OmitEmptyTitleComma bool // write EXTINF of segments without title without the comma
+	NoTrailingNewline   bool // don't terminate the last line of the playlist
+}
+
+// SetEncodeOptions sets byte level options of the encoded playlist.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SetEncodeOptions(opts EncodeOptions) {
+	p.encodeOpts = opts
+	p.buf.Reset()
+}
+
+// SetEncodeOptions sets byte level options of the encoded playlist.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetEncodeOptions(opts EncodeOptions) {
+	p.encodeOpts = opts
+	p.buf.Reset()
+}
+
+// eol returns the line terminator of the encoded playlist.
+func (o EncodeOptions) eol() string {
+	if o.CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// trim drops the line terminator of the last line of the playlist encoded
+// to the buffer if the options require it.
+func (o EncodeOptions) trim(buf *bytes.Buffer, eol string) {
+	if o.NoTrailingNewline && bytes.HasSuffix(buf.Bytes(), []byte(eol)) {
+		buf.Truncate(buf.Len() - len(eol))
+	}
+}
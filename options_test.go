@@ -0,0 +1,154 @@
+/*
+ Package m3u8. Decode and encode options tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const optionsPlaylist = "\xef\xbb\xbf#EXTM3U\r\n#EXT-X-TARGETDURATION:10\r\n#EXT-X-VENDOR-TAG:1\r\n#EXTINF:10.000,\r\nseg0.ts\r\n"
+
+func TestDecodeWithOptionsBOM(t *testing.T) {
+	if _, _, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), DecodeOptions{Strict: true}); err == nil {
+		t.Error("Expected error for byte order mark in strict mode")
+	}
+	p, listType, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), DecodeOptions{Strict: true, AllowBOM: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listType != MEDIA || p.(*MediaPlaylist).Segments[0].URI != "seg0.ts" {
+		t.Errorf("Unexpected playlist:\n%s", p)
+	}
+}
+
+func TestDecodeWithOptionsMissingHeader(t *testing.T) {
+	playlist := strings.TrimPrefix(optionsPlaylist, "\xef\xbb\xbf#EXTM3U\r\n")
+	if _, _, err := DecodeWithOptions(*bytes.NewBufferString(playlist), DecodeOptions{Strict: true}); err == nil {
+		t.Error("Expected error for missing #EXTM3U")
+	}
+	p, _ := NewMediaPlaylist(0, 1)
+	if err := p.DecodeWithOptions(strings.NewReader(playlist), DecodeOptions{Strict: true, AllowMissingHeader: true}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Count() != 1 {
+		t.Errorf("Expected 1 segment, got %d", p.Count())
+	}
+}
+
+func TestDecodeWithOptionsMaxSize(t *testing.T) {
+	opts := DecodeOptions{AllowBOM: true, MaxSize: int64(len(optionsPlaylist)) - 1}
+	if _, _, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), opts); err == nil {
+		t.Error("Expected error for playlist exceeding maximum size")
+	}
+	opts.MaxSize++
+	if _, _, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), opts); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeWithOptionsUnknownTags(t *testing.T) {
+	opts := DecodeOptions{Strict: true, AllowBOM: true, UnknownTags: UnknownTagsError}
+	if _, _, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), opts); err == nil || err.Error() != "line 3: unknown tag #EXT-X-VENDOR-TAG" {
+		t.Errorf("Expected unknown tag error, got %v", err)
+	}
+	opts.UnknownTags = UnknownTagsKeep
+	p, _, err := DecodeWithOptions(strings.NewReader(optionsPlaylist), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown := p.(*MediaPlaylist).Unknown; len(unknown) != 1 || unknown[0].Line != "#EXT-X-VENDOR-TAG:1" {
+		t.Errorf("Expected kept unknown tag, got %+v", unknown)
+	}
+	m := NewMasterPlaylist()
+	opts.UnknownTags = UnknownTagsError
+	if err = m.DecodeWithOptions(strings.NewReader("#EXTM3U\n#EXT-X-VENDOR-TAG:1\n"), opts); err == nil {
+		t.Error("Expected unknown tag error for master playlist")
+	}
+}
+
+func TestEncodeOptions(t *testing.T) {
+	p, _ := NewMediaPlaylist(2, 2)
+	p.Append("a.ts", 4, "")
+	p.Append("b.ts", 4, "title")
+	p.Close()
+	plain := p.String()
+
+	p.SetEncodeOptions(EncodeOptions{CRLF: true, OmitEmptyTitleComma: true, NoTrailingNewline: true})
+	out := p.String()
+	if !strings.HasPrefix(out, "#EXTM3U\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Errorf("Expected CRLF line ends, got %q", out)
+	}
+	if !strings.Contains(out, "#EXTINF:4.000\r\na.ts\r\n") || !strings.Contains(out, "#EXTINF:4.000,title\r\n") {
+		t.Errorf("Expected EXTINF without comma for empty title only, got %q", out)
+	}
+	if !strings.HasSuffix(out, "#EXT-X-ENDLIST") {
+		t.Errorf("Expected no trailing newline, got %q", out)
+	}
+
+	// EncodeTo keeps the content of the buffer
+	buf := bytes.NewBufferString("prefix\n")
+	p.ResetCache()
+	p.EncodeTo(buf)
+	if buf.String() != "prefix\n"+out {
+		t.Errorf("Expected prefix kept, got %q", buf.String())
+	}
+
+	// the output is decoded leniently as the original one, EXTINF without
+	// the comma is rejected in strict mode
+	decoded, _ := NewMediaPlaylist(2, 2)
+	if err := decoded.DecodeFrom(bytes.NewBufferString(out), false); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != plain {
+		t.Errorf("Expected decoded playlist:\n%s\ngot:\n%s", plain, decoded.String())
+	}
+
+	m := NewMasterPlaylist()
+	m.Append("low.m3u8", p, VariantParams{Bandwidth: 1000000})
+	m.SetEncodeOptions(EncodeOptions{CRLF: true})
+	if !strings.HasSuffix(m.String(), "\r\nlow.m3u8\r\n") {
+		t.Errorf("Expected CRLF line ends, got %q", m.String())
+	}
+}
+
+func TestEncodeOptionsLinesOnly(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	p.Append("a.ts,", 4, "")
+	p.SetCustomSegmentTag(&MockCustomTag{name: "#X-VENDOR", segment: true, encodedString: "#X-VENDOR:1\n#X-VENDOR-LINE:2"})
+	p.SetEncodeOptions(EncodeOptions{CRLF: true, OmitEmptyTitleComma: true})
+	out := p.String()
+	if !strings.Contains(out, "#X-VENDOR:1\r\n#X-VENDOR-LINE:2\r\n#EXTINF:4.000\r\na.ts,\r\n") {
+		t.Errorf("Expected CRLF after every line of the custom tag and URI kept, got %q", out)
+	}
+}
+
+func TestEncodeOptionsClose(t *testing.T) {
+	for _, opts := range []EncodeOptions{{CRLF: true}, {OmitEmptyTitleComma: true}, {NoTrailingNewline: true}} {
+		p, _ := NewMediaPlaylist(2, 2)
+		p.SetEncodeOptions(opts)
+		p.Append("a.ts", 4, "")
+		p.Encode() // cached before closing
+		p.Close()
+		expected := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-TARGETDURATION:4\n#EXTINF:4.000,\na.ts\n#EXT-X-ENDLIST\n"
+		switch {
+		case opts.CRLF:
+			expected = strings.ReplaceAll(expected, "\n", "\r\n")
+		case opts.OmitEmptyTitleComma:
+			expected = strings.Replace(expected, "#EXTINF:4.000,", "#EXTINF:4.000", 1)
+		case opts.NoTrailingNewline:
+			expected = strings.TrimSuffix(expected, "\n")
+		}
+		if out := p.String(); out != expected {
+			t.Errorf("Expected for %+v:\n%q\ngot:\n%q", opts, expected, out)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines helpers for partial segments and preload hints of
+ Low-Latency HLS.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// nextMSN returns the media sequence number of the segment following the
+// last segment of the playlist, pending parts belong to it.
+func (p *MediaPlaylist) nextMSN() uint64 {
+	if p.count == 0 {
+		return p.SeqNo
+	}
+	return p.segmentAt(p.count-1).SeqId + 1
+}
+
+// LastPart returns the media sequence number and the index of the last
+// part in the playlist, as used by LAST-MSN and LAST-PART attributes of
+// EXT-X-RENDITION-REPORT. It returns false if the playlist has no parts.
+func (p *MediaPlaylist) LastPart() (msn uint64, index int, ok bool) {
+	if len(p.PendingParts) > 0 {
+		return p.nextMSN(), len(p.PendingParts) - 1, true
+	}
+	if p.count > 0 {
+		if seg := p.segmentAt(p.count - 1); len(seg.Parts) > 0 {
+			return seg.SeqId, len(seg.Parts) - 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// NextPart returns the media sequence number and the index of the part
+// expected to be published next, as requested by _HLS_msn and _HLS_part
+// delivery directives.
+func (p *MediaPlaylist) NextPart() (msn uint64, index int) {
+	return p.nextMSN(), len(p.PendingParts)
+}
+
+// PartProgramDateTime returns the date and time of the first sample of the
+// part. It is interpolated from EXT-X-PROGRAM-DATE-TIME of the nearest
+// segment and the durations of segments and parts in between.
+func (p *MediaPlaylist) PartProgramDateTime(msn uint64, index int) (time.Time, error) {
+	ends, err := segmentEnds(p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	first := p.segmentAt(0).SeqId
+	var start time.Time
+	var parts []*PartialSegment
+	switch {
+	case msn >= first && msn < p.nextMSN():
+		seg := p.segmentAt(uint(msn - first))
+		start = ends[msn-first].Add(-seconds(seg.Duration))
+		parts = seg.Parts
+	case msn == p.nextMSN():
+		start = ends[len(ends)-1]
+		parts = p.PendingParts
+	default:
+		return time.Time{}, fmt.Errorf("segment %d is not in the playlist", msn)
+	}
+	if index < 0 || index >= len(parts) {
+		return time.Time{}, fmt.Errorf("part %d.%d is not in the playlist", msn, index)
+	}
+	for _, part := range parts[:index] {
+		start = start.Add(seconds(part.Duration))
+	}
+	return start, nil
+}
+
+// ValidatePreloadHints checks that the playlist has at most one hint of
+// each type and that the hinted part is the next expected one: it must not
+// be published already and, when it continues the byte range of the last
+// part, it must start where that part ends.
+func (p *MediaPlaylist) ValidatePreloadHints() error {
+	seen := make(map[string]bool)
+	for _, hint := range p.PreloadHints {
+		switch hint.Type {
+		case "PART", "MAP":
+		default:
+			return fmt.Errorf("unknown preload hint type %q", hint.Type)
+		}
+		if seen[hint.Type] {
+			return fmt.Errorf("more than one %s preload hint", hint.Type)
+		}
+		seen[hint.Type] = true
+		if hint.URI == "" {
+			return errors.New("preload hint without URI")
+		}
+		if hint.Type != "PART" {
+			continue
+		}
+		if p.PartTarget == 0 {
+			return errors.New("part preload hint in playlist without parts")
+		}
+		last := p.lastPartSegment()
+		if last == nil {
+			continue
+		}
+		if last.URI == hint.URI {
+			if last.Limit == 0 {
+				return fmt.Errorf("hinted part %s is already published", hint.URI)
+			}
+			if end := last.Offset + last.Limit; hint.Start != end {
+				return fmt.Errorf("hinted part %s starts at %d, expected %d", hint.URI, hint.Start, end)
+			}
+		}
+	}
+	return nil
+}
+
+// lastPartSegment returns the last part of the playlist or nil.
+func (p *MediaPlaylist) lastPartSegment() *PartialSegment {
+	msn, index, ok := p.LastPart()
+	if !ok {
+		return nil
+	}
+	if msn == p.nextMSN() {
+		return p.PendingParts[index]
+	}
+	return p.segmentAt(p.count - 1).Parts[index]
+}
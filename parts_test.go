@@ -0,0 +1,109 @@
+/*
+ Package m3u8. Partial segments and preload hints tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bufio"
+	"os"
+	"testing"
+	"time"
+)
+
+func decodeSampleWithParts(t *testing.T) *MediaPlaylist {
+	f, err := os.Open("sample-playlists/media-playlist-with-parts.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, _ := NewMediaPlaylist(0, 8)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLastAndNextPart(t *testing.T) {
+	p := decodeSampleWithParts(t)
+	if msn, index, ok := p.LastPart(); !ok || msn != 270 || index != 2 {
+		t.Errorf("Expected last part 270.2, got %d.%d %v", msn, index, ok)
+	}
+	if msn, index := p.NextPart(); msn != 270 || index != 3 {
+		t.Errorf("Expected next part 270.3, got %d.%d", msn, index)
+	}
+	empty, _ := NewMediaPlaylist(3, 5)
+	if _, _, ok := empty.LastPart(); ok {
+		t.Error("Playlist without parts has no last part")
+	}
+}
+
+func TestPartProgramDateTime(t *testing.T) {
+	p := decodeSampleWithParts(t)
+	cases := []struct {
+		msn   uint64
+		index int
+		pdt   string
+	}{
+		{269, 0, "2019-02-14T02:13:48.10624Z"},
+		{269, 1, "2019-02-14T02:13:48.43958Z"},
+		{270, 2, "2019-02-14T02:13:52.773Z"},
+	}
+	for _, c := range cases {
+		expected, _ := time.Parse(time.RFC3339Nano, c.pdt)
+		pdt, err := p.PartProgramDateTime(c.msn, c.index)
+		if err != nil {
+			t.Errorf("Part %d.%d: %v", c.msn, c.index, err)
+			continue
+		}
+		if diff := pdt.Sub(expected); diff > time.Microsecond || diff < -time.Microsecond {
+			t.Errorf("Part %d.%d: expected %v, got %v", c.msn, c.index, expected, pdt)
+		}
+	}
+	if _, err := p.PartProgramDateTime(270, 3); err == nil {
+		t.Error("Expected error for the part not published yet")
+	}
+	if _, err := p.PartProgramDateTime(265, 0); err == nil {
+		t.Error("Expected error for the segment out of the playlist")
+	}
+}
+
+func TestValidatePreloadHints(t *testing.T) {
+	p := decodeSampleWithParts(t)
+	if len(p.PreloadHints) != 1 || p.PreloadHints[0].URI != "filePart270.3.mp4" {
+		t.Fatalf("Unexpected preload hints: %+v", p.PreloadHints)
+	}
+	if err := p.ValidatePreloadHints(); err != nil {
+		t.Errorf("Valid hints: %v", err)
+	}
+	p.SetPreloadHints([]*PreloadHint{{Type: "PART", URI: "filePart270.2.mp4"}})
+	if err := p.ValidatePreloadHints(); err == nil {
+		t.Error("Expected error for the part already published")
+	}
+	p.SetPreloadHints([]*PreloadHint{{Type: "PART", URI: "a.mp4"}, {Type: "PART", URI: "b.mp4"}})
+	if err := p.ValidatePreloadHints(); err == nil {
+		t.Error("Expected error for two part hints")
+	}
+
+	r, _ := NewMediaPlaylist(3, 5)
+	r.AppendPartialSegment(&PartialSegment{URI: "seg1.mp4", Duration: 0.5, Limit: 1000, Offset: 0})
+	r.SetPreloadHints([]*PreloadHint{{Type: "PART", URI: "seg1.mp4", Start: 1000}})
+	if err := r.ValidatePreloadHints(); err != nil {
+		t.Errorf("Valid byte range hint: %v", err)
+	}
+	r.PreloadHints[0].Start = 500
+	if err := r.ValidatePreloadHints(); err == nil {
+		t.Error("Expected error for the byte range hint not continuing the last part")
+	}
+	expected := "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"seg1.mp4\",BYTERANGE-START=1000\n"
+	r.PreloadHints[0].Start = 1000
+	r.ResetCache()
+	if out := r.String(); out[len(out)-len(expected):] != expected {
+		t.Errorf("Expected suffix:\n%s\ngot:\n%s", expected, out)
+	}
+}
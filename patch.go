@@ -0,0 +1,199 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines patches describing changes between versions of
+ a media playlist.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PlaylistPatch describes changes between two versions of a media
+// playlist. It is designed to be marshaled to compact JSON, so replicas of
+// an origin may exchange updates instead of complete playlists. Custom
+// tags of segments are not transferred.
+type PlaylistPatch struct {
+	First            uint64            `json:"first"`           // media sequence number of the first segment of the base version
+	Last             uint64            `json:"last"`            // media sequence number of the last segment of the base version
+	SeqNo            uint64            `json:"seq"`             // EXT-X-MEDIA-SEQUENCE of the new version
+	Remove           uint              `json:"rm,omitempty"`    // number of segments removed from the head
+	Append           []PatchSegment    `json:"add,omitempty"`   // segments appended to the tail
+	TargetDuration   float64           `json:"td"`              // EXT-X-TARGETDURATION of the new version
+	DiscontinuitySeq uint64            `json:"ds,omitempty"`    // EXT-X-DISCONTINUITY-SEQUENCE of the new version
+	Closed           bool              `json:"end,omitempty"`   // the new version has EXT-X-ENDLIST
+	PartTarget       float64           `json:"pt,omitempty"`    // EXT-X-PART-INF PART-TARGET of the new version
+	PendingParts     []*PartialSegment `json:"parts,omitempty"` // partial segments after the last segment of the new version
+}
+
+// PatchSegment is the representation of a media segment in a patch.
+type PatchSegment struct {
+	URI             string            `json:"u"`
+	Duration        float64           `json:"d"`
+	Title           string            `json:"t,omitempty"`
+	Limit           int64             `json:"l,omitempty"`
+	Offset          int64             `json:"o,omitempty"`
+	Key             *Key              `json:"k,omitempty"`
+	Keys            []*Key            `json:"ks,omitempty"`
+	Map             *Map              `json:"m,omitempty"`
+	Discontinuity   bool              `json:"dc,omitempty"`
+	Gap             bool              `json:"gap,omitempty"`
+	SCTE            []*SCTE           `json:"scte,omitempty"`
+	ProgramDateTime *time.Time        `json:"pdt,omitempty"`
+	DateRanges      []*DateRange      `json:"dr,omitempty"`
+	Tiles           *Tiles            `json:"tiles,omitempty"`
+	Parts           []*PartialSegment `json:"p,omitempty"`
+	Unknown         []string          `json:"x,omitempty"`
+}
+
+// DiffMediaPlaylists calculates the patch which converts the old version
+// of the playlist to the new one. Versions are matched by media sequence
+// numbers of their segments.
+func DiffMediaPlaylists(old, new *MediaPlaylist) (*PlaylistPatch, error) {
+	if old.count == 0 {
+		return nil, errors.New("base playlist is empty")
+	}
+	first, last := old.segmentAt(0).SeqId, old.segmentAt(old.count-1).SeqId
+	patch := &PlaylistPatch{
+		First:            first,
+		Last:             last,
+		SeqNo:            new.SeqNo,
+		TargetDuration:   new.TargetDuration,
+		DiscontinuitySeq: new.DiscontinuitySeq,
+		Closed:           new.Closed,
+		PartTarget:       new.PartTarget,
+		PendingParts:     new.PendingParts,
+	}
+	if new.count > 0 && new.segmentAt(0).SeqId < first {
+		return nil, errors.New("new version starts before the base version")
+	}
+	patch.Remove = old.count
+	if new.count > 0 && new.segmentAt(0).SeqId <= last {
+		patch.Remove = uint(new.segmentAt(0).SeqId - first)
+	}
+	for i := uint(0); i < new.count; i++ {
+		seg := new.segmentAt(i)
+		if seg.SeqId <= last {
+			if prev := old.segmentAt(uint(seg.SeqId - first)); prev.URI != seg.URI {
+				return nil, fmt.Errorf("segment %d differs between versions", seg.SeqId)
+			}
+			continue
+		}
+		ps := PatchSegment{
+			URI:           seg.URI,
+			Duration:      seg.Duration,
+			Title:         seg.Title,
+			Limit:         seg.Limit,
+			Offset:        seg.Offset,
+			Key:           seg.Key,
+			Keys:          seg.Keys,
+			Map:           seg.Map,
+			Discontinuity: seg.Discontinuity,
+			Gap:           seg.Gap,
+			SCTE:          seg.SCTE,
+			DateRanges:    seg.DateRanges,
+			Tiles:         seg.Tiles,
+			Parts:         seg.Parts,
+			Unknown:       seg.Unknown,
+		}
+		if !seg.ProgramDateTime.IsZero() {
+			pdt := seg.ProgramDateTime
+			ps.ProgramDateTime = &pdt
+		}
+		patch.Append = append(patch.Append, ps)
+	}
+	return patch, nil
+}
+
+// ApplyPatch applies the patch to the playlist, the playlist must be the
+// same version the patch was calculated against.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) ApplyPatch(patch *PlaylistPatch) error {
+	if p.count == 0 || p.segmentAt(0).SeqId != patch.First || p.segmentAt(p.count-1).SeqId != patch.Last {
+		return errors.New("patch doesn't match the playlist version")
+	}
+	if patch.Remove > p.count {
+		return errors.New("patch removes more segments than the playlist has")
+	}
+	p.removeHead(patch.Remove)
+	p.PendingParts = nil
+	for _, ps := range patch.Append {
+		seg := &MediaSegment{
+			URI:           ps.URI,
+			Duration:      ps.Duration,
+			Title:         ps.Title,
+			Limit:         ps.Limit,
+			Offset:        ps.Offset,
+			Key:           ps.Key,
+			Keys:          ps.Keys,
+			Map:           ps.Map,
+			Discontinuity: ps.Discontinuity,
+			Gap:           ps.Gap,
+			SCTE:          ps.SCTE,
+			DateRanges:    ps.DateRanges,
+			Tiles:         ps.Tiles,
+			Parts:         ps.Parts,
+			Unknown:       ps.Unknown,
+		}
+		if ps.ProgramDateTime != nil {
+			seg.ProgramDateTime = *ps.ProgramDateTime
+		}
+		if p.count == 0 {
+			// the whole window was replaced, keep the numbering of the new version
+			p.SeqNo = patch.SeqNo
+		}
+		if err := p.appendGrow(seg); err != nil {
+			return err
+		}
+	}
+	p.SeqNo = patch.SeqNo
+	p.PendingParts = patch.PendingParts
+	p.TargetDuration = patch.TargetDuration
+	p.PartTarget = patch.PartTarget
+	p.DiscontinuitySeq = patch.DiscontinuitySeq
+	p.Closed = patch.Closed
+	p.buf.Reset()
+	return nil
+}
+
+// appendGrow appends the segment and extends the capacity of the playlist
+// when it is full.
+func (p *MediaPlaylist) appendGrow(seg *MediaSegment) error {
+	err := p.AppendSegment(seg)
+	if err == ErrPlaylistFull {
+		p.grow()
+		err = p.AppendSegment(seg)
+	}
+	return err
+}
+
+// removeHead removes n segments from the head of the playlist without
+// changing its media sequence number.
+func (p *MediaPlaylist) removeHead(n uint) {
+	for i := uint(0); i < n; i++ {
+		p.Segments[p.head] = nil
+		p.head = (p.head + 1) % p.capacity
+		p.count--
+	}
+}
+
+// grow doubles the capacity of a full playlist.
+func (p *MediaPlaylist) grow() {
+	segments := make([]*MediaSegment, 2*p.capacity+1)
+	for i := uint(0); i < p.count; i++ {
+		segments[i] = p.segmentAt(i)
+	}
+	p.Segments = segments
+	p.capacity = uint(len(segments))
+	p.head = 0
+	p.tail = p.count
+}
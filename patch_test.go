@@ -0,0 +1,83 @@
+/*
+ Package m3u8. Media playlist patch tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDiffAndApplyPatch(t *testing.T) {
+	origin, _ := NewMediaPlaylist(4, 16)
+	for i := 0; i < 4; i++ {
+		origin.Slide(fmt.Sprintf("seg%d.ts", i), 6, "")
+	}
+	replica, _ := NewMediaPlaylist(4, 4)
+	if err := replica.DecodeFrom(origin.Encode(), true); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := NewMediaPlaylist(4, 16)
+	old.DecodeFrom(origin.Encode(), true)
+
+	pdt := time.Date(2019, 9, 20, 12, 0, 0, 0, time.UTC)
+	for i := 4; i < 7; i++ {
+		origin.Slide(fmt.Sprintf("seg%d.ts", i), 6, "")
+	}
+	origin.SetProgramDateTime(pdt)
+	origin.SetDiscontinuity()
+	origin.AppendPartial("seg7.0.ts", 2, true)
+
+	patch, err := DiffMediaPlaylists(old, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch.Remove != 3 || len(patch.Append) != 3 {
+		t.Errorf("Expected 3 removed and 3 appended segments, got %d/%d", patch.Remove, len(patch.Append))
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received PlaylistPatch
+	if err = json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if err = replica.ApplyPatch(&received); err != nil {
+		t.Fatal(err)
+	}
+	if replica.String() != origin.String() {
+		t.Errorf("Patched playlist differs:\n%s\nexpected:\n%s", replica, origin)
+	}
+	if err = replica.ApplyPatch(&received); err == nil {
+		t.Error("Expected error on applying patch twice")
+	}
+}
+
+func TestApplyPatchReplacingWindow(t *testing.T) {
+	old, _ := NewMediaPlaylist(2, 2)
+	old.Slide("seg0.ts", 6, "")
+	old.Slide("seg1.ts", 6, "")
+	new, _ := NewMediaPlaylist(2, 2)
+	new.SeqNo = 10
+	new.Slide("seg10.ts", 6, "")
+	new.Slide("seg11.ts", 6, "")
+	patch, err := DiffMediaPlaylists(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = old.ApplyPatch(patch); err != nil {
+		t.Fatal(err)
+	}
+	if old.String() != new.String() {
+		t.Errorf("Patched playlist differs:\n%s\nexpected:\n%s", old, new)
+	}
+}
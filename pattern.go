@@ -0,0 +1,115 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines inference of segment URI numbering patterns.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reDigits = regexp.MustCompile(`[0-9]+`)
+
+// URIPattern describes how segment URIs of a media playlist are numbered,
+// e.g. "chunk_00042.ts" has Prefix "chunk_", Width 5 and Suffix ".ts".
+type URIPattern struct {
+	Prefix string // part of the URI before the counter
+	Suffix string // part of the URI after the counter (usually the extension)
+	Width  int    // zero padding width of the counter, 0 if the counter is not padded
+	Offset int64  // counter value minus the media sequence number of the segment
+}
+
+// URI returns the segment URI expected for the media sequence number.
+func (u *URIPattern) URI(seqId uint64) string {
+	counter := strconv.FormatInt(int64(seqId)+u.Offset, 10)
+	if pad := u.Width - len(counter); pad > 0 {
+		counter = strings.Repeat("0", pad) + counter
+	}
+	return u.Prefix + counter + u.Suffix
+}
+
+// Next returns a generator which yields URIs of the segments following the
+// segment with the given media sequence number, one per call.
+func (u *URIPattern) Next(seqId uint64) func() string {
+	return func() string {
+		seqId++
+		return u.URI(seqId)
+	}
+}
+
+// InferURIPattern detects the numbering pattern of the segment URIs of the
+// playlist. The counter is the rightmost number in the URI which grows
+// along with the media sequence number of the segments. At least two
+// segments are required.
+func (p *MediaPlaylist) InferURIPattern() (*URIPattern, error) {
+	if p.count < 2 {
+		return nil, errors.New("at least two segments required")
+	}
+	first := p.segmentAt(0)
+	runs := reDigits.FindAllStringIndex(first.URI, -1)
+	for i := len(runs) - 1; i >= 0; i-- {
+		start, end := runs[i][0], runs[i][1]
+		counter, err := strconv.ParseInt(first.URI[start:end], 10, 64)
+		if err != nil {
+			continue
+		}
+		u := &URIPattern{
+			Prefix: first.URI[:start],
+			Suffix: first.URI[end:],
+			Offset: counter - int64(first.SeqId),
+		}
+		if p.matchURIPattern(u) {
+			return u, nil
+		}
+	}
+	return nil, errors.New("no URI numbering pattern found")
+}
+
+// matchURIPattern checks the pattern against all the segments and detects
+// zero padding of the counter.
+func (p *MediaPlaylist) matchURIPattern(u *URIPattern) bool {
+	width, padded := -1, false
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg == nil {
+			continue
+		}
+		if len(seg.URI) <= len(u.Prefix)+len(u.Suffix) ||
+			!strings.HasPrefix(seg.URI, u.Prefix) || !strings.HasSuffix(seg.URI, u.Suffix) {
+			return false
+		}
+		digits := seg.URI[len(u.Prefix) : len(seg.URI)-len(u.Suffix)]
+		counter, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil || digits[0] == '-' || digits[0] == '+' {
+			return false
+		}
+		if counter-int64(seg.SeqId) != u.Offset {
+			return false
+		}
+		if len(digits) > 1 && digits[0] == '0' {
+			padded = true
+		}
+		if width == -1 {
+			width = len(digits)
+		} else if width != len(digits) {
+			width = 0
+		}
+	}
+	if padded {
+		if width <= 0 {
+			return false
+		}
+		u.Width = width
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+/*
+ Package m3u8. URI pattern inference tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestInferURIPattern(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 10)
+	p.SeqNo = 100
+	for i := 0; i < 4; i++ {
+		p.Append(fmt.Sprintf("video_720p/seg_%05d.ts", i+7), 6, "")
+	}
+	u, err := p.InferURIPattern()
+	if err != nil {
+		t.Fatalf("Infer URI pattern failed: %s", err)
+	}
+	expected := URIPattern{Prefix: "video_720p/seg_", Suffix: ".ts", Width: 5, Offset: -93}
+	if *u != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, *u)
+	}
+	next := u.Next(103)
+	if uri := next(); uri != "video_720p/seg_00011.ts" {
+		t.Errorf("Unexpected next URI: %s", uri)
+	}
+	if uri := next(); uri != "video_720p/seg_00012.ts" {
+		t.Errorf("Unexpected next URI: %s", uri)
+	}
+}
+
+func TestInferURIPatternDecoded(t *testing.T) {
+	f, err := os.Open("sample-playlists/wowza-vod-chunklist.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewMediaPlaylist(0, 64)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	u, err := p.InferURIPattern()
+	if err != nil {
+		t.Fatalf("Infer URI pattern failed: %s", err)
+	}
+	last := p.Segments[p.last()]
+	if uri := u.URI(last.SeqId); uri != last.URI {
+		t.Errorf("Expected %s, got %s", last.URI, uri)
+	}
+}
+
+func TestInferURIPatternNoCounter(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 10)
+	p.Append("a.ts", 6, "")
+	p.Append("b.ts", 6, "")
+	if _, err := p.InferURIPattern(); err == nil {
+		t.Error("Expected error for URIs without counter")
+	}
+	p, _ = NewMediaPlaylist(3, 10)
+	p.Append("seg1.ts", 6, "")
+	p.Append("seg5.ts", 6, "")
+	if _, err := p.InferURIPattern(); err == nil {
+		t.Error("Expected error for non sequential counter")
+	}
+}
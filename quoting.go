@@ -0,0 +1,183 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines checks of strings which can't be represented in
+ playlists. The specification provides no escaping of quoted strings, so
+ the writer strips double quotes and line breaks from them, as well as
+ line breaks from titles and URIs.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateStrings checks that the quoted strings and URIs of the master
+// playlist contain no double quotes and line breaks. They are stripped by
+// Encode, ValidateStrings allows to refuse the playlist instead of encoding
+// the altered values.
+func (p *MasterPlaylist) ValidateStrings() error {
+	var c stringChecker
+	if p.ContentSteering != nil {
+		c.quoted("EXT-X-CONTENT-STEERING", "SERVER-URI", p.ContentSteering.ServerURI)
+		c.quoted("EXT-X-CONTENT-STEERING", "PATHWAY-ID", p.ContentSteering.PathwayID)
+	}
+	c.defines(p.Defines)
+	for _, v := range p.Variants {
+		if v == nil {
+			continue
+		}
+		for _, alt := range v.Alternatives {
+			if alt == nil {
+				continue
+			}
+			c.quoted("EXT-X-MEDIA", "GROUP-ID", alt.GroupId)
+			c.quoted("EXT-X-MEDIA", "NAME", alt.Name)
+			c.quoted("EXT-X-MEDIA", "LANGUAGE", alt.Language)
+			c.quoted("EXT-X-MEDIA", "ASSOC-LANGUAGE", alt.AssocLanguage)
+			c.quoted("EXT-X-MEDIA", "INSTREAM-ID", alt.InstreamID)
+			c.quoted("EXT-X-MEDIA", "CHARACTERISTICS", alt.Characteristics)
+			c.quoted("EXT-X-MEDIA", "CHANNELS", alt.Channels)
+			c.quoted("EXT-X-MEDIA", "SUBTITLES", alt.Subtitles)
+			c.quoted("EXT-X-MEDIA", "STABLE-RENDITION-ID", alt.StableRenditionID)
+			c.quoted("EXT-X-MEDIA", "URI", alt.URI)
+		}
+		tag := "EXT-X-STREAM-INF"
+		switch {
+		case v.Iframe:
+			tag = "EXT-X-I-FRAME-STREAM-INF"
+		case v.Image:
+			tag = "EXT-X-IMAGE-STREAM-INF"
+		}
+		c.quoted(tag, "URI", v.URI)
+		c.quoted(tag, "CODECS", v.Codecs)
+		c.quoted(tag, "AUDIO", v.Audio)
+		c.quoted(tag, "VIDEO", v.Video)
+		c.quoted(tag, "SUBTITLES", v.Subtitles)
+		c.quoted(tag, "CLOSED-CAPTIONS", v.Captions)
+		c.quoted(tag, "NAME", v.Name)
+		c.quoted(tag, "REQ-VIDEO-LAYOUT", v.ReqVideoLayout)
+		c.quoted(tag, "PATHWAY-ID", v.PathwayID)
+		c.quoted(tag, "STABLE-VARIANT-ID", v.StableVariantID)
+		for keyformat, cpc := range v.AllowedCPC {
+			c.quoted(tag, "ALLOWED-CPC", keyformat+":"+strings.Join(cpc, "/"))
+		}
+		c.extra(tag, v.ExtraAttributes, v.ExtraQuoted)
+	}
+	return c.err
+}
+
+// ValidateStrings checks that titles, URIs and quoted strings of the media
+// playlist contain no characters which can't be represented in it: line
+// breaks in titles and URIs, double quotes and line breaks in quoted
+// strings. They are stripped by Encode, ValidateStrings allows to refuse the
+// playlist instead of encoding the altered values.
+func (p *MediaPlaylist) ValidateStrings() error {
+	var c stringChecker
+	c.defines(p.Defines)
+	c.key(p.Key)
+	c.xmap(p.Map)
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg == nil {
+			continue
+		}
+		c.line("EXTINF", "title", seg.Title)
+		c.line("EXTINF", "URI", seg.URI)
+		for _, key := range seg.keys() {
+			c.key(key)
+		}
+		c.xmap(seg.Map)
+		for _, scte := range seg.SCTE {
+			c.quoted("SCTE-35", "ID", scte.ID)
+			c.quoted("SCTE-35", "CUE", scte.Cue)
+			c.extra("SCTE-35", scte.Attributes, scte.AttributesQuoted)
+		}
+		for _, dr := range seg.DateRanges {
+			c.quoted("EXT-X-DATERANGE", "ID", dr.ID)
+			c.quoted("EXT-X-DATERANGE", "CLASS", dr.Class)
+			c.extra("EXT-X-DATERANGE", dr.X, dr.XQuoted)
+		}
+		c.parts(seg.Parts)
+	}
+	c.parts(p.PendingParts)
+	for _, hint := range p.PreloadHints {
+		c.quoted("EXT-X-PRELOAD-HINT", "URI", hint.URI)
+	}
+	for _, rr := range p.RenditionReports {
+		c.quoted("EXT-X-RENDITION-REPORT", "URI", rr.URI)
+	}
+	for _, id := range p.RecentlyRemoved {
+		c.check("EXT-X-SKIP", "RECENTLY-REMOVED-DATERANGES", id, "\"\r\n\t") // IDs are separated by tabs
+	}
+	return c.err
+}
+
+// stringChecker keeps the first string which can't be represented.
+type stringChecker struct {
+	err error
+}
+
+func (c *stringChecker) check(tag, attr, value, invalid string) {
+	if c.err == nil && strings.ContainsAny(value, invalid) {
+		c.err = fmt.Errorf("%s of %s can't be represented: %q", attr, tag, value)
+	}
+}
+
+// quoted checks the value of a quoted string attribute.
+func (c *stringChecker) quoted(tag, attr, value string) {
+	c.check(tag, attr, value, "\"\r\n")
+}
+
+// line checks the value written at the end of a line.
+func (c *stringChecker) line(tag, attr, value string) {
+	c.check(tag, attr, value, "\r\n")
+}
+
+// extra checks the values of attributes unknown to the library, non
+// numeric ones and the ones named in quoted are quoted.
+func (c *stringChecker) extra(tag string, attrs map[string]string, quoted map[string]bool) {
+	for k, v := range attrs {
+		if reAttrName.MatchString(k) && (quoted[k] || !reUnquotedValue.MatchString(v)) {
+			c.quoted(tag, k, v)
+		}
+	}
+}
+
+func (c *stringChecker) defines(defines []Define) {
+	for _, d := range defines {
+		c.quoted("EXT-X-DEFINE", "NAME", d.Name)
+		if d.Type == DefineValue {
+			c.quoted("EXT-X-DEFINE", "VALUE", d.Value)
+		}
+	}
+}
+
+func (c *stringChecker) key(key *Key) {
+	if key == nil {
+		return
+	}
+	c.quoted("EXT-X-KEY", "URI", key.URI)
+	c.quoted("EXT-X-KEY", "KEYFORMAT", key.Keyformat)
+	c.quoted("EXT-X-KEY", "KEYFORMATVERSIONS", key.Keyformatversions)
+}
+
+func (c *stringChecker) xmap(m *Map) {
+	if m != nil {
+		c.quoted("EXT-X-MAP", "URI", m.URI)
+	}
+}
+
+func (c *stringChecker) parts(parts []*PartialSegment) {
+	for _, part := range parts {
+		c.quoted("EXT-X-PART", "URI", part.URI)
+		c.key(part.Key)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ Package m3u8. Quoted strings tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeStripsUnrepresentable(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	if err := p.Append("seg\n1.ts", 4, "title with \"quotes\"\nand line break"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetKey("AES-128", "https://example.com/\"key\"", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ValidateStrings(); err == nil {
+		t.Error("Expected error for line breaks and quotes")
+	}
+	out := p.String()
+	if !strings.Contains(out, "#EXTINF:4.000,title with \"quotes\"and line break\nseg1.ts\n") {
+		t.Errorf("Expected title and URI without line breaks, got:\n%s", out)
+	}
+	if !strings.Contains(out, `URI="https://example.com/key"`) {
+		t.Errorf("Expected key URI without quotes, got:\n%s", out)
+	}
+
+	// the encoded playlist is decoded with the same segments
+	decoded, _ := NewMediaPlaylist(1, 1)
+	if err := decoded.DecodeFrom(bytes.NewBufferString(out), true); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Count() != 1 {
+		t.Errorf("Expected 1 segment, got %d", decoded.Count())
+	}
+}
+
+func TestMasterValidateStrings(t *testing.T) {
+	m := NewMasterPlaylist()
+	p, _ := NewMediaPlaylist(1, 1)
+	m.Append("low.m3u8", p, VariantParams{Bandwidth: 1000000, Name: "low", Alternatives: []*Alternative{
+		{GroupId: "aud", Type: "AUDIO", Name: "English"},
+	}})
+	if err := m.ValidateStrings(); err != nil {
+		t.Fatal(err)
+	}
+	m.Variants[0].Alternatives[0].Name = "The \"English\""
+	err := m.ValidateStrings()
+	if err == nil || !strings.Contains(err.Error(), "NAME of EXT-X-MEDIA") {
+		t.Errorf("Expected error for NAME of EXT-X-MEDIA, got %v", err)
+	}
+	if !strings.Contains(m.String(), `NAME="The English"`) {
+		t.Errorf("Expected name without quotes, got:\n%s", m.String())
+	}
+}
+
+func TestEncodeStripsCaptionsAndCues(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("low.m3u8", nil, VariantParams{Bandwidth: 1000000, Captions: "cc\"1\"\n"})
+	if !strings.Contains(m.String(), ",CLOSED-CAPTIONS=\"cc1\"\nlow.m3u8\n") {
+		t.Errorf("Expected CLOSED-CAPTIONS without quotes and line breaks, got:\n%s", m.String())
+	}
+
+	p, _ := NewMediaPlaylist(2, 2)
+	p.Append("seg0.ts", 4, "")
+	p.SetSCTE35(&SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_Start, Cue: "/DAlAAAA\r\n#EXT-X-ENDLIST", Time: 15})
+	p.Append("seg1.ts", 4, "")
+	p.SetSCTE35(&SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_Mid, Cue: "/DAlAAAA\n", Time: 15, Elapsed: 4})
+	out := p.String()
+	if strings.Contains(out, "\n#EXT-X-ENDLIST") || !strings.Contains(out, "#EXT-OATCLS-SCTE35:/DAlAAAA#EXT-X-ENDLIST\n") {
+		t.Errorf("Expected OATCLS cue without line breaks, got:\n%s", out)
+	}
+	if !strings.Contains(out, ",SCTE35=/DAlAAAA\n#EXTINF") {
+		t.Errorf("Expected CUE-OUT-CONT cue without line breaks, got:\n%s", out)
+	}
+}
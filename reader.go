@@ -12,10 +12,12 @@ package m3u8
 */
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -33,7 +35,7 @@ var TimeParse func(value string) (time.Time, error) = FullTimeParse
 // Decode parses a master playlist passed from the buffer. If `strict`
 // parameter is true then it returns first syntax error.
 func (p *MasterPlaylist) Decode(data bytes.Buffer, strict bool) error {
-	return p.decode(&data, strict)
+	return p.decode(&data, DecodeOptions{Strict: strict})
 }
 
 // DecodeFrom parses a master playlist passed from the io.Reader
@@ -45,7 +47,7 @@ func (p *MasterPlaylist) DecodeFrom(reader io.Reader, strict bool) error {
 	if err != nil {
 		return err
 	}
-	return p.decode(buf, strict)
+	return p.decode(buf, DecodeOptions{Strict: strict})
 }
 
 // WithCustomDecoders adds custom tag decoders to the master playlist for decoding
@@ -61,25 +63,47 @@ func (p *MasterPlaylist) WithCustomDecoders(customDecoders []CustomDecoder) Play
 }
 
 // Parse master playlist. Internal function.
-func (p *MasterPlaylist) decode(buf *bytes.Buffer, strict bool) error {
+func (p *MasterPlaylist) decode(buf *bytes.Buffer, opts DecodeOptions) error {
 	var eof bool
 
 	state := new(decodingState)
+	strict := opts.Strict
+	if opts.UnknownTags == UnknownTagsKeep {
+		p.keepUnknown = true
+	}
 
-	for !eof {
+	attach := func(_ CustomDecoder, t CustomTag) {
+		setCustomTag(&p.Custom, &p.customOrder, t)
+	}
+	for n := 1; !eof; n++ {
 		line, err := buf.ReadString('\n')
 		if err == io.EOF {
 			eof = true
 		} else if err != nil {
 			break
 		}
+		consumed, err := state.decodeMultiline(p.customDecoders, line, attach)
+		if strict && err != nil {
+			return parseError(n, line, err)
+		}
+		if consumed {
+			continue
+		}
+		if err = checkUnknownTag(line, opts, p.customDecoders); err != nil {
+			return parseError(n, line, err)
+		}
+		warnings := len(p.Warnings)
 		err = decodeLineOfMasterPlaylist(p, state, line, strict)
 		if strict && err != nil {
-			return err
+			return parseError(n, line, err)
 		}
+		reportWarnings(opts.WarningHandler, n, line, p.Warnings[warnings:])
 	}
-	if strict && !state.m3u {
-		return errors.New("#EXTM3U absent")
+	if err := state.endMultiline(attach); strict && err != nil {
+		return err
+	}
+	if strict && !opts.AllowMissingHeader && !state.m3u {
+		return missingHeader()
 	}
 	return nil
 }
@@ -87,7 +111,7 @@ func (p *MasterPlaylist) decode(buf *bytes.Buffer, strict bool) error {
 // Decode parses a media playlist passed from the buffer. If `strict`
 // parameter is true then return first syntax error.
 func (p *MediaPlaylist) Decode(data bytes.Buffer, strict bool) error {
-	return p.decode(&data, strict)
+	return p.decode(&data, DecodeOptions{Strict: strict})
 }
 
 // DecodeFrom parses a media playlist passed from the io.Reader
@@ -99,7 +123,7 @@ func (p *MediaPlaylist) DecodeFrom(reader io.Reader, strict bool) error {
 	if err != nil {
 		return err
 	}
-	return p.decode(buf, strict)
+	return p.decode(buf, DecodeOptions{Strict: strict})
 }
 
 // WithCustomDecoders adds custom tag decoders to the media playlist for decoding
@@ -114,32 +138,201 @@ func (p *MediaPlaylist) WithCustomDecoders(customDecoders []CustomDecoder) Playl
 	return p
 }
 
-func (p *MediaPlaylist) decode(buf *bytes.Buffer, strict bool) error {
+func (p *MediaPlaylist) decode(buf *bytes.Buffer, opts DecodeOptions) error {
+	return p.decodeState(buf, opts, new(decodingState))
+}
+
+// decodeState decodes the media playlist starting with the decoding state.
+func (p *MediaPlaylist) decodeState(buf *bytes.Buffer, opts DecodeOptions, state *decodingState) error {
 	var eof bool
 	var line string
 	var err error
 
-	state := new(decodingState)
 	wv := new(WV)
+	strict := opts.Strict
+	if opts.UnknownTags == UnknownTagsKeep {
+		p.keepUnknown = true
+	}
 
-	for !eof {
+	attach := func(d CustomDecoder, t CustomTag) {
+		p.attachCustomTag(state, d, t)
+	}
+	for n := 1; !eof; n++ {
 		if line, err = buf.ReadString('\n'); err == io.EOF {
 			eof = true
 		} else if err != nil {
 			break
 		}
 
+		consumed, err := state.decodeMultiline(p.customDecoders, line, attach)
+		if strict && err != nil {
+			return parseError(n, line, err)
+		}
+		if consumed {
+			continue
+		}
+		if err = checkUnknownTag(line, opts, p.customDecoders); err != nil {
+			return parseError(n, line, err)
+		}
+		warnings := len(p.Warnings)
 		err = decodeLineOfMediaPlaylist(p, wv, state, line, strict)
 		if strict && err != nil {
-			return err
+			return parseError(n, line, err)
 		}
+		reportWarnings(opts.WarningHandler, n, line, p.Warnings[warnings:])
 
 	}
+	if err = state.endMultiline(attach); strict && err != nil {
+		return err
+	}
 	if state.tagWV {
 		p.WV = wv
 	}
-	if strict && !state.m3u {
-		return errors.New("#EXTM3U absent")
+	for _, line := range state.unknown {
+		p.Unknown = append(p.Unknown, UnknownTag{line, TrailerPosition})
+	}
+	if strict && !opts.AllowMissingHeader && !state.m3u {
+		return missingHeader()
+	}
+	return nil
+}
+
+// DecodeSegments parses a media playlist from the io.Reader stream and
+// calls fn with every segment as soon as it is parsed. Segments are not
+// kept, so memory use doesn't depend on the length of the playlist. The
+// playlist is decoded strictly, decoding stops with the first syntax error
+// or with the first error returned by fn.
+func DecodeSegments(r io.Reader, fn func(*MediaSegment) error) error {
+	p, err := NewMediaPlaylist(0, 1)
+	if err != nil {
+		return err
+	}
+	return p.decodeStream(r, fn)
+}
+
+// DecodeUpdate parses a newer version of the same live playlist from the
+// io.Reader stream and updates the playlist instead of decoding it again.
+// Versions are matched by media sequence numbers: segments the playlist
+// already has are skipped by the decoder without being built, new segments
+// are appended and the segments before EXT-X-MEDIA-SEQUENCE of the new
+// version are removed. Segments kept by the playlist are not copied. If
+// segments between the versions were missed the playlist starts over with
+// the new ones. Other tags of the playlist (e.g. playlist type, variables,
+// the default key and map, server control, preload hints and rendition
+// reports) are replaced by the tags of the new version. The update is
+// decoded strictly, the playlist is not changed if the update is invalid.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) DecodeUpdate(r io.Reader) error {
+	update, err := NewMediaPlaylist(0, 1)
+	if err != nil {
+		return err
+	}
+	if p.customDecoders != nil {
+		update.WithCustomDecoders(p.customDecoders)
+	}
+	update.duplicates = p.duplicates
+	update.keepUnknown, update.keepComments = p.keepUnknown, p.keepComments
+	buf := new(bytes.Buffer)
+	if _, err = buf.ReadFrom(r); err != nil {
+		return err
+	}
+	state := new(decodingState)
+	if p.count > 0 {
+		state.skipping = true
+		state.skipThrough = p.segmentAt(p.count - 1).SeqId
+	}
+	if err = update.decodeState(buf, DecodeOptions{Strict: true}, state); err != nil {
+		return err
+	}
+	// skipped segments moved the media sequence of the new segments
+	update.SeqNo -= state.skipped
+	added := make([]*MediaSegment, update.count)
+	for i := range added {
+		added[i] = update.segmentAt(uint(i))
+	}
+	if p.frozenTarget {
+		for _, seg := range added {
+			if math.Round(seg.Duration) > p.TargetDuration {
+				return fmt.Errorf("segment duration %v exceeds frozen target duration %v", seg.Duration, p.TargetDuration)
+			}
+		}
+	}
+
+	// the segments which left the window of the new version are removed,
+	// parts of the last version are completed by the new segments
+	p.Closed = false
+	p.PendingParts = nil
+	for p.count > 0 && p.segmentAt(0).SeqId < update.SeqNo {
+		p.Remove()
+	}
+	if len(added) > 0 && p.count > 0 && added[0].SeqId > p.segmentAt(p.count-1).SeqId+1 {
+		// segments between the versions were missed
+		for p.count > 0 {
+			p.Remove()
+		}
+	}
+	if p.count == 0 {
+		p.SeqNo = update.SeqNo
+		if len(added) > 0 {
+			p.SeqNo = added[0].SeqId - p.SkippedSegments
+		}
+	}
+	for _, seg := range added {
+		if err = p.appendGrow(seg); err != nil {
+			return err
+		}
+	}
+
+	if p.ver < update.ver {
+		p.ver = update.ver
+	}
+	p.MediaType = update.MediaType
+	p.AllowCache = update.AllowCache
+	p.Iframe = update.Iframe
+	p.StartTime, p.StartTimePrecise = update.StartTime, update.StartTimePrecise
+	p.Defines = update.Defines
+	p.Key = update.Key
+	p.Map = update.Map
+	p.ServerControl = update.ServerControl
+	p.PendingParts = update.PendingParts
+	p.PreloadHints = update.PreloadHints
+	p.RenditionReports = update.RenditionReports
+	p.TargetDuration = update.TargetDuration
+	p.PartTarget = update.PartTarget
+	p.DiscontinuitySeq = update.DiscontinuitySeq
+	p.Closed = update.Closed
+	p.buf.Reset()
+	p.publish()
+	return nil
+}
+
+// decodeStream decodes the media playlist from the stream strictly and
+// passes every segment to fn as soon as it is parsed instead of keeping it.
+func (p *MediaPlaylist) decodeStream(r io.Reader, fn func(*MediaSegment) error) error {
+	reader := bufio.NewReader(r)
+	state := new(decodingState)
+	wv := new(WV)
+	for n, eof := 1, false; !eof; n++ {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return err
+		}
+		if err = decodeLineOfMediaPlaylist(p, wv, state, line, true); err != nil {
+			return parseError(n, line, err)
+		}
+		// all tags of a segment precede its URI so the segment is complete
+		if p.count > 0 {
+			seg := p.segmentAt(0)
+			p.Remove()
+			if err = fn(seg); err != nil {
+				return err
+			}
+		}
+	}
+	if !state.m3u {
+		return missingHeader()
 	}
 	return nil
 }
@@ -147,7 +340,7 @@ func (p *MediaPlaylist) decode(buf *bytes.Buffer, strict bool) error {
 // Decode detects type of playlist and decodes it. It accepts bytes
 // buffer as input.
 func Decode(data bytes.Buffer, strict bool) (Playlist, ListType, error) {
-	return decode(&data, strict, nil)
+	return decode(&data, DecodeOptions{Strict: strict}, nil, DuplicateLastWins)
 }
 
 // DecodeFrom detects type of playlist and decodes it. It accepts data
@@ -158,30 +351,23 @@ func DecodeFrom(reader io.Reader, strict bool) (Playlist, ListType, error) {
 	if err != nil {
 		return nil, 0, err
 	}
-	return decode(buf, strict, nil)
+	return decode(buf, DecodeOptions{Strict: strict}, nil, DuplicateLastWins)
 }
 
 // DecodeWith detects the type of playlist and decodes it. It accepts either bytes.Buffer
 // or io.Reader as input. Any custom decoders provided will be used during decoding.
 func DecodeWith(input interface{}, strict bool, customDecoders []CustomDecoder) (Playlist, ListType, error) {
-	switch v := input.(type) {
-	case bytes.Buffer:
-		return decode(&v, strict, customDecoders)
-	case io.Reader:
-		buf := new(bytes.Buffer)
-		_, err := buf.ReadFrom(v)
-		if err != nil {
-			return nil, 0, err
-		}
-		return decode(buf, strict, customDecoders)
-	default:
-		return nil, 0, errors.New("input must be bytes.Buffer or io.Reader type")
+	opts := DecodeOptions{Strict: strict}
+	buf, err := readInput(input, opts)
+	if err != nil {
+		return nil, 0, err
 	}
+	return decode(buf, opts, customDecoders, DuplicateLastWins)
 }
 
 // Detect playlist type and decode it. May be used as decoder for both
 // master and media playlists.
-func decode(buf *bytes.Buffer, strict bool, customDecoders []CustomDecoder) (Playlist, ListType, error) {
+func decode(buf *bytes.Buffer, opts DecodeOptions, customDecoders []CustomDecoder, policy DuplicatePolicy) (Playlist, ListType, error) {
 	var eof bool
 	var line string
 	var master *MasterPlaylist
@@ -197,6 +383,10 @@ func decode(buf *bytes.Buffer, strict bool, customDecoders []CustomDecoder) (Pla
 	if err != nil {
 		return nil, 0, fmt.Errorf("Create media playlist failed: %s", err)
 	}
+	master.duplicates, media.duplicates = policy, policy
+	master.keepUnknown = opts.UnknownTags == UnknownTagsKeep
+	media.keepUnknown = master.keepUnknown
+	strict := opts.Strict
 
 	// If we have custom tags to parse
 	if customDecoders != nil {
@@ -204,8 +394,16 @@ func decode(buf *bytes.Buffer, strict bool, customDecoders []CustomDecoder) (Pla
 		master = master.WithCustomDecoders(customDecoders).(*MasterPlaylist)
 		state.custom = make(map[string]CustomTag)
 	}
+	// tags of the master playlist can't be attached to segments
+	attach := func(d CustomDecoder, t CustomTag) {
+		if state.listType == MASTER {
+			setCustomTag(&master.Custom, &master.customOrder, t)
+		} else {
+			media.attachCustomTag(state, d, t)
+		}
+	}
 
-	for !eof {
+	for n := 1; !eof; n++ {
 		if line, err = buf.ReadString('\n'); err == io.EOF {
 			eof = true
 		} else if err != nil {
@@ -219,23 +417,43 @@ func decode(buf *bytes.Buffer, strict bool, customDecoders []CustomDecoder) (Pla
 			continue
 		}
 
+		consumed, err := state.decodeMultiline(customDecoders, line, attach)
+		if strict && err != nil {
+			return nil, state.listType, parseError(n, line, err)
+		}
+		if consumed {
+			continue
+		}
+		if err = checkUnknownTag(line, opts, customDecoders); err != nil {
+			return nil, state.listType, parseError(n, line, err)
+		}
+		masterWarnings, mediaWarnings := len(master.Warnings), len(media.Warnings)
 		err = decodeLineOfMasterPlaylist(master, state, line, strict)
 		if strict && err != nil {
-			return master, state.listType, err
+			return master, state.listType, parseError(n, line, err)
 		}
 
 		err = decodeLineOfMediaPlaylist(media, wv, state, line, strict)
 		if strict && err != nil {
-			return media, state.listType, err
+			return media, state.listType, parseError(n, line, err)
+		}
+		// both decoders see header tags, e.g. EXT-X-VERSION
+		if state.listType == MASTER {
+			reportWarnings(opts.WarningHandler, n, line, master.Warnings[masterWarnings:])
+		} else {
+			reportWarnings(opts.WarningHandler, n, line, media.Warnings[mediaWarnings:])
 		}
 
 	}
+	if err = state.endMultiline(attach); strict && err != nil {
+		return nil, state.listType, err
+	}
 	if state.listType == MEDIA && state.tagWV {
 		media.WV = wv
 	}
 
-	if strict && !state.m3u {
-		return nil, listType, errors.New("#EXTM3U absent")
+	if strict && !opts.AllowMissingHeader && !state.m3u {
+		return nil, listType, missingHeader()
 	}
 
 	switch state.listType {
@@ -251,6 +469,31 @@ func decode(buf *bytes.Buffer, strict bool, customDecoders []CustomDecoder) (Pla
 	return nil, state.listType, errors.New("Can't detect playlist type")
 }
 
+// decodeVersion parses the value of EXT-X-VERSION tag. Versions newer
+// than the library knows are accepted in lenient mode, the tags it
+// understands are decoded and the condition is recorded in warnings.
+func decodeVersion(value string, strict bool, ver *uint8, warnings *[]string) error {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		if strict {
+			return fmt.Errorf("invalid EXT-X-VERSION: %s", value)
+		}
+		*warnings = append(*warnings, "invalid EXT-X-VERSION "+value)
+		return nil
+	}
+	if v > uint64(maxver) {
+		if strict {
+			return fmt.Errorf("unknown EXT-X-VERSION %d", v)
+		}
+		*warnings = append(*warnings, fmt.Sprintf("unknown EXT-X-VERSION %d", v))
+		if v > math.MaxUint8 {
+			v = math.MaxUint8
+		}
+	}
+	*ver = uint8(v)
+	return nil
+}
+
 // DecodeAttributeList turns an attribute list into a key, value map. You should trim
 // any characters not part of the attribute list, such as the tag and ':'.
 func DecodeAttributeList(line string) map[string]string {
@@ -266,11 +509,148 @@ func decodeParamsLine(line string) map[string]string {
 	return out
 }
 
+// quotedParams returns the names of attributes of the line with quoted
+// string values.
+func quotedParams(line string) map[string]bool {
+	out := make(map[string]bool)
+	for _, kv := range reKeyValue.FindAllStringSubmatch(line, -1) {
+		out[kv[1]] = strings.HasPrefix(kv[2], `"`)
+	}
+	return out
+}
+
+// skipSegment drops the tags of the segment at the URI line if the
+// playlist updated by DecodeUpdate already has the segment. The segment
+// isn't built, the media sequence moves on to the next segment instead.
+func (state *decodingState) skipSegment(p *MediaPlaylist) bool {
+	if !state.skipping || p.count > 0 || p.SeqNo+p.SkippedSegments > state.skipThrough {
+		state.skipping = false
+		return false
+	}
+	// the first key and map are default ones of the playlist as on Append
+	if state.tagKey && p.Key == nil && len(state.xkeys) < 2 {
+		p.Key = state.xkey
+	}
+	if state.tagMap && p.Map == nil {
+		p.Map = state.xmap
+	}
+	p.SeqNo++
+	p.PendingParts = nil
+	state.skipped++
+	state.tagInf, state.tagRange, state.tagDiscontinuity, state.tagGap = false, false, false, false
+	state.tagProgramDateTime, state.tagKey, state.tagMap, state.tagCustom = false, false, false, false
+	state.unknown, state.sctes, state.scte = nil, nil, nil
+	state.tiles, state.dateRanges, state.xkeys = nil, nil, nil
+	state.custom, state.customOrder = make(map[string]CustomTag), nil
+	return true
+}
+
+// decodeCueAttributes sets the cue from attributes of EXT-X-CUE-OUT or
+// EXT-X-CUE tags. DURATION, ID and the cue attribute named cueAttr have
+// their own fields, other attributes are kept as is together with their
+// quoting.
+func decodeCueAttributes(scte *SCTE, params map[string]string, quoted map[string]bool, cueAttr string) {
+	for attribute, value := range params {
+		switch attribute {
+		case "DURATION":
+			scte.Time, _ = strconv.ParseFloat(value, 64)
+		case "ID":
+			scte.ID = value
+		case cueAttr:
+			scte.Cue = value
+		default:
+			if scte.Attributes == nil {
+				scte.Attributes = make(map[string]string)
+			}
+			scte.Attributes[attribute] = value
+			if quoted[attribute] {
+				if scte.AttributesQuoted == nil {
+					scte.AttributesQuoted = make(map[string]bool)
+				}
+				scte.AttributesQuoted[attribute] = true
+			}
+		}
+	}
+}
+
+// decodeAllowedCPC parses the value of ALLOWED-CPC attribute, a comma
+// separated list of KEYFORMAT:CPC entries with slash separated CPC labels.
+// Key formats may contain colons (e.g. urn:uuid:...) so the last colon
+// separates the labels.
+func decodeAllowedCPC(value string) map[string][]string {
+	out := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		i := strings.LastIndexByte(entry, ':')
+		if i <= 0 {
+			continue
+		}
+		out[strings.TrimSpace(entry[:i])] = strings.Split(entry[i+1:], "/")
+	}
+	return out
+}
+
+// attributeNames returns names of attributes of the attribute list in
+// their order.
+func attributeNames(line string) []string {
+	attrs := decodeAttributes(line)
+	names := make([]string, len(attrs))
+	for i, kv := range attrs {
+		names[i] = kv[0]
+	}
+	return names
+}
+
+// setAttrOrder records the decoded order of attributes of a key or a map
+// for encoding in fidelity mode.
+func (p *MediaPlaylist) setAttrOrder(tag interface{}, names []string) {
+	if p.attrOrders == nil {
+		p.attrOrders = make(map[interface{}][]string)
+	}
+	p.attrOrders[tag] = names
+}
+
+// decodeAttributes splits an attribute list into key/value pairs keeping
+// their order. Quoted values keep their quotes so callers can distinguish
+// quoted strings from enumerated and numeric values.
+func decodeAttributes(line string) [][2]string {
+	var (
+		out    [][2]string
+		quoted bool
+		start  int
+	)
+	for i := 0; i <= len(line); i++ {
+		if i < len(line) {
+			if line[i] == '"' {
+				quoted = !quoted
+			}
+			if quoted || line[i] != ',' {
+				continue
+			}
+		}
+		kv := strings.TrimSpace(line[start:i])
+		start = i + 1
+		if kv == "" {
+			continue
+		}
+		if eq := strings.IndexByte(kv, '='); eq > 0 {
+			out = append(out, [2]string{strings.TrimSpace(kv[:eq]), strings.TrimSpace(kv[eq+1:])})
+		} else {
+			out = append(out, [2]string{kv, ""})
+		}
+	}
+	return out
+}
+
 // Parse one line of master playlist.
 func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line string, strict bool) error {
 	var err error
 
+	// the last read at the end of input is empty, blank lines end with \n
+	blank := line != "" && strings.TrimSpace(line) == ""
 	line = strings.TrimSpace(line)
+	if line, err = expandVariables(line, state, p.vars); strict && err != nil {
+		return err
+	}
 
 	// check for custom tags first to allow custom parsing of existing tags
 	if p.Custom != nil {
@@ -282,22 +662,71 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 					return err
 				}
 
-				p.Custom[t.TagName()] = t
+				setCustomTag(&p.Custom, &p.customOrder, t)
 			}
 		}
 	}
 
+	// tags of registered extensions are not parsed by the library itself
+	if handled, err := decodeMasterExtension(p, line); handled {
+		return err
+	}
+
+	if state.masterTags == nil {
+		state.masterTags = make(map[string]bool)
+	}
+	if skip, err := checkDuplicate(line, masterHeaderTags, state.masterTags, p.duplicates, &p.Warnings); skip {
+		return err
+	}
+	if keepsLine(line, blank, p.keepUnknown, p.keepComments, p.customDecoders) {
+		position := HeaderPosition
+		if len(p.Variants) > 0 {
+			position = TrailerPosition
+		}
+		p.Unknown = append(p.Unknown, UnknownTag{line, position})
+		return nil
+	}
+
 	switch {
 	case line == "#EXTM3U": // start tag first
 		state.m3u = true
+	case strings.HasPrefix(line, "#EXT-X-DEFINE:"):
+		var define Define
+		if define, err = decodeDefine(line[14:], state, p.vars); strict && err != nil {
+			return err
+		}
+		p.Defines = append(p.Defines, define)
 	case strings.HasPrefix(line, "#EXT-X-VERSION:"): // version tag
 		state.listType = MASTER
-		_, err = fmt.Sscanf(line, "#EXT-X-VERSION:%d", &p.ver)
-		if strict && err != nil {
+		if err = decodeVersion(line[15:], strict, &p.ver, &p.Warnings); err != nil {
 			return err
 		}
 	case line == "#EXT-X-INDEPENDENT-SEGMENTS":
 		p.SetIndependentSegments(true)
+	case strings.HasPrefix(line, "#EXT-X-START:"):
+		for k, v := range decodeParamsLine(line[13:]) {
+			switch k {
+			case "TIME-OFFSET":
+				st, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return fmt.Errorf("Invalid TIME-OFFSET: %s: %v", v, err)
+				}
+				p.StartTime = st
+			case "PRECISE":
+				p.StartTimePrecise = v == "YES"
+			}
+		}
+	case strings.HasPrefix(line, "#EXT-X-CONTENT-STEERING:"):
+		state.listType = MASTER
+		p.ContentSteering = new(ContentSteering)
+		for k, v := range decodeParamsLine(line[24:]) {
+			switch k {
+			case "SERVER-URI":
+				p.ContentSteering.ServerURI = v
+			case "PATHWAY-ID":
+				p.ContentSteering.PathwayID = v
+			}
+		}
 	case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
 		var alt Alternative
 		state.listType = MASTER
@@ -309,6 +738,8 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 				alt.GroupId = v
 			case "LANGUAGE":
 				alt.Language = v
+			case "ASSOC-LANGUAGE":
+				alt.AssocLanguage = v
 			case "NAME":
 				alt.Name = v
 			case "DEFAULT":
@@ -329,10 +760,26 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 				alt.Characteristics = v
 			case "CHANNELS":
 				alt.Channels = v
+			case "BIT-DEPTH":
+				var val uint64
+				if val, err = strconv.ParseUint(v, 10, 32); strict && err != nil {
+					return fmt.Errorf("Invalid BIT-DEPTH: %s: %v", v, err)
+				}
+				alt.BitDepth = uint(val)
+			case "SAMPLE-RATE":
+				var val uint64
+				if val, err = strconv.ParseUint(v, 10, 32); strict && err != nil {
+					return fmt.Errorf("Invalid SAMPLE-RATE: %s: %v", v, err)
+				}
+				alt.SampleRate = uint(val)
 			case "SUBTITLES":
 				alt.Subtitles = v
+			case "STABLE-RENDITION-ID":
+				alt.StableRenditionID = v
 			case "URI":
 				alt.URI = v
+			default:
+				p.Warnings = append(p.Warnings, unknownAttribute("EXT-X-MEDIA", k))
 			}
 		}
 		state.alternatives = append(state.alternatives, &alt)
@@ -345,7 +792,18 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 			state.alternatives = nil
 		}
 		p.Variants = append(p.Variants, state.variant)
-		for k, v := range decodeParamsLine(line[18:]) {
+		params := decodeParamsLine(line[18:])
+		quoted := quotedParams(line[18:])
+		if _, ok := params["BANDWIDTH"]; !ok {
+			// BANDWIDTH is required but some packagers omit it, lenient
+			// decoding keeps such variants marked as incomplete
+			if strict {
+				return errors.New("EXT-X-STREAM-INF without BANDWIDTH")
+			}
+			state.variant.Incomplete = true
+			p.Warnings = append(p.Warnings, "EXT-X-STREAM-INF without BANDWIDTH")
+		}
+		for k, v := range params {
 			switch k {
 			case "PROGRAM-ID":
 				var val int
@@ -364,6 +822,9 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 			case "CODECS":
 				state.variant.Codecs = v
 			case "RESOLUTION":
+				if _, err = ParseResolution(v); strict && err != nil {
+					return err
+				}
 				state.variant.Resolution = v
 			case "AUDIO":
 				state.variant.Audio = v
@@ -390,11 +851,56 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 				state.variant.VideoRange = v
 			case "HDCP-LEVEL":
 				state.variant.HDCPLevel = v
+			case "ALLOWED-CPC":
+				state.variant.AllowedCPC = decodeAllowedCPC(v)
+			case "REQ-VIDEO-LAYOUT":
+				state.variant.ReqVideoLayout = v
+			case "PATHWAY-ID":
+				state.variant.PathwayID = v
+			case "STABLE-VARIANT-ID":
+				state.variant.StableVariantID = v
+			default:
+				if quoted[k] {
+					state.variant.SetQuotedExtraAttribute(k, v)
+				} else {
+					state.variant.SetExtraAttribute(k, v)
+				}
 			}
 		}
 	case state.tagStreamInf && !strings.HasPrefix(line, "#"):
 		state.tagStreamInf = false
 		state.variant.URI = line
+	case strings.HasPrefix(line, "#EXT-X-IMAGE-STREAM-INF:"):
+		state.listType = MASTER
+		state.variant = new(Variant)
+		state.variant.Image = true
+		p.Variants = append(p.Variants, state.variant)
+		for k, v := range decodeParamsLine(line[24:]) {
+			switch k {
+			case "URI":
+				state.variant.URI = v
+			case "BANDWIDTH":
+				var val int
+				val, err = strconv.Atoi(v)
+				if strict && err != nil {
+					return err
+				}
+				state.variant.Bandwidth = uint32(val)
+			case "CODECS":
+				state.variant.Codecs = v
+			case "RESOLUTION":
+				if _, err = ParseResolution(v); strict && err != nil {
+					return err
+				}
+				state.variant.Resolution = v
+			case "PATHWAY-ID":
+				state.variant.PathwayID = v
+			case "STABLE-VARIANT-ID":
+				state.variant.StableVariantID = v
+			default:
+				p.Warnings = append(p.Warnings, unknownAttribute("EXT-X-IMAGE-STREAM-INF", k))
+			}
+		}
 	case strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:"):
 		state.listType = MASTER
 		state.variant = new(Variant)
@@ -425,6 +931,9 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 			case "CODECS":
 				state.variant.Codecs = v
 			case "RESOLUTION":
+				if _, err = ParseResolution(v); strict && err != nil {
+					return err
+				}
 				state.variant.Resolution = v
 			case "AUDIO":
 				state.variant.Audio = v
@@ -441,6 +950,14 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 				state.variant.VideoRange = v
 			case "HDCP-LEVEL":
 				state.variant.HDCPLevel = v
+			case "ALLOWED-CPC":
+				state.variant.AllowedCPC = decodeAllowedCPC(v)
+			case "PATHWAY-ID":
+				state.variant.PathwayID = v
+			case "STABLE-VARIANT-ID":
+				state.variant.StableVariantID = v
+			default:
+				p.Warnings = append(p.Warnings, unknownAttribute("EXT-X-I-FRAME-STREAM-INF", k))
 			}
 		}
 	case strings.HasPrefix(line, "#"):
@@ -453,7 +970,17 @@ func decodeLineOfMasterPlaylist(p *MasterPlaylist, state *decodingState, line st
 func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, line string, strict bool) error {
 	var err error
 
+	// the last read at the end of input is empty, blank lines end with \n
+	blank := line != "" && strings.TrimSpace(line) == ""
+	// titles of EXTINF may end with spaces, so keep the untrimmed line for them
+	raw := strings.TrimRight(line, "\r\n")
 	line = strings.TrimSpace(line)
+	if line, err = expandVariables(line, state, p.vars); strict && err != nil {
+		return err
+	}
+	if raw, err = expandVariables(raw, state, p.vars); strict && err != nil {
+		return err
+	}
 
 	// check for custom tags first to allow custom parsing of existing tags
 	if p.Custom != nil {
@@ -465,16 +992,31 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 					return err
 				}
 
-				if v.SegmentTag() {
-					state.tagCustom = true
-					state.custom[v.TagName()] = t
-				} else {
-					p.Custom[v.TagName()] = t
-				}
+				p.attachCustomTag(state, v, t)
 			}
 		}
 	}
 
+	// tags of registered extensions are not parsed by the library itself
+	if handled, err := decodeMediaExtension(p, state, line); handled {
+		return err
+	}
+
+	if state.mediaTags == nil {
+		state.mediaTags = make(map[string]bool)
+	}
+	if skip, err := checkDuplicate(line, mediaHeaderTags, state.mediaTags, p.duplicates, &p.Warnings); skip {
+		return err
+	}
+	if keepsLine(line, blank, p.keepUnknown, p.keepComments, p.customDecoders) {
+		if p.count == 0 && !state.tagInf {
+			p.Unknown = append(p.Unknown, UnknownTag{line, HeaderPosition})
+		} else {
+			state.unknown = append(state.unknown, line)
+		}
+		return nil
+	}
+
 	switch {
 	case !state.tagInf && strings.HasPrefix(line, "#EXTINF:"):
 		state.tagInf = true
@@ -492,10 +1034,16 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				return fmt.Errorf("Duration parsing error: %s", err)
 			}
 		}
-		if len(line) > sepIndex {
-			state.title = line[sepIndex+1:]
+		// the title is everything after the first comma, it may contain
+		// commas, quotes and any UTF-8 text
+		state.title = ""
+		if comma := strings.Index(raw, ","); comma != -1 {
+			state.title = raw[comma+1:]
 		}
 	case !strings.HasPrefix(line, "#"):
+		if state.tagInf && state.skipSegment(p) {
+			return nil
+		}
 		if state.tagInf {
 			err := p.Append(line, state.duration, state.title)
 			if err == ErrPlaylistFull {
@@ -512,6 +1060,10 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 			if err != nil {
 				return err
 			}
+			if len(state.unknown) > 0 {
+				p.Segments[p.last()].Unknown = state.unknown
+				state.unknown = nil
+			}
 			state.tagInf = false
 		}
 		if state.tagRange {
@@ -520,11 +1072,11 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 			}
 			state.tagRange = false
 		}
-		if state.tagSCTE35 {
-			state.tagSCTE35 = false
-			if err = p.SetSCTE35(state.scte); strict && err != nil {
-				return err
+		if len(state.sctes) > 0 {
+			if p.Count() > 0 {
+				p.Segments[p.last()].SCTE = state.sctes
 			}
+			state.sctes, state.scte = nil, nil
 		}
 		if state.tagDiscontinuity {
 			state.tagDiscontinuity = false
@@ -532,6 +1084,20 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				return err
 			}
 		}
+		if state.tagGap {
+			state.tagGap = false
+			if err = p.SetGap(); strict && err != nil {
+				return err
+			}
+		}
+		if state.tiles != nil && p.Count() > 0 {
+			p.Segments[p.last()].Tiles = state.tiles
+			state.tiles = nil
+		}
+		if len(state.dateRanges) > 0 && p.Count() > 0 {
+			p.Segments[p.last()].DateRanges = state.dateRanges
+			state.dateRanges = nil
+		}
 		if state.tagProgramDateTime && p.Count() > 0 {
 			state.tagProgramDateTime = false
 			if err = p.SetProgramDateTime(state.programDateTime); strict && err != nil {
@@ -541,16 +1107,30 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 		// If EXT-X-KEY appeared before reference to segment (EXTINF) then it linked to this segment
 		if state.tagKey {
 			p.Segments[p.last()].Key = &Key{state.xkey.Method, state.xkey.URI, state.xkey.IV, state.xkey.Keyformat, state.xkey.Keyformatversions}
+			p.setAttrOrder(p.Segments[p.last()].Key, state.keyAttrs)
+			// several key systems are signalled by consecutive tags
+			if len(state.xkeys) > 1 {
+				seg := p.Segments[p.last()]
+				seg.Keys = make([]*Key, len(state.xkeys))
+				for i, key := range state.xkeys {
+					seg.Keys[i] = &Key{key.Method, key.URI, key.IV, key.Keyformat, key.Keyformatversions}
+					p.setAttrOrder(seg.Keys[i], p.attrOrders[key])
+				}
+				seg.Key = seg.Keys[0]
+			}
 			// First EXT-X-KEY may appeared in the header of the playlist and linked to first segment
 			// but for convenient playlist generation it also linked as default playlist key
-			if p.Key == nil {
+			// unless there are keys of several key systems
+			if p.Key == nil && len(state.xkeys) < 2 {
 				p.Key = state.xkey
 			}
+			state.xkeys = nil
 			state.tagKey = false
 		}
 		// If EXT-X-MAP appeared before reference to segment (EXTINF) then it linked to this segment
 		if state.tagMap {
-			p.Segments[p.last()].Map = &Map{state.xmap.URI, state.xmap.Limit, state.xmap.Offset}
+			p.Segments[p.last()].Map = &Map{state.xmap.URI, state.xmap.Limit, state.xmap.Offset, state.xmap.Key}
+			p.setAttrOrder(p.Segments[p.last()].Map, state.mapAttrs)
 			// First EXT-X-MAP may appeared in the header of the playlist and linked to first segment
 			// but for convenient playlist generation it also linked as default playlist map
 			if p.Map == nil {
@@ -562,7 +1142,9 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 		// if segment custom tag appeared before EXTINF then it links to this segment
 		if state.tagCustom {
 			p.Segments[p.last()].Custom = state.custom
+			p.Segments[p.last()].customOrder = state.customOrder
 			state.custom = make(map[string]CustomTag)
+			state.customOrder = nil
 			state.tagCustom = false
 		}
 	// start tag first
@@ -571,9 +1153,15 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 	case line == "#EXT-X-ENDLIST":
 		state.listType = MEDIA
 		p.Closed = true
+	case strings.HasPrefix(line, "#EXT-X-DEFINE:"):
+		var define Define
+		if define, err = decodeDefine(line[14:], state, p.vars); strict && err != nil {
+			return err
+		}
+		p.Defines = append(p.Defines, define)
 	case strings.HasPrefix(line, "#EXT-X-VERSION:"):
 		state.listType = MEDIA
-		if _, err = fmt.Sscanf(line, "#EXT-X-VERSION:%d", &p.ver); strict && err != nil {
+		if err = decodeVersion(line[15:], strict, &p.ver, &p.Warnings); err != nil {
 			return err
 		}
 	case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
@@ -586,6 +1174,18 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 		if _, err = fmt.Sscanf(line, "#EXT-X-MEDIA-SEQUENCE:%d", &p.SeqNo); strict && err != nil {
 			return err
 		}
+	case strings.HasPrefix(line, "#EXT-X-ALLOW-CACHE:"):
+		state.listType = MEDIA
+		switch line[19:] {
+		case "YES":
+			p.AllowCache = AllowCacheYes
+		case "NO":
+			p.AllowCache = AllowCacheNo
+		default:
+			if strict {
+				return fmt.Errorf("invalid EXT-X-ALLOW-CACHE: %s", line[19:])
+			}
+		}
 	case strings.HasPrefix(line, "#EXT-X-PLAYLIST-TYPE:"):
 		state.listType = MEDIA
 		var playlistType string
@@ -608,7 +1208,6 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 			return err
 		}
 	case strings.HasPrefix(line, "#EXT-X-START:"):
-		state.listType = MEDIA
 		for k, v := range decodeParamsLine(line[13:]) {
 			switch k {
 			case "TIME-OFFSET":
@@ -621,9 +1220,93 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				p.StartTimePrecise = v == "YES"
 			}
 		}
+	case strings.HasPrefix(line, "#EXT-X-SERVER-CONTROL:"):
+		state.listType = MEDIA
+		sc := new(ServerControl)
+		for k, v := range decodeParamsLine(line[22:]) {
+			switch k {
+			case "CAN-SKIP-UNTIL":
+				if sc.CanSkipUntil, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Invalid CAN-SKIP-UNTIL: %s: %v", v, err)
+				}
+			case "CAN-SKIP-DATERANGES":
+				sc.CanSkipDateRanges = v == "YES"
+			case "HOLD-BACK":
+				if sc.HoldBack, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Invalid HOLD-BACK: %s: %v", v, err)
+				}
+			case "PART-HOLD-BACK":
+				if sc.PartHoldBack, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Invalid PART-HOLD-BACK: %s: %v", v, err)
+				}
+			case "CAN-BLOCK-RELOAD":
+				sc.CanBlockReload = v == "YES"
+			}
+		}
+		p.ServerControl = sc
+	case strings.HasPrefix(line, "#EXT-X-SKIP:"):
+		state.listType = MEDIA
+		for k, v := range decodeParamsLine(line[12:]) {
+			switch k {
+			case "SKIPPED-SEGMENTS":
+				if p.SkippedSegments, err = strconv.ParseUint(v, 10, 64); strict && err != nil {
+					return fmt.Errorf("Invalid SKIPPED-SEGMENTS: %s: %v", v, err)
+				}
+			case "RECENTLY-REMOVED-DATERANGES":
+				if v != "" {
+					p.RecentlyRemoved = strings.Split(v, "\t")
+				}
+			}
+		}
+	case strings.HasPrefix(line, "#EXT-X-PRELOAD-HINT:"):
+		state.listType = MEDIA
+		hint := new(PreloadHint)
+		for k, v := range decodeParamsLine(line[20:]) {
+			switch k {
+			case "TYPE":
+				hint.Type = v
+			case "URI":
+				hint.URI = v
+			case "BYTERANGE-START":
+				if hint.Start, err = strconv.ParseInt(v, 10, 64); strict && err != nil {
+					return fmt.Errorf("Invalid BYTERANGE-START: %s: %v", v, err)
+				}
+			case "BYTERANGE-LENGTH":
+				if hint.Length, err = strconv.ParseInt(v, 10, 64); strict && err != nil {
+					return fmt.Errorf("Invalid BYTERANGE-LENGTH: %s: %v", v, err)
+				}
+			}
+		}
+		p.PreloadHints = append(p.PreloadHints, hint)
+	case strings.HasPrefix(line, "#EXT-X-RENDITION-REPORT:"):
+		state.listType = MEDIA
+		var rr RenditionReport
+		for k, v := range decodeParamsLine(line[24:]) {
+			switch k {
+			case "URI":
+				rr.URI = v
+			case "LAST-MSN":
+				if rr.LastMSN, err = strconv.ParseUint(v, 10, 64); strict && err != nil {
+					return fmt.Errorf("Invalid LAST-MSN: %s: %v", v, err)
+				}
+			case "LAST-PART":
+				if lastPart, err := strconv.Atoi(v); err == nil {
+					rr.LastPart = &lastPart
+				} else if strict {
+					return fmt.Errorf("Invalid LAST-PART: %s: %v", v, err)
+				}
+			}
+		}
+		p.RenditionReports = append(p.RenditionReports, rr)
 	case strings.HasPrefix(line, "#EXT-X-KEY:"):
 		state.listType = MEDIA
+		if state.tagInf {
+			p.Warnings = append(p.Warnings, "EXT-X-KEY between EXTINF and segment URI")
+		}
 		state.xkey = new(Key)
+		state.xkeys = append(state.xkeys, state.xkey)
+		state.keyAttrs = attributeNames(line[11:])
+		p.setAttrOrder(state.xkey, state.keyAttrs)
 		for k, v := range decodeParamsLine(line[11:]) {
 			switch k {
 			case "METHOD":
@@ -636,12 +1319,19 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				state.xkey.Keyformat = v
 			case "KEYFORMATVERSIONS":
 				state.xkey.Keyformatversions = v
+			default:
+				p.Warnings = append(p.Warnings, unknownAttribute("EXT-X-KEY", k))
 			}
 		}
+		if err = checkKeyMethod(state.xkey.Method); strict && err != nil {
+			return err
+		}
 		state.tagKey = true
 	case strings.HasPrefix(line, "#EXT-X-MAP:"):
 		state.listType = MEDIA
 		state.xmap = new(Map)
+		state.mapAttrs = attributeNames(line[11:])
+		p.setAttrOrder(state.xmap, state.mapAttrs)
 		for k, v := range decodeParamsLine(line[11:]) {
 			switch k {
 			case "URI":
@@ -650,9 +1340,63 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				if _, err = fmt.Sscanf(v, "%d@%d", &state.xmap.Limit, &state.xmap.Offset); strict && err != nil {
 					return fmt.Errorf("Byterange sub-range length value parsing error: %s", err)
 				}
+			default:
+				p.Warnings = append(p.Warnings, unknownAttribute("EXT-X-MAP", k))
 			}
 		}
+		// the key in effect encrypts the whole map with AES-128 only,
+		// SAMPLE-AES encrypts media samples which the map doesn't have
+		if state.xkey != nil && state.xkey.Method == "AES-128" {
+			state.xmap.Key = state.xkey
+			state.xkeys = nil // keys in front of the map are not keys of several key systems
+		}
 		state.tagMap = true
+	case strings.HasPrefix(line, "#EXT-X-PART-INF:"):
+		state.listType = MEDIA
+		for k, v := range decodeParamsLine(line[16:]) {
+			if k == "PART-TARGET" {
+				if p.PartTarget, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Invalid PART-TARGET: %s: %v", v, err)
+				}
+			}
+		}
+	case strings.HasPrefix(line, "#EXT-X-PART:"):
+		state.listType = MEDIA
+		part := new(PartialSegment)
+		for k, v := range decodeParamsLine(line[12:]) {
+			switch k {
+			case "URI":
+				part.URI = v
+			case "DURATION":
+				if part.Duration, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Part duration parsing error: %s", err)
+				}
+			case "INDEPENDENT":
+				part.Independent = v == "YES"
+			case "GAP":
+				part.Gap = v == "YES"
+			case "BYTERANGE":
+				params := strings.SplitN(v, "@", 2)
+				if part.Limit, err = strconv.ParseInt(params[0], 10, 64); strict && err != nil {
+					return fmt.Errorf("Part byterange length value parsing error: %s", err)
+				}
+				if len(params) > 1 {
+					if part.Offset, err = strconv.ParseInt(params[1], 10, 64); strict && err != nil {
+						return fmt.Errorf("Part byterange offset value parsing error: %s", err)
+					}
+				}
+			}
+		}
+		// EXT-X-KEY in front of the part changes the key in the middle of
+		// the segment, the segment itself gets the last key of its parts
+		if state.tagKey {
+			part.Key = &Key{state.xkey.Method, state.xkey.URI, state.xkey.IV, state.xkey.Keyformat, state.xkey.Keyformatversions}
+			p.setAttrOrder(part.Key, state.keyAttrs)
+			state.xkeys = nil
+		}
+		if err = p.AppendPartialSegment(part); strict && err != nil {
+			return err
+		}
 	case !state.tagProgramDateTime && strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
 		state.tagProgramDateTime = true
 		state.listType = MEDIA
@@ -672,51 +1416,101 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 				return fmt.Errorf("Byterange sub-range offset value parsing error: %s", err)
 			}
 		}
-	case !state.tagSCTE35 && strings.HasPrefix(line, "#EXT-SCTE35:"):
-		state.tagSCTE35 = true
+	case strings.HasPrefix(line, "#EXT-SCTE35:"):
 		state.listType = MEDIA
-		state.scte = new(SCTE)
-		state.scte.Syntax = SCTE35_67_2014
+		scte := &SCTE{Syntax: SCTE35_67_2014}
 		for attribute, value := range decodeParamsLine(line[12:]) {
 			switch attribute {
 			case "CUE":
-				state.scte.Cue = value
+				scte.Cue = value
 			case "ID":
-				state.scte.ID = value
+				scte.ID = value
 			case "TIME":
-				state.scte.Time, _ = strconv.ParseFloat(value, 64)
+				scte.Time, _ = strconv.ParseFloat(value, 64)
 			}
 		}
-	case !state.tagSCTE35 && strings.HasPrefix(line, "#EXT-OATCLS-SCTE35:"):
+		state.sctes = append(state.sctes, scte)
+	case strings.HasPrefix(line, "#EXT-OATCLS-SCTE35:"):
 		// EXT-OATCLS-SCTE35 contains the SCTE35 tag, EXT-X-CUE-OUT contains duration
-		state.tagSCTE35 = true
-		state.scte = new(SCTE)
-		state.scte.Syntax = SCTE35_OATCLS
-		state.scte.Cue = line[19:]
-	case state.tagSCTE35 && state.scte.Syntax == SCTE35_OATCLS && strings.HasPrefix(line, "#EXT-X-CUE-OUT:"):
+		state.scte = &SCTE{Syntax: SCTE35_OATCLS, Cue: line[19:]}
+		state.sctes = append(state.sctes, state.scte)
+	case state.scte != nil && strings.HasPrefix(line, "#EXT-X-CUE-OUT:"):
 		// EXT-OATCLS-SCTE35 contains the SCTE35 tag, EXT-X-CUE-OUT contains duration
 		state.scte.Time, _ = strconv.ParseFloat(line[15:], 64)
 		state.scte.CueType = SCTE35Cue_Start
-	case !state.tagSCTE35 && strings.HasPrefix(line, "#EXT-X-CUE-OUT-CONT:"):
-		state.tagSCTE35 = true
-		state.scte = new(SCTE)
-		state.scte.Syntax = SCTE35_OATCLS
-		state.scte.CueType = SCTE35Cue_Mid
+		state.scte = nil
+	case strings.HasPrefix(line, "#EXT-X-CUE-OUT:") || line == "#EXT-X-CUE-OUT":
+		state.listType = MEDIA
+		scte := &SCTE{Syntax: SCTE35_CUE_OUT, CueType: SCTE35Cue_Start}
+		if params := strings.TrimPrefix(line[14:], ":"); strings.Contains(params, "=") {
+			decodeCueAttributes(scte, decodeParamsLine(params), quotedParams(params), "SCTE35")
+		} else {
+			scte.Time, _ = strconv.ParseFloat(params, 64)
+		}
+		state.sctes = append(state.sctes, scte)
+	case strings.HasPrefix(line, "#EXT-X-CUE-OUT-CONT:") && !strings.Contains(line, "="):
+		// elapsed and planned duration separated by slash, e.g. 10/30
+		scte := &SCTE{Syntax: SCTE35_CUE_OUT, CueType: SCTE35Cue_Mid}
+		params := strings.SplitN(line[20:], "/", 2)
+		scte.Elapsed, _ = strconv.ParseFloat(params[0], 64)
+		if len(params) > 1 {
+			scte.Time, _ = strconv.ParseFloat(params[1], 64)
+		}
+		state.sctes = append(state.sctes, scte)
+	case strings.HasPrefix(line, "#EXT-X-CUE-OUT-CONT:"):
+		scte := &SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_Mid}
 		for attribute, value := range decodeParamsLine(line[20:]) {
 			switch attribute {
 			case "SCTE35":
-				state.scte.Cue = value
+				scte.Cue = value
 			case "Duration":
-				state.scte.Time, _ = strconv.ParseFloat(value, 64)
+				scte.Time, _ = strconv.ParseFloat(value, 64)
 			case "ElapsedTime":
-				state.scte.Elapsed, _ = strconv.ParseFloat(value, 64)
+				scte.Elapsed, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		state.sctes = append(state.sctes, scte)
+	case line == "#EXT-X-CUE-IN":
+		state.sctes = append(state.sctes, &SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_End})
+	case strings.HasPrefix(line, "#EXT-X-CUE:"):
+		state.listType = MEDIA
+		scte := &SCTE{Syntax: SCTE35_ADOBE, CueType: SCTE35Cue_Mid}
+		params := decodeParamsLine(line[11:])
+		switch params["TYPE"] {
+		case "SpliceOut":
+			scte.CueType = SCTE35Cue_Start
+			delete(params, "TYPE")
+		case "SpliceIn":
+			scte.CueType = SCTE35Cue_End
+			delete(params, "TYPE")
+		}
+		decodeCueAttributes(scte, params, quotedParams(line[11:]), "CUE")
+		state.sctes = append(state.sctes, scte)
+	case strings.HasPrefix(line, "#EXT-X-DATERANGE:"):
+		state.listType = MEDIA
+		dr, err := decodeDateRange(line, strict)
+		if err != nil {
+			return err
+		}
+		state.dateRanges = append(state.dateRanges, dr)
+	case strings.HasPrefix(line, "#EXT-X-TILES:"):
+		state.listType = MEDIA
+		state.tiles = new(Tiles)
+		for k, v := range decodeParamsLine(line[13:]) {
+			switch k {
+			case "RESOLUTION":
+				state.tiles.Resolution = v
+			case "LAYOUT":
+				state.tiles.Layout = v
+			case "DURATION":
+				if state.tiles.Duration, err = strconv.ParseFloat(v, 64); strict && err != nil {
+					return fmt.Errorf("Invalid DURATION of EXT-X-TILES: %s: %v", v, err)
+				}
 			}
 		}
-	case !state.tagSCTE35 && line == "#EXT-X-CUE-IN":
-		state.tagSCTE35 = true
-		state.scte = new(SCTE)
-		state.scte.Syntax = SCTE35_OATCLS
-		state.scte.CueType = SCTE35Cue_End
+	case !state.tagGap && line == "#EXT-X-GAP":
+		state.tagGap = true
+		state.listType = MEDIA
 	case !state.tagDiscontinuity && strings.HasPrefix(line, "#EXT-X-DISCONTINUITY"):
 		state.tagDiscontinuity = true
 		state.listType = MEDIA
@@ -825,6 +1619,78 @@ func decodeLineOfMediaPlaylist(p *MediaPlaylist, wv *WV, state *decodingState, l
 	return err
 }
 
+// decodeMultiline passes the line to the stateful custom decoder of the
+// multi-line tag being decoded or begins a new tag if the line starts with
+// a tag name of a stateful decoder. It returns true if the line belongs to
+// the tag. Completed tags are passed to attach.
+func (state *decodingState) decodeMultiline(decoders []CustomDecoder, line string, attach func(CustomDecoder, CustomTag)) (bool, error) {
+	if line == "" {
+		// the last read at the end of input, the tag is completed after it
+		return false, nil
+	}
+	line = strings.TrimSpace(line)
+	if d := state.multiline; d != nil {
+		ok, err := d.Continue(line)
+		if err != nil {
+			state.multiline = nil
+			return true, err
+		}
+		if ok {
+			return true, nil
+		}
+		if err = state.endMultiline(attach); err != nil {
+			return false, err
+		}
+	}
+	for _, v := range decoders {
+		if d, ok := v.(StatefulCustomDecoder); ok && strings.HasPrefix(line, v.TagName()) {
+			if err := d.Begin(line); err != nil {
+				return true, err
+			}
+			state.multiline = d
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// endMultiline completes the multi-line tag being decoded, if any.
+func (state *decodingState) endMultiline(attach func(CustomDecoder, CustomTag)) error {
+	d := state.multiline
+	if d == nil {
+		return nil
+	}
+	state.multiline = nil
+	t, err := d.End()
+	if err == nil && t != nil {
+		attach(d, t)
+	}
+	return err
+}
+
+// attachCustomTag attaches the decoded custom tag to the playlist or to the
+// upcoming segment. Segment tags placed after URI (PlacementFooter) are
+// attached to the segment before them.
+func (p *MediaPlaylist) attachCustomTag(state *decodingState, d CustomDecoder, t CustomTag) {
+	if d.SegmentTag() {
+		if placer, ok := t.(CustomTagPlacer); ok && placer.Placement() == PlacementFooter && !state.tagInf {
+			switch {
+			case p.count > 0:
+				seg := p.Segments[p.last()]
+				setCustomTag(&seg.Custom, &seg.customOrder, t)
+				return
+			case state.skipped > 0:
+				// the segment was skipped by DecodeUpdate
+				return
+			}
+		}
+		state.tagCustom = true
+		setCustomTag(&state.custom, &state.customOrder, t)
+		return
+	}
+	setCustomTag(&p.Custom, &p.customOrder, t)
+}
+
 // StrictTimeParse implements RFC3339 with Nanoseconds accuracy.
 func StrictTimeParse(value string) (time.Time, error) {
 	return time.Parse(DATETIME, value)
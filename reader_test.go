@@ -14,9 +14,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 )
 
@@ -288,6 +291,35 @@ func TestDecodeMasterWithHLSV7(t *testing.T) {
 	}
 }
 
+func TestDecodeMasterPlaylistWithExtraAttributes(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1500000,X-CDN-HINT="edge-1",X-WEIGHT=10
+low.m3u8
+`
+	p := NewMasterPlaylist()
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"X-CDN-HINT": "edge-1", "X-WEIGHT": "10"}
+	if !reflect.DeepEqual(p.Variants[0].ExtraAttributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, p.Variants[0].ExtraAttributes)
+	}
+}
+
+func TestDecodeMasterPlaylistExtraAttributesQuoting(t *testing.T) {
+	const line = "#EXT-X-STREAM-INF:BANDWIDTH=1500000,X-ID=\"123\",X-RATIO=0x1F,X-WEIGHT=10\n"
+	p := NewMasterPlaylist()
+	if err := p.DecodeFrom(strings.NewReader("#EXTM3U\n"+line+"low.m3u8\n"), true); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Variants[0].ExtraQuoted["X-ID"] || p.Variants[0].ExtraQuoted["X-WEIGHT"] {
+		t.Errorf("Expected X-ID quoted only, got %v", p.Variants[0].ExtraQuoted)
+	}
+	if out := p.String(); !strings.Contains(out, ",X-ID=\"123\",X-RATIO=0x1F,X-WEIGHT=10\n") {
+		t.Errorf("Expected quoting kept:\n%s\ngot:\n%s", line, out)
+	}
+}
+
 /****************************
  * Begin Test MediaPlaylist *
  ****************************/
@@ -556,9 +588,9 @@ func TestMediaPlaylistWithOATCLSSCTE35Tag(t *testing.T) {
 		2: {Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_End},
 	}
 	for i := 0; i < int(pp.Count()); i++ {
-		if !reflect.DeepEqual(pp.Segments[i].SCTE, expect[i]) {
+		if !reflect.DeepEqual(pp.Segments[i].SCTE35(), expect[i]) {
 			t.Errorf("OATCLS SCTE35 segment %v (uri: %v)\ngot: %#v\nexp: %#v",
-				i, pp.Segments[i].URI, pp.Segments[i].SCTE, expect[i],
+				i, pp.Segments[i].URI, pp.Segments[i].SCTE35(), expect[i],
 			)
 		}
 	}
@@ -647,6 +679,11 @@ func TestDecodeMasterPlaylistWithCustomTags(t *testing.T) {
 
 		p, listType, err := DecodeWith(bufio.NewReader(f), true, testCase.customDecoders)
 
+		// errors of the decoders are reported with the line
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			err = perr.Err
+		}
 		if !reflect.DeepEqual(err, testCase.expectedError) {
 			t.Fatal(err)
 		}
@@ -677,6 +714,104 @@ func TestDecodeMasterPlaylistWithCustomTags(t *testing.T) {
 	}
 }
 
+// multilineDecoder decodes vendor tags continued with #EXT-X-VENDOR-LINE
+// lines.
+type multilineDecoder struct {
+	segment bool
+	lines   []string
+}
+
+func (d *multilineDecoder) TagName() string {
+	return "#EXT-X-VENDOR-BLOCK"
+}
+
+func (d *multilineDecoder) Decode(line string) (CustomTag, error) {
+	return nil, errors.New("not called")
+}
+
+func (d *multilineDecoder) SegmentTag() bool {
+	return d.segment
+}
+
+func (d *multilineDecoder) Begin(line string) error {
+	d.lines = []string{line}
+	return nil
+}
+
+func (d *multilineDecoder) Continue(line string) (bool, error) {
+	if !strings.HasPrefix(line, "#EXT-X-VENDOR-LINE:") {
+		return false, nil
+	}
+	if line == "#EXT-X-VENDOR-LINE:bad" {
+		return false, errors.New("bad vendor line")
+	}
+	d.lines = append(d.lines, line)
+	return true, nil
+}
+
+func (d *multilineDecoder) End() (CustomTag, error) {
+	text := strings.Join(d.lines, "\n")
+	return &MockCustomTag{name: d.TagName(), encodedString: text}, nil
+}
+
+func TestDecodeMultilineCustomTags(t *testing.T) {
+	const src = `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-VENDOR-BLOCK:1
+#EXT-X-VENDOR-LINE:a
+#EXT-X-VENDOR-LINE:b
+#EXTINF:10,
+seg0.ts
+#EXTINF:10,
+seg1.ts
+#EXT-X-VENDOR-BLOCK:2
+#EXT-X-VENDOR-LINE:c
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	p.WithCustomDecoders([]CustomDecoder{&multilineDecoder{}})
+	if err := p.DecodeWithOptions(strings.NewReader(src), DecodeOptions{Strict: true, UnknownTags: UnknownTagsError}); err != nil {
+		t.Fatal(err)
+	}
+	// the tag at the end of the playlist replaces the first one
+	if tag := p.Custom["#EXT-X-VENDOR-BLOCK"]; tag == nil || tag.String() != "#EXT-X-VENDOR-BLOCK:2\n#EXT-X-VENDOR-LINE:c" {
+		t.Errorf("Expected the last vendor block, got %v", tag)
+	}
+	if p.Count() != 2 {
+		t.Errorf("Expected 2 segments, got %d", p.Count())
+	}
+
+	p, _ = NewMediaPlaylist(0, 2)
+	p.WithCustomDecoders([]CustomDecoder{&multilineDecoder{segment: true}})
+	if err := p.DecodeFrom(strings.NewReader(src), true); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Custom) != 0 {
+		t.Errorf("Expected no playlist tags, got %v", p.Custom)
+	}
+	if tag := p.Segments[0].Custom["#EXT-X-VENDOR-BLOCK"]; tag == nil || tag.String() != "#EXT-X-VENDOR-BLOCK:1\n#EXT-X-VENDOR-LINE:a\n#EXT-X-VENDOR-LINE:b" {
+		t.Errorf("Expected vendor block of the first segment, got %v", tag)
+	}
+	if p.Segments[1].Custom != nil {
+		t.Errorf("Expected no tags of the second segment, got %v", p.Segments[1].Custom)
+	}
+
+	playlist, listType, err := DecodeWith(strings.NewReader(src), true, []CustomDecoder{&multilineDecoder{segment: true}})
+	if err != nil || listType != MEDIA {
+		t.Fatal(err)
+	}
+	if tag := playlist.(*MediaPlaylist).Segments[0].Custom["#EXT-X-VENDOR-BLOCK"]; tag == nil {
+		t.Error("Expected vendor block of the first segment")
+	}
+
+	p, _ = NewMediaPlaylist(0, 2)
+	p.WithCustomDecoders([]CustomDecoder{&multilineDecoder{}})
+	var perr *ParseError
+	err = p.DecodeFrom(strings.NewReader(strings.Replace(src, "VENDOR-LINE:b", "VENDOR-LINE:bad", 1)), true)
+	if !errors.As(err, &perr) || perr.Line != 5 {
+		t.Errorf("Expected error at line 5, got %v", err)
+	}
+}
+
 func TestDecodeMediaPlaylistWithCustomTags(t *testing.T) {
 	cases := []struct {
 		src                  string
@@ -760,6 +895,11 @@ func TestDecodeMediaPlaylistWithCustomTags(t *testing.T) {
 
 		p, listType, err := DecodeWith(bufio.NewReader(f), true, testCase.customDecoders)
 
+		// errors of the decoders are reported with the line
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			err = perr.Err
+		}
 		if !reflect.DeepEqual(err, testCase.expectedError) {
 			t.Fatal(err)
 		}
@@ -898,12 +1038,12 @@ func TestMediaPlaylistWithSCTE35Tag(t *testing.T) {
 			} else if index == c.expectedSCTEIndex && item.SCTE == nil {
 				t.Error("Expecting SCTE information on this segment")
 			} else if index == c.expectedSCTEIndex && item.SCTE != nil {
-				if (*item.SCTE).Cue != c.expectedSCTECue {
-					t.Error("Expected ", c.expectedSCTECue, " got ", (*item.SCTE).Cue)
-				} else if (*item.SCTE).ID != c.expectedSCTEID {
-					t.Error("Expected ", c.expectedSCTEID, " got ", (*item.SCTE).ID)
-				} else if (*item.SCTE).Time != c.expectedSCTETime {
-					t.Error("Expected ", c.expectedSCTETime, " got ", (*item.SCTE).Time)
+				if item.SCTE35().Cue != c.expectedSCTECue {
+					t.Error("Expected ", c.expectedSCTECue, " got ", item.SCTE35().Cue)
+				} else if item.SCTE35().ID != c.expectedSCTEID {
+					t.Error("Expected ", c.expectedSCTEID, " got ", item.SCTE35().ID)
+				} else if item.SCTE35().Time != c.expectedSCTETime {
+					t.Error("Expected ", c.expectedSCTETime, " got ", item.SCTE35().Time)
 				}
 			}
 		}
@@ -978,6 +1118,858 @@ func TestDecodeMediaPlaylistStartTime(t *testing.T) {
 	}
 }
 
+func TestDecodeMediaPlaylistWithParts(t *testing.T) {
+	f, err := os.Open("sample-playlists/media-playlist-with-parts.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, listType, err := DecodeFrom(bufio.NewReader(f), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listType != MEDIA {
+		t.Fatal("Sample not recognized as media playlist.")
+	}
+	pp := p.(*MediaPlaylist)
+	if pp.Count() != 4 {
+		t.Fatalf("Expected 4 segments, got %d", pp.Count())
+	}
+	if len(pp.Segments[2].Parts) != 0 {
+		t.Errorf("Segment 268 must not have parts, got %d", len(pp.Segments[2].Parts))
+	}
+	parts := pp.Segments[3].Parts
+	if len(parts) != 12 {
+		t.Fatalf("Expected 12 parts of segment 269, got %d", len(parts))
+	}
+	if !parts[0].Independent || parts[1].Independent || parts[0].Duration != 0.33334 || parts[0].URI != "filePart269.0.mp4" {
+		t.Errorf("Unexpected first part: %+v", parts[0])
+	}
+	if len(pp.PendingParts) != 3 {
+		t.Fatalf("Expected 3 pending parts, got %d", len(pp.PendingParts))
+	}
+	if !pp.PendingParts[2].Gap {
+		t.Error("Last pending part must be a gap")
+	}
+	if !strings.Contains(pp.String(), "#EXT-X-PART:DURATION=0.33334,URI=\"filePart270.2.mp4\",GAP=YES\n#EXT-X-PRELOAD-HINT:") {
+		t.Errorf("Pending parts must be encoded after the last segment:\n%s", pp)
+	}
+}
+
+func TestDecodeMediaPlaylistWithPartKeys(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXT-X-PART:DURATION=2,URI="seg0.0.mp4",BYTERANGE="100@20"
+#EXT-X-KEY:METHOD=AES-128,URI="key2"
+#EXT-X-PART:DURATION=2,URI="seg0.1.mp4",BYTERANGE="100"
+#EXTINF:4,
+seg0.mp4
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	parts := p.Segments[0].Parts
+	if len(parts) != 2 || parts[0].Key.URI != "key1" || parts[1].Key.URI != "key2" {
+		t.Fatalf("Unexpected parts: %+v", parts)
+	}
+	if parts[0].Limit != 100 || parts[0].Offset != 20 || parts[1].Limit != 100 {
+		t.Errorf("Unexpected part byteranges: %+v %+v", parts[0], parts[1])
+	}
+	if p.Segments[0].Key.URI != "key2" {
+		t.Errorf("Segment must get the key of its final part, got %+v", p.Segments[0].Key)
+	}
+	if err := p.ValidatePartKeys(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeMediaPlaylistWithServerControl(t *testing.T) {
+	f, err := os.Open("sample-playlists/media-playlist-with-parts.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewMediaPlaylist(0, 8)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	expected := ServerControl{CanSkipUntil: 24, PartHoldBack: 1.002, CanBlockReload: true}
+	if p.ServerControl == nil || *p.ServerControl != expected {
+		t.Errorf("Expected %+v, got %+v", expected, p.ServerControl)
+	}
+}
+
+func TestDecodeMediaPlaylistWithPartInf(t *testing.T) {
+	f, err := os.Open("sample-playlists/media-playlist-with-parts.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewMediaPlaylist(0, 8)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	if p.PartTarget != 0.334 {
+		t.Errorf("Expected PART-TARGET 0.334, got %v", p.PartTarget)
+	}
+}
+
+func TestDecodeMediaPlaylistWithRenditionReports(t *testing.T) {
+	f, err := os.Open("sample-playlists/media-playlist-with-parts.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewMediaPlaylist(0, 8)
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		t.Fatal(err)
+	}
+	two, one := 2, 1
+	expected := []RenditionReport{
+		{URI: "../1M/waitForMSN.php", LastMSN: 270, LastPart: &two},
+		{URI: "../4M/waitForMSN.php", LastMSN: 270, LastPart: &one},
+	}
+	if !reflect.DeepEqual(p.RenditionReports, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, p.RenditionReports)
+	}
+}
+
+func TestDecodeMediaPlaylistDeltaUpdate(t *testing.T) {
+	full, _ := NewMediaPlaylist(0, 8)
+	for i := 0; i < 6; i++ {
+		full.Append(fmt.Sprintf("seg%d.ts", i), 4, "")
+	}
+	full.SetServerControl(&ServerControl{CanSkipUntil: 12})
+	delta, err := full.EncodeDelta(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewMediaPlaylist(0, 8)
+	if err = p.Decode(*delta, true); err != nil {
+		t.Fatal(err)
+	}
+	if p.SkippedSegments != 3 || p.Count() != 3 || p.Segments[0].SeqId != 3 {
+		t.Fatalf("Unexpected delta update: skipped %d, count %d, first %d", p.SkippedSegments, p.Count(), p.Segments[0].SeqId)
+	}
+	stale, _ := NewMediaPlaylist(0, 8)
+	stale.SeqNo = 1
+	stale.Append("seg1.ts", 4, "")
+	if err = p.ApplyDelta(stale); err == nil {
+		t.Error("Expected error for previous playlist without all skipped segments")
+	}
+	if err = p.ApplyDelta(full); err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != full.String() {
+		t.Errorf("Reconstructed playlist differs:\n%s\nexpected:\n%s", p, full)
+	}
+}
+
+func TestDecodeMediaPlaylistWithGap(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.000,
+seg0.ts
+#EXT-X-GAP
+#EXTINF:4.000,
+seg1.ts
+#EXTINF:4.000,
+seg2.ts
+`
+	p, _ := NewMediaPlaylist(0, 3)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[0].Gap || !p.Segments[1].Gap || p.Segments[2].Gap {
+		t.Errorf("Expected only the second segment to be a gap")
+	}
+}
+
+func TestDecodeMediaPlaylistWithTiles(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-IMAGES-ONLY
+#EXT-X-TILES:RESOLUTION=312x180,LAYOUT=5x2,DURATION=1.000
+#EXTINF:10.000,
+tiles0.jpg
+#EXTINF:10.000,
+tiles1.jpg
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	tiles := p.Segments[0].Tiles
+	if tiles == nil || tiles.Resolution != "312x180" || tiles.Layout != "5x2" || tiles.Duration != 1 {
+		t.Errorf("Unexpected tiles %+v", tiles)
+	}
+	if p.Segments[1].Tiles != nil {
+		t.Error("Expected tiles of the first segment only")
+	}
+}
+
+func TestDecodeMasterPlaylistWithAllowedCPC(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,ALLOWED-CPC="com.apple.streamingkeydelivery:AppleMain/Baseline,urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed:HW"
+hi.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"com.apple.streamingkeydelivery":                {"AppleMain", "Baseline"},
+		"urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed": {"HW"},
+	}
+	if !reflect.DeepEqual(m.Variants[0].AllowedCPC, expected) {
+		t.Errorf("Unexpected ALLOWED-CPC %v", m.Variants[0].AllowedCPC)
+	}
+	if len(m.Variants[0].ExtraAttributes) != 0 {
+		t.Errorf("ALLOWED-CPC must not be kept as extra attribute")
+	}
+}
+
+func TestDecodeMasterPlaylistWithReqVideoLayout(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:12
+#EXT-X-STREAM-INF:BANDWIDTH=20000000,REQ-VIDEO-LAYOUT="CH-STEREO"
+stereo.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=10000000,REQ-VIDEO-LAYOUT="CH-MONO"
+mono.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Variants[0].ReqVideoLayout != "CH-STEREO" || m.Variants[1].ReqVideoLayout != "CH-MONO" {
+		t.Errorf("Unexpected video layouts %q %q", m.Variants[0].ReqVideoLayout, m.Variants[1].ReqVideoLayout)
+	}
+}
+
+func TestDecodeMasterPlaylistWithHiResAudio(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="flac",NAME="Lossless",CHANNELS="2",BIT-DEPTH=24,SAMPLE-RATE=96000,URI="flac.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AUDIO="flac"
+hi.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	alt := m.Variants[0].Alternatives[0]
+	if alt.BitDepth != 24 || alt.SampleRate != 96000 {
+		t.Errorf("Unexpected rendition %+v", alt)
+	}
+	m = NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(strings.Replace(playlist, "BIT-DEPTH=24", "BIT-DEPTH=high", 1)), true); err == nil {
+		t.Error("Expected error for invalid BIT-DEPTH")
+	}
+}
+
+func TestDecodeMediaPlaylistWithSeveralSCTE35Cues(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE-IN
+#EXT-OATCLS-SCTE35:/DAlAAAAAAAAAP/wFAUAAAABf+/+ANgNkv4AFJlwAAEBAQAA5xULLA==
+#EXT-X-CUE-OUT:30
+#EXTINF:10.000,
+seg1.ts
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	cues := p.Segments[1].SCTE
+	if len(cues) != 2 || cues[0].CueType != SCTE35Cue_End || cues[1].CueType != SCTE35Cue_Start || cues[1].Time != 30 {
+		t.Fatalf("Unexpected cues %+v", cues)
+	}
+	if p.Segments[1].SCTE35() != cues[0] {
+		t.Error("SCTE35 must return the first cue")
+	}
+	if out := p.String(); !strings.Contains(out, "#EXT-X-CUE-IN\n#EXT-OATCLS-SCTE35:/DAlAAAAAAAAAP/wFAUAAAABf+/+ANgNkv4AFJlwAAEBAQAA5xULLA==\n#EXT-X-CUE-OUT:30\n#EXTINF:10.000,\nseg1.ts\n") {
+		t.Errorf("Expected cues in their order:\n%s", out)
+	}
+}
+
+func TestDecodeMediaPlaylistWithCueOutAttributes(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-CUE-OUT:DURATION=30,CAID=0x000000002310D2E0,ID="42"
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE-OUT-CONT:10/30
+#EXTINF:10.000,
+seg1.ts
+#EXT-X-CUE-IN
+#EXT-X-CUE-OUT:15
+#EXTINF:10.000,
+seg2.ts
+`
+	p, _ := NewMediaPlaylist(0, 3)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[int][]*SCTE{
+		0: {{Syntax: SCTE35_CUE_OUT, CueType: SCTE35Cue_Start, ID: "42", Time: 30, Attributes: map[string]string{"CAID": "0x000000002310D2E0"}}},
+		1: {{Syntax: SCTE35_CUE_OUT, CueType: SCTE35Cue_Mid, Time: 30, Elapsed: 10}},
+		2: {{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_End}, {Syntax: SCTE35_CUE_OUT, CueType: SCTE35Cue_Start, Time: 15}},
+	}
+	for i, cues := range expect {
+		if !reflect.DeepEqual(p.Segments[i].SCTE, cues) {
+			t.Errorf("Segment %d\ngot: %+v\nexp: %+v", i, p.Segments[i].SCTE, cues)
+		}
+	}
+	expected := `#EXT-X-CUE-OUT:DURATION=30,ID="42",CAID=0x000000002310D2E0
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE-OUT-CONT:10/30
+#EXTINF:10.000,
+seg1.ts
+#EXT-X-CUE-IN
+#EXT-X-CUE-OUT:DURATION=15
+`
+	if out := p.String(); !strings.Contains(out, expected) {
+		t.Errorf("Expected cues:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestDecodeMediaPlaylistCueAttributesQuoting(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-CUE-OUT:DURATION=30,CAID=0x000000002310D2E0,X-BREAK="7"
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE:ID="ad1",TYPE="SpliceOut",DURATION=30,X-POD="2",X-SLOTS=3
+#EXTINF:10.000,
+seg1.ts
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	out := p.String()
+	for _, line := range []string{
+		`#EXT-X-CUE-OUT:DURATION=30,CAID=0x000000002310D2E0,X-BREAK="7"`,
+		`#EXT-X-CUE:ID="ad1",TYPE="SpliceOut",DURATION=30,X-POD="2",X-SLOTS=3`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected %s, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestDecodeMediaPlaylistWithAdobeCue(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-CUE:ID="ad1",TYPE="SpliceOut",DURATION=30,TIME=414.171,CUE="/DAlAAAAAAAAAP/wFAUAAAABf+/+ANgNkv4AFJlwAAEBAQAA5xULLA=="
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE:ID="ad1",TYPE="SpliceIn"
+#EXTINF:10.000,
+seg1.ts
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	out := p.Segments[0].SCTE35()
+	expect := &SCTE{Syntax: SCTE35_ADOBE, CueType: SCTE35Cue_Start, ID: "ad1", Time: 30,
+		Cue: "/DAlAAAAAAAAAP/wFAUAAAABf+/+ANgNkv4AFJlwAAEBAQAA5xULLA==", Attributes: map[string]string{"TIME": "414.171"}}
+	if !reflect.DeepEqual(out, expect) {
+		t.Errorf("Unexpected cue\ngot: %+v\nexp: %+v", out, expect)
+	}
+	if in := p.Segments[1].SCTE35(); in == nil || in.Syntax != SCTE35_ADOBE || in.CueType != SCTE35Cue_End || in.ID != "ad1" {
+		t.Errorf("Unexpected cue %+v", in)
+	}
+	expected := `#EXT-X-CUE:ID="ad1",TYPE="SpliceOut",DURATION=30,CUE="/DAlAAAAAAAAAP/wFAUAAAABf+/+ANgNkv4AFJlwAAEBAQAA5xULLA==",TIME=414.171
+#EXTINF:10.000,
+seg0.ts
+#EXT-X-CUE:ID="ad1",TYPE="SpliceIn"
+`
+	if out := p.String(); !strings.Contains(out, expected) {
+		t.Errorf("Expected cues:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestDecodeMasterPlaylistWithAssocLanguage(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="Deutsch",LANGUAGE="de",ASSOC-LANGUAGE="gsw",URI="de.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,AUDIO="aud"
+video.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	alt := m.Variants[0].Alternatives[0]
+	if alt.Language != "de" || alt.AssocLanguage != "gsw" {
+		t.Errorf("Unexpected rendition %+v", alt)
+	}
+	if out := m.String(); !strings.Contains(out, `LANGUAGE="de",ASSOC-LANGUAGE="gsw",URI="de.m3u8"`) {
+		t.Errorf("Expected ASSOC-LANGUAGE in playlist:\n%s", out)
+	}
+}
+
+func TestDecodeMediaPlaylistWithMultiDRMKeys(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:5
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAA",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed",KEYFORMATVERSIONS="1"
+#EXTINF:4.000,
+seg0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAA",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed",KEYFORMATVERSIONS="1"
+#EXTINF:4.000,
+seg1.ts
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	seg := p.Segments[0]
+	if len(seg.Keys) != 2 || seg.Key != seg.Keys[0] || seg.Keys[1].Keyformat != "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed" {
+		t.Fatalf("Unexpected keys %+v", seg.Keys)
+	}
+	// the same set of keys is not repeated for the next segment
+	out := p.String()
+	if c := strings.Count(out, "#EXT-X-KEY:"); c != 2 {
+		t.Errorf("Expected 2 EXT-X-KEY tags, got %d:\n%s", c, out)
+	}
+}
+
+func TestDecodeMediaPlaylistWithEncryptedMap(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:0
+#EXTINF:4.000,
+seg0.mp4
+#EXT-X-KEY:METHOD=AES-128,URI="init.key",IV=0x1
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4.000,
+seg1.mp4
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	seg := p.Segments[1]
+	if seg.Map == nil || seg.Map.Key == nil || seg.Map.Key.URI != "init.key" {
+		t.Fatalf("Expected map encrypted with init.key, got %+v", seg.Map)
+	}
+	if seg.Key == nil || seg.Key.Method != "NONE" {
+		t.Errorf("Expected unencrypted segment, got %+v", seg.Key)
+	}
+}
+
+func TestDecodeMediaPlaylistWithUnknownVersion(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:13
+#EXT-X-TARGETDURATION:4
+#EXT-X-FUTURE-TAG:VALUE=1
+#EXTINF:4.000,
+seg0.ts
+`
+	p, _ := NewMediaPlaylist(0, 1)
+	if err := p.DecodeFrom(strings.NewReader(playlist), false); err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != 13 || p.Count() != 1 {
+		t.Errorf("Expected version 13 with 1 segment, got %d with %d", p.Version(), p.Count())
+	}
+	if len(p.Warnings) != 1 || p.Warnings[0] != "unknown EXT-X-VERSION 13" {
+		t.Errorf("Unexpected warnings %q", p.Warnings)
+	}
+	p, _ = NewMediaPlaylist(0, 1)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err == nil {
+		t.Error("Expected error for unknown version in strict mode")
+	}
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader("#EXTM3U\n#EXT-X-VERSION:1000\n#EXT-X-STREAM-INF:BANDWIDTH=1000\nv.m3u8\n"), false); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version() != 255 || len(m.Warnings) != 1 {
+		t.Errorf("Expected version 255 with a warning, got %d %q", m.Version(), m.Warnings)
+	}
+}
+
+func TestDecodeMasterPlaylistWithStart(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000
+v.m3u8
+#EXT-X-START:TIME-OFFSET=10,PRECISE=YES
+`
+	p, listType, err := DecodeFrom(strings.NewReader(playlist), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listType != MASTER {
+		t.Fatalf("Expected master playlist, got %v", listType)
+	}
+	m := p.(*MasterPlaylist)
+	if m.StartTime != 10 || !m.StartTimePrecise {
+		t.Errorf("Unexpected start %v %v", m.StartTime, m.StartTimePrecise)
+	}
+}
+
+func TestDecodeMediaPlaylistAllowCache(t *testing.T) {
+	playlist := "#EXTM3U\n#EXT-X-ALLOW-CACHE:YES\n#EXT-X-TARGETDURATION:4\n#EXTINF:4.000,\nseg0.ts\n"
+	p, _ := NewMediaPlaylist(0, 1)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	if p.AllowCache != AllowCacheYes {
+		t.Errorf("Expected AllowCacheYes, got %v", p.AllowCache)
+	}
+	p, _ = NewMediaPlaylist(0, 1)
+	if err := p.DecodeFrom(strings.NewReader(strings.Replace(playlist, "YES", "MAYBE", 1)), true); err == nil {
+		t.Error("Expected error for invalid EXT-X-ALLOW-CACHE")
+	}
+}
+
+func TestDecodeMediaPlaylistWithUnknownTags(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-TARGETDURATION:4
+#EXT-X-VENDOR-HEADER:ID=1
+#EXTINF:4.000,
+seg0.ts
+#EXT-X-VENDOR-MARK:AD
+#EXTINF:4.000,
+seg1.ts
+#EXT-X-VENDOR-TRAILER
+#EXT-X-ENDLIST
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	p.WithUnknownTags(true)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	expected := []UnknownTag{{"#EXT-X-VENDOR-HEADER:ID=1", HeaderPosition}, {"#EXT-X-VENDOR-TRAILER", TrailerPosition}}
+	if !reflect.DeepEqual(p.Unknown, expected) {
+		t.Errorf("Unexpected unknown tags of the playlist %v", p.Unknown)
+	}
+	if len(p.Segments[1].Unknown) != 1 || p.Segments[1].Unknown[0] != "#EXT-X-VENDOR-MARK:AD" {
+		t.Errorf("Unexpected unknown tags of the segment %v", p.Segments[1].Unknown)
+	}
+	if out := p.String(); out != playlist {
+		t.Errorf("Expected the same playlist:\n%s", out)
+	}
+
+	p, _ = NewMediaPlaylist(0, 2)
+	p.DecodeFrom(strings.NewReader(playlist), true)
+	if strings.Contains(p.String(), "VENDOR") {
+		t.Error("Unknown tags must be dropped by default")
+	}
+}
+
+func TestDecodeMasterPlaylistWithUnknownTags(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-VENDOR-SESSION:ID=1
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,PROGRAM-ID=0
+video.m3u8
+#EXT-X-VENDOR-END
+`
+	m := NewMasterPlaylist()
+	m.WithUnknownTags(true)
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	if out := m.String(); out != playlist {
+		t.Errorf("Expected the same playlist:\n%s", out)
+	}
+}
+
+func TestDecodeMediaPlaylistWithComments(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-TARGETDURATION:4
+# origin: packager-1
+#EXTINF:4.000,
+seg0.ts
+
+# ad break follows
+#EXT-X-VENDOR-MARK:AD
+#EXTINF:4.000,
+seg1.ts
+# end of event
+#EXT-X-ENDLIST
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	p.WithComments(true)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"", "# ad break follows"}
+	if !reflect.DeepEqual(p.Segments[1].Unknown, expected) {
+		t.Errorf("Unexpected comments of the segment %q", p.Segments[1].Unknown)
+	}
+	// unknown tags are dropped unless they are kept too
+	if out := p.String(); out != strings.Replace(playlist, "#EXT-X-VENDOR-MARK:AD\n", "", 1) {
+		t.Errorf("Expected the same playlist:\n%s", out)
+	}
+
+	p, _ = NewMediaPlaylist(0, 2)
+	p.WithComments(true)
+	p.WithUnknownTags(true)
+	p.DecodeFrom(strings.NewReader(playlist), true)
+	if out := p.String(); out != playlist {
+		t.Errorf("Expected the same playlist:\n%s", out)
+	}
+}
+
+func TestDecodeSegments(t *testing.T) {
+	f, err := os.Open("sample-playlists/media-playlist-with-discontinuity.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, _ := NewMediaPlaylist(0, 16)
+	if err = p.DecodeFrom(f, true); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, io.SeekStart)
+	var segments []*MediaSegment
+	err = DecodeSegments(f, func(seg *MediaSegment) error {
+		segments = append(segments, seg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != int(p.Count()) {
+		t.Fatalf("Expected %d segments, got %d", p.Count(), len(segments))
+	}
+	for i, seg := range segments {
+		if !reflect.DeepEqual(seg, p.Segments[i]) {
+			t.Errorf("Segment %d differs: %+v != %+v", i, seg, p.Segments[i])
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = DecodeSegments(strings.NewReader("#EXTM3U\n#EXTINF:4,\n1.ts\n#EXTINF:4,\n2.ts\n"), func(seg *MediaSegment) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Expected decoding stopped by the callback, got %v after %d calls", err, calls)
+	}
+}
+
+func TestDecodeUpdate(t *testing.T) {
+	version := func(first, last int, closed bool) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:%d\n#EXT-X-TARGETDURATION:4\n", first)
+		for i := first; i <= last; i++ {
+			fmt.Fprintf(&b, "#EXTINF:4.000,\n%d.ts\n", i)
+		}
+		if closed {
+			b.WriteString("#EXT-X-ENDLIST\n")
+		}
+		return b.String()
+	}
+	p, _ := NewMediaPlaylist(0, 4)
+	if err := p.DecodeFrom(strings.NewReader(version(10, 13, false)), true); err != nil {
+		t.Fatal(err)
+	}
+	kept := p.Segments[p.head+3]
+	for _, v := range []struct {
+		first, last int
+		closed      bool
+	}{
+		{12, 15, false}, // the window slides
+		{12, 15, false}, // nothing changed
+		{20, 22, false}, // segments were missed
+		{21, 23, true},
+	} {
+		update := version(v.first, v.last, v.closed)
+		if err := p.DecodeUpdate(strings.NewReader(update)); err != nil {
+			t.Fatal(err)
+		}
+		if p.Count() != uint(v.last-v.first+1) || p.SeqNo != uint64(v.first) || p.Closed != v.closed {
+			t.Errorf("Unexpected playlist after update %d-%d: %d segments from %d", v.first, v.last, p.Count(), p.SeqNo)
+		}
+		if out := p.String(); out != update {
+			t.Errorf("Expected the playlist equal to the update:\n%s", out)
+		}
+	}
+	if kept.URI != "13.ts" || kept.SeqId != 13 {
+		t.Errorf("Segments already decoded must not be changed: %+v", kept)
+	}
+}
+
+func TestDecodeUpdateState(t *testing.T) {
+	const first = `#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=3
+#EXT-X-PART-INF:PART-TARGET=1
+#EXT-X-KEY:METHOD=AES-128,URI="key-1"
+#EXTINF:4.000,
+10.ts
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="11.0.mp4"
+#EXT-X-RENDITION-REPORT:URI="../low/index.m3u8",LAST-MSN=10
+`
+	p, _ := NewMediaPlaylist(0, 4)
+	if err := p.DecodeFrom(strings.NewReader(first), true); err != nil {
+		t.Fatal(err)
+	}
+	update := strings.NewReplacer("SEQUENCE:10", "SEQUENCE:11", "#EXTINF:4.000,\n10.ts", "#EXTINF:4.000,\n11.ts",
+		"key-1", "key-2", "11.0.mp4", "12.0.mp4", "LAST-MSN=10", "LAST-MSN=11").Replace(first)
+	if err := p.DecodeUpdate(strings.NewReader(update)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Key == nil || p.Key.URI != "key-2" {
+		t.Errorf("Expected the key of the update, got %+v", p.Key)
+	}
+	if len(p.PreloadHints) != 1 || p.PreloadHints[0].URI != "12.0.mp4" {
+		t.Errorf("Expected preload hints of the update, got %+v", p.PreloadHints)
+	}
+	if len(p.RenditionReports) != 1 || p.RenditionReports[0].LastMSN != 11 {
+		t.Errorf("Expected rendition reports of the update, got %+v", p.RenditionReports)
+	}
+	if p.ServerControl == nil || !p.ServerControl.CanBlockReload {
+		t.Errorf("Expected server control of the update, got %+v", p.ServerControl)
+	}
+
+	// an invalid update doesn't change the playlist
+	before := p.String()
+	invalid := strings.Replace(update, "#EXT-X-PRELOAD-HINT", "#EXTINF:bad,\n12.ts\n#EXT-X-PRELOAD-HINT", 1)
+	invalid = strings.Replace(invalid, "SEQUENCE:11", "SEQUENCE:12", 1)
+	if err := p.DecodeUpdate(strings.NewReader(invalid)); err == nil {
+		t.Error("Expected error for invalid update")
+	}
+	if p.String() != before || p.SeqNo != 11 || p.Count() != 1 {
+		t.Errorf("Expected the playlist unchanged, got:\n%s", p)
+	}
+
+	// an update without segments keeps its media sequence number
+	const empty = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:15\n#EXT-X-TARGETDURATION:4\n"
+	if err := p.DecodeUpdate(strings.NewReader(empty)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Count() != 0 || p.SeqNo != 15 {
+		t.Errorf("Expected empty playlist from 15, got %d segments from %d", p.Count(), p.SeqNo)
+	}
+}
+
+func TestDecodeUpdateKeepsSegments(t *testing.T) {
+	const first = `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.000,
+10.ts
+#EXTINF:4.000,
+11.ts
+#EXTINF:4.000,
+12.ts
+`
+	p, _ := NewMediaPlaylist(0, 3)
+	if err := p.DecodeFrom(strings.NewReader(first), true); err != nil {
+		t.Fatal(err)
+	}
+	segments := make(map[uint64]*MediaSegment)
+	for i := uint(0); i < p.Count(); i++ {
+		seg := p.segmentAt(i)
+		segments[seg.SeqId] = seg
+	}
+	const update = `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-PLAYLIST-TYPE:EVENT
+#EXT-X-DEFINE:NAME="host",VALUE="cdn.example.com"
+#EXT-X-MEDIA-SEQUENCE:11
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.000,
+11.ts
+#EXTINF:4.000,
+12.ts
+#EXTINF:4.000,
+13.ts
+#EXTINF:4.000,
+14.ts
+`
+	if err := p.DecodeUpdate(strings.NewReader(update)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Count() != 4 || p.SeqNo != 11 {
+		t.Fatalf("Expected 4 segments from 11, got %d from %d", p.Count(), p.SeqNo)
+	}
+	for i, seq := range []uint64{11, 12} {
+		if seg := p.segmentAt(uint(i)); seg != segments[seq] {
+			t.Errorf("Segment %d must be kept, got %+v", seq, seg)
+		}
+	}
+	if seg := p.segmentAt(3); seg.SeqId != 14 || seg.URI != "14.ts" {
+		t.Errorf("Unexpected last segment %+v", seg)
+	}
+	if p.MediaType != EVENT || len(p.Defines) != 1 || p.Defines[0].Name != "host" {
+		t.Errorf("Expected playlist type and variables of the update, got %v %+v", p.MediaType, p.Defines)
+	}
+}
+
+// decodeTitle encodes a segment with the title and decodes it back.
+func decodeTitle(title string) (string, error) {
+	p, _ := NewMediaPlaylist(1, 1)
+	p.Append("seg0.ts", 4, title)
+	decoded, _ := NewMediaPlaylist(0, 1)
+	if err := decoded.Decode(*p.Encode(), true); err != nil {
+		return "", err
+	}
+	return decoded.Segments[0].Title, nil
+}
+
+func TestEXTINFTitleRoundTrip(t *testing.T) {
+	for _, title := range []string{
+		"",
+		"Artist, The - Song, Part 1",
+		`He said "hello", twice`,
+		"Мумий Тролль – Утекай, 1997 ♪",
+		" padded title ",
+		",",
+	} {
+		decoded, err := decodeTitle(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != title {
+			t.Errorf("Expected title %q, got %q", title, decoded)
+		}
+	}
+}
+
+func TestEXTINFTitleRoundTripProperty(t *testing.T) {
+	roundTrip := func(title string) bool {
+		// line breaks can't be encoded in the title and are removed
+		title = strings.NewReplacer("\r", "", "\n", "").Replace(title)
+		decoded, err := decodeTitle(title)
+		return err == nil && decoded == title
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeEXTINFWithoutTitleDoesNotInheritTitle(t *testing.T) {
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,first\nseg0.ts\n#EXTINF:4\nseg1.ts\n"
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.Decode(*bytes.NewBufferString(playlist), false); err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[1].Title != "" {
+		t.Errorf("Expected empty title, got %q", p.Segments[1].Title)
+	}
+}
+
 /****************
  *  Benchmarks  *
  ****************/
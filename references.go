@@ -0,0 +1,55 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines checks of references between variants and renditions
+ of master playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidateReferences checks that every AUDIO, VIDEO, SUBTITLES and
+// CLOSED-CAPTIONS group referenced by a variant has at least one
+// EXT-X-MEDIA rendition, and that CLOSED-CAPTIONS=NONE is set on all
+// EXT-X-STREAM-INF variants if any of them has it.
+func (p *MasterPlaylist) ValidateReferences() error {
+	type reference struct{ typ, group string }
+	groups := renditionGroups(p)
+	var none, captions int
+	for _, v := range p.Variants {
+		if v.Image {
+			continue
+		}
+		refs := []reference{{"AUDIO", v.Audio}, {"VIDEO", v.Video}}
+		if !v.Iframe {
+			refs = append(refs, reference{"SUBTITLES", v.Subtitles})
+			if v.Captions == "NONE" {
+				none++
+			} else {
+				captions++
+				refs = append(refs, reference{"CLOSED-CAPTIONS", v.Captions})
+			}
+		}
+		for _, ref := range refs {
+			if ref.group == "" {
+				continue
+			}
+			if _, ok := groups[ref.typ+"/"+ref.group]; !ok {
+				return fmt.Errorf("variant %s references %s group %s without renditions", v.URI, ref.typ, ref.group)
+			}
+		}
+	}
+	if none > 0 && captions > 0 {
+		return errors.New("CLOSED-CAPTIONS=NONE must be set on all variants if any of them has it")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+ Package m3u8. Rendition references tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateReferences(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",URI="en.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",URI="subs.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,AUDIO="aud",SUBTITLES="subs",CLOSED-CAPTIONS=NONE
+lo.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,AUDIO="aud",CLOSED-CAPTIONS=NONE
+hi.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=100000,URI="iframe.m3u8"
+`
+	decode := func(playlist string) *MasterPlaylist {
+		m := NewMasterPlaylist()
+		if err := m.Decode(*bytes.NewBufferString(playlist), true); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	if err := decode(playlist).ValidateReferences(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	for _, c := range []struct {
+		old, new, expected string
+	}{
+		{`AUDIO="aud",CLOSED`, `AUDIO="surround",CLOSED`, "variant hi.m3u8 references AUDIO group surround without renditions"},
+		{`SUBTITLES="subs"`, `SUBTITLES="forced"`, "variant lo.m3u8 references SUBTITLES group forced without renditions"},
+		{`AUDIO="aud",CLOSED-CAPTIONS=NONE
+hi`, `AUDIO="aud",CLOSED-CAPTIONS="cc"
+hi`, "variant hi.m3u8 references CLOSED-CAPTIONS group cc without renditions"},
+		{`AUDIO="aud",CLOSED-CAPTIONS=NONE
+hi`, `AUDIO="aud"
+hi`, "CLOSED-CAPTIONS=NONE must be set on all variants"},
+	} {
+		err := decode(strings.Replace(playlist, c.old, c.new, 1)).ValidateReferences()
+		if err == nil || !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("Expected error %q, got %v", c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines typed RESOLUTION attribute of variants.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resolution is the value of RESOLUTION attribute, the video dimensions
+// in pixels.
+type Resolution struct {
+	Width  int
+	Height int
+}
+
+// ParseResolution parses RESOLUTION value formatted as WxH, e.g. 1280x720.
+func ParseResolution(s string) (Resolution, error) {
+	var r Resolution
+	parts := strings.Split(s, "x")
+	if len(parts) != 2 {
+		return r, fmt.Errorf("invalid resolution %q", s)
+	}
+	var err error
+	if r.Width, err = strconv.Atoi(parts[0]); err == nil {
+		r.Height, err = strconv.Atoi(parts[1])
+	}
+	if err != nil || r.Width <= 0 || r.Height <= 0 {
+		return Resolution{}, fmt.Errorf("invalid resolution %q", s)
+	}
+	return r, nil
+}
+
+// String formats the resolution as WxH, zero resolution is empty.
+func (r Resolution) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	return strconv.Itoa(r.Width) + "x" + strconv.Itoa(r.Height)
+}
+
+// IsZero reports whether the resolution is unknown.
+func (r Resolution) IsZero() bool {
+	return r.Width == 0 && r.Height == 0
+}
+
+// Pixels returns the number of pixels of a frame.
+func (r Resolution) Pixels() int {
+	return r.Width * r.Height
+}
+
+// Less reports whether the resolution has less pixels than the other one,
+// the narrower one is less if both have the same number of pixels.
+func (r Resolution) Less(other Resolution) bool {
+	if r.Pixels() != other.Pixels() {
+		return r.Pixels() < other.Pixels()
+	}
+	return r.Width < other.Width
+}
+
+// Fits reports whether both dimensions are within the limits.
+func (r Resolution) Fits(limit Resolution) bool {
+	return r.Width <= limit.Width && r.Height <= limit.Height
+}
+
+// Dimensions returns RESOLUTION of the variant parsed, zero resolution if
+// the variant has none or it is invalid.
+func (vp *VariantParams) Dimensions() Resolution {
+	r, _ := ParseResolution(vp.Resolution)
+	return r
+}
+
+// SetDimensions sets RESOLUTION of the variant, zero resolution removes it.
+func (vp *VariantParams) SetDimensions(r Resolution) {
+	vp.Resolution = r.String()
+}
@@ -0,0 +1,84 @@
+/*
+ Package m3u8. Resolution tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseResolution(t *testing.T) {
+	r, err := ParseResolution("1280x720")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Resolution{1280, 720}) {
+		t.Errorf("got %+v", r)
+	}
+	if r.String() != "1280x720" {
+		t.Errorf("formatted as %q", r.String())
+	}
+	for _, s := range []string{"", "1280", "1280x", "x720", "1280x720x1", "-1x720", "wide"} {
+		if _, err := ParseResolution(s); err == nil {
+			t.Errorf("%q must be rejected", s)
+		}
+	}
+	if (Resolution{}).String() != "" {
+		t.Error("zero resolution must be empty")
+	}
+}
+
+func TestResolutionCompare(t *testing.T) {
+	sd, hd := Resolution{640, 360}, Resolution{1280, 720}
+	if !sd.Less(hd) || hd.Less(sd) || hd.Less(hd) {
+		t.Error("wrong order by pixels")
+	}
+	if !(Resolution{360, 640}).Less(Resolution{640, 360}) {
+		t.Error("narrower resolution must be less")
+	}
+	if !sd.Fits(hd) || hd.Fits(sd) || !(Resolution{1280, 360}).Fits(hd) {
+		t.Error("wrong fitting")
+	}
+}
+
+func TestVariantDimensions(t *testing.T) {
+	m := NewMasterPlaylist()
+	p, _ := NewMediaPlaylist(1, 1)
+	m.Append("hd.m3u8", p, VariantParams{Bandwidth: 3000000})
+	m.Variants[0].SetDimensions(Resolution{1920, 1080})
+	if m.Variants[0].Resolution != "1920x1080" {
+		t.Errorf("resolution set as %q", m.Variants[0].Resolution)
+	}
+	if !bytes.Contains(m.Encode().Bytes(), []byte("RESOLUTION=1920x1080")) {
+		t.Error("resolution not encoded")
+	}
+	if d := m.Variants[0].Dimensions(); d != (Resolution{1920, 1080}) {
+		t.Errorf("got %+v", d)
+	}
+	m.Variants[0].Resolution = "unknown"
+	if !m.Variants[0].Dimensions().IsZero() {
+		t.Error("invalid resolution must be zero")
+	}
+}
+
+func TestDecodeInvalidResolution(t *testing.T) {
+	const playlist = "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000,RESOLUTION=wide\nlow.m3u8\n"
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(bytes.NewBufferString(playlist), true); err == nil {
+		t.Error("invalid resolution must fail strict decoding")
+	}
+	m = NewMasterPlaylist()
+	if err := m.DecodeFrom(bytes.NewBufferString(playlist), false); err != nil {
+		t.Fatal(err)
+	}
+	if m.Variants[0].Resolution != "wide" {
+		t.Errorf("resolution decoded as %q", m.Variants[0].Resolution)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+ Package scte35 decodes SCTE-35 splice information sections carried by
+ cue tags of M3U8 playlists (ANSI/SCTE 35 2022).
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package scte35
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TicksPerSecond is the frequency of the 90 kHz clock of PTS values and
+// durations.
+const TicksPerSecond = 90000
+
+// CommandType is splice_command_type of the section.
+type CommandType uint8
+
+const (
+	SpliceNull           CommandType = 0x00
+	SpliceSchedule       CommandType = 0x04
+	SpliceInsertCommand  CommandType = 0x05
+	TimeSignalCommand    CommandType = 0x06
+	BandwidthReservation CommandType = 0x07
+	PrivateCommand       CommandType = 0xff
+)
+
+// SegmentationType is segmentation_type_id of the segmentation descriptor.
+type SegmentationType uint8
+
+const (
+	ProgramStart                         SegmentationType = 0x10
+	ProgramEnd                           SegmentationType = 0x11
+	ChapterStart                         SegmentationType = 0x20
+	ChapterEnd                           SegmentationType = 0x21
+	BreakStart                           SegmentationType = 0x22
+	BreakEnd                             SegmentationType = 0x23
+	ProviderAdvertisementStart           SegmentationType = 0x30
+	ProviderAdvertisementEnd             SegmentationType = 0x31
+	DistributorAdvertisementStart        SegmentationType = 0x32
+	DistributorAdvertisementEnd          SegmentationType = 0x33
+	ProviderPlacementOpportunityStart    SegmentationType = 0x34
+	ProviderPlacementOpportunityEnd      SegmentationType = 0x35
+	DistributorPlacementOpportunityStart SegmentationType = 0x36
+	DistributorPlacementOpportunityEnd   SegmentationType = 0x37
+	ProviderAdBlockStart                 SegmentationType = 0x44
+	ProviderAdBlockEnd                   SegmentationType = 0x45
+	DistributorAdBlockStart              SegmentationType = 0x46
+	DistributorAdBlockEnd                SegmentationType = 0x47
+)
+
+// Descriptor tags of splice descriptors.
+const (
+	availDescriptorTag        = 0x00
+	segmentationDescriptorTag = 0x02
+)
+
+// SpliceInfo is a decoded splice_info_section. Commands and descriptors
+// which are not decoded by the package are skipped.
+type SpliceInfo struct {
+	ProtocolVersion uint8
+	Encrypted       bool   // encrypted_packet, the command and descriptors are not decoded then
+	PTSAdjustment   uint64 // pts_adjustment in 90 kHz ticks added to all PTS values of the section
+	Tier            uint16
+	CommandType     CommandType
+	SpliceInsert    *SpliceInsert // splice_insert command
+	TimeSignal      *SpliceTime   // time_signal command
+	AvailIDs        []uint32      // provider_avail_id of avail descriptors
+	Segmentation    []*SegmentationDescriptor
+}
+
+// SpliceTime is splice_time structure, the time of a splice point.
+type SpliceTime struct {
+	Specified bool   // time_specified_flag
+	PTS       uint64 // pts_time in 90 kHz ticks
+}
+
+// SpliceInsert is splice_insert command, the start or the end of a break.
+type SpliceInsert struct {
+	EventID         uint32
+	Cancel          bool // splice_event_cancel_indicator, other fields are zero then
+	OutOfNetwork    bool // out_of_network_indicator is set at the start of a break
+	Immediate       bool // splice_immediate_flag, the splice time is absent
+	Time            *SpliceTime
+	BreakDuration   time.Duration // zero if absent
+	AutoReturn      bool          // auto_return of break_duration
+	UniqueProgramID uint16
+	AvailNum        uint8
+	AvailsExpected  uint8
+}
+
+// SegmentationDescriptor is segmentation_descriptor, it marks the
+// boundary of a program, chapter, ad or placement opportunity.
+type SegmentationDescriptor struct {
+	EventID               uint32
+	Cancel                bool // segmentation_event_cancel_indicator, other fields are zero then
+	DeliveryNotRestricted bool
+	WebDeliveryAllowed    bool
+	NoRegionalBlackout    bool
+	ArchiveAllowed        bool
+	DeviceRestrictions    uint8
+	Duration              time.Duration // segmentation_duration, zero if absent
+	UPIDType              uint8
+	UPID                  []byte
+	Type                  SegmentationType
+	SegmentNum            uint8
+	SegmentsExpected      uint8
+	SubSegmentNum         uint8
+	SubSegmentsExpected   uint8
+}
+
+// DecodeString decodes the section from its text form used by playlists,
+// base64 or hexadecimal with 0x prefix.
+func DecodeString(cue string) (*SpliceInfo, error) {
+	data, err := Bytes(cue)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Bytes returns the binary section of its text form, base64 or
+// hexadecimal with 0x prefix.
+func Bytes(cue string) ([]byte, error) {
+	cue = strings.TrimSpace(cue)
+	if strings.HasPrefix(cue, "0x") || strings.HasPrefix(cue, "0X") {
+		return hex.DecodeString(cue[2:])
+	}
+	return base64.StdEncoding.DecodeString(cue)
+}
+
+// Decode decodes the binary splice_info_section and checks its CRC.
+func Decode(data []byte) (*SpliceInfo, error) {
+	if len(data) < 3 {
+		return nil, errors.New("section is too short")
+	}
+	if data[0] != 0xfc {
+		return nil, fmt.Errorf("unexpected table id 0x%02x", data[0])
+	}
+	length := int(data[1]&0x0f)<<8 | int(data[2])
+	if length+3 > len(data) || length < 4 {
+		return nil, errors.New("section is truncated")
+	}
+	data = data[:length+3]
+	if crc32MPEG(data) != 0 {
+		return nil, errors.New("CRC mismatch")
+	}
+
+	r := &bitReader{data: data[3 : len(data)-4]}
+	info := new(SpliceInfo)
+	info.ProtocolVersion = uint8(r.read(8))
+	info.Encrypted = r.flag()
+	r.skip(6) // encryption_algorithm
+	info.PTSAdjustment = r.read(33)
+	r.skip(8) // cw_index
+	info.Tier = uint16(r.read(12))
+	commandLength := int(r.read(12))
+	info.CommandType = CommandType(r.read(8))
+	if r.err != nil || info.Encrypted {
+		return info, r.err
+	}
+
+	start := r.pos
+	switch info.CommandType {
+	case SpliceInsertCommand:
+		info.SpliceInsert = r.spliceInsert()
+	case TimeSignalCommand:
+		info.TimeSignal = r.spliceTime()
+	}
+	if commandLength != 0xfff {
+		// legacy sections don't state the length of the command
+		r.pos = start + commandLength*8
+	} else if info.CommandType != SpliceInsertCommand && info.CommandType != TimeSignalCommand && info.CommandType != SpliceNull {
+		return nil, fmt.Errorf("length of splice command 0x%02x is unknown", uint8(info.CommandType))
+	}
+
+	loop := int(r.read(16))
+	end := r.pos + loop*8
+	for r.err == nil && r.pos < end {
+		tag, length := r.read(8), int(r.read(8))
+		next := r.pos + length*8
+		if next > end {
+			return nil, errors.New("splice descriptor is truncated")
+		}
+		identifier := r.read(32)
+		switch {
+		case identifier != 0x43554549: // "CUEI"
+		case tag == availDescriptorTag:
+			info.AvailIDs = append(info.AvailIDs, uint32(r.read(32)))
+		case tag == segmentationDescriptorTag:
+			info.Segmentation = append(info.Segmentation, r.segmentation(next))
+		}
+		r.pos = next
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return info, nil
+}
+
+// Out reports whether the section starts a break, an ad or a placement
+// opportunity.
+func (info *SpliceInfo) Out() bool {
+	if info.SpliceInsert != nil && !info.SpliceInsert.Cancel {
+		return info.SpliceInsert.OutOfNetwork
+	}
+	for _, d := range info.Segmentation {
+		if !d.Cancel && d.Type.Start() {
+			return true
+		}
+	}
+	return false
+}
+
+// In reports whether the section ends a break, an ad or a placement
+// opportunity.
+func (info *SpliceInfo) In() bool {
+	if info.SpliceInsert != nil && !info.SpliceInsert.Cancel {
+		return !info.SpliceInsert.OutOfNetwork
+	}
+	for _, d := range info.Segmentation {
+		if !d.Cancel && d.Type.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration returns the duration of the break, either break_duration of
+// splice_insert or duration of the first segmentation descriptor with
+// one, zero if it is unknown.
+func (info *SpliceInfo) Duration() time.Duration {
+	if info.SpliceInsert != nil && info.SpliceInsert.BreakDuration > 0 {
+		return info.SpliceInsert.BreakDuration
+	}
+	for _, d := range info.Segmentation {
+		if d.Duration > 0 {
+			return d.Duration
+		}
+	}
+	return 0
+}
+
+// Start reports whether the type starts a break, an ad or a placement
+// opportunity.
+func (t SegmentationType) Start() bool {
+	switch t {
+	case BreakStart, ProviderAdvertisementStart, DistributorAdvertisementStart,
+		ProviderPlacementOpportunityStart, DistributorPlacementOpportunityStart,
+		ProviderAdBlockStart, DistributorAdBlockStart:
+		return true
+	}
+	return false
+}
+
+// End reports whether the type ends a break, an ad or a placement
+// opportunity.
+func (t SegmentationType) End() bool {
+	switch t {
+	case BreakEnd, ProviderAdvertisementEnd, DistributorAdvertisementEnd,
+		ProviderPlacementOpportunityEnd, DistributorPlacementOpportunityEnd,
+		ProviderAdBlockEnd, DistributorAdBlockEnd:
+		return true
+	}
+	return false
+}
+
+// ticks converts 90 kHz ticks to duration.
+func ticks(t uint64) time.Duration {
+	return time.Duration(t) * time.Second / TicksPerSecond
+}
+
+// spliceInsert reads splice_insert command.
+func (r *bitReader) spliceInsert() *SpliceInsert {
+	cmd := &SpliceInsert{EventID: uint32(r.read(32))}
+	cmd.Cancel = r.flag()
+	r.skip(7)
+	if cmd.Cancel {
+		return cmd
+	}
+	cmd.OutOfNetwork = r.flag()
+	program := r.flag()
+	hasDuration := r.flag()
+	cmd.Immediate = r.flag()
+	r.skip(4)
+	if program && !cmd.Immediate {
+		cmd.Time = r.spliceTime()
+	}
+	if !program {
+		for n := r.read(8); n > 0 && r.err == nil; n-- {
+			r.skip(8) // component_tag
+			if !cmd.Immediate {
+				r.spliceTime()
+			}
+		}
+	}
+	if hasDuration {
+		cmd.AutoReturn = r.flag()
+		r.skip(6)
+		cmd.BreakDuration = ticks(r.read(33))
+	}
+	cmd.UniqueProgramID = uint16(r.read(16))
+	cmd.AvailNum = uint8(r.read(8))
+	cmd.AvailsExpected = uint8(r.read(8))
+	return cmd
+}
+
+// spliceTime reads splice_time structure.
+func (r *bitReader) spliceTime() *SpliceTime {
+	t := &SpliceTime{Specified: r.flag()}
+	if t.Specified {
+		r.skip(6)
+		t.PTS = r.read(33)
+	} else {
+		r.skip(7)
+	}
+	return t
+}
+
+// segmentation reads segmentation_descriptor ending at the bit position.
+func (r *bitReader) segmentation(end int) *SegmentationDescriptor {
+	d := &SegmentationDescriptor{EventID: uint32(r.read(32))}
+	d.Cancel = r.flag()
+	r.skip(7)
+	if d.Cancel {
+		return d
+	}
+	program := r.flag()
+	hasDuration := r.flag()
+	d.DeliveryNotRestricted = r.flag()
+	if d.DeliveryNotRestricted {
+		r.skip(5)
+	} else {
+		d.WebDeliveryAllowed = r.flag()
+		d.NoRegionalBlackout = r.flag()
+		d.ArchiveAllowed = r.flag()
+		d.DeviceRestrictions = uint8(r.read(2))
+	}
+	if !program {
+		for n := r.read(8); n > 0 && r.err == nil; n-- {
+			r.skip(48) // component_tag and pts_offset
+		}
+	}
+	if hasDuration {
+		d.Duration = ticks(r.read(40))
+	}
+	d.UPIDType = uint8(r.read(8))
+	if n := int(r.read(8)); r.err == nil {
+		d.UPID = r.bytes(n)
+	}
+	d.Type = SegmentationType(r.read(8))
+	d.SegmentNum = uint8(r.read(8))
+	d.SegmentsExpected = uint8(r.read(8))
+	// sub segments were added to the standard later, old encoders omit them
+	if r.pos+16 <= end {
+		switch d.Type {
+		case ProviderPlacementOpportunityStart, DistributorPlacementOpportunityStart, ProviderAdBlockStart, DistributorAdBlockStart:
+			d.SubSegmentNum = uint8(r.read(8))
+			d.SubSegmentsExpected = uint8(r.read(8))
+		}
+	}
+	return d
+}
+
+// bitReader reads big-endian bit fields, the first error is kept and
+// the following reads return zeros.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+	err  error
+}
+
+func (r *bitReader) read(n int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos+n > len(r.data)*8 {
+		r.err = errors.New("section is truncated")
+		return 0
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[(r.pos+i)/8] >> (7 - uint((r.pos+i)%8)) & 1
+		v = v<<1 | uint64(bit)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *bitReader) flag() bool {
+	return r.read(1) == 1
+}
+
+func (r *bitReader) skip(n int) {
+	r.read(n)
+}
+
+func (r *bitReader) bytes(n int) []byte {
+	out := make([]byte, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		out = append(out, byte(r.read(8)))
+	}
+	return out
+}
+
+// crc32MPEG calculates CRC-32/MPEG-2 of the data, it is zero for data
+// ending with its CRC.
+func crc32MPEG(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
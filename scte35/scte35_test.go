@@ -0,0 +1,85 @@
+/*
+ Package scte35. Splice information decoding tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package scte35
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// Samples of section 14 of the standard.
+
+func TestDecodeTimeSignal(t *testing.T) {
+	info, err := DecodeString("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CommandType != TimeSignalCommand || info.TimeSignal == nil || !info.TimeSignal.Specified || info.TimeSignal.PTS != 0x072bd0050 {
+		t.Fatalf("Unexpected time signal %+v", info.TimeSignal)
+	}
+	if len(info.Segmentation) != 1 {
+		t.Fatalf("Expected 1 segmentation descriptor, got %d", len(info.Segmentation))
+	}
+	d := info.Segmentation[0]
+	if d.EventID != 0x4800008e || d.Type != ProviderPlacementOpportunityStart || d.SegmentNum != 2 || d.SegmentsExpected != 0 {
+		t.Errorf("Unexpected segmentation descriptor %+v", d)
+	}
+	if d.Duration != 307*time.Second {
+		t.Errorf("Expected duration 307s, got %s", d.Duration)
+	}
+	if d.UPIDType != 8 || !bytes.Equal(d.UPID, []byte{0, 0, 0, 0, 0x2c, 0xa0, 0xa1, 0x8a}) {
+		t.Errorf("Unexpected UPID %d/%x", d.UPIDType, d.UPID)
+	}
+	if !info.Out() || info.In() || info.Duration() != 307*time.Second {
+		t.Errorf("Expected the start of a placement opportunity of 307s")
+	}
+}
+
+func TestDecodeSpliceInsert(t *testing.T) {
+	info, err := DecodeString("/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := info.SpliceInsert
+	if info.CommandType != SpliceInsertCommand || cmd == nil {
+		t.Fatalf("Expected splice insert, got %+v", info)
+	}
+	if cmd.EventID != 0x4800008f || !cmd.OutOfNetwork || cmd.Immediate || !cmd.AutoReturn {
+		t.Errorf("Unexpected splice insert %+v", cmd)
+	}
+	if cmd.Time == nil || cmd.Time.PTS != 0x07369c02e {
+		t.Errorf("Unexpected splice time %+v", cmd.Time)
+	}
+	if cmd.BreakDuration != ticks(0x00052ccf5) {
+		t.Errorf("Unexpected break duration %s", cmd.BreakDuration)
+	}
+	if len(info.AvailIDs) != 1 || info.AvailIDs[0] != 0x135 {
+		t.Errorf("Unexpected avail descriptors %v", info.AvailIDs)
+	}
+	if !info.Out() {
+		t.Error("Expected the start of a break")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data, _ := Bytes("/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=")
+	if _, err := DecodeString("0x" + hex.EncodeToString(data)); err != nil {
+		t.Errorf("Unexpected error of hexadecimal cue %s", err)
+	}
+	data[20] ^= 1
+	if _, err := Decode(data); err == nil {
+		t.Error("Expected CRC mismatch")
+	}
+	if _, err := Decode(data[:10]); err == nil {
+		t.Error("Expected error of truncated section")
+	}
+}
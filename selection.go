@@ -0,0 +1,197 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines selection of audio and subtitle renditions the way
+ players choose them from user preferences.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Accessibility characteristics of renditions (CHARACTERISTICS attribute).
+const (
+	DescribesVideo          = "public.accessibility.describes-video"
+	TranscribesSpokenDialog = "public.accessibility.transcribes-spoken-dialog"
+)
+
+// ForcedPolicy tells how renditions with FORCED=YES are selected.
+type ForcedPolicy uint8
+
+const (
+	// ForcedWithAudio selects forced subtitles in the language of the
+	// selected audio when subtitles are off (the default of players).
+	ForcedWithAudio ForcedPolicy = iota
+	// ForcedNever never selects forced subtitles.
+	ForcedNever
+)
+
+// RenditionPreferences are the user settings which players use to choose
+// renditions of a master playlist.
+type RenditionPreferences struct {
+	Languages         []string     // preferred languages (RFC 5646 tags) in order, "en" matches "en-US"
+	Subtitles         bool         // subtitles are on, otherwise only forced subtitles may be selected
+	DescribesVideo    bool         // audio description is needed (public.accessibility.describes-video)
+	TranscribesDialog bool         // subtitles for the deaf and hard of hearing are needed (public.accessibility.transcribes-spoken-dialog)
+	Forced            ForcedPolicy // selection of forced subtitles when subtitles are off
+}
+
+// SelectRenditions returns audio and subtitle renditions a compliant
+// player would choose with the preferences, at most one of each group. A
+// rendition of a preferred language wins, DEFAULT=YES decides between
+// renditions of the same preference, audio falls back to DEFAULT=YES
+// rendition of the group. Renditions with accessibility characteristics
+// are chosen only when they are needed and available. Audio renditions
+// come first, groups keep the order of the playlist.
+func (p *MasterPlaylist) SelectRenditions(prefs RenditionPreferences) []*Alternative {
+	var audioGroups, subtitleGroups []string
+	groups := make(map[string][]*Alternative)
+	seen := make(map[string]bool)
+	for _, v := range p.Variants {
+		for _, alt := range v.Alternatives {
+			key := fmt.Sprintf("%s-%s-%s-%s", alt.Type, alt.GroupId, alt.Name, alt.Language)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			group := alt.Type + "/" + alt.GroupId
+			if len(groups[group]) == 0 {
+				switch alt.Type {
+				case "AUDIO":
+					audioGroups = append(audioGroups, group)
+				case "SUBTITLES":
+					subtitleGroups = append(subtitleGroups, group)
+				}
+			}
+			groups[group] = append(groups[group], alt)
+		}
+	}
+
+	var selected []*Alternative
+	audioLanguage := ""
+	for _, group := range audioGroups {
+		candidates := withCharacteristic(groups[group], DescribesVideo, prefs.DescribesVideo)
+		alt := selectByLanguage(candidates, prefs.Languages)
+		if alt == nil {
+			alt = selectDefault(candidates)
+		}
+		if alt != nil {
+			selected = append(selected, alt)
+			if audioLanguage == "" {
+				audioLanguage = alt.Language
+			}
+		}
+	}
+	if audioLanguage == "" && len(prefs.Languages) > 0 {
+		audioLanguage = prefs.Languages[0]
+	}
+	for _, group := range subtitleGroups {
+		var regular, forced []*Alternative
+		for _, alt := range groups[group] {
+			if strings.EqualFold(alt.Forced, "YES") {
+				forced = append(forced, alt)
+			} else {
+				regular = append(regular, alt)
+			}
+		}
+		var alt *Alternative
+		switch {
+		case prefs.Subtitles:
+			alt = selectByLanguage(withCharacteristic(regular, TranscribesSpokenDialog, prefs.TranscribesDialog), prefs.Languages)
+		case prefs.Forced == ForcedWithAudio && audioLanguage != "":
+			// "de" subtitles are forced for "de-DE" audio too
+			alt = selectByLanguage(forced, []string{audioLanguage, strings.SplitN(audioLanguage, "-", 2)[0]})
+		}
+		if alt != nil {
+			selected = append(selected, alt)
+		}
+	}
+	return selected
+}
+
+// withCharacteristic returns the renditions with the characteristic if it
+// is needed and any rendition has it, otherwise the renditions without it.
+// All the renditions are returned if none of them matches.
+func withCharacteristic(alts []*Alternative, characteristic string, needed bool) []*Alternative {
+	var with, without []*Alternative
+	for _, alt := range alts {
+		if hasCharacteristic(alt, characteristic) {
+			with = append(with, alt)
+		} else {
+			without = append(without, alt)
+		}
+	}
+	switch {
+	case needed && len(with) > 0:
+		return with
+	case len(without) > 0:
+		return without
+	}
+	return alts
+}
+
+// hasCharacteristic reports whether the comma separated CHARACTERISTICS of
+// the rendition contain the characteristic.
+func hasCharacteristic(alt *Alternative, characteristic string) bool {
+	for _, c := range strings.Split(alt.Characteristics, ",") {
+		if strings.TrimSpace(c) == characteristic {
+			return true
+		}
+	}
+	return false
+}
+
+// selectByLanguage returns the rendition of the first preferred language,
+// DEFAULT=YES and AUTOSELECT=YES renditions are preferred within a
+// language.
+func selectByLanguage(alts []*Alternative, languages []string) *Alternative {
+	for _, lang := range languages {
+		var matching []*Alternative
+		for _, alt := range alts {
+			if languageMatches(alt.Language, lang) {
+				matching = append(matching, alt)
+			}
+		}
+		if alt := selectDefault(matching); alt != nil {
+			return alt
+		}
+	}
+	return nil
+}
+
+// selectDefault returns DEFAULT=YES rendition, AUTOSELECT=YES rendition or
+// the first one.
+func selectDefault(alts []*Alternative) *Alternative {
+	for _, alt := range alts {
+		if alt.Default {
+			return alt
+		}
+	}
+	for _, alt := range alts {
+		if strings.EqualFold(alt.Autoselect, "YES") {
+			return alt
+		}
+	}
+	if len(alts) > 0 {
+		return alts[0]
+	}
+	return nil
+}
+
+// languageMatches reports whether the language tag of the rendition
+// matches the preferred one, so "en" matches "en-US" but not "eng".
+func languageMatches(tag, preferred string) bool {
+	if tag == "" || preferred == "" {
+		return false
+	}
+	tag, preferred = strings.ToLower(tag), strings.ToLower(preferred)
+	return tag == preferred || strings.HasPrefix(tag, preferred+"-")
+}
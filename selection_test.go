@@ -0,0 +1,53 @@
+/*
+ Package m3u8. Rendition selection tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectRenditions(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,URI="en.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English AD",LANGUAGE="en",AUTOSELECT=YES,CHARACTERISTICS="public.accessibility.describes-video",URI="en-ad.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="Deutsch",LANGUAGE="de-DE",AUTOSELECT=YES,URI="de.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",AUTOSELECT=YES,URI="sub-en.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English SDH",LANGUAGE="en",AUTOSELECT=YES,CHARACTERISTICS="public.accessibility.transcribes-spoken-dialog,public.accessibility.describes-music-and-sound",URI="sub-en-sdh.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Deutsch forced",LANGUAGE="de",FORCED=YES,URI="sub-de-forced.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,AUDIO="aud",SUBTITLES="subs"
+video.m3u8
+`
+	m := NewMasterPlaylist()
+	if err := m.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		prefs    RenditionPreferences
+		expected []string
+	}{
+		{"no preferences", RenditionPreferences{}, []string{"en.m3u8"}},
+		{"language", RenditionPreferences{Languages: []string{"fr", "de"}}, []string{"de.m3u8", "sub-de-forced.m3u8"}},
+		{"audio description", RenditionPreferences{Languages: []string{"en"}, DescribesVideo: true}, []string{"en-ad.m3u8"}},
+		{"subtitles", RenditionPreferences{Languages: []string{"en"}, Subtitles: true}, []string{"en.m3u8", "sub-en.m3u8"}},
+		{"SDH", RenditionPreferences{Languages: []string{"en"}, Subtitles: true, TranscribesDialog: true}, []string{"en.m3u8", "sub-en-sdh.m3u8"}},
+		{"no forced", RenditionPreferences{Languages: []string{"de"}, Forced: ForcedNever}, []string{"de.m3u8"}},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, alt := range m.SelectRenditions(tc.prefs) {
+			got = append(got, alt.URI)
+		}
+		if strings.Join(got, " ") != strings.Join(tc.expected, " ") {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines generation of VOD playlists for media stored in a
+ single file and addressed with byte ranges.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+)
+
+// IndexEntry describes a media segment inside of a single media file, as
+// found in a segment index (e.g. `sidx` box of MP4 or a packager report).
+type IndexEntry struct {
+	Offset   int64 // offset of the segment in the file
+	Size     int64 // size of the segment in bytes
+	Duration float64
+}
+
+// NewSingleFilePlaylist creates a closed VOD playlist of the segments of
+// the media file. Every segment gets EXT-X-BYTERANGE from the index. The
+// media initialization section is referenced by a single EXT-X-MAP when
+// initSize is not zero, it is expected at the start of the file. Segments
+// must follow each other without holes or overlaps.
+func NewSingleFilePlaylist(uri string, initSize int64, index []IndexEntry) (*MediaPlaylist, error) {
+	if len(index) == 0 {
+		return nil, errors.New("segment index is empty")
+	}
+	if initSize < 0 {
+		return nil, fmt.Errorf("invalid initialization section size %d", initSize)
+	}
+	p, err := NewMediaPlaylist(0, uint(len(index)))
+	if err != nil {
+		return nil, err
+	}
+	if initSize > 0 {
+		p.SetDefaultMap(uri, initSize, 0)
+	}
+	next := initSize
+	for i, entry := range index {
+		if entry.Size <= 0 {
+			return nil, fmt.Errorf("segment %d has invalid size %d", i, entry.Size)
+		}
+		if entry.Offset != next {
+			return nil, fmt.Errorf("segment %d starts at %d, expected %d", i, entry.Offset, next)
+		}
+		next = entry.Offset + entry.Size
+		if err = p.Append(uri, entry.Duration, ""); err != nil {
+			return nil, err
+		}
+		if err = p.SetRange(entry.Size, entry.Offset); err != nil {
+			return nil, err
+		}
+	}
+	p.MediaType = VOD
+	p.Close()
+	return p, nil
+}
@@ -0,0 +1,60 @@
+/*
+ Package m3u8. Single file playlist tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"testing"
+)
+
+func TestNewSingleFilePlaylist(t *testing.T) {
+	p, err := NewSingleFilePlaylist("movie.mp4", 800, []IndexEntry{
+		{Offset: 800, Size: 1000, Duration: 6},
+		{Offset: 1800, Size: 1200, Duration: 6},
+		{Offset: 3000, Size: 500, Duration: 2.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `#EXTM3U
+#EXT-X-VERSION:5
+#EXT-X-MAP:URI="movie.mp4",BYTERANGE=800@0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-TARGETDURATION:6
+#EXT-X-BYTERANGE:1000@800
+#EXTINF:6.000,
+movie.mp4
+#EXT-X-BYTERANGE:1200@1800
+#EXTINF:6.000,
+movie.mp4
+#EXT-X-BYTERANGE:500@3000
+#EXTINF:2.500,
+movie.mp4
+#EXT-X-ENDLIST
+`
+	if out := p.String(); out != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestNewSingleFilePlaylistContiguity(t *testing.T) {
+	if _, err := NewSingleFilePlaylist("movie.ts", 0, []IndexEntry{
+		{Offset: 0, Size: 1000, Duration: 6},
+		{Offset: 1500, Size: 1000, Duration: 6},
+	}); err == nil {
+		t.Error("Expected error for a hole between segments")
+	}
+	if _, err := NewSingleFilePlaylist("movie.mp4", 800, []IndexEntry{{Offset: 0, Size: 1000, Duration: 6}}); err == nil {
+		t.Error("Expected error for segment overlapping initialization section")
+	}
+	if _, err := NewSingleFilePlaylist("movie.ts", 0, nil); err == nil {
+		t.Error("Expected error for empty index")
+	}
+}
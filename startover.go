@@ -0,0 +1,107 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines derivation of startover playlists which let viewers
+ of a live channel restart the current program.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Startover derives an EVENT playlist beginning with the segment playing
+// at fromPDT, the start of the program. The playlist keeps media sequence
+// numbers of the source and segments appended to the source later are
+// appended to the startover playlist too, until the source is closed or
+// the startover playlist is released with RemoveStartover. Segments are
+// shared with the source, except the first one which gets the keys and
+// the map in effect and EXT-X-PROGRAM-DATE-TIME interpolated from the
+// source if it has none.
+func (p *MediaPlaylist) Startover(fromPDT time.Time) (*MediaPlaylist, error) {
+	ends, err := segmentEnds(p)
+	if err != nil {
+		return nil, err
+	}
+	if start := ends[0].Add(-seconds(p.segmentAt(0).Duration)); fromPDT.Before(start) {
+		return nil, fmt.Errorf("program start %s is before the first segment", fromPDT.Format(DATETIME))
+	}
+	first := -1
+	for i, end := range ends {
+		if end.After(fromPDT) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil, fmt.Errorf("program start %s is after the last segment", fromPDT.Format(DATETIME))
+	}
+
+	s, err := NewMediaPlaylist(0, p.count-uint(first))
+	if err != nil {
+		return nil, err
+	}
+	s.MediaType = EVENT
+	s.ver = p.ver
+	s.TargetDuration = p.TargetDuration
+	s.Key = p.Key
+	s.Map = p.Map
+	s.SeqNo = p.segmentAt(uint(first)).SeqId
+	s.DiscontinuitySeq = p.DiscontinuitySeq
+	for i := 1; i <= first; i++ {
+		if p.segmentAt(uint(i)).Discontinuity {
+			s.DiscontinuitySeq++
+		}
+	}
+	for i := first; i < len(ends); i++ {
+		seg := p.segmentAt(uint(i))
+		if i == first {
+			copied := *seg
+			if copied.ProgramDateTime.IsZero() {
+				copied.ProgramDateTime = ends[i].Add(-seconds(seg.Duration))
+			}
+			p.inheritStateAt(uint(i), &copied)
+			seg = &copied
+		}
+		if err = s.appendGrow(seg); err != nil {
+			return nil, err
+		}
+	}
+	if p.Closed {
+		s.Close()
+	} else {
+		p.startovers = append(p.startovers, s)
+	}
+	return s, nil
+}
+
+// RemoveStartover stops appending of segments of the playlist to the
+// startover playlist derived from it.
+func (p *MediaPlaylist) RemoveStartover(s *MediaPlaylist) error {
+	for i, so := range p.startovers {
+		if so == s {
+			p.startovers = append(p.startovers[:i], p.startovers[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("playlist is not a startover of the source")
+}
+
+// followStartovers appends the segment appended to the source to its
+// startover playlists.
+func (p *MediaPlaylist) followStartovers(seg *MediaSegment) error {
+	for _, s := range p.startovers {
+		if err := s.appendGrow(seg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
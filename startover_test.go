@@ -0,0 +1,116 @@
+/*
+ Package m3u8. Startover playlist tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartover(t *testing.T) {
+	start := time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC)
+	p, _ := NewMediaPlaylist(3, 3)
+	p.SeqNo = 100
+	for i := 0; i < 3; i++ {
+		p.Slide(fmt.Sprintf("seg%d.ts", 100+i), 4, "")
+	}
+	p.SetProgramDateTime(start.Add(-4 * time.Second)) // PDT of the last segment
+	p.Segments[p.last()].Discontinuity = true
+
+	// the program starts in the middle of the second segment
+	s, err := p.Startover(start.Add(-6 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MediaType != EVENT || s.Count() != 2 || s.SeqNo != 101 || s.DiscontinuitySeq != 0 {
+		t.Fatalf("Unexpected startover playlist: type %d, count %d, seq %d", s.MediaType, s.Count(), s.SeqNo)
+	}
+	if pdt := s.Segments[0].ProgramDateTime; !pdt.Equal(start.Add(-8 * time.Second)) {
+		t.Errorf("Expected interpolated PDT of the first segment, got %v", pdt)
+	}
+	if !p.Segments[1].ProgramDateTime.IsZero() {
+		t.Error("Segment of the source must not be changed")
+	}
+
+	// the source slides, the startover playlist grows
+	for i := 3; i < 6; i++ {
+		p.Slide(fmt.Sprintf("seg%d.ts", 100+i), 4, "")
+	}
+	if s.Count() != 5 || p.Count() != 3 {
+		t.Fatalf("Expected 5 segments of startover and 3 of the source, got %d and %d", s.Count(), p.Count())
+	}
+	out := s.String()
+	for _, expected := range []string{"#EXT-X-PLAYLIST-TYPE:EVENT\n", "#EXT-X-MEDIA-SEQUENCE:101\n", "seg101.ts\n", "seg105.ts\n"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in startover playlist:\n%s", expected, out)
+		}
+	}
+	p.Close()
+	if !s.Closed {
+		t.Error("Startover playlist must be closed with the source")
+	}
+}
+
+func TestStartoverEncrypted(t *testing.T) {
+	start := time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC)
+	p, _ := NewMediaPlaylist(0, 4)
+	for _, uri := range []string{"a.ts", "b.ts", "c.ts", "d.ts"} {
+		p.Append(uri, 4, "")
+		if uri == "a.ts" {
+			p.SetProgramDateTime(start)
+			p.SetKey("AES-128", "key-1", "", "", "")
+			p.SetMap("init.mp4", 0, 0)
+		}
+	}
+	s, err := p.Startover(start.Add(4 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := s.String()
+	expected := "#EXT-X-KEY:METHOD=AES-128,URI=\"key-1\"\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXT-X-PROGRAM-DATE-TIME:2020-01-01T20:00:04Z\n#EXTINF:4.000,\nb.ts\n"
+	if !strings.Contains(out, expected) {
+		t.Errorf("Expected the key and the map in effect:\n%s\ngot:\n%s", expected, out)
+	}
+	if p.Segments[1].Key != nil || p.Segments[1].Map != nil {
+		t.Error("Segment of the source must not be changed")
+	}
+}
+
+func TestStartoverOutOfPlaylist(t *testing.T) {
+	start := time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC)
+	p, _ := NewMediaPlaylist(3, 3)
+	if _, err := p.Startover(start); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("seg0.ts", 4, "")
+	p.SetProgramDateTime(start)
+	if _, err := p.Startover(start.Add(-time.Second)); err == nil {
+		t.Error("Expected error for program start before the playlist")
+	}
+	if _, err := p.Startover(start.Add(4 * time.Second)); err == nil {
+		t.Error("Expected error for program start after the playlist")
+	}
+	s, err := p.Startover(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.RemoveStartover(s); err != nil {
+		t.Fatal(err)
+	}
+	p.Append("seg1.ts", 4, "")
+	if s.Count() != 1 {
+		t.Errorf("Removed startover playlist must not follow the source, got %d segments", s.Count())
+	}
+	if err = p.RemoveStartover(s); err == nil {
+		t.Error("Expected error for removed startover playlist")
+	}
+}
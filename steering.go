@@ -0,0 +1,204 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines content steering manifests and resolution of the
+ variants a client uses following pathway priority.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var reStableID = regexp.MustCompile(`^[a-zA-Z0-9+/=._-]+$`)
+
+// DefaultPathway is the pathway of variants without PATHWAY-ID attribute.
+const DefaultPathway = "."
+
+// SteeringManifest is the content steering manifest served by the steering
+// server referenced by EXT-X-CONTENT-STEERING tag.
+type SteeringManifest struct {
+	Version         int            `json:"VERSION"`
+	TTL             int            `json:"TTL"`
+	ReloadURI       string         `json:"RELOAD-URI,omitempty"`
+	PathwayPriority []string       `json:"PATHWAY-PRIORITY"`
+	PathwayClones   []PathwayClone `json:"PATHWAY-CLONES,omitempty"`
+}
+
+// PathwayClone defines a new pathway as a copy of an existing one with
+// rewritten URIs.
+type PathwayClone struct {
+	BaseID         string         `json:"BASE-ID"`
+	ID             string         `json:"ID"`
+	URIReplacement URIReplacement `json:"URI-REPLACEMENT"`
+}
+
+// URIReplacement describes how URIs of a cloned pathway are rewritten.
+type URIReplacement struct {
+	Host             string            `json:"HOST,omitempty"`
+	Params           map[string]string `json:"PARAMS,omitempty"`
+	PerVariantURIs   map[string]string `json:"PER-VARIANT-URIS,omitempty"`   // keyed by STABLE-VARIANT-ID
+	PerRenditionURIs map[string]string `json:"PER-RENDITION-URIS,omitempty"` // keyed by STABLE-RENDITION-ID
+}
+
+// DecodeSteeringManifest parses the JSON steering manifest.
+func DecodeSteeringManifest(data []byte) (*SteeringManifest, error) {
+	m := new(SteeringManifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Version != 1 {
+		return nil, fmt.Errorf("unsupported steering manifest version %d", m.Version)
+	}
+	return m, nil
+}
+
+// Pathway returns the pathway of the variant.
+func (v *Variant) Pathway() string {
+	if v.PathwayID != "" {
+		return v.PathwayID
+	}
+	return DefaultPathway
+}
+
+// Pathways returns the variants of every pathway of the master playlist
+// including the pathways cloned by the manifest. URIs of the master
+// playlist are resolved against base when clones replace their hosts.
+// Variants are copies, the master playlist is not changed.
+func (m *SteeringManifest) Pathways(p *MasterPlaylist, base string) (map[string][]*Variant, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]*Variant)
+	for _, v := range p.Variants {
+		out[v.Pathway()] = append(out[v.Pathway()], v.copy())
+	}
+	// clones are applied in order so a clone may be based on a previous one
+	for _, clone := range m.PathwayClones {
+		source, ok := out[clone.BaseID]
+		if !ok {
+			return nil, fmt.Errorf("clone %s of unknown pathway %s", clone.ID, clone.BaseID)
+		}
+		if _, ok := out[clone.ID]; ok {
+			return nil, fmt.Errorf("clone %s duplicates existing pathway", clone.ID)
+		}
+		for _, v := range source {
+			nv := v.copy()
+			nv.PathwayID = clone.ID
+			if uri, ok := clone.URIReplacement.PerVariantURIs[v.StableVariantID]; ok && v.StableVariantID != "" {
+				nv.URI = uri
+			} else if nv.URI, err = clone.URIReplacement.rewrite(baseURL, v.URI); err != nil {
+				return nil, err
+			}
+			if len(v.Alternatives) > 0 {
+				nv.Alternatives = make([]*Alternative, len(v.Alternatives))
+				for i, alt := range v.Alternatives {
+					nalt := *alt
+					if uri, ok := clone.URIReplacement.PerRenditionURIs[alt.StableRenditionID]; ok && alt.StableRenditionID != "" {
+						nalt.URI = uri
+					} else if nalt.URI != "" {
+						if nalt.URI, err = clone.URIReplacement.rewrite(baseURL, alt.URI); err != nil {
+							return nil, err
+						}
+					}
+					nv.Alternatives[i] = &nalt
+				}
+			}
+			out[clone.ID] = append(out[clone.ID], nv)
+		}
+	}
+	return out, nil
+}
+
+// Resolve simulates a client following the pathway priority of the
+// manifest. It returns the first pathway which is not unavailable and has
+// variants together with its variants.
+func (m *SteeringManifest) Resolve(p *MasterPlaylist, base string, unavailable ...string) (string, []*Variant, error) {
+	pathways, err := m.Pathways(p, base)
+	if err != nil {
+		return "", nil, err
+	}
+	down := make(map[string]bool, len(unavailable))
+	for _, id := range unavailable {
+		down[id] = true
+	}
+	for _, id := range m.PathwayPriority {
+		if !down[id] && len(pathways[id]) > 0 {
+			return id, pathways[id], nil
+		}
+	}
+	return "", nil, errors.New("no pathway available")
+}
+
+// ValidateStableIDs checks STABLE-VARIANT-ID and STABLE-RENDITION-ID
+// attributes of the master playlist. IDs must consist of the characters
+// allowed by the specification and must be unique within a pathway, the
+// same ID in different pathways marks the same content.
+func (p *MasterPlaylist) ValidateStableIDs() error {
+	variants := make(map[string]*Variant)
+	renditions := make(map[string]string)
+	for _, v := range p.Variants {
+		if id := v.StableVariantID; id != "" {
+			if !reStableID.MatchString(id) {
+				return fmt.Errorf("invalid STABLE-VARIANT-ID %q", id)
+			}
+			key := v.Pathway() + "/" + id
+			if prev, ok := variants[key]; ok && prev != v {
+				return fmt.Errorf("STABLE-VARIANT-ID %s of variants %s and %s", id, prev.URI, v.URI)
+			}
+			variants[key] = v
+		}
+		for _, alt := range v.Alternatives {
+			id := alt.StableRenditionID
+			if id == "" {
+				continue
+			}
+			if !reStableID.MatchString(id) {
+				return fmt.Errorf("invalid STABLE-RENDITION-ID %q", id)
+			}
+			// renditions are shared by variants so compare them the same
+			// way the encoder does
+			rendition := fmt.Sprintf("%s-%s-%s-%s", alt.Type, alt.GroupId, alt.Name, alt.Language)
+			key := v.Pathway() + "/" + id
+			if prev, ok := renditions[key]; ok && prev != rendition {
+				return fmt.Errorf("STABLE-RENDITION-ID %s of renditions %s and %s", id, prev, rendition)
+			}
+			renditions[key] = rendition
+		}
+	}
+	return nil
+}
+
+// rewrite applies host and query parameter replacement to the URI.
+func (r *URIReplacement) rewrite(base *url.URL, uri string) (string, error) {
+	if r.Host == "" && len(r.Params) == 0 {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	u = base.ResolveReference(u)
+	if r.Host != "" {
+		u.Host = r.Host
+	}
+	if len(r.Params) > 0 {
+		query := u.Query()
+		for k, v := range r.Params {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
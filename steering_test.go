@@ -0,0 +1,179 @@
+/*
+ Package m3u8. Content steering tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSteeringManifest = `{
+  "VERSION": 1,
+  "TTL": 300,
+  "PATHWAY-PRIORITY": ["CDN-A", "CDN-B", "CDN-C"],
+  "PATHWAY-CLONES": [{
+    "BASE-ID": "CDN-B",
+    "ID": "CDN-C",
+    "URI-REPLACEMENT": {
+      "HOST": "backup.example.com",
+      "PARAMS": {"token": "abc"},
+      "PER-VARIANT-URIS": {"lo": "https://special.example.com/lo.m3u8"}
+    }
+  }]
+}`
+
+func testSteeringMaster() *MasterPlaylist {
+	m := NewMasterPlaylist()
+	for _, pathway := range []string{"CDN-A", "CDN-B"} {
+		for _, id := range []string{"hi", "lo"} {
+			m.Append(id+".m3u8", nil, VariantParams{Bandwidth: 1000000, PathwayID: pathway, StableVariantID: id})
+		}
+	}
+	return m
+}
+
+func TestSteeringResolve(t *testing.T) {
+	manifest, err := DecodeSteeringManifest([]byte(testSteeringManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := testSteeringMaster()
+	base := "https://b.example.com/live/master.m3u8"
+
+	pathway, variants, err := manifest.Resolve(m, base)
+	if err != nil || pathway != "CDN-A" || len(variants) != 2 {
+		t.Fatalf("Expected CDN-A, got %s %v %v", pathway, variants, err)
+	}
+
+	pathway, variants, err = manifest.Resolve(m, base, "CDN-A", "CDN-B")
+	if err != nil || pathway != "CDN-C" || len(variants) != 2 {
+		t.Fatalf("Expected CDN-C, got %s %v %v", pathway, variants, err)
+	}
+	if variants[0].URI != "https://backup.example.com/live/hi.m3u8?token=abc" {
+		t.Errorf("Unexpected rewritten URI %s", variants[0].URI)
+	}
+	if variants[1].URI != "https://special.example.com/lo.m3u8" {
+		t.Errorf("Unexpected per variant URI %s", variants[1].URI)
+	}
+	if variants[0].Pathway() != "CDN-C" || m.Variants[2].Pathway() != "CDN-B" || m.Variants[2].URI != "hi.m3u8" {
+		t.Error("Clones must not change variants of the base pathway")
+	}
+
+	if _, _, err = manifest.Resolve(m, base, "CDN-A", "CDN-B", "CDN-C"); err == nil {
+		t.Error("Expected error when all pathways are unavailable")
+	}
+}
+
+func TestDecodeSteeringManifestVersion(t *testing.T) {
+	if _, err := DecodeSteeringManifest([]byte(`{"VERSION": 2}`)); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestContentSteeringRoundTrip(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.SetContentSteering("https://steering.example.com/manifest.json", "CDN-A")
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, PathwayID: "CDN-A", StableVariantID: "hi"})
+	m.Append("hi-iframe.m3u8", nil, VariantParams{Bandwidth: 300000, Iframe: true, PathwayID: "CDN-A", StableVariantID: "hi-i"})
+	out := m.String()
+	for _, expected := range []string{
+		"#EXT-X-CONTENT-STEERING:SERVER-URI=\"https://steering.example.com/manifest.json\",PATHWAY-ID=\"CDN-A\"\n",
+		"#EXT-X-STREAM-INF:BANDWIDTH=3000000,PROGRAM-ID=0,PATHWAY-ID=\"CDN-A\",STABLE-VARIANT-ID=\"hi\"\n",
+		",PATHWAY-ID=\"CDN-A\",STABLE-VARIANT-ID=\"hi-i\",URI=\"hi-iframe.m3u8\"\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out)
+		}
+	}
+	decoded := NewMasterPlaylist()
+	if err := decoded.DecodeFrom(strings.NewReader(out), true); err != nil {
+		t.Fatal(err)
+	}
+	if *decoded.ContentSteering != *m.ContentSteering {
+		t.Errorf("Unexpected content steering %+v", decoded.ContentSteering)
+	}
+	for i, v := range decoded.Variants {
+		if v.PathwayID != "CDN-A" || v.StableVariantID != m.Variants[i].StableVariantID || len(v.ExtraAttributes) != 0 {
+			t.Errorf("Unexpected variant %+v", v.VariantParams)
+		}
+	}
+}
+
+func TestStableRenditionIDRoundTrip(t *testing.T) {
+	alt := &Alternative{Type: "AUDIO", GroupId: "aud", Name: "English", URI: "en.m3u8", StableRenditionID: "audio-en"}
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 1000000, Audio: "aud", StableVariantID: "hi", Alternatives: []*Alternative{alt}})
+	out := m.String()
+	if !strings.Contains(out, `STABLE-RENDITION-ID="audio-en",URI="en.m3u8"`) {
+		t.Fatalf("Unexpected playlist:\n%s", out)
+	}
+	decoded := NewMasterPlaylist()
+	if err := decoded.DecodeFrom(strings.NewReader(out), true); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Variants[0].Alternatives[0].StableRenditionID != "audio-en" {
+		t.Errorf("Unexpected rendition %+v", decoded.Variants[0].Alternatives[0])
+	}
+}
+
+func TestValidateStableIDs(t *testing.T) {
+	m := testSteeringMaster()
+	if err := m.ValidateStableIDs(); err != nil {
+		t.Fatalf("IDs repeated in other pathways must be valid: %v", err)
+	}
+	m.Append("other.m3u8", nil, VariantParams{Bandwidth: 500000, PathwayID: "CDN-A", StableVariantID: "lo"})
+	if err := m.ValidateStableIDs(); err == nil {
+		t.Error("Expected error for duplicate STABLE-VARIANT-ID")
+	}
+
+	m = NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 1000000, StableVariantID: "hi!"})
+	if err := m.ValidateStableIDs(); err == nil {
+		t.Error("Expected error for invalid STABLE-VARIANT-ID")
+	}
+
+	en := &Alternative{Type: "AUDIO", GroupId: "aud", Name: "English", StableRenditionID: "audio"}
+	fr := &Alternative{Type: "AUDIO", GroupId: "aud", Name: "French", StableRenditionID: "audio"}
+	m = NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 1000000, Alternatives: []*Alternative{en}})
+	m.Append("lo.m3u8", nil, VariantParams{Bandwidth: 500000, Alternatives: []*Alternative{en}})
+	if err := m.ValidateStableIDs(); err != nil {
+		t.Fatalf("Rendition shared by variants must be valid: %v", err)
+	}
+	m.Variants[1].Alternatives = append(m.Variants[1].Alternatives, fr)
+	if err := m.ValidateStableIDs(); err == nil {
+		t.Error("Expected error for duplicate STABLE-RENDITION-ID")
+	}
+}
+
+func TestSteeringPerRenditionURIs(t *testing.T) {
+	manifest := &SteeringManifest{
+		Version:         1,
+		PathwayPriority: []string{"CDN-B"},
+		PathwayClones: []PathwayClone{{
+			BaseID:         DefaultPathway,
+			ID:             "CDN-B",
+			URIReplacement: URIReplacement{PerRenditionURIs: map[string]string{"audio-en": "https://b.example.com/en.m3u8"}},
+		}},
+	}
+	alt := &Alternative{Type: "AUDIO", GroupId: "aud", Name: "English", URI: "en.m3u8", StableRenditionID: "audio-en"}
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 1000000, Audio: "aud", Alternatives: []*Alternative{alt}})
+	_, variants, err := manifest.Resolve(m, "https://a.example.com/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri := variants[0].Alternatives[0].URI; uri != "https://b.example.com/en.m3u8" {
+		t.Errorf("Unexpected rendition URI %s", uri)
+	}
+	if alt.URI != "en.m3u8" {
+		t.Error("Master playlist must not be changed")
+	}
+}
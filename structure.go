@@ -32,6 +32,7 @@ const (
 		   o  The AUDIO and VIDEO attributes of the EXT-X-STREAM-INF tag.
 	*/
 	minver   = uint8(3)
+	maxver   = uint8(12)        // the latest version known to the library, newer ones are decoded leniently
 	DATETIME = time.RFC3339Nano // Format for EXT-X-PROGRAM-DATE-TIME defined in section 3.4.5
 )
 
@@ -52,6 +53,16 @@ const (
 	VOD
 )
 
+// for EXT-X-ALLOW-CACHE tag removed from the specification but still
+// respected by legacy players
+type AllowCache uint
+
+const (
+	// use 0 for not defined value, EVENT playlists get NO then
+	AllowCacheYes AllowCache = iota + 1
+	AllowCacheNo
+)
+
 // SCTE35Syntax defines the format of the SCTE-35 cue points which do not use
 // the draft-pantos-http-live-streaming-19 EXT-X-DATERANGE tag and instead
 // have their own custom tags
@@ -61,6 +72,8 @@ const (
 	// SCTE35_67_2014 will be the default due to backwards compatibility reasons.
 	SCTE35_67_2014 SCTE35Syntax = iota // SCTE35_67_2014 defined in http://www.scte.org/documents/pdf/standards/SCTE%2067%202014.pdf
 	SCTE35_OATCLS                      // SCTE35_OATCLS is a non-standard but common format
+	SCTE35_CUE_OUT                     // SCTE35_CUE_OUT is EXT-X-CUE-OUT without EXT-OATCLS-SCTE35, e.g. DURATION=30,CAID=0x...
+	SCTE35_ADOBE                       // SCTE35_ADOBE is the Adobe EXT-X-CUE tag, e.g. ID="1",TYPE="SpliceOut",DURATION=30
 )
 
 // SCTE35CueType defines the type of cue point, used by readers and writers to
@@ -102,17 +115,23 @@ const (
    https://priv.example.com/fileSequence2682.ts
 */
 type MediaPlaylist struct {
-	TargetDuration   float64
-	SeqNo            uint64 // EXT-X-MEDIA-SEQUENCE
-	Segments         []*MediaSegment
-	Args             string // optional arguments placed after URIs (URI?Args)
-	Iframe           bool   // EXT-X-I-FRAMES-ONLY
-	Closed           bool   // is this VOD (closed) or Live (sliding) playlist?
-	MediaType        MediaType
-	DiscontinuitySeq uint64 // EXT-X-DISCONTINUITY-SEQUENCE
-	StartTime        float64
-	StartTimePrecise bool
-	durationAsInt    bool // output durations as integers of floats?
+	TargetDuration float64 `json:"targetDuration"`
+	SeqNo          uint64  `json:"seqNo"` // EXT-X-MEDIA-SEQUENCE
+	// Deprecated: Segments is the internal ring buffer of the playlist, it
+	// contains unused slots and isn't ordered for sliding playlists. Use
+	// SegmentSeq, SegmentIter, GetSegment, Count and Slide instead.
+	Segments         []*MediaSegment `json:"-"`
+	Args             string          `json:"args,omitempty"`   // optional arguments placed after URIs (URI?Args)
+	Iframe           bool            `json:"iframe,omitempty"` // EXT-X-I-FRAMES-ONLY
+	Closed           bool            `json:"closed,omitempty"` // is this VOD (closed) or Live (sliding) playlist?
+	MediaType        MediaType       `json:"mediaType,omitempty"`
+	AllowCache       AllowCache      `json:"allowCache,omitempty"`       // EXT-X-ALLOW-CACHE, not written unless set or the playlist is EVENT
+	DiscontinuitySeq uint64          `json:"discontinuitySeq,omitempty"` // EXT-X-DISCONTINUITY-SEQUENCE
+	SkippedSegments  uint64          `json:"skippedSegments,omitempty"`  // EXT-X-SKIP SKIPPED-SEGMENTS is the number of segments omitted at the head of a delta update
+	RecentlyRemoved  []string        `json:"recentlyRemoved,omitempty"`  // EXT-X-SKIP RECENTLY-REMOVED-DATERANGES are IDs of date ranges removed from the playlist recently, written by delta updates skipping date ranges
+	StartTime        float64         `json:"startTime,omitempty"`
+	StartTimePrecise bool            `json:"startTimePrecise,omitempty"`
+	durationAsInt    bool            // output durations as integers of floats?
 	keyformat        int
 	winsize          uint // max number of segments displayed in an encoded playlist; need set to zero for VOD playlists
 	capacity         uint // total capacity of slice used for the playlist
@@ -121,11 +140,36 @@ type MediaPlaylist struct {
 	count            uint // number of segments added to the playlist
 	buf              bytes.Buffer
 	ver              uint8
-	Key              *Key // EXT-X-KEY is optional encryption key displayed before any segments (default key for the playlist)
-	Map              *Map // EXT-X-MAP is optional tag specifies how to obtain the Media Initialization Section (default map for the playlist)
-	WV               *WV  // Widevine related tags outside of M3U8 specs
-	Custom           map[string]CustomTag
+	Key              *Key                 `json:"key,omitempty"`              // EXT-X-KEY is optional encryption key displayed before any segments (default key for the playlist)
+	Map              *Map                 `json:"map,omitempty"`              // EXT-X-MAP is optional tag specifies how to obtain the Media Initialization Section (default map for the playlist)
+	WV               *WV                  `json:"wv,omitempty"`               // Widevine related tags outside of M3U8 specs
+	ServerControl    *ServerControl       `json:"serverControl,omitempty"`    // EXT-X-SERVER-CONTROL is optional tag with delivery directives supported by the server (LL-HLS)
+	PartTarget       float64              `json:"partTarget,omitempty"`       // EXT-X-PART-INF PART-TARGET is maximum duration of partial segments, calculated from appended parts
+	PendingParts     []*PartialSegment    `json:"pendingParts,omitempty"`     // EXT-X-PART tags after the last segment, parts of the segment not completed yet
+	PreloadHints     []*PreloadHint       `json:"preloadHints,omitempty"`     // EXT-X-PRELOAD-HINT tags with resources the server is going to publish next (LL-HLS)
+	RenditionReports []RenditionReport    `json:"renditionReports,omitempty"` // EXT-X-RENDITION-REPORT tags with the state of peer renditions (LL-HLS)
+	Defines          []Define             `json:"defines,omitempty"`          // EXT-X-DEFINE variables of the playlist
+	Warnings         []string             `json:"warnings,omitempty"`         // problems tolerated by the decoder, e.g. duplicate header tags
+	Unknown          []UnknownTag         `json:"unknown,omitempty"`          // tags unknown to the library and comments in the header and after the last segment, see WithUnknownTags and WithComments
+	Custom           map[string]CustomTag `json:"-"`
+	customOrder      []string             // names of custom tags in order they were set
 	customDecoders   []CustomDecoder
+	vars             *Variables
+	duplicates       DuplicatePolicy
+	fidelity         bool
+	autoPDT          bool                     // derive missing EXT-X-PROGRAM-DATE-TIME on encode
+	autoDiscSeq      bool                     // increment DiscontinuitySeq when Remove drops EXT-X-DISCONTINUITY
+	frozenTarget     bool                     // TargetDuration doesn't grow with appended segments
+	durationPrec     *int                     // decimals of EXTINF durations, 3 if nil
+	encodeOpts       EncodeOptions            // byte level output options
+	attrOrders       map[interface{}][]string // decoded order of attributes of keys and maps
+	keepUnknown      bool
+	keepComments     bool
+	uriFilter        func(kind URIKind, uri string) string
+	tokens           TokenProvider
+	keys             KeyProvider      // keys of appended segments, see RotateKey
+	edge             *liveEdge        // last published segment and part for WaitForMSN
+	startovers       []*MediaPlaylist // startover playlists following the segments of the playlist
 }
 
 /*
@@ -144,97 +188,196 @@ type MediaPlaylist struct {
    http://example.com/audio-only.m3u8
 */
 type MasterPlaylist struct {
-	Variants            []*Variant
-	Args                string // optional arguments placed after URI (URI?Args)
-	CypherVersion       string // non-standard tag for Widevine (see also WV struct)
+	Variants            []*Variant `json:"variants"`
+	Args                string     `json:"args,omitempty"`          // optional arguments placed after URI (URI?Args)
+	CypherVersion       string     `json:"cypherVersion,omitempty"` // non-standard tag for Widevine (see also WV struct)
 	buf                 bytes.Buffer
 	ver                 uint8
 	independentSegments bool
-	Custom              map[string]CustomTag
+	StartTime           float64              `json:"startTime,omitempty"`        // EXT-X-START TIME-OFFSET is the preferred start point for all the variants, negative values are offsets from the end
+	StartTimePrecise    bool                 `json:"startTimePrecise,omitempty"` // EXT-X-START PRECISE=YES
+	Defines             []Define             `json:"defines,omitempty"`          // EXT-X-DEFINE variables of the playlist
+	ContentSteering     *ContentSteering     `json:"contentSteering,omitempty"`  // EXT-X-CONTENT-STEERING is optional tag referencing the steering server
+	Warnings            []string             `json:"warnings,omitempty"`         // problems tolerated by the decoder, e.g. duplicate header tags
+	Unknown             []UnknownTag         `json:"unknown,omitempty"`          // tags unknown to the library and comments, see WithUnknownTags and WithComments
+	Custom              map[string]CustomTag `json:"-"`
+	customOrder         []string             // names of custom tags in order they were set
 	customDecoders      []CustomDecoder
+	vars                *Variables
+	duplicates          DuplicatePolicy
+	keepUnknown         bool
+	keepComments        bool
+	omitProgramID       bool          // don't write deprecated PROGRAM-ID attribute
+	encodeOpts          EncodeOptions // byte level output options
+	uriFilter           func(kind URIKind, uri string) string
+	tokens              TokenProvider
 }
 
 // This structure represents variants for master playlist.
 // Variants included in a master playlist and point to media playlists.
 type Variant struct {
-	URI       string
-	Chunklist *MediaPlaylist
+	URI        string         `json:"uri"`
+	Chunklist  *MediaPlaylist `json:"chunklist,omitempty"`
+	Incomplete bool           `json:"incomplete,omitempty"` // EXT-X-STREAM-INF without BANDWIDTH was tolerated by the decoder, see EstimateBandwidth
 	VariantParams
 }
 
 // This structure represents additional parameters for a variant
 // used in EXT-X-STREAM-INF and EXT-X-I-FRAME-STREAM-INF
 type VariantParams struct {
-	ProgramId        uint32
-	Bandwidth        uint32
-	AverageBandwidth uint32 // EXT-X-STREAM-INF only
-	Codecs           string
-	Resolution       string
-	Audio            string // EXT-X-STREAM-INF only
-	Video            string
-	Subtitles        string // EXT-X-STREAM-INF only
-	Captions         string // EXT-X-STREAM-INF only
-	Name             string // EXT-X-STREAM-INF only (non standard Wowza/JWPlayer extension to name the variant/quality in UA)
-	Iframe           bool   // EXT-X-I-FRAME-STREAM-INF
-	VideoRange       string
-	HDCPLevel        string
-	FrameRate        float64        // EXT-X-STREAM-INF
-	Alternatives     []*Alternative // EXT-X-MEDIA
+	ProgramId        uint32              `json:"programId,omitempty"`
+	Bandwidth        uint32              `json:"bandwidth"`
+	AverageBandwidth uint32              `json:"averageBandwidth,omitempty"` // EXT-X-STREAM-INF only
+	Codecs           string              `json:"codecs,omitempty"`
+	Resolution       string              `json:"resolution,omitempty"`
+	Audio            string              `json:"audio,omitempty"` // EXT-X-STREAM-INF only
+	Video            string              `json:"video,omitempty"`
+	Subtitles        string              `json:"subtitles,omitempty"` // EXT-X-STREAM-INF only
+	Captions         string              `json:"captions,omitempty"`  // EXT-X-STREAM-INF only
+	Name             string              `json:"name,omitempty"`      // EXT-X-STREAM-INF only (non standard Wowza/JWPlayer extension to name the variant/quality in UA)
+	Iframe           bool                `json:"iframe,omitempty"`    // EXT-X-I-FRAME-STREAM-INF
+	Image            bool                `json:"image,omitempty"`     // EXT-X-IMAGE-STREAM-INF (non standard Roku/DVB extension for trick-play thumbnails)
+	VideoRange       string              `json:"videoRange,omitempty"`
+	HDCPLevel        string              `json:"hdcpLevel,omitempty"`
+	AllowedCPC       map[string][]string `json:"allowedCPC,omitempty"`      // ALLOWED-CPC lists Content Protection Configurations by KEYFORMAT
+	ReqVideoLayout   string              `json:"reqVideoLayout,omitempty"`  // EXT-X-STREAM-INF only, REQ-VIDEO-LAYOUT is slash separated list of video layout specifiers, e.g. CH-STEREO
+	FrameRate        float64             `json:"frameRate,omitempty"`       // EXT-X-STREAM-INF
+	Alternatives     []*Alternative      `json:"alternatives,omitempty"`    // EXT-X-MEDIA
+	PathwayID        string              `json:"pathwayID,omitempty"`       // PATHWAY-ID is the content steering pathway of the variant
+	StableVariantID  string              `json:"stableVariantID,omitempty"` // STABLE-VARIANT-ID identifies the variant across pathways
+	ExtraAttributes  map[string]string   `json:"extraAttributes,omitempty"` // EXT-X-STREAM-INF only, attributes unknown to the library (e.g. CDN-internal hints)
+	ExtraQuoted      map[string]bool     `json:"extraQuoted,omitempty"`     // names of ExtraAttributes written as quoted strings whatever their values
+}
+
+// This structure represents the steering server of the presentation
+// used to switch between pathways (e.g. CDNs).
+//
+// Realizes EXT-X-CONTENT-STEERING tag.
+type ContentSteering struct {
+	ServerURI string `json:"serverURI"`           // SERVER-URI is the URI of the steering manifest
+	PathwayID string `json:"pathwayID,omitempty"` // PATHWAY-ID is the initial pathway, optional
 }
 
 // This structure represents EXT-X-MEDIA tag in variants.
 type Alternative struct {
-	GroupId         string
-	URI             string
-	Type            string
-	Language        string
-	Name            string
-	Default         bool
-	Autoselect      string
-	Forced          string
-	InstreamID      string
-	Characteristics string
-	Channels        string
-	Subtitles       string
+	GroupId           string `json:"groupId"`
+	URI               string `json:"uri,omitempty"`
+	Type              string `json:"type"`
+	Language          string `json:"language,omitempty"`
+	AssocLanguage     string `json:"assocLanguage,omitempty"` // ASSOC-LANGUAGE is a language associated with the rendition, e.g. a spoken variant of LANGUAGE
+	Name              string `json:"name"`
+	Default           bool   `json:"default,omitempty"`
+	Autoselect        string `json:"autoselect,omitempty"`
+	Forced            string `json:"forced,omitempty"`
+	InstreamID        string `json:"instreamID,omitempty"`
+	Characteristics   string `json:"characteristics,omitempty"`
+	Channels          string `json:"channels,omitempty"`
+	Subtitles         string `json:"subtitles,omitempty"`
+	BitDepth          uint   `json:"bitDepth,omitempty"`          // BIT-DEPTH of audio samples, zero if absent
+	SampleRate        uint   `json:"sampleRate,omitempty"`        // SAMPLE-RATE of audio in Hz, zero if absent
+	StableRenditionID string `json:"stableRenditionID,omitempty"` // STABLE-RENDITION-ID identifies the rendition across pathways
+
+	// Chunklist is the media playlist of the rendition, e.g. linked by
+	// ImportBundle
+	Chunklist *MediaPlaylist `json:"chunklist,omitempty"`
 }
 
 // This structure represents a media segment included in a media playlist.
 // Media segment may be encrypted.
 // Widevine supports own tags for encryption metadata.
 type MediaSegment struct {
-	SeqId           uint64
-	Title           string // optional second parameter for EXTINF tag
-	URI             string
-	Duration        float64   // first parameter for EXTINF tag; duration must be integers if protocol version is less than 3 but we are always keep them float
-	Limit           int64     // EXT-X-BYTERANGE <n> is length in bytes for the file under URI
-	Offset          int64     // EXT-X-BYTERANGE [@o] is offset from the start of the file under URI
-	Key             *Key      // EXT-X-KEY displayed before the segment and means changing of encryption key (in theory each segment may have own key)
-	Map             *Map      // EXT-X-MAP displayed before the segment
-	Discontinuity   bool      // EXT-X-DISCONTINUITY indicates an encoding discontinuity between the media segment that follows it and the one that preceded it (i.e. file format, number and type of tracks, encoding parameters, encoding sequence, timestamp sequence)
-	SCTE            *SCTE     // SCTE-35 used for Ad signaling in HLS
-	ProgramDateTime time.Time // EXT-X-PROGRAM-DATE-TIME tag associates the first sample of a media segment with an absolute date and/or time
-	Custom          map[string]CustomTag
+	SeqId           uint64               `json:"seqId"`
+	Title           string               `json:"title,omitempty"` // optional second parameter for EXTINF tag
+	URI             string               `json:"uri"`
+	Duration        float64              `json:"duration"`                  // first parameter for EXTINF tag; duration must be integers if protocol version is less than 3 but we are always keep them float
+	Limit           int64                `json:"limit,omitempty"`           // EXT-X-BYTERANGE <n> is length in bytes for the file under URI
+	Offset          int64                `json:"offset,omitempty"`          // EXT-X-BYTERANGE [@o] is offset from the start of the file under URI
+	Key             *Key                 `json:"key,omitempty"`             // EXT-X-KEY displayed before the segment and means changing of encryption key (in theory each segment may have own key)
+	Keys            []*Key               `json:"keys,omitempty"`            // EXT-X-KEY tags of several key systems (e.g. FairPlay, Widevine and PlayReady) displayed before the segment, Key is the first of them
+	Map             *Map                 `json:"map,omitempty"`             // EXT-X-MAP displayed before the segment
+	Discontinuity   bool                 `json:"discontinuity,omitempty"`   // EXT-X-DISCONTINUITY indicates an encoding discontinuity between the media segment that follows it and the one that preceded it (i.e. file format, number and type of tracks, encoding parameters, encoding sequence, timestamp sequence)
+	Gap             bool                 `json:"gap,omitempty"`             // EXT-X-GAP indicates that the segment is missing and must not be loaded by clients
+	SCTE            []*SCTE              `json:"scte,omitempty"`            // SCTE-35 cues used for Ad signaling in HLS in order of their tags, see SCTE35 for the single cue
+	ProgramDateTime time.Time            `json:"programDateTime,omitempty"` // EXT-X-PROGRAM-DATE-TIME tag associates the first sample of a media segment with an absolute date and/or time
+	DateRanges      []*DateRange         `json:"dateRanges,omitempty"`      // EXT-X-DATERANGE tags displayed before the segment
+	Tiles           *Tiles               `json:"tiles,omitempty"`           // EXT-X-TILES describes the thumbnail grid of an image segment
+	Parts           []*PartialSegment    `json:"parts,omitempty"`           // EXT-X-PART tags of the segment used by Low-Latency HLS
+	Unknown         []string             `json:"unknown,omitempty"`         // tags unknown to the library and comments in front of the segment, see WithUnknownTags and WithComments
+	Custom          map[string]CustomTag `json:"-"`
+	duration        time.Duration        // exact duration of the segment appended by AppendDuration
+	customOrder     []string             // names of custom tags in order they were set
+}
+
+// This structure represents a partial segment (EXT-X-PART tag) of
+// a media segment used by Low-Latency HLS.
+type PartialSegment struct {
+	URI         string  `json:"uri"`
+	Duration    float64 `json:"duration"`              // DURATION attribute
+	Independent bool    `json:"independent,omitempty"` // INDEPENDENT=YES if the part contains an independent frame
+	Limit       int64   `json:"limit,omitempty"`       // BYTERANGE <n> is length in bytes for the file under URI
+	Offset      int64   `json:"offset,omitempty"`      // BYTERANGE [@o] is offset from the start of the file under URI
+	Gap         bool    `json:"gap,omitempty"`         // GAP=YES if the part is not available
+	Key         *Key    `json:"key,omitempty"`         // EXT-X-KEY displayed before the part, used when the key changes in the middle of the segment
 }
 
 // SCTE holds custom, non EXT-X-DATERANGE, SCTE-35 tags
 type SCTE struct {
-	Syntax  SCTE35Syntax  // Syntax defines the format of the SCTE-35 cue tag
-	CueType SCTE35CueType // CueType defines whether the cue is a start, mid, end (if applicable)
-	Cue     string
-	ID      string
-	Time    float64
-	Elapsed float64
+	Syntax  SCTE35Syntax  `json:"syntax,omitempty"`  // Syntax defines the format of the SCTE-35 cue tag
+	CueType SCTE35CueType `json:"cueType,omitempty"` // CueType defines whether the cue is a start, mid, end (if applicable)
+	Cue     string        `json:"cue"`
+	ID      string        `json:"id,omitempty"`
+	Time    float64       `json:"time,omitempty"`
+	Elapsed float64       `json:"elapsed,omitempty"`
+	// Attributes holds other attributes of EXT-X-CUE-OUT and EXT-X-CUE
+	// attribute lists, e.g. CAID
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// AttributesQuoted holds names of Attributes written as quoted
+	// strings whatever their values
+	AttributesQuoted map[string]bool `json:"attributesQuoted,omitempty"`
+}
+
+// This structure represents delivery directives supported by the
+// server for Low-Latency HLS.
+//
+// Realizes EXT-X-SERVER-CONTROL tag.
+type ServerControl struct {
+	CanSkipUntil      float64 `json:"canSkipUntil,omitempty"`      // CAN-SKIP-UNTIL is the skip boundary in seconds for playlist delta updates
+	CanSkipDateRanges bool    `json:"canSkipDateRanges,omitempty"` // CAN-SKIP-DATERANGES=YES if delta updates may skip older EXT-X-DATERANGE tags
+	HoldBack          float64 `json:"holdBack,omitempty"`          // HOLD-BACK is the minimum distance in seconds from the end of the playlist to start playback
+	PartHoldBack      float64 `json:"partHoldBack,omitempty"`      // PART-HOLD-BACK is HOLD-BACK for playback in low-latency mode
+	CanBlockReload    bool    `json:"canBlockReload,omitempty"`    // CAN-BLOCK-RELOAD=YES if the server supports blocking playlist reload
+}
+
+// This structure represents a resource of the media playlist which is not
+// available yet, so clients may request it ahead of time.
+//
+// Realizes EXT-X-PRELOAD-HINT tag.
+type PreloadHint struct {
+	Type   string `json:"type"` // PART or MAP
+	URI    string `json:"uri"`
+	Start  int64  `json:"start,omitempty"`  // BYTERANGE-START is the offset of the resource, zero if absent
+	Length int64  `json:"length,omitempty"` // BYTERANGE-LENGTH is the length of the resource, zero if unknown
+}
+
+// This structure represents the state of another rendition of the
+// presentation for Low-Latency HLS.
+//
+// Realizes EXT-X-RENDITION-REPORT tag.
+type RenditionReport struct {
+	URI      string `json:"uri"`
+	LastMSN  uint64 `json:"lastMSN"`            // LAST-MSN is the media sequence number of the last segment of the rendition
+	LastPart *int   `json:"lastPart,omitempty"` // LAST-PART is the index of the last part of the rendition, nil if absent
 }
 
 // This structure represents information about stream encryption.
 //
 // Realizes EXT-X-KEY tag.
 type Key struct {
-	Method            string
-	URI               string
-	IV                string
-	Keyformat         string
-	Keyformatversions string
+	Method            string `json:"method"`
+	URI               string `json:"uri,omitempty"`
+	IV                string `json:"iv,omitempty"`
+	Keyformat         string `json:"keyformat,omitempty"`
+	Keyformatversions string `json:"keyformatversions,omitempty"`
 }
 
 // This structure represents specifies how to obtain the Media
@@ -247,28 +390,39 @@ type Key struct {
 //
 // Realizes EXT-MAP tag.
 type Map struct {
-	URI    string
-	Limit  int64 // <n> is length in bytes for the file under URI
-	Offset int64 // [@o] is offset from the start of the file under URI
+	URI    string `json:"uri"`
+	Limit  int64  `json:"limit,omitempty"`  // <n> is length in bytes for the file under URI
+	Offset int64  `json:"offset,omitempty"` // [@o] is offset from the start of the file under URI
+	Key    *Key   `json:"key,omitempty"`    // EXT-X-KEY which encrypts the Media Initialization Section, nil if it is not encrypted
+}
+
+// This structure represents the grid of thumbnails of an image media
+// segment used in playlists of I-frame image streams.
+//
+// Realizes EXT-X-TILES tag.
+type Tiles struct {
+	Resolution string  `json:"resolution,omitempty"` // RESOLUTION of a single tile, e.g. 312x180
+	Layout     string  `json:"layout,omitempty"`     // LAYOUT of the grid as columns x rows, e.g. 5x2
+	Duration   float64 `json:"duration,omitempty"`   // DURATION of a single tile in seconds
 }
 
 // This structure represents metadata  for Google Widevine playlists.
 // This format not described in IETF draft but provied by Widevine Live Packager as
 // additional tags with #WV-prefix.
 type WV struct {
-	AudioChannels          uint
-	AudioFormat            uint
-	AudioProfileIDC        uint
-	AudioSampleSize        uint
-	AudioSamplingFrequency uint
-	CypherVersion          string
-	ECM                    string
-	VideoFormat            uint
-	VideoFrameRate         uint
-	VideoLevelIDC          uint
-	VideoProfileIDC        uint
-	VideoResolution        string
-	VideoSAR               string
+	AudioChannels          uint   `json:"audioChannels,omitempty"`
+	AudioFormat            uint   `json:"audioFormat,omitempty"`
+	AudioProfileIDC        uint   `json:"audioProfileIDC,omitempty"`
+	AudioSampleSize        uint   `json:"audioSampleSize,omitempty"`
+	AudioSamplingFrequency uint   `json:"audioSamplingFrequency,omitempty"`
+	CypherVersion          string `json:"cypherVersion,omitempty"`
+	ECM                    string `json:"ecm,omitempty"`
+	VideoFormat            uint   `json:"videoFormat,omitempty"`
+	VideoFrameRate         uint   `json:"videoFrameRate,omitempty"`
+	VideoLevelIDC          uint   `json:"videoLevelIDC,omitempty"`
+	VideoProfileIDC        uint   `json:"videoProfileIDC,omitempty"`
+	VideoResolution        string `json:"videoResolution,omitempty"`
+	VideoSAR               string `json:"videoSAR,omitempty"`
 }
 
 // Interface applied to various playlist types.
@@ -293,6 +447,25 @@ type CustomDecoder interface {
 	SegmentTag() bool
 }
 
+// StatefulCustomDecoder is a CustomDecoder of multi-line vendor tags. The
+// decoder begins with the line starting with TagName and accumulates the
+// following lines until Continue refuses one, then End returns the tag.
+// The tag is attached to the playlist or, if SegmentTag returns true, to
+// the upcoming segment. Lines of the tag are consumed by the decoder and
+// are not decoded by the library, Decode of the decoder is not called.
+type StatefulCustomDecoder interface {
+	CustomDecoder
+	// Begin starts decoding of a tag with its first line.
+	Begin(line string) error
+	// Continue passes the next line to the decoder, it returns false if
+	// the line doesn't belong to the tag. Such line is decoded as usual
+	// after the tag ends.
+	Continue(line string) (bool, error)
+	// End completes the tag, it is called before the next tag or at the
+	// end of the playlist. Return nil tag to drop it.
+	End() (CustomTag, error)
+}
+
 // Interface for encoding custom and unsupported tags
 type CustomTag interface {
 	// TagName should return the full indentifier including the leading '#' as well as the
@@ -306,6 +479,36 @@ type CustomTag interface {
 	String() string
 }
 
+// TagPlacement tells where a custom tag is written in the playlist.
+type TagPlacement uint8
+
+const (
+	// PlacementDefault writes playlist tags in the header and segment tags
+	// in front of EXTINF of their segment.
+	PlacementDefault TagPlacement = iota
+	// PlacementHeader writes playlist tags in the header and segment tags
+	// in front of other tags of their segment.
+	PlacementHeader
+	// PlacementAfterEXTINF writes segment tags right after EXTINF of their
+	// segment. Playlist tags are written in the header.
+	PlacementAfterEXTINF
+	// PlacementBeforeURI writes segment tags right before URI of their
+	// segment, after the tags placed after EXTINF. Playlist tags are
+	// written in the header.
+	PlacementBeforeURI
+	// PlacementFooter writes playlist tags after the last segment or
+	// variant and segment tags after URI of their segment. The decoder
+	// attaches segment tags of this placement to the segment before them.
+	PlacementFooter
+)
+
+// CustomTagPlacer is implemented by custom tags which are written elsewhere
+// than at their default place, see TagPlacement.
+type CustomTagPlacer interface {
+	CustomTag
+	Placement() TagPlacement
+}
+
 // Internal structure for decoding a line of input stream with a list type detection
 type decodingState struct {
 	listType           ListType
@@ -313,9 +516,9 @@ type decodingState struct {
 	tagWV              bool
 	tagStreamInf       bool
 	tagInf             bool
-	tagSCTE35          bool
 	tagRange           bool
 	tagDiscontinuity   bool
+	tagGap             bool
 	tagProgramDateTime bool
 	tagKey             bool
 	tagMap             bool
@@ -328,7 +531,22 @@ type decodingState struct {
 	variant            *Variant
 	alternatives       []*Alternative
 	xkey               *Key
+	xkeys              []*Key   // consecutive EXT-X-KEY tags of the segment
+	unknown            []string // unknown tags and comments waiting for the next segment
 	xmap               *Map
-	scte               *SCTE
+	keyAttrs           []string
+	mapAttrs           []string
+	scte               *SCTE // EXT-OATCLS-SCTE35 cue waiting for EXT-X-CUE-OUT
+	sctes              []*SCTE
+	dateRanges         []*DateRange
+	masterTags         map[string]bool
+	mediaTags          map[string]bool
+	tiles              *Tiles
 	custom             map[string]CustomTag
+	customOrder        []string
+	multiline          StatefulCustomDecoder // decoder of the multi-line custom tag being decoded
+	skipping           bool                  // segments up to skipThrough are not built, see DecodeUpdate
+	skipThrough        uint64
+	skipped            uint64 // number of segments not built
+	vars               map[string]string
 }
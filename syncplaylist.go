@@ -0,0 +1,138 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines the media playlist safe for concurrent use, so live
+ origins may append segments and serve the playlist from several
+ goroutines.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// SyncMediaPlaylist wraps a media playlist with a lock, its methods may be
+// called from multiple goroutines. Other methods of the playlist are
+// available through Do and View.
+type SyncMediaPlaylist struct {
+	mu sync.RWMutex
+	p  *MediaPlaylist
+}
+
+// NewSyncMediaPlaylist creates a new media playlist safe for concurrent
+// use, see NewMediaPlaylist.
+func NewSyncMediaPlaylist(winsize uint, capacity uint) (*SyncMediaPlaylist, error) {
+	p, err := NewMediaPlaylist(winsize, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncMediaPlaylist{p: p}, nil
+}
+
+// SyncMedia wraps the media playlist, it must not be used directly after
+// that.
+func SyncMedia(p *MediaPlaylist) *SyncMediaPlaylist {
+	return &SyncMediaPlaylist{p: p}
+}
+
+// Append appends a general chunk, see MediaPlaylist.Append.
+func (s *SyncMediaPlaylist) Append(uri string, duration float64, title string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.p.Append(uri, duration, title)
+}
+
+// AppendSegment appends the segment, see MediaPlaylist.AppendSegment.
+func (s *SyncMediaPlaylist) AppendSegment(seg *MediaSegment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.p.AppendSegment(seg)
+}
+
+// Remove removes the oldest segment, see MediaPlaylist.Remove.
+func (s *SyncMediaPlaylist) Remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.p.Remove()
+}
+
+// Slide removes the oldest segment and appends a new one, see
+// MediaPlaylist.Slide.
+func (s *SyncMediaPlaylist) Slide(uri string, duration float64, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.Slide(uri, duration, title)
+}
+
+// Close closes the playlist, see MediaPlaylist.Close.
+func (s *SyncMediaPlaylist) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.Close()
+}
+
+// Encode returns a copy of the playlist in M3U8 format. Readers share the
+// cached output and only the first one after a change encodes the
+// playlist.
+func (s *SyncMediaPlaylist) Encode() []byte {
+	s.mu.RLock()
+	if s.p.buf.Len() > 0 && s.p.tokens == nil {
+		out := append([]byte(nil), s.p.buf.Bytes()...)
+		s.mu.RUnlock()
+		return out
+	}
+	s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.p.Encode().Bytes()...)
+}
+
+// EncodeTo writes the playlist in M3U8 format to the buffer, see
+// MediaPlaylist.EncodeTo.
+func (s *SyncMediaPlaylist) EncodeTo(buf *bytes.Buffer) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.p.EncodeTo(buf)
+}
+
+// String returns the playlist in M3U8 format.
+func (s *SyncMediaPlaylist) String() string {
+	return string(s.Encode())
+}
+
+// Count returns the number of segments of the playlist.
+func (s *SyncMediaPlaylist) Count() uint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.p.Count()
+}
+
+// WaitForMSN blocks until the segment or its part is appended, see
+// MediaPlaylist.WaitForMSN. It doesn't hold the lock while waiting.
+func (s *SyncMediaPlaylist) WaitForMSN(ctx context.Context, msn uint64, part int) error {
+	return s.p.WaitForMSN(ctx, msn, part)
+}
+
+// Do calls fn with the playlist locked for changes. The playlist must not
+// be kept after fn returns.
+func (s *SyncMediaPlaylist) Do(fn func(p *MediaPlaylist) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.p)
+}
+
+// View calls fn with the playlist locked for reading, fn must not change
+// the playlist nor encode it with Encode which fills the cache.
+func (s *SyncMediaPlaylist) View(fn func(p *MediaPlaylist) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.p)
+}
@@ -0,0 +1,88 @@
+/*
+ Package m3u8. Concurrent playlist tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncMediaPlaylist(t *testing.T) {
+	s, err := NewSyncMediaPlaylist(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.Slide(fmt.Sprintf("seg%d.ts", i), 4, "")
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			for i := 0; i < 100; i++ {
+				if out := s.String(); !strings.HasPrefix(out, "#EXTM3U\n") {
+					t.Errorf("Unexpected playlist %q", out)
+				}
+				buf.Reset()
+				s.EncodeTo(&buf)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.Do(func(p *MediaPlaylist) error {
+		return p.SetDiscontinuity()
+	})
+	s.View(func(p *MediaPlaylist) error {
+		if seg, _ := p.GetSegment(p.Count() - 1); seg.URI != "seg99.ts" || !seg.Discontinuity {
+			t.Errorf("Unexpected last segment %+v", seg)
+		}
+		return nil
+	})
+	if !strings.Contains(s.String(), "#EXT-X-DISCONTINUITY\n#EXTINF:4.000,\nseg99.ts\n") {
+		t.Errorf("Unexpected playlist:\n%s", s.String())
+	}
+}
+
+func TestSyncMediaPlaylistAutoProgramDateTime(t *testing.T) {
+	s, _ := NewSyncMediaPlaylist(3, 5)
+	s.Do(func(p *MediaPlaylist) error {
+		p.Append("seg0.ts", 4, "")
+		p.SetProgramDateTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		p.Append("seg1.ts", 4, "")
+		p.SetAutoProgramDateTime(true)
+		return nil
+	})
+	// concurrent encoding of derived dates must not write to segments,
+	// run with -race
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			s.EncodeTo(&buf)
+			if !strings.Contains(buf.String(), "#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:04Z\n") {
+				t.Errorf("Expected derived date, got:\n%s", buf.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,125 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines wall clock timeline of media playlists, e.g. for DVR
+ windows and catch-up TV.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Duration returns the total duration of segments of the playlist, e.g.
+// the length of the DVR window of a sliding playlist.
+func (p *MediaPlaylist) Duration() time.Duration {
+	var total time.Duration
+	for i := uint(0); i < p.count; i++ {
+		if seg := p.segmentAt(i); seg != nil {
+			total += seg.DurationD()
+		}
+	}
+	return total
+}
+
+// DurationD returns the duration of the segment as time.Duration. It is
+// the exact duration passed to AppendDuration unless Duration was changed
+// since, otherwise Duration rounded to nanoseconds.
+func (seg *MediaSegment) DurationD() time.Duration {
+	if seg.duration != 0 && seg.duration.Seconds() == seg.Duration {
+		return seg.duration
+	}
+	return time.Duration(math.Round(seg.Duration * float64(time.Second)))
+}
+
+// EndTime returns the wall clock time of the end of the last segment, the
+// live edge. It is calculated from EXT-X-PROGRAM-DATE-TIME of the segments
+// and their durations, at least one segment must have the tag.
+func (p *MediaPlaylist) EndTime() (time.Time, error) {
+	ends, err := segmentEnds(p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ends[len(ends)-1], nil
+}
+
+// SegmentAtTime returns the segment playing at the wall clock time t and
+// the offset of t from the start of the segment. Segments without
+// EXT-X-PROGRAM-DATE-TIME get it interpolated from the nearest segment
+// with the tag and durations in between.
+func (p *MediaPlaylist) SegmentAtTime(t time.Time) (*MediaSegment, time.Duration, error) {
+	ends, err := segmentEnds(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	if start := ends[0].Add(-seconds(p.segmentAt(0).Duration)); t.Before(start) {
+		return nil, 0, fmt.Errorf("time %s is before the first segment", t.Format(DATETIME))
+	}
+	for i, end := range ends {
+		if end.After(t) {
+			seg := p.segmentAt(uint(i))
+			return seg, t.Sub(end.Add(-seconds(seg.Duration))), nil
+		}
+	}
+	return nil, 0, fmt.Errorf("time %s is after the last segment", t.Format(DATETIME))
+}
+
+// SetAutoProgramDateTime enables derivation of EXT-X-PROGRAM-DATE-TIME of
+// all segments on encode, see FillProgramDateTimes. Derived dates are only
+// written, the segments are left as they are.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetAutoProgramDateTime(enabled bool) {
+	p.autoPDT = enabled
+	p.buf.Reset()
+}
+
+// FillProgramDateTimes sets EXT-X-PROGRAM-DATE-TIME of segments without
+// it from the nearest segment with the tag and durations in between,
+// forward and backward. Dates don't cross EXT-X-DISCONTINUITY, segments
+// of a period without any date are left as they are.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) FillProgramDateTimes() {
+	for i, date := range p.programDateTimes() {
+		p.segmentAt(uint(i)).ProgramDateTime = date
+	}
+	p.buf.Reset()
+}
+
+// programDateTimes returns the dates of the segments of the window with
+// the missing ones derived, see FillProgramDateTimes.
+func (p *MediaPlaylist) programDateTimes() []time.Time {
+	dates := make([]time.Time, p.count)
+	for i := range dates {
+		dates[i] = p.segmentAt(uint(i)).ProgramDateTime
+	}
+	for first := uint(0); first < p.count; {
+		last := first + 1
+		for last < p.count && !p.segmentAt(last).Discontinuity {
+			last++
+		}
+		known := first
+		for known < last && dates[known].IsZero() {
+			known++
+		}
+		if known < last {
+			for i := known + 1; i < last; i++ {
+				if dates[i].IsZero() {
+					dates[i] = dates[i-1].Add(seconds(p.segmentAt(i - 1).Duration))
+				}
+			}
+			for i := known; i > first; i-- {
+				dates[i-1] = dates[i].Add(-seconds(p.segmentAt(i - 1).Duration))
+			}
+		}
+		first = last
+	}
+	return dates
+}
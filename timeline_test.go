@@ -0,0 +1,146 @@
+/*
+ Package m3u8. Playlist timeline tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationAndEndTime(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	if d := p.Duration(); d != 0 {
+		t.Errorf("Expected zero duration of empty playlist, got %s", d)
+	}
+	if _, err := p.EndTime(); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		p.Slide("seg.ts", 4.5, "")
+	}
+	// the window keeps the last three segments
+	if d := p.Duration(); d != 13500*time.Millisecond {
+		t.Errorf("Expected 13.5s, got %s", d)
+	}
+	if _, err := p.EndTime(); err == nil {
+		t.Error("Expected error for playlist without EXT-X-PROGRAM-DATE-TIME")
+	}
+	// PDT of the second segment is interpolated backwards for the first one
+	p.segmentAt(1).ProgramDateTime = start
+	end, err := p.EndTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := start.Add(9 * time.Second); !end.Equal(expected) {
+		t.Errorf("Expected end %s, got %s", expected, end)
+	}
+}
+
+func TestSegmentAtTime(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 4)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.Append("seg0.ts", 4, "")
+	p.Append("seg1.ts", 6, "")
+	p.Append("seg2.ts", 4, "")
+	// a PDT in the middle, the first segment is interpolated backwards
+	p.segmentAt(1).ProgramDateTime = start.Add(4 * time.Second)
+	cases := []struct {
+		at     time.Duration
+		uri    string
+		offset time.Duration
+	}{
+		{0, "seg0.ts", 0},
+		{3 * time.Second, "seg0.ts", 3 * time.Second},
+		{4 * time.Second, "seg1.ts", 0},
+		{11500 * time.Millisecond, "seg2.ts", 1500 * time.Millisecond},
+	}
+	for _, c := range cases {
+		seg, offset, err := p.SegmentAtTime(start.Add(c.at))
+		if err != nil {
+			t.Fatalf("%s: %s", c.at, err)
+		}
+		if seg.URI != c.uri || offset != c.offset {
+			t.Errorf("%s: expected %s at %s, got %s at %s", c.at, c.uri, c.offset, seg.URI, offset)
+		}
+	}
+	if _, _, err := p.SegmentAtTime(start.Add(-time.Second)); err == nil {
+		t.Error("Expected error for time before the first segment")
+	}
+	if _, _, err := p.SegmentAtTime(start.Add(14 * time.Second)); err == nil {
+		t.Error("Expected error for time after the last segment")
+	}
+}
+
+func TestFillProgramDateTimes(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 6)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.Append("seg0.ts", 4, "")
+	p.Append("seg1.ts", 4, "")
+	p.SetProgramDateTime(start.Add(4 * time.Second))
+	p.Append("seg2.ts", 4, "")
+	p.Append("seg3.ts", 4, "")
+	p.SetDiscontinuity()
+	p.Append("seg4.ts", 4, "")
+	p.Append("seg5.ts", 4, "")
+	p.SetDiscontinuity()
+	p.SetProgramDateTime(start.Add(time.Hour))
+	p.Encode()
+
+	p.FillProgramDateTimes()
+	expected := []time.Time{start, start.Add(4 * time.Second), start.Add(8 * time.Second), {}, {}, start.Add(time.Hour)}
+	for i, pdt := range expected {
+		if got := p.segmentAt(uint(i)).ProgramDateTime; !got.Equal(pdt) {
+			t.Errorf("Segment %d: expected %s, got %s", i, pdt, got)
+		}
+	}
+}
+
+func TestEncodeWithAutoProgramDateTime(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	p.Append("seg0.ts", 4, "")
+	p.SetProgramDateTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	p.Append("seg1.ts", 4, "")
+	p.SetAutoProgramDateTime(true)
+	expected := "#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:04Z\n#EXTINF:4.000,\nseg1.ts\n"
+	if out := p.String(); !strings.Contains(out, expected) {
+		t.Errorf("Expected derived date:\n%s\ngot:\n%s", expected, out)
+	}
+	// encoding doesn't write to segments shared e.g. with derived playlists
+	if date := p.segmentAt(1).ProgramDateTime; !date.IsZero() {
+		t.Errorf("Expected the date of the segment unchanged, got %s", date)
+	}
+}
+
+func TestAppendDuration(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 10000)
+	d := 6006 * time.Millisecond
+	for i := 0; i < 10000; i++ {
+		if err := p.AppendDuration("seg.ts", d, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total := p.Duration(); total != 10000*d {
+		t.Errorf("Expected %s, got %s", 10000*d, total)
+	}
+	seg := p.segmentAt(0)
+	if seg.Duration != 6.006 || seg.DurationD() != d {
+		t.Errorf("Expected 6.006s, got %v and %s", seg.Duration, seg.DurationD())
+	}
+	if !strings.Contains(p.String(), "#EXTINF:6.006,") {
+		t.Error("Expected EXTINF of 6.006s")
+	}
+	// changed float duration takes precedence over the exact one
+	seg.Duration = 2.5
+	if seg.DurationD() != 2500*time.Millisecond {
+		t.Errorf("Expected 2.5s, got %s", seg.DurationD())
+	}
+}
@@ -0,0 +1,138 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines keeping of tags unknown to the library and of comments,
+ so playlists of packagers with vendor tags and operator annotations
+ survive decoding and encoding.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import (
+	"bytes"
+	"strings"
+)
+
+// TagPosition tells where an unknown tag appeared in the playlist.
+type TagPosition uint8
+
+const (
+	// HeaderPosition is the header of the playlist before the first segment or variant.
+	HeaderPosition TagPosition = iota
+	// SegmentPosition is in front of a segment, such tags are kept by the segment.
+	SegmentPosition
+	// TrailerPosition is after the last segment or variant.
+	TrailerPosition
+)
+
+// UnknownTag is a tag the decoder doesn't recognize, a comment or a blank
+// line, it is encoded verbatim at its position.
+type UnknownTag struct {
+	Line     string      `json:"line"`
+	Position TagPosition `json:"position"`
+}
+
+// Tags decoded by the library, other tags starting with #EXT are unknown.
+var knownTags = map[string]bool{
+	"#EXTM3U": true, "#EXTINF": true,
+	"#EXT-X-VERSION": true, "#EXT-X-DEFINE": true, "#EXT-X-INDEPENDENT-SEGMENTS": true, "#EXT-X-START": true,
+	"#EXT-X-CONTENT-STEERING": true, "#EXT-X-MEDIA": true, "#EXT-X-STREAM-INF": true,
+	"#EXT-X-I-FRAME-STREAM-INF": true, "#EXT-X-IMAGE-STREAM-INF": true,
+	"#EXT-X-TARGETDURATION": true, "#EXT-X-MEDIA-SEQUENCE": true, "#EXT-X-DISCONTINUITY-SEQUENCE": true,
+	"#EXT-X-PLAYLIST-TYPE": true, "#EXT-X-ALLOW-CACHE": true, "#EXT-X-I-FRAMES-ONLY": true, "#EXT-X-ENDLIST": true,
+	"#EXT-X-SERVER-CONTROL": true, "#EXT-X-PART-INF": true, "#EXT-X-PART": true, "#EXT-X-PRELOAD-HINT": true,
+	"#EXT-X-RENDITION-REPORT": true, "#EXT-X-SKIP": true,
+	"#EXT-X-KEY": true, "#EXT-X-MAP": true, "#EXT-X-BYTERANGE": true, "#EXT-X-DISCONTINUITY": true, "#EXT-X-GAP": true,
+	"#EXT-X-PROGRAM-DATE-TIME": true, "#EXT-X-DATERANGE": true, "#EXT-X-TILES": true,
+	"#EXT-SCTE35": true, "#EXT-OATCLS-SCTE35": true, "#EXT-X-CUE-OUT": true, "#EXT-X-CUE-OUT-CONT": true, "#EXT-X-CUE-IN": true,
+	"#EXT-X-CUE": true,
+}
+
+// WithUnknownTags sets whether the decoder keeps tags unknown to the
+// library in Unknown fields of the master playlist and its variants.
+func (p *MasterPlaylist) WithUnknownTags(keep bool) Playlist {
+	p.keepUnknown = keep
+	return p
+}
+
+// WithUnknownTags sets whether the decoder keeps tags unknown to the
+// library in Unknown fields of the media playlist and its segments.
+func (p *MediaPlaylist) WithUnknownTags(keep bool) Playlist {
+	p.keepUnknown = keep
+	return p
+}
+
+// WithComments sets whether the decoder keeps comments and blank lines of
+// the master playlist. They are kept in Unknown fields together with
+// unknown tags in the order of the playlist.
+func (p *MasterPlaylist) WithComments(keep bool) Playlist {
+	p.keepComments = keep
+	return p
+}
+
+// WithComments sets whether the decoder keeps comments and blank lines of
+// the media playlist. They are kept in Unknown fields of the playlist and
+// of the segments together with unknown tags in the order of the playlist.
+func (p *MediaPlaylist) WithComments(keep bool) Playlist {
+	p.keepComments = keep
+	return p
+}
+
+// keepsLine reports whether the decoder keeps the line verbatim instead of
+// decoding it. Blank lines are only kept with comments.
+func keepsLine(line string, blank, unknown, comments bool, customDecoders []CustomDecoder) bool {
+	if comments && (blank || isComment(line, customDecoders)) {
+		return true
+	}
+	return unknown && isUnknownTag(line, customDecoders)
+}
+
+// isComment reports whether the line is a comment, that is it starts with
+// # but it is neither a tag nor a tag of custom decoders.
+func isComment(line string, customDecoders []CustomDecoder) bool {
+	if !strings.HasPrefix(line, "#") || strings.HasPrefix(line, "#EXT") || strings.HasPrefix(line, "#WV-") {
+		return false
+	}
+	for _, v := range customDecoders {
+		if strings.HasPrefix(line, v.TagName()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnknownTag reports whether the line is a tag which neither the library
+// nor the custom decoders decode. Comments are not tags.
+func isUnknownTag(line string, customDecoders []CustomDecoder) bool {
+	if !strings.HasPrefix(line, "#EXT") {
+		return false
+	}
+	name := line
+	if i := strings.IndexByte(line, ':'); i != -1 {
+		name = line[:i]
+	}
+	if knownTags[name] || strings.HasPrefix(name, "#WV-") {
+		return false
+	}
+	for _, v := range customDecoders {
+		if strings.HasPrefix(line, v.TagName()) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeUnknownTags writes the unknown tags of the position.
+func writeUnknownTags(buf *bytes.Buffer, tags []UnknownTag, position TagPosition, eol string) {
+	for _, tag := range tags {
+		if tag.Position == position {
+			buf.WriteString(tag.Line)
+			buf.WriteString(eol)
+		}
+	}
+}
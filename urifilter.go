@@ -0,0 +1,118 @@
+package m3u8
+
+/*
+ Part of M3U8 parser & generator library.
+ This file defines rewriting of URIs and their tokens during encoding, so
+ proxies may absolutize or sign URIs without changing the playlists.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+
+import "strings"
+
+// URIKind tells which tag or line the URI passed to the URI filter
+// belongs to.
+type URIKind uint8
+
+const (
+	URISegment         URIKind = iota // URI line of a media segment
+	URIPart                           // EXT-X-PART
+	URIPreloadHint                    // EXT-X-PRELOAD-HINT
+	URIRenditionReport                // EXT-X-RENDITION-REPORT
+	URIKey                            // EXT-X-KEY
+	URIMap                            // EXT-X-MAP
+	URIVariant                        // URI line of EXT-X-STREAM-INF, EXT-X-I-FRAME-STREAM-INF and EXT-X-IMAGE-STREAM-INF
+	URIRendition                      // EXT-X-MEDIA
+)
+
+// SetURIFilter sets the function which rewrites URIs of variants and
+// renditions when the playlist is encoded, nil removes it. Args are
+// appended to the rewritten URI.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SetURIFilter(filter func(kind URIKind, uri string) string) {
+	p.uriFilter = filter
+	p.buf.Reset()
+}
+
+// SetURIFilter sets the function which rewrites URIs of segments, parts,
+// keys, maps, preload hints and rendition reports when the playlist is
+// encoded, nil removes it. Args are appended to the rewritten URI.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetURIFilter(filter func(kind URIKind, uri string) string) {
+	p.uriFilter = filter
+	p.buf.Reset()
+}
+
+// filterURI returns the URI rewritten by the URI filter of the playlist
+// with the token of the URI.
+func (p *MasterPlaylist) filterURI(kind URIKind, uri string) string {
+	if p.uriFilter != nil {
+		uri = p.uriFilter(kind, uri)
+	}
+	if p.tokens != nil {
+		if token := p.tokens.Token(kind, uri); token != "" {
+			uri = appendQuery(uri, token)
+		}
+	}
+	return uri
+}
+
+// filterURI returns the URI rewritten by the URI filter of the playlist
+// with the token of the URI.
+func (p *MediaPlaylist) filterURI(kind URIKind, uri string) string {
+	if p.uriFilter != nil {
+		uri = p.uriFilter(kind, uri)
+	}
+	if p.tokens != nil {
+		if token := p.tokens.Token(kind, uri); token != "" {
+			uri = appendQuery(uri, token)
+		}
+	}
+	return uri
+}
+
+// TokenProvider supplies query tokens of URIs, e.g. CDN tokens signing the
+// path with an expiry. Token returns the query string appended to the URI
+// (after the URI filter) or an empty string for URIs without token.
+type TokenProvider interface {
+	Token(kind URIKind, uri string) string
+}
+
+// TokenFunc is a function used as TokenProvider.
+type TokenFunc func(kind URIKind, uri string) string
+
+// Token calls the function.
+func (f TokenFunc) Token(kind URIKind, uri string) string {
+	return f(kind, uri)
+}
+
+// SetTokenProvider sets the provider of tokens appended to URIs of
+// variants and renditions when the playlist is encoded, nil removes it.
+// Unlike Args tokens may differ for every URI.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SetTokenProvider(tokens TokenProvider) {
+	p.tokens = tokens
+	p.buf.Reset()
+}
+
+// SetTokenProvider sets the provider of tokens appended to URIs of
+// segments, parts, keys, maps, preload hints and rendition reports when
+// the playlist is encoded, nil removes it. Unlike Args tokens may differ
+// for every URI and apply to keys and maps as well.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetTokenProvider(tokens TokenProvider) {
+	p.tokens = tokens
+	p.buf.Reset()
+}
+
+// appendQuery appends the query to the URI.
+func appendQuery(uri, query string) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query
+	}
+	return uri + "?" + query
+}
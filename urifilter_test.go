@@ -0,0 +1,136 @@
+/*
+ Package m3u8. URI filter tests.
+
+ Copyright 2013-2017 The Project Developers.
+ See the AUTHORS and LICENSE files at the top-level directory of this distribution
+ and at https://github.com/grafov/m3u8/
+
+ ॐ तारे तुत्तारे तुरे स्व
+*/
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMediaPlaylistURIFilter(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	p.SetDefaultMap("init.mp4", 0, 0)
+	p.Append("seg0.m4s", 4, "")
+	p.SetKey("AES-128", "key0", "", "", "")
+	p.Append("seg1.m4s", 4, "")
+	p.Args = "t=1"
+	kinds := map[URIKind]string{URISegment: "seg", URIKey: "key", URIMap: "map"}
+	p.SetURIFilter(func(kind URIKind, uri string) string {
+		return "https://cdn.example.com/" + kinds[kind] + "/" + uri
+	})
+	out := p.String()
+	for _, line := range []string{
+		`#EXT-X-KEY:METHOD=AES-128,URI="https://cdn.example.com/key/key0"`,
+		`#EXT-X-MAP:URI="https://cdn.example.com/map/init.mp4"`,
+		"https://cdn.example.com/seg/seg0.m4s?t=1",
+		"https://cdn.example.com/seg/seg1.m4s?t=1",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected %s in playlist:\n%s", line, out)
+		}
+	}
+	if p.Segments[0].URI != "seg0.m4s" || p.Map.URI != "init.mp4" {
+		t.Error("The playlist must not be changed")
+	}
+	p.SetURIFilter(nil)
+	if out = p.String(); !strings.Contains(out, "\nseg0.m4s?t=1\n") {
+		t.Errorf("Expected URIs without filter:\n%s", out)
+	}
+}
+
+func TestMasterPlaylistURIFilter(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("video.m3u8", nil, VariantParams{Bandwidth: 1000000, Audio: "aud", Alternatives: []*Alternative{
+		{Type: "AUDIO", GroupId: "aud", Name: "English", URI: "audio.m3u8"},
+	}})
+	m.Append("iframes.m3u8", nil, VariantParams{Bandwidth: 100000, Iframe: true})
+	m.SetURIFilter(func(kind URIKind, uri string) string {
+		if kind == URIRendition {
+			return "/renditions/" + uri
+		}
+		return "/variants/" + uri
+	})
+	out := m.String()
+	for _, s := range []string{`URI="/renditions/audio.m3u8"`, "\n/variants/video.m3u8\n", `URI="/variants/iframes.m3u8"`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected %s in playlist:\n%s", s, out)
+		}
+	}
+}
+
+func TestTokenProvider(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	p.SetDefaultMap("init.mp4", 0, 0)
+	p.Append("seg0.m4s", 4, "")
+	p.SetKey("AES-128", "key?id=1", "", "", "")
+	p.Append("seg1.m4s", 4, "")
+	p.Args = "session=abc"
+	p.SetTokenProvider(TokenFunc(func(kind URIKind, uri string) string {
+		if kind == URIKey {
+			return ""
+		}
+		return "token=" + strings.TrimSuffix(uri, ".m4s")
+	}))
+	out := p.String()
+	for _, line := range []string{
+		`#EXT-X-KEY:METHOD=AES-128,URI="key?id=1"`,
+		`#EXT-X-MAP:URI="init.mp4?token=init.mp4"`,
+		"seg0.m4s?token=seg0&session=abc",
+		"seg1.m4s?token=seg1&session=abc",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected %s in playlist:\n%s", line, out)
+		}
+	}
+}
+
+func TestMasterPlaylistTokenProviderArgs(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("low.m3u8", nil, VariantParams{Bandwidth: 1000000})
+	m.Append("high.m3u8?q=1", nil, VariantParams{Bandwidth: 2000000})
+	m.Args = "session=1"
+	m.SetTokenProvider(TokenFunc(func(kind URIKind, uri string) string {
+		return "tok=abc"
+	}))
+	out := m.String()
+	for _, line := range []string{"\nlow.m3u8?tok=abc&session=1\n", "\nhigh.m3u8?q=1&tok=abc&session=1\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in playlist:\n%s", line, out)
+		}
+	}
+}
+
+func TestTokenProviderNotCached(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 1)
+	p.Append("seg0.ts", 4, "")
+	m := NewMasterPlaylist()
+	m.Append("low.m3u8", p, VariantParams{Bandwidth: 1000000})
+	var n int
+	tokens := TokenFunc(func(kind URIKind, uri string) string {
+		n++
+		return fmt.Sprintf("token=%d", n)
+	})
+	p.SetTokenProvider(tokens)
+	m.SetTokenProvider(tokens)
+	first := p.Encode().String()
+	if second := p.Encode().String(); first == second || !strings.Contains(second, "seg0.ts?token=2\n") {
+		t.Errorf("Expected a new token on every encode, got:\n%s", second)
+	}
+	var buf bytes.Buffer
+	if p.EncodeTo(&buf); !strings.Contains(buf.String(), "seg0.ts?token=3\n") {
+		t.Errorf("Expected a new token on EncodeTo, got:\n%s", buf.String())
+	}
+	first = m.String()
+	if second := m.String(); first == second {
+		t.Errorf("Expected a new token on every encode of the master playlist, got:\n%s", second)
+	}
+}
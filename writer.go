@@ -16,6 +16,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,6 +27,19 @@ var (
 	ErrPlaylistFull = errors.New("playlist is full")
 )
 
+// MinimalPrecision of EXTINF durations strips trailing zeros, see
+// SetDurationPrecision.
+const MinimalPrecision = -1
+
+var (
+	reAttrName      = regexp.MustCompile(`^[A-Z0-9-]+$`)
+	reUnquotedValue = regexp.MustCompile(`^(-?[0-9]+(\.[0-9]+)?|0[xX][0-9a-fA-F]+|[0-9]+x[0-9]+)$`)
+	stripLineBreaks = strings.NewReplacer("\r", "", "\n", "")
+	// quoted strings can't contain double quotes and line breaks, the
+	// specification provides no escaping of them
+	quotedString = strings.NewReplacer("\"", "", "\r", "", "\n", "")
+)
+
 // Set version of the playlist accordingly with section 7
 func version(ver *uint8, newver uint8) {
 	if *ver < newver {
@@ -52,6 +67,9 @@ func (p *MasterPlaylist) Append(uri string, chunklist *MediaPlaylist, params Var
 	v.Chunklist = chunklist
 	v.VariantParams = params
 	p.Variants = append(p.Variants, v)
+	if v.ReqVideoLayout != "" {
+		version(&p.ver, 12) // due section 4.4.6.2
+	}
 	if len(v.Alternatives) > 0 {
 		// From section 7:
 		// The EXT-X-MEDIA tag and the AUDIO, VIDEO and SUBTITLES attributes of
@@ -65,34 +83,78 @@ func (p *MasterPlaylist) Append(uri string, chunklist *MediaPlaylist, params Var
 	p.buf.Reset()
 }
 
+// Reset playlist cache. Next called Encode() will regenerate playlist.
 func (p *MasterPlaylist) ResetCache() {
 	p.buf.Reset()
 }
 
-// Generate output in M3U8 format.
+// Generate output in M3U8 format. The output is cached until the playlist
+// is changed by its methods, call ResetCache after changing its fields
+// directly. The output isn't cached with a token provider as tokens may
+// change on every encode.
 func (p *MasterPlaylist) Encode() *bytes.Buffer {
 	if p.buf.Len() > 0 {
-		return &p.buf
+		if p.tokens == nil {
+			return &p.buf
+		}
+		p.buf.Reset()
+	}
+	p.encode(&p.buf)
+	return &p.buf
+}
+
+// EncodeTo writes the playlist in M3U8 format to the buffer, so servers
+// may reuse buffers (e.g. from sync.Pool) instead of allocating them for
+// every request. The cached output is copied if the playlist was encoded
+// before, the output of EncodeTo is not cached.
+func (p *MasterPlaylist) EncodeTo(buf *bytes.Buffer) {
+	if p.buf.Len() > 0 && p.tokens == nil {
+		buf.Write(p.buf.Bytes())
+		return
 	}
+	p.encode(buf)
+}
 
-	p.buf.WriteString("#EXTM3U\n#EXT-X-VERSION:")
-	p.buf.WriteString(strver(p.ver))
-	p.buf.WriteRune('\n')
+// encode writes the playlist to the buffer.
+func (p *MasterPlaylist) encode(buf *bytes.Buffer) {
+	eol := p.encodeOpts.eol()
+	defer p.encodeOpts.trim(buf, eol)
+	buf.WriteString("#EXTM3U")
+	buf.WriteString(eol)
+	buf.WriteString("#EXT-X-VERSION:")
+	buf.WriteString(strver(p.ver))
+	buf.WriteString(eol)
+	writeDefines(buf, p.Defines, eol)
 
 	if p.IndependentSegments() {
-		p.buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+		buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS")
+		buf.WriteString(eol)
 	}
-
-	// Write any custom master tags
-	if p.Custom != nil {
-		for _, v := range p.Custom {
-			if customBuf := v.Encode(); customBuf != nil {
-				p.buf.WriteString(customBuf.String())
-				p.buf.WriteRune('\n')
-			}
+	if p.StartTime != 0 {
+		buf.WriteString("#EXT-X-START:TIME-OFFSET=")
+		buf.WriteString(strconv.FormatFloat(p.StartTime, 'f', -1, 64))
+		if p.StartTimePrecise {
+			buf.WriteString(",PRECISE=YES")
+		}
+		buf.WriteString(eol)
+	}
+	if p.ContentSteering != nil {
+		buf.WriteString("#EXT-X-CONTENT-STEERING:SERVER-URI=\"")
+		buf.WriteString(quotedString.Replace(p.ContentSteering.ServerURI))
+		buf.WriteRune('"')
+		if p.ContentSteering.PathwayID != "" {
+			buf.WriteString(",PATHWAY-ID=\"")
+			buf.WriteString(quotedString.Replace(p.ContentSteering.PathwayID))
+			buf.WriteRune('"')
 		}
+		buf.WriteString(eol)
 	}
 
+	// Write any custom master tags
+	writeCustomTags(buf, p.Custom, p.customOrder, eol, PlacementDefault, PlacementHeader, PlacementAfterEXTINF, PlacementBeforeURI)
+
+	writeUnknownTags(buf, p.Unknown, HeaderPosition, eol)
+
 	var altsWritten map[string]bool = make(map[string]bool)
 
 	for _, pl := range p.Variants {
@@ -105,198 +167,402 @@ func (p *MasterPlaylist) Encode() *bytes.Buffer {
 				}
 				altsWritten[altKey] = true
 
-				p.buf.WriteString("#EXT-X-MEDIA:")
+				buf.WriteString("#EXT-X-MEDIA:")
 				if alt.Type != "" {
-					p.buf.WriteString("TYPE=") // Type should not be quoted
-					p.buf.WriteString(alt.Type)
+					buf.WriteString("TYPE=") // Type should not be quoted
+					buf.WriteString(alt.Type)
 				}
 				if alt.GroupId != "" {
-					p.buf.WriteString(",GROUP-ID=\"")
-					p.buf.WriteString(alt.GroupId)
-					p.buf.WriteRune('"')
+					buf.WriteString(",GROUP-ID=\"")
+					buf.WriteString(quotedString.Replace(alt.GroupId))
+					buf.WriteRune('"')
 				}
 				if alt.Name != "" {
-					p.buf.WriteString(",NAME=\"")
-					p.buf.WriteString(alt.Name)
-					p.buf.WriteRune('"')
+					buf.WriteString(",NAME=\"")
+					buf.WriteString(quotedString.Replace(alt.Name))
+					buf.WriteRune('"')
 				}
-				p.buf.WriteString(",DEFAULT=")
+				buf.WriteString(",DEFAULT=")
 				if alt.Default {
-					p.buf.WriteString("YES")
+					buf.WriteString("YES")
 				} else {
-					p.buf.WriteString("NO")
+					buf.WriteString("NO")
 				}
 				if alt.Autoselect != "" {
-					p.buf.WriteString(",AUTOSELECT=")
-					p.buf.WriteString(alt.Autoselect)
+					buf.WriteString(",AUTOSELECT=")
+					buf.WriteString(alt.Autoselect)
 				}
 				if alt.Language != "" {
-					p.buf.WriteString(",LANGUAGE=\"")
-					p.buf.WriteString(alt.Language)
-					p.buf.WriteRune('"')
+					buf.WriteString(",LANGUAGE=\"")
+					buf.WriteString(quotedString.Replace(alt.Language))
+					buf.WriteRune('"')
+				}
+				if alt.AssocLanguage != "" {
+					buf.WriteString(",ASSOC-LANGUAGE=\"")
+					buf.WriteString(quotedString.Replace(alt.AssocLanguage))
+					buf.WriteRune('"')
 				}
 				if alt.Forced != "" {
-					p.buf.WriteString(",FORCED=")
-					p.buf.WriteString(alt.Forced)
+					buf.WriteString(",FORCED=")
+					buf.WriteString(alt.Forced)
 				}
 				if alt.Type == "CLOSED-CAPTIONS" && alt.InstreamID != "" {
-					p.buf.WriteString(",INSTREAM-ID=\"")
-					p.buf.WriteString(alt.InstreamID)
-					p.buf.WriteRune('"')
+					buf.WriteString(",INSTREAM-ID=\"")
+					buf.WriteString(quotedString.Replace(alt.InstreamID))
+					buf.WriteRune('"')
 				}
 				if alt.Characteristics != "" {
-					p.buf.WriteString(",CHARACTERISTICS=\"")
-					p.buf.WriteString(alt.Characteristics)
-					p.buf.WriteRune('"')
+					buf.WriteString(",CHARACTERISTICS=\"")
+					buf.WriteString(quotedString.Replace(alt.Characteristics))
+					buf.WriteRune('"')
 				}
 				if alt.Channels != "" {
-					p.buf.WriteString(",CHANNELS=\"")
-					p.buf.WriteString(alt.Channels)
-					p.buf.WriteRune('"')
+					buf.WriteString(",CHANNELS=\"")
+					buf.WriteString(quotedString.Replace(alt.Channels))
+					buf.WriteRune('"')
+				}
+				if alt.BitDepth != 0 {
+					buf.WriteString(",BIT-DEPTH=")
+					buf.WriteString(strconv.FormatUint(uint64(alt.BitDepth), 10))
+				}
+				if alt.SampleRate != 0 {
+					buf.WriteString(",SAMPLE-RATE=")
+					buf.WriteString(strconv.FormatUint(uint64(alt.SampleRate), 10))
 				}
 				if alt.Subtitles != "" {
-					p.buf.WriteString(",SUBTITLES=\"")
-					p.buf.WriteString(alt.Subtitles)
-					p.buf.WriteRune('"')
+					buf.WriteString(",SUBTITLES=\"")
+					buf.WriteString(quotedString.Replace(alt.Subtitles))
+					buf.WriteRune('"')
+				}
+				if alt.StableRenditionID != "" {
+					buf.WriteString(",STABLE-RENDITION-ID=\"")
+					buf.WriteString(quotedString.Replace(alt.StableRenditionID))
+					buf.WriteRune('"')
 				}
 				if alt.URI != "" {
-					p.buf.WriteString(",URI=\"")
-					p.buf.WriteString(alt.URI)
-					p.buf.WriteRune('"')
+					buf.WriteString(",URI=\"")
+					buf.WriteString(quotedString.Replace(p.filterURI(URIRendition, alt.URI)))
+					buf.WriteRune('"')
 				}
-				p.buf.WriteRune('\n')
+				buf.WriteString(eol)
 			}
 		}
-		if pl.Iframe {
-			p.buf.WriteString("#EXT-X-I-FRAME-STREAM-INF:")
+		if pl.Image {
+			buf.WriteString("#EXT-X-IMAGE-STREAM-INF:")
 
-			p.buf.WriteString("BANDWIDTH=")
-			p.buf.WriteString(strconv.FormatUint(uint64(pl.Bandwidth), 10))
-			if p.ver < 6 {
-				p.buf.WriteString(",PROGRAM-ID=")
-				p.buf.WriteString(strconv.FormatUint(uint64(pl.ProgramId), 10))
+			buf.WriteString("BANDWIDTH=")
+			buf.WriteString(strconv.FormatUint(uint64(pl.Bandwidth), 10))
+			if pl.Resolution != "" {
+				buf.WriteString(",RESOLUTION=") // Resolution should not be quoted
+				buf.WriteString(pl.Resolution)
+			}
+			if pl.Codecs != "" {
+				buf.WriteString(",CODECS=\"")
+				buf.WriteString(quotedString.Replace(pl.Codecs))
+				buf.WriteRune('"')
+			}
+			writeSteeringAttributes(buf, &pl.VariantParams)
+			buf.WriteString(",URI=\"")
+			buf.WriteString(quotedString.Replace(p.filterURI(URIVariant, pl.URI)))
+			buf.WriteRune('"')
+			buf.WriteString(eol)
+		} else if pl.Iframe {
+			buf.WriteString("#EXT-X-I-FRAME-STREAM-INF:")
+
+			buf.WriteString("BANDWIDTH=")
+			buf.WriteString(strconv.FormatUint(uint64(pl.Bandwidth), 10))
+			if p.ver < 6 && !p.omitProgramID {
+				buf.WriteString(",PROGRAM-ID=")
+				buf.WriteString(strconv.FormatUint(uint64(pl.ProgramId), 10))
 			}
 			if pl.AverageBandwidth != 0 {
-				p.buf.WriteString(",AVERAGE-BANDWIDTH=")
-				p.buf.WriteString(strconv.FormatUint(uint64(pl.AverageBandwidth), 10))
+				buf.WriteString(",AVERAGE-BANDWIDTH=")
+				buf.WriteString(strconv.FormatUint(uint64(pl.AverageBandwidth), 10))
 			}
 			if pl.Codecs != "" {
-				p.buf.WriteString(",CODECS=\"")
-				p.buf.WriteString(pl.Codecs)
-				p.buf.WriteRune('"')
+				buf.WriteString(",CODECS=\"")
+				buf.WriteString(quotedString.Replace(pl.Codecs))
+				buf.WriteRune('"')
 			}
 			if pl.Resolution != "" {
-				p.buf.WriteString(",RESOLUTION=") // Resolution should not be quoted
-				p.buf.WriteString(pl.Resolution)
+				buf.WriteString(",RESOLUTION=") // Resolution should not be quoted
+				buf.WriteString(pl.Resolution)
 			}
 			if pl.Video != "" {
-				p.buf.WriteString(",VIDEO=\"")
-				p.buf.WriteString(pl.Video)
-				p.buf.WriteRune('"')
+				buf.WriteString(",VIDEO=\"")
+				buf.WriteString(quotedString.Replace(pl.Video))
+				buf.WriteRune('"')
 			}
 			if pl.VideoRange != "" {
-				p.buf.WriteString(",VIDEO-RANGE=")
-				p.buf.WriteString(pl.VideoRange)
+				buf.WriteString(",VIDEO-RANGE=")
+				buf.WriteString(pl.VideoRange)
 			}
 			if pl.HDCPLevel != "" {
-				p.buf.WriteString(",HDCP-LEVEL=")
-				p.buf.WriteString(pl.HDCPLevel)
+				buf.WriteString(",HDCP-LEVEL=")
+				buf.WriteString(pl.HDCPLevel)
 			}
+			writeAllowedCPC(buf, pl.AllowedCPC)
+			writeSteeringAttributes(buf, &pl.VariantParams)
 			if pl.URI != "" {
-				p.buf.WriteString(",URI=\"")
-				p.buf.WriteString(pl.URI)
-				p.buf.WriteRune('"')
+				buf.WriteString(",URI=\"")
+				buf.WriteString(quotedString.Replace(p.filterURI(URIVariant, pl.URI)))
+				buf.WriteRune('"')
 			}
-			p.buf.WriteRune('\n')
+			buf.WriteString(eol)
 		} else {
-			p.buf.WriteString("#EXT-X-STREAM-INF:")
+			buf.WriteString("#EXT-X-STREAM-INF:")
 
-			p.buf.WriteString("BANDWIDTH=")
-			p.buf.WriteString(strconv.FormatUint(uint64(pl.Bandwidth), 10))
-			if p.ver < 6 {
-				p.buf.WriteString(",PROGRAM-ID=")
-				p.buf.WriteString(strconv.FormatUint(uint64(pl.ProgramId), 10))
+			buf.WriteString("BANDWIDTH=")
+			buf.WriteString(strconv.FormatUint(uint64(pl.Bandwidth), 10))
+			if p.ver < 6 && !p.omitProgramID {
+				buf.WriteString(",PROGRAM-ID=")
+				buf.WriteString(strconv.FormatUint(uint64(pl.ProgramId), 10))
 			}
 			if pl.AverageBandwidth != 0 {
-				p.buf.WriteString(",AVERAGE-BANDWIDTH=")
-				p.buf.WriteString(strconv.FormatUint(uint64(pl.AverageBandwidth), 10))
+				buf.WriteString(",AVERAGE-BANDWIDTH=")
+				buf.WriteString(strconv.FormatUint(uint64(pl.AverageBandwidth), 10))
 			}
 			if pl.Codecs != "" {
-				p.buf.WriteString(",CODECS=\"")
-				p.buf.WriteString(pl.Codecs)
-				p.buf.WriteRune('"')
+				buf.WriteString(",CODECS=\"")
+				buf.WriteString(quotedString.Replace(pl.Codecs))
+				buf.WriteRune('"')
 			}
 			if pl.Resolution != "" {
-				p.buf.WriteString(",RESOLUTION=") // Resolution should not be quoted
-				p.buf.WriteString(pl.Resolution)
+				buf.WriteString(",RESOLUTION=") // Resolution should not be quoted
+				buf.WriteString(pl.Resolution)
 			}
 			if pl.Audio != "" {
-				p.buf.WriteString(",AUDIO=\"")
-				p.buf.WriteString(pl.Audio)
-				p.buf.WriteRune('"')
+				buf.WriteString(",AUDIO=\"")
+				buf.WriteString(quotedString.Replace(pl.Audio))
+				buf.WriteRune('"')
 			}
 			if pl.Video != "" {
-				p.buf.WriteString(",VIDEO=\"")
-				p.buf.WriteString(pl.Video)
-				p.buf.WriteRune('"')
+				buf.WriteString(",VIDEO=\"")
+				buf.WriteString(quotedString.Replace(pl.Video))
+				buf.WriteRune('"')
 			}
 			if pl.Captions != "" {
-				p.buf.WriteString(",CLOSED-CAPTIONS=")
+				buf.WriteString(",CLOSED-CAPTIONS=")
 				if pl.Captions == "NONE" {
-					p.buf.WriteString(pl.Captions) // CC should not be quoted when eq NONE
+					buf.WriteString(pl.Captions) // CC should not be quoted when eq NONE
 				} else {
-					p.buf.WriteRune('"')
-					p.buf.WriteString(pl.Captions)
-					p.buf.WriteRune('"')
+					buf.WriteRune('"')
+					buf.WriteString(quotedString.Replace(pl.Captions))
+					buf.WriteRune('"')
 				}
 			}
 			if pl.Subtitles != "" {
-				p.buf.WriteString(",SUBTITLES=\"")
-				p.buf.WriteString(pl.Subtitles)
-				p.buf.WriteRune('"')
+				buf.WriteString(",SUBTITLES=\"")
+				buf.WriteString(quotedString.Replace(pl.Subtitles))
+				buf.WriteRune('"')
 			}
 			if pl.Name != "" {
-				p.buf.WriteString(",NAME=\"")
-				p.buf.WriteString(pl.Name)
-				p.buf.WriteRune('"')
+				buf.WriteString(",NAME=\"")
+				buf.WriteString(quotedString.Replace(pl.Name))
+				buf.WriteRune('"')
 			}
 			if pl.FrameRate != 0 {
-				p.buf.WriteString(",FRAME-RATE=")
-				p.buf.WriteString(strconv.FormatFloat(pl.FrameRate, 'f', 3, 64))
+				buf.WriteString(",FRAME-RATE=")
+				buf.WriteString(strconv.FormatFloat(pl.FrameRate, 'f', 3, 64))
 			}
 			if pl.VideoRange != "" {
-				p.buf.WriteString(",VIDEO-RANGE=")
-				p.buf.WriteString(pl.VideoRange)
+				buf.WriteString(",VIDEO-RANGE=")
+				buf.WriteString(pl.VideoRange)
 			}
 			if pl.HDCPLevel != "" {
-				p.buf.WriteString(",HDCP-LEVEL=")
-				p.buf.WriteString(pl.HDCPLevel)
+				buf.WriteString(",HDCP-LEVEL=")
+				buf.WriteString(pl.HDCPLevel)
+			}
+			writeAllowedCPC(buf, pl.AllowedCPC)
+			if pl.ReqVideoLayout != "" {
+				buf.WriteString(",REQ-VIDEO-LAYOUT=\"")
+				buf.WriteString(quotedString.Replace(pl.ReqVideoLayout))
+				buf.WriteRune('"')
 			}
+			writeSteeringAttributes(buf, &pl.VariantParams)
+			writeExtraAttributes(buf, pl.ExtraAttributes, pl.ExtraQuoted)
 
-			p.buf.WriteRune('\n')
-			p.buf.WriteString(pl.URI)
+			buf.WriteString(eol)
+			uri := p.filterURI(URIVariant, pl.URI)
 			if p.Args != "" {
-				if strings.Contains(pl.URI, "?") {
-					p.buf.WriteRune('&')
-				} else {
-					p.buf.WriteRune('?')
-				}
-				p.buf.WriteString(p.Args)
+				uri = appendQuery(uri, p.Args)
 			}
-			p.buf.WriteRune('\n')
+			buf.WriteString(stripLineBreaks.Replace(uri))
+			buf.WriteString(eol)
 		}
 	}
+	writeCustomTags(buf, p.Custom, p.customOrder, eol, PlacementFooter)
+	writeUnknownTags(buf, p.Unknown, TrailerPosition, eol)
 
-	return &p.buf
 }
 
-// SetCustomTag sets the provided tag on the master playlist for its TagName
+// writeAllowedCPC writes ALLOWED-CPC attribute of a variant with key
+// formats sorted by their names.
+func writeAllowedCPC(buf *bytes.Buffer, cpc map[string][]string) {
+	if len(cpc) == 0 {
+		return
+	}
+	keyformats := make([]string, 0, len(cpc))
+	for k := range cpc {
+		keyformats = append(keyformats, k)
+	}
+	sort.Strings(keyformats)
+	buf.WriteString(",ALLOWED-CPC=\"")
+	for i, k := range keyformats {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteString(quotedString.Replace(k))
+		buf.WriteRune(':')
+		buf.WriteString(quotedString.Replace(strings.Join(cpc[k], "/")))
+	}
+	buf.WriteRune('"')
+}
+
+// writeSteeringAttributes writes content steering attributes of a variant.
+func writeSteeringAttributes(buf *bytes.Buffer, v *VariantParams) {
+	if v.PathwayID != "" {
+		buf.WriteString(",PATHWAY-ID=\"")
+		buf.WriteString(quotedString.Replace(v.PathwayID))
+		buf.WriteRune('"')
+	}
+	if v.StableVariantID != "" {
+		buf.WriteString(",STABLE-VARIANT-ID=\"")
+		buf.WriteString(quotedString.Replace(v.StableVariantID))
+		buf.WriteRune('"')
+	}
+}
+
+// SetContentSteering sets the steering server of the presentation.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SetContentSteering(serverURI, pathwayID string) {
+	p.ContentSteering = &ContentSteering{ServerURI: serverURI, PathwayID: pathwayID}
+	p.buf.Reset()
+}
+
+// SetExtraAttribute registers an additional attribute appended to the
+// EXT-X-STREAM-INF tag of the variant. Numeric, hexadecimal and resolution
+// values are written as is, other values are written as quoted strings.
+func (v *VariantParams) SetExtraAttribute(key, value string) {
+	if v.ExtraAttributes == nil {
+		v.ExtraAttributes = make(map[string]string)
+	}
+	v.ExtraAttributes[key] = value
+	delete(v.ExtraQuoted, key)
+}
+
+// SetQuotedExtraAttribute registers an additional attribute appended to
+// the EXT-X-STREAM-INF tag of the variant, the value is written as quoted
+// string even if it is a number.
+func (v *VariantParams) SetQuotedExtraAttribute(key, value string) {
+	v.SetExtraAttribute(key, value)
+	if v.ExtraQuoted == nil {
+		v.ExtraQuoted = make(map[string]bool)
+	}
+	v.ExtraQuoted[key] = true
+}
+
+// writeExtraAttributes writes extra attributes of a variant sorted by their
+// names. Attributes with invalid names are skipped, attributes named in
+// quoted are written as quoted strings.
+func writeExtraAttributes(buf *bytes.Buffer, attrs map[string]string, quoted map[string]bool) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		if reAttrName.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := attrs[k]
+		buf.WriteRune(',')
+		buf.WriteString(k)
+		buf.WriteRune('=')
+		if !quoted[k] && reUnquotedValue.MatchString(v) {
+			buf.WriteString(v)
+		} else {
+			buf.WriteRune('"')
+			buf.WriteString(quotedString.Replace(v))
+			buf.WriteRune('"')
+		}
+	}
+}
+
+// SetCustomTag sets the provided tag on the master playlist for its TagName.
+// This operation does reset playlist cache.
 func (p *MasterPlaylist) SetCustomTag(tag CustomTag) {
-	if p.Custom == nil {
-		p.Custom = make(map[string]CustomTag)
+	setCustomTag(&p.Custom, &p.customOrder, tag)
+	version(&p.ver, extensionVersion(tag.TagName()))
+	p.buf.Reset()
+}
+
+// setCustomTag sets the tag in the custom tags and remembers the order of
+// their names, so custom tags are encoded in the order they were set.
+func setCustomTag(custom *map[string]CustomTag, order *[]string, tag CustomTag) {
+	if *custom == nil {
+		*custom = make(map[string]CustomTag)
+	}
+	name := tag.TagName()
+	(*custom)[name] = tag
+	for _, n := range *order {
+		if n == name {
+			return
+		}
 	}
+	*order = append(*order, name)
+}
 
-	p.Custom[tag.TagName()] = tag
+// customTagNames returns names of the custom tags in the order they were
+// set, tags added to the map directly follow them sorted by name.
+func customTagNames(custom map[string]CustomTag, order []string) []string {
+	names := make([]string, 0, len(custom))
+	seen := make(map[string]bool, len(custom))
+	for _, name := range order {
+		if _, ok := custom[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range custom {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// hasPlacement reports whether the tag is placed at any of the placements.
+func hasPlacement(tag CustomTag, placements []TagPlacement) bool {
+	placement := PlacementDefault
+	if placer, ok := tag.(CustomTagPlacer); ok {
+		placement = placer.Placement()
+	}
+	for _, p := range placements {
+		if p == placement {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCustomTags writes the custom tags with any of the placements in the
+// order they were set.
+func writeCustomTags(buf *bytes.Buffer, custom map[string]CustomTag, order []string, eol string, placements ...TagPlacement) {
+	for _, name := range customTagNames(custom, order) {
+		if tag := custom[name]; tag != nil && hasPlacement(tag, placements) {
+			if customBuf := tag.Encode(); customBuf != nil {
+				data := customBuf.Bytes()
+				if eol != "\n" {
+					// lines of multi-line tags end the same way
+					data = bytes.ReplaceAll(data, []byte("\n"), []byte(eol))
+				}
+				buf.Write(data)
+				buf.WriteString(eol)
+			}
+		}
+	}
 }
 
 // Version returns the current playlist version number
@@ -306,8 +572,10 @@ func (p *MasterPlaylist) Version() uint8 {
 
 // SetVersion sets the playlist version number, note the version maybe changed
 // automatically by other Set methods.
+// This operation does reset playlist cache.
 func (p *MasterPlaylist) SetVersion(ver uint8) {
 	p.ver = ver
+	p.buf.Reset()
 }
 
 // IndependentSegments returns true if all media samples in a segment can be
@@ -318,8 +586,28 @@ func (p *MasterPlaylist) IndependentSegments() bool {
 
 // SetIndependentSegments sets whether all media samples in a segment can be
 // decoded without information from other segments.
+// This operation does reset playlist cache.
 func (p *MasterPlaylist) SetIndependentSegments(b bool) {
 	p.independentSegments = b
+	p.buf.Reset()
+}
+
+// OmitProgramID sets whether the deprecated PROGRAM-ID attribute of
+// EXT-X-STREAM-INF and EXT-X-I-FRAME-STREAM-INF is omitted, it is written
+// for protocol versions below 6 otherwise. Some validators flag it.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) OmitProgramID(omit bool) {
+	p.omitProgramID = omit
+	p.buf.Reset()
+}
+
+// SetStart sets the preferred point to start playback of any variant
+// (EXT-X-START). Negative offset is counted from the end of the playlist.
+// This operation does reset playlist cache.
+func (p *MasterPlaylist) SetStart(offset float64, precise bool) {
+	p.StartTime = offset
+	p.StartTimePrecise = precise
+	p.buf.Reset()
 }
 
 // For compatibility with Stringer interface
@@ -340,6 +628,7 @@ func NewMediaPlaylist(winsize uint, capacity uint) (*MediaPlaylist, error) {
 		return nil, err
 	}
 	p.Segments = make([]*MediaSegment, capacity)
+	p.edge = newLiveEdge()
 	return p, nil
 }
 
@@ -351,21 +640,39 @@ func (p *MediaPlaylist) last() uint {
 	return p.tail - 1
 }
 
+// segmentAt returns the i-th segment counting from the head of the playlist.
+func (p *MediaPlaylist) segmentAt(i uint) *MediaSegment {
+	return p.Segments[(p.head+i)%p.capacity]
+}
+
 // Remove current segment from the head of chunk slice form a media playlist. Useful for sliding playlists.
 // This operation does reset playlist cache.
 func (p *MediaPlaylist) Remove() (err error) {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
+	removed := p.segmentAt(0)
 	p.head = (p.head + 1) % p.capacity
 	p.count--
 	if !p.Closed {
 		p.SeqNo++
+		if p.autoDiscSeq && removed != nil && removed.Discontinuity {
+			p.DiscontinuitySeq++
+		}
 	}
 	p.buf.Reset()
 	return nil
 }
 
+// SetAutoDiscontinuitySeq enables incrementing of DiscontinuitySeq
+// (EXT-X-DISCONTINUITY-SEQUENCE) each time Remove or Slide drops a segment
+// with EXT-X-DISCONTINUITY, as sliding live windows must do.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetAutoDiscontinuitySeq(enabled bool) {
+	p.autoDiscSeq = enabled
+	p.buf.Reset()
+}
+
 // Append general chunk to the tail of chunk slice for a media playlist.
 // This operation does reset playlist cache.
 func (p *MediaPlaylist) Append(uri string, duration float64, title string) error {
@@ -376,26 +683,87 @@ func (p *MediaPlaylist) Append(uri string, duration float64, title string) error
 	return p.AppendSegment(seg)
 }
 
+// AppendDuration appends general chunk like Append does but takes the
+// duration as time.Duration. The exact duration is kept by the segment and
+// returned by DurationD, so durations of long playlists are summed without
+// float rounding errors.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendDuration(uri string, d time.Duration, title string) error {
+	seg := new(MediaSegment)
+	seg.URI = uri
+	seg.Duration = d.Seconds()
+	seg.duration = d
+	seg.Title = title
+	return p.AppendSegment(seg)
+}
+
 // AppendSegment appends a MediaSegment to the tail of chunk slice for a media playlist.
 // This operation does reset playlist cache.
 func (p *MediaPlaylist) AppendSegment(seg *MediaSegment) error {
 	if p.head == p.tail && p.count > 0 {
 		return ErrPlaylistFull
 	}
-	seg.SeqId = p.SeqNo
+	if p.frozenTarget && math.Round(seg.Duration) > p.TargetDuration {
+		return fmt.Errorf("segment duration %v exceeds frozen target duration %v", seg.Duration, p.TargetDuration)
+	}
+	seg.SeqId = p.SeqNo + p.SkippedSegments
 	if p.count > 0 {
 		seg.SeqId = p.Segments[(p.capacity+p.tail-1)%p.capacity].SeqId + 1
 	}
+	p.applyKeyProvider(seg)
+	// parts appended before the segment was completed belong to it
+	if len(seg.Parts) == 0 && len(p.PendingParts) > 0 {
+		seg.Parts = p.PendingParts
+		p.PendingParts = nil
+	}
+	for _, part := range seg.Parts {
+		p.updatePartTarget(part)
+	}
 	p.Segments[p.tail] = seg
 	p.tail = (p.tail + 1) % p.capacity
 	p.count++
-	if p.TargetDuration < seg.Duration {
+	if !p.frozenTarget && p.TargetDuration < seg.Duration {
 		p.TargetDuration = math.Ceil(seg.Duration)
 	}
 	p.buf.Reset()
+	p.publish()
+	return p.followStartovers(seg)
+}
+
+// AppendPartial appends a partial segment (EXT-X-PART) of the segment
+// which is being produced. The parts are linked to the segment on the
+// next Append call.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendPartial(uri string, duration float64, independent bool) error {
+	part := new(PartialSegment)
+	part.URI = uri
+	part.Duration = duration
+	part.Independent = independent
+	return p.AppendPartialSegment(part)
+}
+
+// AppendPartialSegment appends a PartialSegment of the segment which is
+// being produced. The parts are linked to the segment on the next Append
+// call.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendPartialSegment(part *PartialSegment) error {
+	if p.Closed {
+		return errors.New("playlist is closed")
+	}
+	p.PendingParts = append(p.PendingParts, part)
+	p.updatePartTarget(part)
+	p.buf.Reset()
+	p.publish()
 	return nil
 }
 
+// updatePartTarget grows EXT-X-PART-INF PART-TARGET to fit the part.
+func (p *MediaPlaylist) updatePartTarget(part *PartialSegment) {
+	if p.PartTarget < part.Duration {
+		p.PartTarget = part.Duration
+	}
+}
+
 // Combines two operations: firstly it removes one chunk from the head of chunk slice and move pointer to
 // next chunk. Secondly it appends one chunk to the tail of chunk slice. Useful for sliding playlists.
 // This operation does reset cache.
@@ -412,174 +780,368 @@ func (p *MediaPlaylist) ResetCache() {
 }
 
 // Generate output in M3U8 format. Marshal `winsize` elements from bottom of the `segments` queue.
+// The output is cached until the playlist is changed by its methods, call
+// ResetCache after changing its fields or segments directly. The output
+// isn't cached with a token provider as tokens may change on every encode.
 func (p *MediaPlaylist) Encode() *bytes.Buffer {
 	if p.buf.Len() > 0 {
-		return &p.buf
+		if p.tokens == nil {
+			return &p.buf
+		}
+		p.buf.Reset()
 	}
+	p.encode(&p.buf, 0)
+	return &p.buf
+}
 
-	p.buf.WriteString("#EXTM3U\n#EXT-X-VERSION:")
-	p.buf.WriteString(strver(p.ver))
-	p.buf.WriteRune('\n')
+// EncodeTo writes the playlist in M3U8 format to the buffer, so servers
+// may reuse buffers (e.g. from sync.Pool) instead of allocating them for
+// every request. The cached output is copied if the playlist was encoded
+// before, the output of EncodeTo is not cached.
+func (p *MediaPlaylist) EncodeTo(buf *bytes.Buffer) {
+	if p.buf.Len() > 0 && p.tokens == nil {
+		buf.Write(p.buf.Bytes())
+		return
+	}
+	p.encode(buf, 0)
+}
 
-	// Write any custom master tags
-	if p.Custom != nil {
-		for _, v := range p.Custom {
-			if customBuf := v.Encode(); customBuf != nil {
-				p.buf.WriteString(customBuf.String())
-				p.buf.WriteRune('\n')
+// EncodeDelta generates a Playlist Delta Update for _HLS_skip=YES
+// requests. Segments which end more than skippedUntil seconds before the
+// end of the playlist are replaced with EXT-X-SKIP tag. The value must not
+// be less than CAN-SKIP-UNTIL of EXT-X-SERVER-CONTROL. EXT-X-DATERANGE tags
+// of skipped segments are kept unless CAN-SKIP-DATERANGES is set, then they
+// are skipped too and RecentlyRemoved lists the date ranges removed from
+// the playlist. The result is not cached.
+func (p *MediaPlaylist) EncodeDelta(skippedUntil float64) (*bytes.Buffer, error) {
+	if p.ServerControl == nil || p.ServerControl.CanSkipUntil <= 0 {
+		return nil, errors.New("playlist doesn't allow delta updates")
+	}
+	if skippedUntil < p.ServerControl.CanSkipUntil {
+		return nil, fmt.Errorf("skip boundary %v is less than CAN-SKIP-UNTIL %v", skippedUntil, p.ServerControl.CanSkipUntil)
+	}
+	buf := new(bytes.Buffer)
+	p.encode(buf, p.skippable(skippedUntil))
+	return buf, nil
+}
+
+// ApplyDelta reconstructs the complete playlist from the delta update
+// (decoded playlist with EXT-X-SKIP tag) and the previously known version
+// of the playlist, which must contain all the skipped segments. Skipped
+// segments are shared with the previous version.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) ApplyDelta(previous *MediaPlaylist) error {
+	if p.SkippedSegments == 0 {
+		return nil
+	}
+	if previous.count == 0 {
+		return errors.New("previous playlist is empty")
+	}
+	first := previous.segmentAt(0).SeqId
+	last := previous.segmentAt(previous.count - 1).SeqId
+	if p.SeqNo < first || p.SeqNo+p.SkippedSegments-1 > last {
+		return fmt.Errorf("previous playlist doesn't contain skipped segments %d-%d", p.SeqNo, p.SeqNo+p.SkippedSegments-1)
+	}
+	total := uint(p.SkippedSegments) + p.count
+	segments := make([]*MediaSegment, total)
+	for i := uint(0); i < uint(p.SkippedSegments); i++ {
+		segments[i] = previous.segmentAt(uint(p.SeqNo-first) + i)
+	}
+	for i := uint(0); i < p.count; i++ {
+		segments[uint(p.SkippedSegments)+i] = p.segmentAt(i)
+	}
+	// date ranges of skipped segments are repeated in front of the first
+	// segment of the delta update, they are kept by the skipped segments
+	if p.count > 0 {
+		seg := p.segmentAt(0)
+		var kept []*DateRange
+		for _, dr := range seg.DateRanges {
+			if !skippedDateRange(segments[:p.SkippedSegments], dr) {
+				kept = append(kept, dr)
 			}
 		}
+		seg.DateRanges = kept
 	}
+	if p.capacity > total {
+		segments = append(segments, make([]*MediaSegment, p.capacity-total)...)
+	}
+	p.Segments = segments
+	p.capacity = uint(len(segments))
+	p.head = 0
+	p.tail = total % p.capacity
+	p.count = total
+	if p.winsize > 0 && p.winsize < total {
+		p.winsize = total
+	}
+	p.SkippedSegments = 0
+	p.buf.Reset()
+	return nil
+}
 
-	// default key (workaround for Widevine)
-	if p.Key != nil {
-		p.buf.WriteString("#EXT-X-KEY:")
-		p.buf.WriteString("METHOD=")
-		p.buf.WriteString(p.Key.Method)
-		if p.Key.Method != "NONE" {
-			p.buf.WriteString(",URI=\"")
-			p.buf.WriteString(p.Key.URI)
-			p.buf.WriteRune('"')
-			if p.Key.IV != "" {
-				p.buf.WriteString(",IV=")
-				p.buf.WriteString(p.Key.IV)
-			}
-			if p.Key.Keyformat != "" {
-				p.buf.WriteString(",KEYFORMAT=\"")
-				p.buf.WriteString(p.Key.Keyformat)
-				p.buf.WriteRune('"')
-			}
-			if p.Key.Keyformatversions != "" {
-				p.buf.WriteString(",KEYFORMATVERSIONS=\"")
-				p.buf.WriteString(p.Key.Keyformatversions)
-				p.buf.WriteRune('"')
+// skippedDateRange reports whether the same EXT-X-DATERANGE tag is
+// attached to one of the skipped segments.
+func skippedDateRange(skipped []*MediaSegment, dr *DateRange) bool {
+	var tag, other bytes.Buffer
+	writeDateRanges(&tag, []*DateRange{dr}, "\n")
+	for _, seg := range skipped {
+		for _, sdr := range seg.DateRanges {
+			other.Reset()
+			writeDateRanges(&other, []*DateRange{sdr}, "\n")
+			if bytes.Equal(tag.Bytes(), other.Bytes()) {
+				return true
 			}
 		}
-		p.buf.WriteRune('\n')
+	}
+	return false
+}
+
+// skippable returns the number of segments at the head of the encoded
+// window which end more than skippedUntil seconds before its end.
+func (p *MediaPlaylist) skippable(skippedUntil float64) uint {
+	var durations []float64
+	for i := uint(0); i < p.count && (uint(len(durations)) < p.winsize || p.winsize == 0); i++ {
+		if seg := p.segmentAt(i); seg != nil { // encoding ignores empty slots as well
+			durations = append(durations, seg.Duration)
+		}
+	}
+	var remaining float64
+	for _, d := range durations {
+		remaining += d
+	}
+	var skip uint
+	for _, d := range durations {
+		remaining -= d
+		if remaining < skippedUntil {
+			break
+		}
+		skip++
+	}
+	return skip
+}
+
+// mediaSequence returns EXT-X-MEDIA-SEQUENCE of the encoded window, that
+// is the number of the first segment written including the segments
+// skipped by a delta update. SeqNo is only a counter of removed segments
+// which drifts from the window, e.g. when segments are removed from a
+// closed playlist, so it is used for an empty playlist only.
+func (p *MediaPlaylist) mediaSequence() uint64 {
+	for i := uint(0); i < p.count; i++ {
+		if seg := p.segmentAt(i); seg != nil {
+			return seg.SeqId - p.SkippedSegments
+		}
+	}
+	return p.SeqNo
+}
+
+// encode writes the playlist to the buffer replacing the first skip
+// segments of the window with EXT-X-SKIP tag.
+func (p *MediaPlaylist) encode(buf *bytes.Buffer, skip uint) {
+	eol := p.encodeOpts.eol()
+	defer p.encodeOpts.trim(buf, eol)
+	var dates []time.Time // derived dates of the window
+	if p.autoPDT {
+		dates = p.programDateTimes()
+	}
+	ver := p.ver
+	if (skip > 0 || p.SkippedSegments > 0) && ver < 9 {
+		ver = 9
+	}
+	buf.WriteString("#EXTM3U")
+	buf.WriteString(eol)
+	buf.WriteString("#EXT-X-VERSION:")
+	buf.WriteString(strver(ver))
+	buf.WriteString(eol)
+	writeDefines(buf, p.Defines, eol)
+
+	// Write any custom master tags
+	writeCustomTags(buf, p.Custom, p.customOrder, eol, PlacementDefault, PlacementHeader, PlacementAfterEXTINF, PlacementBeforeURI)
+
+	// default key (workaround for Widevine), the key of the encrypted
+	// default map is written in front of the map
+	var current []*Key // keys in effect, written once until they change
+	if p.Map != nil && p.Map.Key != nil {
+		current = p.writeKeys(buf, []*Key{p.Map.Key}, current, false)
+	}
+	if p.Key != nil && (p.Map == nil || p.Map.Key == nil) {
+		current = p.writeKeys(buf, []*Key{p.Key}, current, false)
 	}
 	if p.Map != nil {
-		p.buf.WriteString("#EXT-X-MAP:")
-		p.buf.WriteString("URI=\"")
-		p.buf.WriteString(p.Map.URI)
-		p.buf.WriteRune('"')
-		if p.Map.Limit > 0 {
-			p.buf.WriteString(",BYTERANGE=")
-			p.buf.WriteString(strconv.FormatInt(p.Map.Limit, 10))
-			p.buf.WriteRune('@')
-			p.buf.WriteString(strconv.FormatInt(p.Map.Offset, 10))
-		}
-		p.buf.WriteRune('\n')
+		writeMap(buf, p.Map, p.filterURI(URIMap, p.Map.URI), p.mapOrder(p.Map), eol)
+		if p.Map.Key != nil {
+			key := p.Key
+			if key == nil {
+				key = &Key{Method: "NONE"}
+			}
+			current = p.writeKeys(buf, []*Key{key}, current, false)
+		}
 	}
 	if p.MediaType > 0 {
-		p.buf.WriteString("#EXT-X-PLAYLIST-TYPE:")
+		buf.WriteString("#EXT-X-PLAYLIST-TYPE:")
 		switch p.MediaType {
 		case EVENT:
-			p.buf.WriteString("EVENT\n")
-			p.buf.WriteString("#EXT-X-ALLOW-CACHE:NO\n")
+			buf.WriteString("EVENT")
+			buf.WriteString(eol)
 		case VOD:
-			p.buf.WriteString("VOD\n")
+			buf.WriteString("VOD")
+			buf.WriteString(eol)
+		}
+	}
+	switch {
+	case p.AllowCache == AllowCacheYes:
+		buf.WriteString("#EXT-X-ALLOW-CACHE:YES")
+		buf.WriteString(eol)
+	case p.AllowCache == AllowCacheNo || p.MediaType == EVENT:
+		buf.WriteString("#EXT-X-ALLOW-CACHE:NO")
+		buf.WriteString(eol)
+	}
+	buf.WriteString("#EXT-X-MEDIA-SEQUENCE:")
+	buf.WriteString(strconv.FormatUint(p.mediaSequence(), 10))
+	buf.WriteString(eol)
+	buf.WriteString("#EXT-X-TARGETDURATION:")
+	buf.WriteString(strconv.FormatInt(int64(math.Ceil(p.TargetDuration)), 10)) // due section 3.4.2 of M3U8 specs EXT-X-TARGETDURATION must be integer
+	buf.WriteString(eol)
+	if p.ServerControl != nil && *p.ServerControl != (ServerControl{}) {
+		var attrs []string
+		sc := p.ServerControl
+		if sc.CanSkipUntil > 0 {
+			attrs = append(attrs, "CAN-SKIP-UNTIL="+strconv.FormatFloat(sc.CanSkipUntil, 'f', -1, 64))
+			if sc.CanSkipDateRanges {
+				attrs = append(attrs, "CAN-SKIP-DATERANGES=YES")
+			}
+		}
+		if sc.HoldBack > 0 {
+			attrs = append(attrs, "HOLD-BACK="+strconv.FormatFloat(sc.HoldBack, 'f', -1, 64))
 		}
+		if sc.PartHoldBack > 0 {
+			attrs = append(attrs, "PART-HOLD-BACK="+strconv.FormatFloat(sc.PartHoldBack, 'f', -1, 64))
+		}
+		if sc.CanBlockReload {
+			attrs = append(attrs, "CAN-BLOCK-RELOAD=YES")
+		}
+		buf.WriteString("#EXT-X-SERVER-CONTROL:")
+		buf.WriteString(strings.Join(attrs, ","))
+		buf.WriteString(eol)
+	}
+	if p.PartTarget > 0 {
+		buf.WriteString("#EXT-X-PART-INF:PART-TARGET=")
+		buf.WriteString(strconv.FormatFloat(p.PartTarget, 'f', -1, 64))
+		buf.WriteString(eol)
 	}
-	p.buf.WriteString("#EXT-X-MEDIA-SEQUENCE:")
-	p.buf.WriteString(strconv.FormatUint(p.SeqNo, 10))
-	p.buf.WriteRune('\n')
-	p.buf.WriteString("#EXT-X-TARGETDURATION:")
-	p.buf.WriteString(strconv.FormatInt(int64(math.Ceil(p.TargetDuration)), 10)) // due section 3.4.2 of M3U8 specs EXT-X-TARGETDURATION must be integer
-	p.buf.WriteRune('\n')
 	if p.StartTime > 0.0 {
-		p.buf.WriteString("#EXT-X-START:TIME-OFFSET=")
-		p.buf.WriteString(strconv.FormatFloat(p.StartTime, 'f', -1, 64))
+		buf.WriteString("#EXT-X-START:TIME-OFFSET=")
+		buf.WriteString(strconv.FormatFloat(p.StartTime, 'f', -1, 64))
 		if p.StartTimePrecise {
-			p.buf.WriteString(",PRECISE=YES")
+			buf.WriteString(",PRECISE=YES")
 		}
-		p.buf.WriteRune('\n')
+		buf.WriteString(eol)
 	}
 	if p.DiscontinuitySeq != 0 {
-		p.buf.WriteString("#EXT-X-DISCONTINUITY-SEQUENCE:")
-		p.buf.WriteString(strconv.FormatUint(uint64(p.DiscontinuitySeq), 10))
-		p.buf.WriteRune('\n')
+		buf.WriteString("#EXT-X-DISCONTINUITY-SEQUENCE:")
+		buf.WriteString(strconv.FormatUint(uint64(p.DiscontinuitySeq), 10))
+		buf.WriteString(eol)
 	}
 	if p.Iframe {
-		p.buf.WriteString("#EXT-X-I-FRAMES-ONLY\n")
+		buf.WriteString("#EXT-X-I-FRAMES-ONLY")
+		buf.WriteString(eol)
 	}
 	// Widevine tags
 	if p.WV != nil {
 		if p.WV.AudioChannels != 0 {
-			p.buf.WriteString("#WV-AUDIO-CHANNELS ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioChannels), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-AUDIO-CHANNELS ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioChannels), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.AudioFormat != 0 {
-			p.buf.WriteString("#WV-AUDIO-FORMAT ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioFormat), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-AUDIO-FORMAT ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioFormat), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.AudioProfileIDC != 0 {
-			p.buf.WriteString("#WV-AUDIO-PROFILE-IDC ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioProfileIDC), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-AUDIO-PROFILE-IDC ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioProfileIDC), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.AudioSampleSize != 0 {
-			p.buf.WriteString("#WV-AUDIO-SAMPLE-SIZE ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioSampleSize), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-AUDIO-SAMPLE-SIZE ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioSampleSize), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.AudioSamplingFrequency != 0 {
-			p.buf.WriteString("#WV-AUDIO-SAMPLING-FREQUENCY ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioSamplingFrequency), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-AUDIO-SAMPLING-FREQUENCY ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.AudioSamplingFrequency), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.CypherVersion != "" {
-			p.buf.WriteString("#WV-CYPHER-VERSION ")
-			p.buf.WriteString(p.WV.CypherVersion)
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-CYPHER-VERSION ")
+			buf.WriteString(p.WV.CypherVersion)
+			buf.WriteString(eol)
 		}
 		if p.WV.ECM != "" {
-			p.buf.WriteString("#WV-ECM ")
-			p.buf.WriteString(p.WV.ECM)
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-ECM ")
+			buf.WriteString(p.WV.ECM)
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoFormat != 0 {
-			p.buf.WriteString("#WV-VIDEO-FORMAT ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoFormat), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-FORMAT ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoFormat), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoFrameRate != 0 {
-			p.buf.WriteString("#WV-VIDEO-FRAME-RATE ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoFrameRate), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-FRAME-RATE ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoFrameRate), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoLevelIDC != 0 {
-			p.buf.WriteString("#WV-VIDEO-LEVEL-IDC")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoLevelIDC), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-LEVEL-IDC")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoLevelIDC), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoProfileIDC != 0 {
-			p.buf.WriteString("#WV-VIDEO-PROFILE-IDC ")
-			p.buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoProfileIDC), 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-PROFILE-IDC ")
+			buf.WriteString(strconv.FormatUint(uint64(p.WV.VideoProfileIDC), 10))
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoResolution != "" {
-			p.buf.WriteString("#WV-VIDEO-RESOLUTION ")
-			p.buf.WriteString(p.WV.VideoResolution)
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-RESOLUTION ")
+			buf.WriteString(p.WV.VideoResolution)
+			buf.WriteString(eol)
 		}
 		if p.WV.VideoSAR != "" {
-			p.buf.WriteString("#WV-VIDEO-SAR ")
-			p.buf.WriteString(p.WV.VideoSAR)
-			p.buf.WriteRune('\n')
+			buf.WriteString("#WV-VIDEO-SAR ")
+			buf.WriteString(p.WV.VideoSAR)
+			buf.WriteString(eol)
 		}
 	}
 
 	var (
 		seg           *MediaSegment
 		durationCache = make(map[float64]string)
+		prec          = 3
 	)
+	if p.durationPrec != nil {
+		prec = *p.durationPrec
+	}
+
+	writeUnknownTags(buf, p.Unknown, HeaderPosition, eol)
+	// date ranges of skipped segments are only skipped if the server
+	// control allows it, the removed ones are listed then
+	skipDateRanges := p.ServerControl != nil && p.ServerControl.CanSkipDateRanges
+	if skipped := p.SkippedSegments + uint64(skip); skipped > 0 {
+		buf.WriteString("#EXT-X-SKIP:SKIPPED-SEGMENTS=")
+		buf.WriteString(strconv.FormatUint(skipped, 10))
+		if skipDateRanges {
+			buf.WriteString(",RECENTLY-REMOVED-DATERANGES=\"")
+			buf.WriteString(quotedString.Replace(strings.Join(p.RecentlyRemoved, "\t")))
+			buf.WriteRune('"')
+		}
+		buf.WriteString(eol)
+	}
 
 	head := p.head
 	count := p.count
 	for i := uint(0); (i < p.winsize || p.winsize == 0) && count > 0; count-- {
 		seg = p.Segments[head]
+		pos := p.count - count
 		head = (head + 1) % p.capacity
 		if seg == nil { // protection from badly filled chunklists
 			continue
@@ -587,140 +1149,270 @@ func (p *MediaPlaylist) Encode() *bytes.Buffer {
 		if p.winsize > 0 { // skip for VOD playlists, where winsize = 0
 			i++
 		}
-		if seg.SCTE != nil {
-			switch seg.SCTE.Syntax {
-			case SCTE35_67_2014:
-				p.buf.WriteString("#EXT-SCTE35:")
-				p.buf.WriteString("CUE=\"")
-				p.buf.WriteString(seg.SCTE.Cue)
-				p.buf.WriteRune('"')
-				if seg.SCTE.ID != "" {
-					p.buf.WriteString(",ID=\"")
-					p.buf.WriteString(seg.SCTE.ID)
-					p.buf.WriteRune('"')
-				}
-				if seg.SCTE.Time != 0 {
-					p.buf.WriteString(",TIME=")
-					p.buf.WriteString(strconv.FormatFloat(seg.SCTE.Time, 'f', -1, 64))
-				}
-				p.buf.WriteRune('\n')
-			case SCTE35_OATCLS:
-				switch seg.SCTE.CueType {
-				case SCTE35Cue_Start:
-					p.buf.WriteString("#EXT-OATCLS-SCTE35:")
-					p.buf.WriteString(seg.SCTE.Cue)
-					p.buf.WriteRune('\n')
-					p.buf.WriteString("#EXT-X-CUE-OUT:")
-					p.buf.WriteString(strconv.FormatFloat(seg.SCTE.Time, 'f', -1, 64))
-					p.buf.WriteRune('\n')
-				case SCTE35Cue_Mid:
-					p.buf.WriteString("#EXT-X-CUE-OUT-CONT:")
-					p.buf.WriteString("ElapsedTime=")
-					p.buf.WriteString(strconv.FormatFloat(seg.SCTE.Elapsed, 'f', -1, 64))
-					p.buf.WriteString(",Duration=")
-					p.buf.WriteString(strconv.FormatFloat(seg.SCTE.Time, 'f', -1, 64))
-					p.buf.WriteString(",SCTE35=")
-					p.buf.WriteString(seg.SCTE.Cue)
-					p.buf.WriteRune('\n')
-				case SCTE35Cue_End:
-					p.buf.WriteString("#EXT-X-CUE-IN")
-					p.buf.WriteRune('\n')
-				}
+		if skip > 0 { // replaced with EXT-X-SKIP in delta updates
+			skip--
+			if !skipDateRanges {
+				writeDateRanges(buf, seg.DateRanges, eol)
 			}
+			continue
 		}
-		// check for key change
-		if seg.Key != nil && p.Key != seg.Key {
-			p.buf.WriteString("#EXT-X-KEY:")
-			p.buf.WriteString("METHOD=")
-			p.buf.WriteString(seg.Key.Method)
-			if seg.Key.Method != "NONE" {
-				p.buf.WriteString(",URI=\"")
-				p.buf.WriteString(seg.Key.URI)
-				p.buf.WriteRune('"')
-				if seg.Key.IV != "" {
-					p.buf.WriteString(",IV=")
-					p.buf.WriteString(seg.Key.IV)
-				}
-				if seg.Key.Keyformat != "" {
-					p.buf.WriteString(",KEYFORMAT=\"")
-					p.buf.WriteString(seg.Key.Keyformat)
-					p.buf.WriteRune('"')
-				}
-				if seg.Key.Keyformatversions != "" {
-					p.buf.WriteString(",KEYFORMATVERSIONS=\"")
-					p.buf.WriteString(seg.Key.Keyformatversions)
-					p.buf.WriteRune('"')
+		for _, line := range seg.Unknown {
+			buf.WriteString(line)
+			buf.WriteString(eol)
+		}
+		writeCustomTags(buf, seg.Custom, seg.customOrder, eol, PlacementHeader)
+		writeSCTE(buf, seg.SCTE, eol)
+		// ignore segment Map if default playlist Map is present
+		xmap := seg.Map
+		if p.Map != nil {
+			xmap = nil
+		}
+		keys := seg.keys()
+		// the key of an encrypted map applies to the segments after the
+		// map as well, so keys of the segment follow the map then
+		if xmap != nil && xmap.Key != nil && len(keys) == 0 {
+			keys = current
+		}
+		// check for key change, when parts carry own keys they are
+		// written in front of the parts instead
+		if len(keys) > 0 && (xmap == nil || xmap.Key == nil) {
+			current = p.writeKeys(buf, keys, current, seg.hasPartKeys())
+		}
+		if seg.Discontinuity {
+			buf.WriteString("#EXT-X-DISCONTINUITY")
+			buf.WriteString(eol)
+		}
+		if seg.Gap {
+			buf.WriteString("#EXT-X-GAP")
+			buf.WriteString(eol)
+		}
+		if xmap != nil {
+			if xmap.Key != nil {
+				current = p.writeKeys(buf, []*Key{xmap.Key}, current, false)
+			}
+			writeMap(buf, xmap, p.filterURI(URIMap, xmap.URI), p.mapOrder(xmap), eol)
+			if xmap.Key != nil {
+				if len(keys) == 0 {
+					keys = []*Key{{Method: "NONE"}}
 				}
+				current = p.writeKeys(buf, keys, current, seg.hasPartKeys())
 			}
-			p.buf.WriteRune('\n')
 		}
-		if seg.Discontinuity {
-			p.buf.WriteString("#EXT-X-DISCONTINUITY\n")
+		date := seg.ProgramDateTime
+		if dates != nil {
+			date = dates[pos]
 		}
-		// ignore segment Map if default playlist Map is present
-		if p.Map == nil && seg.Map != nil {
-			p.buf.WriteString("#EXT-X-MAP:")
-			p.buf.WriteString("URI=\"")
-			p.buf.WriteString(seg.Map.URI)
-			p.buf.WriteRune('"')
-			if seg.Map.Limit > 0 {
-				p.buf.WriteString(",BYTERANGE=")
-				p.buf.WriteString(strconv.FormatInt(seg.Map.Limit, 10))
-				p.buf.WriteRune('@')
-				p.buf.WriteString(strconv.FormatInt(seg.Map.Offset, 10))
-			}
-			p.buf.WriteRune('\n')
-		}
-		if !seg.ProgramDateTime.IsZero() {
-			p.buf.WriteString("#EXT-X-PROGRAM-DATE-TIME:")
-			p.buf.WriteString(seg.ProgramDateTime.Format(DATETIME))
-			p.buf.WriteRune('\n')
+		if !date.IsZero() {
+			buf.WriteString("#EXT-X-PROGRAM-DATE-TIME:")
+			buf.WriteString(date.Format(DATETIME))
+			buf.WriteString(eol)
+		}
+		writeDateRanges(buf, seg.DateRanges, eol)
+		if seg.Tiles != nil {
+			buf.WriteString("#EXT-X-TILES:RESOLUTION=")
+			buf.WriteString(seg.Tiles.Resolution)
+			buf.WriteString(",LAYOUT=")
+			buf.WriteString(seg.Tiles.Layout)
+			buf.WriteString(",DURATION=")
+			buf.WriteString(strconv.FormatFloat(seg.Tiles.Duration, 'f', 3, 64))
+			buf.WriteString(eol)
 		}
 		if seg.Limit > 0 {
-			p.buf.WriteString("#EXT-X-BYTERANGE:")
-			p.buf.WriteString(strconv.FormatInt(seg.Limit, 10))
-			p.buf.WriteRune('@')
-			p.buf.WriteString(strconv.FormatInt(seg.Offset, 10))
-			p.buf.WriteRune('\n')
+			buf.WriteString("#EXT-X-BYTERANGE:")
+			buf.WriteString(strconv.FormatInt(seg.Limit, 10))
+			buf.WriteRune('@')
+			buf.WriteString(strconv.FormatInt(seg.Offset, 10))
+			buf.WriteString(eol)
 		}
 
 		// Add Custom Segment Tags here
-		if seg.Custom != nil {
-			for _, v := range seg.Custom {
-				if customBuf := v.Encode(); customBuf != nil {
-					p.buf.WriteString(customBuf.String())
-					p.buf.WriteRune('\n')
-				}
-			}
-		}
+		writeCustomTags(buf, seg.Custom, seg.customOrder, eol, PlacementDefault)
+
+		current = p.writeParts(buf, seg.Parts, current)
 
-		p.buf.WriteString("#EXTINF:")
+		buf.WriteString("#EXTINF:")
 		if str, ok := durationCache[seg.Duration]; ok {
-			p.buf.WriteString(str)
+			buf.WriteString(str)
 		} else {
 			if p.durationAsInt {
 				// Old Android players has problems with non integer Duration.
 				durationCache[seg.Duration] = strconv.FormatInt(int64(math.Ceil(seg.Duration)), 10)
 			} else {
 				// Wowza Mediaserver and some others prefer floats.
-				durationCache[seg.Duration] = strconv.FormatFloat(seg.Duration, 'f', 3, 32)
+				durationCache[seg.Duration] = strconv.FormatFloat(seg.Duration, 'f', prec, 64)
 			}
-			p.buf.WriteString(durationCache[seg.Duration])
+			buf.WriteString(durationCache[seg.Duration])
 		}
-		p.buf.WriteRune(',')
-		p.buf.WriteString(seg.Title)
-		p.buf.WriteRune('\n')
-		p.buf.WriteString(seg.URI)
+		title := stripLineBreaks.Replace(seg.Title) // line breaks would end the tag
+		if title != "" || !p.encodeOpts.OmitEmptyTitleComma {
+			buf.WriteRune(',')
+		}
+		buf.WriteString(title)
+		buf.WriteString(eol)
+		writeCustomTags(buf, seg.Custom, seg.customOrder, eol, PlacementAfterEXTINF)
+		writeCustomTags(buf, seg.Custom, seg.customOrder, eol, PlacementBeforeURI)
+		uri := p.filterURI(URISegment, seg.URI)
 		if p.Args != "" {
-			p.buf.WriteRune('?')
-			p.buf.WriteString(p.Args)
+			uri = appendQuery(uri, p.Args)
+		}
+		buf.WriteString(stripLineBreaks.Replace(uri))
+		buf.WriteString(eol)
+		writeCustomTags(buf, seg.Custom, seg.customOrder, eol, PlacementFooter)
+	}
+	p.writeParts(buf, p.PendingParts, current)
+	for _, hint := range p.PreloadHints {
+		buf.WriteString("#EXT-X-PRELOAD-HINT:TYPE=")
+		buf.WriteString(hint.Type)
+		buf.WriteString(",URI=\"")
+		buf.WriteString(quotedString.Replace(p.filterURI(URIPreloadHint, hint.URI)))
+		buf.WriteRune('"')
+		if hint.Start > 0 {
+			buf.WriteString(",BYTERANGE-START=")
+			buf.WriteString(strconv.FormatInt(hint.Start, 10))
+		}
+		if hint.Length > 0 {
+			buf.WriteString(",BYTERANGE-LENGTH=")
+			buf.WriteString(strconv.FormatInt(hint.Length, 10))
 		}
-		p.buf.WriteRune('\n')
+		buf.WriteString(eol)
 	}
+	for _, rr := range p.RenditionReports {
+		buf.WriteString("#EXT-X-RENDITION-REPORT:URI=\"")
+		buf.WriteString(quotedString.Replace(p.filterURI(URIRenditionReport, rr.URI)))
+		buf.WriteString("\",LAST-MSN=")
+		buf.WriteString(strconv.FormatUint(rr.LastMSN, 10))
+		if rr.LastPart != nil {
+			buf.WriteString(",LAST-PART=")
+			buf.WriteString(strconv.Itoa(*rr.LastPart))
+		}
+		buf.WriteString(eol)
+	}
+	writeCustomTags(buf, p.Custom, p.customOrder, eol, PlacementFooter)
+	writeUnknownTags(buf, p.Unknown, TrailerPosition, eol)
 	if p.Closed {
-		p.buf.WriteString("#EXT-X-ENDLIST\n")
+		buf.WriteString("#EXT-X-ENDLIST")
+		buf.WriteString(eol)
 	}
-	return &p.buf
+}
+
+// writeKey writes EXT-X-KEY tag for the key with the uri instead of its URI.
+// Attributes are written in the order of the specification unless order
+// of their names is given.
+func writeKey(buf *bytes.Buffer, key *Key, uri string, order []string, eol string) {
+	attrs := [][2]string{{"METHOD", key.Method}}
+	if key.Method != "NONE" {
+		attrs = append(attrs, [2]string{"URI", `"` + quotedString.Replace(uri) + `"`})
+		if key.IV != "" {
+			attrs = append(attrs, [2]string{"IV", key.IV})
+		}
+		if key.Keyformat != "" {
+			attrs = append(attrs, [2]string{"KEYFORMAT", `"` + quotedString.Replace(key.Keyformat) + `"`})
+		}
+		if key.Keyformatversions != "" {
+			attrs = append(attrs, [2]string{"KEYFORMATVERSIONS", `"` + quotedString.Replace(key.Keyformatversions) + `"`})
+		}
+	}
+	writeOrderedAttributes(buf, "#EXT-X-KEY:", attrs, order, eol)
+}
+
+// writeMap writes EXT-X-MAP tag for the map with the uri instead of its URI.
+// Attributes are written in the order of the specification unless order
+// of their names is given.
+func writeMap(buf *bytes.Buffer, m *Map, uri string, order []string, eol string) {
+	attrs := [][2]string{{"URI", `"` + quotedString.Replace(uri) + `"`}}
+	if m.Limit > 0 {
+		attrs = append(attrs, [2]string{"BYTERANGE", strconv.FormatInt(m.Limit, 10) + "@" + strconv.FormatInt(m.Offset, 10)})
+	}
+	writeOrderedAttributes(buf, "#EXT-X-MAP:", attrs, order, eol)
+}
+
+// writeOrderedAttributes writes the tag with attributes sorted by order of
+// their names, attributes missing in the order keep their place after the
+// ordered ones.
+func writeOrderedAttributes(buf *bytes.Buffer, tag string, attrs [][2]string, order []string, eol string) {
+	if len(order) > 0 {
+		index := make(map[string]int, len(order))
+		for i, name := range order {
+			index[name] = i
+		}
+		rank := func(name string) int {
+			if i, ok := index[name]; ok {
+				return i
+			}
+			return len(order)
+		}
+		sort.SliceStable(attrs, func(i, j int) bool { return rank(attrs[i][0]) < rank(attrs[j][0]) })
+	}
+	buf.WriteString(tag)
+	for i, kv := range attrs {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteString(kv[0])
+		buf.WriteRune('=')
+		buf.WriteString(kv[1])
+	}
+	buf.WriteString(eol)
+}
+
+// SetFidelity enables encoding of EXT-X-KEY and EXT-X-MAP tags of the
+// decoded playlist with attributes in their original order, some legacy
+// devices are sensitive to it. Keys and maps set after decoding and all
+// tags of disabled fidelity mode use the order of the specification.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetFidelity(enabled bool) {
+	p.fidelity = enabled
+	p.buf.Reset()
+}
+
+// keyOrder returns the decoded order of attributes of the key in fidelity
+// mode.
+func (p *MediaPlaylist) keyOrder(key *Key) []string {
+	if !p.fidelity {
+		return nil
+	}
+	return p.attrOrders[key]
+}
+
+// mapOrder returns the decoded order of attributes of the map in fidelity
+// mode.
+func (p *MediaPlaylist) mapOrder(m *Map) []string {
+	if !p.fidelity {
+		return nil
+	}
+	return p.attrOrders[m]
+}
+
+// writeParts writes EXT-X-PART tags of a segment. A key of the part is
+// written in front of it when it differs from the keys in effect, it
+// returns the keys in effect after the parts.
+func (p *MediaPlaylist) writeParts(buf *bytes.Buffer, parts []*PartialSegment, current []*Key) []*Key {
+	eol := p.encodeOpts.eol()
+	for _, part := range parts {
+		if part.Key != nil {
+			current = p.writeKeys(buf, []*Key{part.Key}, current, false)
+		}
+		buf.WriteString("#EXT-X-PART:DURATION=")
+		buf.WriteString(strconv.FormatFloat(part.Duration, 'f', -1, 64))
+		buf.WriteString(",URI=\"")
+		buf.WriteString(quotedString.Replace(p.filterURI(URIPart, part.URI)))
+		buf.WriteRune('"')
+		if part.Independent {
+			buf.WriteString(",INDEPENDENT=YES")
+		}
+		if part.Limit > 0 {
+			buf.WriteString(",BYTERANGE=\"")
+			buf.WriteString(strconv.FormatInt(part.Limit, 10))
+			buf.WriteRune('@')
+			buf.WriteString(strconv.FormatInt(part.Offset, 10))
+			buf.WriteRune('"')
+		}
+		if part.Gap {
+			buf.WriteString(",GAP=YES")
+		}
+		buf.WriteString(eol)
+	}
+	return current
 }
 
 // For compatibility with Stringer interface
@@ -730,13 +1422,63 @@ func (p *MediaPlaylist) String() string {
 	return p.Encode().String()
 }
 
-// TargetDuration will be int on Encode
+// TargetDuration will be int on Encode.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) DurationAsInt(yes bool) {
 	if yes {
 		// duration must be integers if protocol version is less than 3
 		version(&p.ver, 3)
 	}
 	p.durationAsInt = yes
+	p.buf.Reset()
+}
+
+// SetDurationPrecision sets the number of decimals of EXTINF durations,
+// 3 by default. MinimalPrecision formats durations with the smallest
+// number of decimals which represents them exactly, e.g. 10 or 9.009.
+// Integer durations set by DurationAsInt take precedence.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetDurationPrecision(prec int) {
+	if prec < 0 {
+		prec = MinimalPrecision
+	}
+	p.durationPrec = &prec
+	p.buf.Reset()
+}
+
+// SetTargetDuration sets EXT-X-TARGETDURATION and freezes it, so appended
+// segments don't change it anymore, changing the target duration of a live
+// playlist violates the specification. Segments longer than the target
+// duration (after rounding) are refused then, see FreezeTargetDuration.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetTargetDuration(d float64) {
+	p.TargetDuration = d
+	p.frozenTarget = true
+	p.buf.Reset()
+}
+
+// FreezeTargetDuration sets whether EXT-X-TARGETDURATION is frozen. The
+// target duration of an unfrozen playlist grows with durations of appended
+// segments.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) FreezeTargetDuration(frozen bool) {
+	p.frozenTarget = frozen
+	p.buf.Reset()
+}
+
+// RecalculateTargetDuration sets EXT-X-TARGETDURATION to the longest
+// segment duration rounded up, so the target duration may shrink after
+// long segments were removed. It is applied to frozen playlists too.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) RecalculateTargetDuration() {
+	var target float64
+	for i := uint(0); i < p.count; i++ {
+		if seg := p.segmentAt(i); seg != nil {
+			target = math.Max(target, math.Ceil(seg.Duration))
+		}
+	}
+	p.TargetDuration = target
+	p.buf.Reset()
 }
 
 // Count tells us the number of items that are currently in the media playlist
@@ -744,71 +1486,305 @@ func (p *MediaPlaylist) Count() uint {
 	return p.count
 }
 
+// GetSegment returns the i-th segment counting from the oldest one in
+// constant time. Unlike indexing of Segments it follows the order of a
+// sliding playlist and skips unused slots of the ring buffer.
+func (p *MediaPlaylist) GetSegment(i uint) (*MediaSegment, bool) {
+	if i >= p.count {
+		return nil, false
+	}
+	return p.segmentAt(i), true
+}
+
+// GetSegmentBySeqId returns the segment with the media sequence number,
+// e.g. to inject tags into it. Sequence numbers of segments of the ring
+// buffer are consecutive, so the segment is found in constant time.
+func (p *MediaPlaylist) GetSegmentBySeqId(seq uint64) (*MediaSegment, bool) {
+	if p.count == 0 {
+		return nil, false
+	}
+	first := p.segmentAt(0).SeqId
+	if seq < first || seq-first >= uint64(p.count) {
+		return nil, false
+	}
+	return p.segmentAt(uint(seq - first)), true
+}
+
+// SegmentIterator walks segments of a media playlist, see SegmentIter.
+type SegmentIterator struct {
+	p   *MediaPlaylist
+	i   uint
+	seg *MediaSegment
+}
+
+// SegmentIter returns an iterator over segments of the playlist from the
+// oldest one. It serves toolchains without range-over-func, newer ones
+// may range over SegmentSeq.
+//
+//	it := p.SegmentIter()
+//	for it.Next() {
+//		seg := it.Segment()
+//		...
+//	}
+func (p *MediaPlaylist) SegmentIter() *SegmentIterator {
+	return &SegmentIterator{p: p}
+}
+
+// Next advances the iterator to the next segment, it returns false after
+// the last one.
+func (it *SegmentIterator) Next() bool {
+	var ok bool
+	it.seg, ok = it.p.GetSegment(it.i)
+	it.i++
+	return ok
+}
+
+// Segment returns the current segment of the iterator.
+func (it *SegmentIterator) Segment() *MediaSegment {
+	return it.seg
+}
+
 // Close sliding playlist and make them fixed.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) Close() {
-	if p.buf.Len() > 0 {
-		p.buf.WriteString("#EXT-X-ENDLIST\n")
-	}
 	p.Closed = true
+	p.buf.Reset()
+	p.publish()
+	for _, s := range p.startovers {
+		s.Close()
+	}
+	p.startovers = nil
 }
 
 // Set encryption key appeared once in header of the playlist (pointer to MediaPlaylist.Key).
 // It useful when keys not changed during playback.
 // Set tag for the whole list.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetDefaultKey(method, uri, iv, keyformat, keyformatversions string) error {
-	// A Media Playlist MUST indicate a EXT-X-VERSION of 5 or higher if it
-	// contains:
-	//   - The KEYFORMAT and KEYFORMATVERSIONS attributes of the EXT-X-KEY tag.
-	if keyformat != "" || keyformatversions != "" {
-		version(&p.ver, 5)
+	if err := checkKeyMethod(method); err != nil {
+		return err
 	}
 	p.Key = &Key{method, uri, iv, keyformat, keyformatversions}
-
+	version(&p.ver, keyVersion(p.Key))
+	p.buf.Reset()
 	return nil
 }
 
 // Set default Media Initialization Section values for playlist (pointer to MediaPlaylist.Map).
 // Set EXT-X-MAP tag for the whole playlist.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetDefaultMap(uri string, limit, offset int64) {
 	version(&p.ver, 5) // due section 4
-	p.Map = &Map{uri, limit, offset}
+	p.Map = &Map{uri, limit, offset, nil}
+	p.buf.Reset()
+}
+
+// SetServerControl sets delivery directives of the server (EXT-X-SERVER-CONTROL).
+// Playlist delta updates require protocol version 9, skipping of
+// EXT-X-DATERANGE tags in delta updates requires version 10.
+func (p *MediaPlaylist) SetServerControl(sc *ServerControl) error {
+	if sc != nil {
+		if sc.CanSkipDateRanges && sc.CanSkipUntil <= 0 {
+			return errors.New("CAN-SKIP-DATERANGES requires CAN-SKIP-UNTIL")
+		}
+		if sc.CanSkipUntil > 0 {
+			version(&p.ver, 9) // due section 4.4.5.1
+		}
+		if sc.CanSkipDateRanges {
+			version(&p.ver, 10)
+		}
+	}
+	p.ServerControl = sc
+	p.buf.Reset()
+	return nil
+}
+
+// SetPreloadHints sets the resources advertised with EXT-X-PRELOAD-HINT
+// tags at the end of the playlist. Use ValidatePreloadHints to check them
+// against the parts of the playlist.
+func (p *MediaPlaylist) SetPreloadHints(hints []*PreloadHint) {
+	p.PreloadHints = hints
+	p.buf.Reset()
+}
+
+// SetRenditionReports sets the state of peer renditions advertised with
+// EXT-X-RENDITION-REPORT tags at the end of the playlist.
+func (p *MediaPlaylist) SetRenditionReports(reports []RenditionReport) {
+	p.RenditionReports = reports
+	p.buf.Reset()
 }
 
 // Mark medialist as consists of only I-frames (Intra frames).
 // Set tag for the whole list.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetIframeOnly() {
 	version(&p.ver, 4) // due section 4.3.3
 	p.Iframe = true
+	p.buf.Reset()
 }
 
-// Set encryption key for the current segment of media playlist (pointer to Segment.Key)
+// Set encryption key for the current segment of media playlist (pointer to Segment.Key).
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetKey(method, uri, iv, keyformat, keyformatversions string) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
+	if err := checkKeyMethod(method); err != nil {
+		return err
+	}
+
+	seg := p.Segments[p.last()]
+	seg.Key = &Key{method, uri, iv, keyformat, keyformatversions}
+	seg.Keys = nil
+	version(&p.ver, keyVersion(seg.Key))
+	p.buf.Reset()
+	return nil
+}
+
+// AddKey adds encryption key of another key system to the current
+// segment, so the segment carries several EXT-X-KEY tags, e.g. for
+// FairPlay, Widevine and PlayReady at once. The first key of the segment
+// remains in its Key field.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AddKey(method, uri, iv, keyformat, keyformatversions string) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	if err := checkKeyMethod(method); err != nil {
+		return err
+	}
+	seg := p.Segments[p.last()]
+	key := &Key{method, uri, iv, keyformat, keyformatversions}
+	version(&p.ver, keyVersion(key))
+	if seg.Key == nil {
+		seg.Key = key
+	} else {
+		seg.Keys = append(seg.keys(), key)
+	}
+	p.buf.Reset()
+	return nil
+}
+
+// sameKey reports whether both keys describe the same EXT-X-KEY tag.
+func sameKey(a, b *Key) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// writeKeys writes EXT-X-KEY tags of the keys unless they are the keys in
+// effect. It returns the keys in effect after the tags. If skip is set the
+// keys are written in front of the parts of the segment instead, so the
+// keys in effect don't change.
+func (p *MediaPlaylist) writeKeys(buf *bytes.Buffer, keys, current []*Key, skip bool) []*Key {
+	if skip {
+		return current
+	}
+	if !sameKeys(keys, current) {
+		for _, key := range keys {
+			writeKey(buf, key, p.filterURI(URIKey, key.URI), p.keyOrder(key), p.encodeOpts.eol())
+		}
+	}
+	return keys
+}
 
-	// A Media Playlist MUST indicate a EXT-X-VERSION of 5 or higher if it
-	// contains:
-	//   - The KEYFORMAT and KEYFORMATVERSIONS attributes of the EXT-X-KEY tag.
-	if keyformat != "" || keyformatversions != "" {
-		version(&p.ver, 5)
+// sameKeys reports whether both sets describe the same EXT-X-KEY tags.
+func sameKeys(a, b []*Key) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameKey(a[i], b[i]) {
+			return false
+		}
 	}
+	return true
+}
 
-	p.Segments[p.last()].Key = &Key{method, uri, iv, keyformat, keyformatversions}
+// keys returns EXT-X-KEY tags of the segment.
+func (seg *MediaSegment) keys() []*Key {
+	if len(seg.Keys) > 0 {
+		return seg.Keys
+	}
+	if seg.Key != nil {
+		return []*Key{seg.Key}
+	}
+	return nil
+}
+
+// hasPartKeys reports whether any of the segment parts has own key.
+func (seg *MediaSegment) hasPartKeys() bool {
+	for _, part := range seg.Parts {
+		if part.Key != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePartKeys checks keys of the partial segments. When the key
+// changes in the middle of a segment the key of the segment itself must be
+// the same as the key of its final part, because both are defined by the
+// same EXT-X-KEY tag in the playlist.
+func (p *MediaPlaylist) ValidatePartKeys() error {
+	for i := uint(0); i < p.count; i++ {
+		seg := p.segmentAt(i)
+		if seg == nil || !seg.hasPartKeys() {
+			continue
+		}
+		var key *Key
+		for _, part := range seg.Parts {
+			if part.Key != nil {
+				key = part.Key
+			}
+		}
+		if !sameKey(key, seg.Key) {
+			return fmt.Errorf("segment %d: key of the segment differs from the key of its final part", seg.SeqId)
+		}
+	}
 	return nil
 }
 
-// Set map for the current segment of media playlist (pointer to Segment.Map)
+// Set map for the current segment of media playlist (pointer to Segment.Map).
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetMap(uri string, limit, offset int64) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
 	version(&p.ver, 5) // due section 4
-	p.Segments[p.last()].Map = &Map{uri, limit, offset}
+	p.Segments[p.last()].Map = &Map{uri, limit, offset, nil}
+	p.buf.Reset()
+	return nil
+}
+
+// SetMapKey sets encryption key of the map of the current segment. The
+// EXT-X-KEY tag is written in front of the EXT-X-MAP tag, keys of the
+// segment are repeated after the map if they differ. AES-128 encryption of
+// the map requires IV.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetMapKey(method, uri, iv, keyformat, keyformatversions string) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	seg := p.Segments[p.last()]
+	if seg.Map == nil {
+		return errors.New("segment has no map")
+	}
+	if err := checkKeyMethod(method); err != nil {
+		return err
+	}
+	if method == KeyMethodAES128 && iv == "" {
+		return errors.New("encrypted map requires IV")
+	}
+	seg.Map.Key = &Key{method, uri, iv, keyformat, keyformatversions}
+	version(&p.ver, keyVersion(seg.Map.Key))
+	p.buf.Reset()
 	return nil
 }
 
 // Set limit and offset for the current media segment (EXT-X-BYTERANGE support for protocol version 4).
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetRange(limit, offset int64) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
@@ -816,6 +1792,7 @@ func (p *MediaPlaylist) SetRange(limit, offset int64) error {
 	version(&p.ver, 4) // due section 3.4.1
 	p.Segments[p.last()].Limit = limit
 	p.Segments[p.last()].Offset = offset
+	p.buf.Reset()
 	return nil
 }
 
@@ -826,24 +1803,184 @@ func (p *MediaPlaylist) SetSCTE(cue string, id string, time float64) error {
 	return p.SetSCTE35(&SCTE{Syntax: SCTE35_67_2014, Cue: cue, ID: id, Time: time})
 }
 
-// SetSCTE35 sets the SCTE cue format for the current media segment
+// SetSCTE35 sets the SCTE cue format for the current media segment,
+// it replaces cues set before.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetSCTE35(scte35 *SCTE) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
-	p.Segments[p.last()].SCTE = scte35
+	p.Segments[p.last()].SetSCTE35(scte35)
+	p.buf.Reset()
 	return nil
 }
 
+// AppendSCTE35 adds the SCTE cue to the current media segment after the
+// cues set before, e.g. CUE-OUT of the next break after CUE-IN of the
+// previous one.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) AppendSCTE35(scte35 *SCTE) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	seg := p.Segments[p.last()]
+	seg.SCTE = append(seg.SCTE, scte35)
+	p.buf.Reset()
+	return nil
+}
+
+// SCTE35 returns the first SCTE cue of the segment or nil.
+func (seg *MediaSegment) SCTE35() *SCTE {
+	if len(seg.SCTE) == 0 {
+		return nil
+	}
+	return seg.SCTE[0]
+}
+
+// SetSCTE35 sets the single SCTE cue of the segment, nil removes the cues.
+func (seg *MediaSegment) SetSCTE35(scte35 *SCTE) {
+	if scte35 == nil {
+		seg.SCTE = nil
+		return
+	}
+	seg.SCTE = []*SCTE{scte35}
+}
+
+// writeSCTE writes tags of the SCTE cues in their order.
+func writeSCTE(buf *bytes.Buffer, cues []*SCTE, eol string) {
+	for _, scte := range cues {
+		switch scte.Syntax {
+		case SCTE35_67_2014:
+			buf.WriteString("#EXT-SCTE35:")
+			buf.WriteString("CUE=\"")
+			buf.WriteString(quotedString.Replace(scte.Cue))
+			buf.WriteRune('"')
+			if scte.ID != "" {
+				buf.WriteString(",ID=\"")
+				buf.WriteString(quotedString.Replace(scte.ID))
+				buf.WriteRune('"')
+			}
+			if scte.Time != 0 {
+				buf.WriteString(",TIME=")
+				buf.WriteString(strconv.FormatFloat(scte.Time, 'f', -1, 64))
+			}
+			buf.WriteString(eol)
+		case SCTE35_OATCLS:
+			switch scte.CueType {
+			case SCTE35Cue_Start:
+				buf.WriteString("#EXT-OATCLS-SCTE35:")
+				buf.WriteString(stripLineBreaks.Replace(scte.Cue))
+				buf.WriteString(eol)
+				buf.WriteString("#EXT-X-CUE-OUT:")
+				buf.WriteString(strconv.FormatFloat(scte.Time, 'f', -1, 64))
+				buf.WriteString(eol)
+			case SCTE35Cue_Mid:
+				buf.WriteString("#EXT-X-CUE-OUT-CONT:")
+				buf.WriteString("ElapsedTime=")
+				buf.WriteString(strconv.FormatFloat(scte.Elapsed, 'f', -1, 64))
+				buf.WriteString(",Duration=")
+				buf.WriteString(strconv.FormatFloat(scte.Time, 'f', -1, 64))
+				buf.WriteString(",SCTE35=")
+				buf.WriteString(stripLineBreaks.Replace(scte.Cue))
+				buf.WriteString(eol)
+			case SCTE35Cue_End:
+				buf.WriteString("#EXT-X-CUE-IN")
+				buf.WriteString(eol)
+			}
+		case SCTE35_CUE_OUT:
+			switch scte.CueType {
+			case SCTE35Cue_Start:
+				buf.WriteString("#EXT-X-CUE-OUT:DURATION=")
+				buf.WriteString(strconv.FormatFloat(scte.Time, 'f', -1, 64))
+				if scte.ID != "" {
+					buf.WriteString(",ID=\"")
+					buf.WriteString(quotedString.Replace(scte.ID))
+					buf.WriteRune('"')
+				}
+				if scte.Cue != "" {
+					buf.WriteString(",SCTE35=")
+					buf.WriteString(stripLineBreaks.Replace(scte.Cue))
+				}
+				writeExtraAttributes(buf, scte.Attributes, scte.AttributesQuoted)
+				buf.WriteString(eol)
+			case SCTE35Cue_Mid:
+				buf.WriteString("#EXT-X-CUE-OUT-CONT:")
+				buf.WriteString(strconv.FormatFloat(scte.Elapsed, 'f', -1, 64))
+				buf.WriteRune('/')
+				buf.WriteString(strconv.FormatFloat(scte.Time, 'f', -1, 64))
+				buf.WriteString(eol)
+			case SCTE35Cue_End:
+				buf.WriteString("#EXT-X-CUE-IN")
+				buf.WriteString(eol)
+			}
+		case SCTE35_ADOBE:
+			buf.WriteString("#EXT-X-CUE:")
+			attrs := make([]string, 0, 4)
+			if scte.ID != "" {
+				attrs = append(attrs, "ID=\""+quotedString.Replace(scte.ID)+"\"")
+			}
+			switch scte.CueType {
+			case SCTE35Cue_Start:
+				attrs = append(attrs, "TYPE=\"SpliceOut\"")
+			case SCTE35Cue_End:
+				attrs = append(attrs, "TYPE=\"SpliceIn\"")
+			}
+			if scte.Time != 0 {
+				attrs = append(attrs, "DURATION="+strconv.FormatFloat(scte.Time, 'f', -1, 64))
+			}
+			if scte.Cue != "" {
+				attrs = append(attrs, "CUE=\""+quotedString.Replace(scte.Cue)+"\"")
+			}
+			var extra bytes.Buffer
+			writeExtraAttributes(&extra, scte.Attributes, scte.AttributesQuoted)
+			if len(attrs) == 0 {
+				// other attributes are written after a comma
+				extra.Next(1)
+			}
+			buf.WriteString(strings.Join(attrs, ","))
+			buf.Write(extra.Bytes())
+			buf.WriteString(eol)
+		}
+	}
+}
+
 // Set discontinuity flag for the current media segment.
 // EXT-X-DISCONTINUITY indicates an encoding discontinuity between the media segment
 // that follows it and the one that preceded it (i.e. file format, number and type of tracks,
 // encoding parameters, encoding sequence, timestamp sequence).
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetDiscontinuity() error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
 	p.Segments[p.last()].Discontinuity = true
+	p.buf.Reset()
+	return nil
+}
+
+// Marks the current media segment as missing (EXT-X-GAP), clients
+// must not try to load it. Useful when an encoder outage leaves a hole
+// in a live stream.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetGap() error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	p.Segments[p.last()].Gap = true
+	version(&p.ver, 8)
+	p.buf.Reset()
+	return nil
+}
+
+// SetTiles sets the thumbnail grid (EXT-X-TILES) of the current media
+// segment of an image playlist.
+// This operation does reset playlist cache.
+func (p *MediaPlaylist) SetTiles(tiles *Tiles) error {
+	if p.count == 0 {
+		return errors.New("playlist is empty")
+	}
+	p.Segments[p.last()].Tiles = tiles
+	p.buf.Reset()
 	return nil
 }
 
@@ -852,37 +1989,35 @@ func (p *MediaPlaylist) SetDiscontinuity() error {
 // media segment with an absolute date and/or time.  It applies only
 // to the current media segment.
 // Date/time format is YYYY-MM-DDThh:mm:ssZ (ISO8601) and includes time zone.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetProgramDateTime(value time.Time) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
 	p.Segments[p.last()].ProgramDateTime = value
+	p.buf.Reset()
 	return nil
 }
 
-// SetCustomTag sets the provided tag on the media playlist for its TagName
+// SetCustomTag sets the provided tag on the media playlist for its TagName.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetCustomTag(tag CustomTag) {
-	if p.Custom == nil {
-		p.Custom = make(map[string]CustomTag)
-	}
-
-	p.Custom[tag.TagName()] = tag
+	setCustomTag(&p.Custom, &p.customOrder, tag)
+	version(&p.ver, extensionVersion(tag.TagName()))
+	p.buf.Reset()
 }
 
-// SetCustomTag sets the provided tag on the current media segment for its TagName
+// SetCustomTag sets the provided tag on the current media segment for its TagName.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetCustomSegmentTag(tag CustomTag) error {
 	if p.count == 0 {
 		return errors.New("playlist is empty")
 	}
 
 	last := p.Segments[p.last()]
-
-	if last.Custom == nil {
-		last.Custom = make(map[string]CustomTag)
-	}
-
-	last.Custom[tag.TagName()] = tag
-
+	setCustomTag(&last.Custom, &last.customOrder, tag)
+	version(&p.ver, extensionVersion(tag.TagName()))
+	p.buf.Reset()
 	return nil
 }
 
@@ -893,8 +2028,10 @@ func (p *MediaPlaylist) Version() uint8 {
 
 // SetVersion sets the playlist version number, note the version maybe changed
 // automatically by other Set methods.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetVersion(ver uint8) {
 	p.ver = ver
+	p.buf.Reset()
 }
 
 // WinSize returns the playlist's window size.
@@ -903,10 +2040,12 @@ func (p *MediaPlaylist) WinSize() uint {
 }
 
 // SetWinSize overwrites the playlist's window size.
+// This operation does reset playlist cache.
 func (p *MediaPlaylist) SetWinSize(winsize uint) error {
 	if winsize > p.capacity {
 		return errors.New("capacity must be greater than winsize or equal")
 	}
 	p.winsize = winsize
+	p.buf.Reset()
 	return nil
 }
@@ -206,8 +206,8 @@ func TestSetSCTE35(t *testing.T) {
 	if err := p.SetSCTE35(scte); err != nil {
 		t.Errorf("SetSCTE35 did not expect error: %v", err)
 	}
-	if !reflect.DeepEqual(p.Segments[0].SCTE, scte) {
-		t.Errorf("SetSCTE35\nexp: %#v\ngot: %#v", scte, p.Segments[0].SCTE)
+	if !reflect.DeepEqual(p.Segments[0].SCTE35(), scte) {
+		t.Errorf("SetSCTE35\nexp: %#v\ngot: %#v", scte, p.Segments[0].SCTE35())
 	}
 }
 
@@ -437,6 +437,105 @@ func TestEncodeMediaPlaylistWithCustomTags(t *testing.T) {
 	}
 }
 
+type placedCustomTag struct {
+	*MockCustomTag
+	placement TagPlacement
+}
+
+func (t placedCustomTag) Placement() TagPlacement {
+	return t.placement
+}
+
+func placedTag(name string, placement TagPlacement) CustomTag {
+	return placedCustomTag{&MockCustomTag{name: name, encodedString: name}, placement}
+}
+
+// Custom tags are encoded at their placements
+func TestEncodeCustomTagsPlacement(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	p.SetCustomTag(placedTag("#PLAYLIST-FOOTER", PlacementFooter))
+	p.SetCustomTag(placedTag("#PLAYLIST-HEADER", PlacementHeader))
+	p.Append("test01.ts", 5.0, "")
+	p.SetProgramDateTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, tag := range []CustomTag{
+		placedTag("#FOOTER", PlacementFooter),
+		placedTag("#BEFORE-URI", PlacementBeforeURI),
+		placedTag("#AFTER-EXTINF", PlacementAfterEXTINF),
+		placedTag("#HEADER", PlacementHeader),
+		&MockCustomTag{name: "#DEFAULT", encodedString: "#DEFAULT"},
+	} {
+		p.SetCustomSegmentTag(tag)
+	}
+	p.Append("test02.ts", 5.0, "")
+	p.Close()
+	expected := "#PLAYLIST-HEADER\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-TARGETDURATION:5\n" +
+		"#HEADER\n#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z\n#DEFAULT\n#EXTINF:5.000,\n#AFTER-EXTINF\n#BEFORE-URI\ntest01.ts\n#FOOTER\n" +
+		"#EXTINF:5.000,\ntest02.ts\n#PLAYLIST-FOOTER\n#EXT-X-ENDLIST\n"
+	encoded := p.String()
+	if !strings.HasSuffix(encoded, expected) {
+		t.Errorf("Custom tags are not at their placements, expected suffix:\n%s\ngot:\n%s", expected, encoded)
+	}
+
+	m := NewMasterPlaylist()
+	m.SetCustomTag(placedTag("#MASTER-FOOTER", PlacementFooter))
+	m.Append("low.m3u8", p, VariantParams{Bandwidth: 1000000})
+	if !strings.HasSuffix(m.String(), "low.m3u8\n#MASTER-FOOTER\n") {
+		t.Errorf("Custom tag is not in the footer:\n%s", m.String())
+	}
+}
+
+// placedDecoder decodes the placed custom tag
+type placedDecoder struct {
+	placedCustomTag
+}
+
+func (d placedDecoder) Decode(line string) (CustomTag, error) {
+	return d.placedCustomTag, nil
+}
+
+// Segment tags placed after URI are decoded back to their segment
+func TestCustomTagsFooterRoundTrip(t *testing.T) {
+	footer := placedCustomTag{&MockCustomTag{name: "#FOOTER", segment: true, encodedString: "#FOOTER"}, PlacementFooter}
+	in := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-TARGETDURATION:5\n" +
+		"#EXTINF:5.000,\ntest01.ts\n#FOOTER\n#EXTINF:5.000,\ntest02.ts\n#EXT-X-ENDLIST\n"
+	p, _ := NewMediaPlaylist(0, 2)
+	p.WithCustomDecoders([]CustomDecoder{placedDecoder{footer}})
+	if err := p.DecodeFrom(strings.NewReader(in), true); err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[0].Custom["#FOOTER"] == nil || len(p.Segments[1].Custom) != 0 {
+		t.Errorf("Expected the tag attached to the first segment, got %v and %v", p.Segments[0].Custom, p.Segments[1].Custom)
+	}
+	if out := p.String(); out != in {
+		t.Errorf("Expected the playlist unchanged:\n%s\ngot:\n%s", in, out)
+	}
+}
+
+// Custom tags are encoded in the order they were set
+func TestEncodeCustomTagsInOrder(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	names := []string{"#ZZZ", "#AAA", "#MMM", "#BBB"}
+	for _, name := range names {
+		p.SetCustomTag(&MockCustomTag{name: name, encodedString: name})
+	}
+	p.Append("test01.ts", 5.0, "")
+	for _, name := range names {
+		p.SetCustomSegmentTag(&MockCustomTag{name: name + "-SEG", encodedString: name + "-SEG"})
+	}
+	// replaced tag keeps its place, tags added to the map directly follow
+	p.SetCustomTag(&MockCustomTag{name: "#AAA", encodedString: "#AAA-2"})
+	p.Custom["#CCC"] = &MockCustomTag{name: "#CCC", encodedString: "#CCC"}
+	expected := "#ZZZ\n#AAA-2\n#MMM\n#BBB\n#CCC\n"
+	expectedSeg := "#ZZZ-SEG\n#AAA-SEG\n#MMM-SEG\n#BBB-SEG\n"
+	for i := 0; i < 10; i++ {
+		p.ResetCache()
+		encoded := p.String()
+		if !strings.Contains(encoded, expected) || !strings.Contains(encoded, expectedSeg) {
+			t.Fatalf("Custom tags are not in order:\n%s", encoded)
+		}
+	}
+}
+
 // Create new media playlist
 // Add two segments to media playlist
 // Encode structures to HLS
@@ -688,6 +787,85 @@ func TestStartTimeOffset(t *testing.T) {
 	}
 }
 
+func TestMediaPlaylistAutoDiscontinuitySeq(t *testing.T) {
+	p, _ := NewMediaPlaylist(2, 4)
+	p.DiscontinuitySeq = 5
+	p.SetAutoDiscontinuitySeq(true)
+	p.Append("t00.ts", 10, "")
+	p.Append("t01.ts", 10, "")
+	p.SetDiscontinuity()
+	p.Append("t02.ts", 10, "")
+	p.Remove()
+	if p.DiscontinuitySeq != 5 {
+		t.Errorf("Expected DiscontinuitySeq 5, got %d", p.DiscontinuitySeq)
+	}
+	p.Slide("t03.ts", 10, "")
+	if p.DiscontinuitySeq != 6 {
+		t.Errorf("Expected DiscontinuitySeq 6, got %d", p.DiscontinuitySeq)
+	}
+	if out := p.String(); !strings.Contains(out, "#EXT-X-DISCONTINUITY-SEQUENCE:6\n") {
+		t.Errorf("Expected EXT-X-DISCONTINUITY-SEQUENCE:6 in:\n%s", out)
+	}
+	// the mode is opt-in
+	p.SetAutoDiscontinuitySeq(false)
+	p.segmentAt(0).Discontinuity = true
+	p.Remove()
+	if p.DiscontinuitySeq != 6 {
+		t.Errorf("Expected DiscontinuitySeq 6, got %d", p.DiscontinuitySeq)
+	}
+}
+
+func TestMediaPlaylistTargetDuration(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 4)
+	p.Append("t00.ts", 9.5, "")
+	p.Append("t01.ts", 4, "")
+	if p.TargetDuration != 10 {
+		t.Errorf("Expected target duration 10, got %v", p.TargetDuration)
+	}
+	p.Remove()
+	p.RecalculateTargetDuration()
+	if p.TargetDuration != 4 {
+		t.Errorf("Expected recalculated target duration 4, got %v", p.TargetDuration)
+	}
+	p.SetTargetDuration(6)
+	if err := p.Append("t02.ts", 6.4, ""); err != nil {
+		t.Errorf("Expected segment rounded to the target duration to be appended: %s", err)
+	}
+	if err := p.Append("t03.ts", 7, ""); err == nil {
+		t.Error("Expected error for segment longer than frozen target duration")
+	}
+	if p.TargetDuration != 6 || p.Count() != 2 {
+		t.Errorf("Expected frozen target duration 6 and 2 segments, got %v and %d", p.TargetDuration, p.Count())
+	}
+	p.FreezeTargetDuration(false)
+	if err := p.Append("t03.ts", 7, ""); err != nil || p.TargetDuration != 7 {
+		t.Errorf("Expected target duration 7, got %v (%v)", p.TargetDuration, err)
+	}
+}
+
+func TestMediaPlaylistDurationPrecision(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 3)
+	p.Append("t00.ts", 10, "")
+	p.Append("t01.ts", 9.00901, "")
+	// float32 formatting lost precision of long segments
+	p.Append("t02.ts", 86400.123, "")
+	for _, expected := range []string{"#EXTINF:10.000,", "#EXTINF:9.009,", "#EXTINF:86400.123,"} {
+		if !strings.Contains(p.String(), expected) {
+			t.Errorf("Expected %s in:\n%s", expected, p.String())
+		}
+	}
+	p.SetDurationPrecision(MinimalPrecision)
+	for _, expected := range []string{"#EXTINF:10,", "#EXTINF:9.00901,", "#EXTINF:86400.123,"} {
+		if !strings.Contains(p.String(), expected) {
+			t.Errorf("Expected %s in:\n%s", expected, p.String())
+		}
+	}
+	p.SetDurationPrecision(1)
+	if !strings.Contains(p.String(), "#EXTINF:9.0,") {
+		t.Errorf("Expected #EXTINF:9.0, in:\n%s", p.String())
+	}
+}
+
 func TestMediaPlaylist_Slide(t *testing.T) {
 	m, e := NewMediaPlaylist(3, 4)
 	if e != nil {
@@ -947,6 +1125,650 @@ func TestMasterSetVersion(t *testing.T) {
 	}
 }
 
+func TestEncodeMediaPlaylistWithPartKeys(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 2)
+	key1 := &Key{Method: "AES-128", URI: "key1"}
+	key2 := &Key{Method: "AES-128", URI: "key2"}
+	p.AppendSegment(&MediaSegment{
+		URI:      "seg0.mp4",
+		Duration: 4,
+		Key:      key2,
+		Parts: []*PartialSegment{
+			{URI: "seg0.0.mp4", Duration: 2, Independent: true, Key: key1},
+			{URI: "seg0.1.mp4", Duration: 2, Key: key2, Limit: 100, Offset: 20},
+		},
+	})
+	expected := `#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXT-X-PART:DURATION=2,URI="seg0.0.mp4",INDEPENDENT=YES
+#EXT-X-KEY:METHOD=AES-128,URI="key2"
+#EXT-X-PART:DURATION=2,URI="seg0.1.mp4",BYTERANGE="100@20"
+#EXTINF:4.000,
+seg0.mp4
+`
+	if out := p.String(); !strings.HasSuffix(out, expected) {
+		t.Errorf("Expected suffix:\n%s\ngot:\n%s", expected, out)
+	}
+	if err := p.ValidatePartKeys(); err != nil {
+		t.Errorf("Unexpected validation error: %s", err)
+	}
+	p.Segments[0].Key = key1
+	if err := p.ValidatePartKeys(); err == nil {
+		t.Error("Expected error for segment key different from its final part key")
+	}
+}
+
+func TestEncodeMediaPlaylistPartKeysAcrossSegments(t *testing.T) {
+	p, _ := NewMediaPlaylist(0, 3)
+	key1 := &Key{Method: "AES-128", URI: "key1"}
+	key2 := &Key{Method: "AES-128", URI: "key2"}
+	for i, keys := range [][]*Key{{key1, key1}, {key1, key1}, {key1, key2}} {
+		seg := &MediaSegment{URI: fmt.Sprintf("seg%d.mp4", i), Duration: 4, Key: keys[1]}
+		for j, key := range keys {
+			seg.Parts = append(seg.Parts, &PartialSegment{URI: fmt.Sprintf("seg%d.%d.mp4", i, j), Duration: 2, Key: key})
+		}
+		p.AppendSegment(seg)
+	}
+	out := p.String()
+	if n := strings.Count(out, "#EXT-X-KEY"); n != 2 {
+		t.Errorf("Expected EXT-X-KEY written on key changes only, got %d:\n%s", n, out)
+	}
+	if !strings.Contains(out, "seg2.0.mp4\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"key2\"\n#EXT-X-PART:DURATION=2,URI=\"seg2.1.mp4\"") {
+		t.Errorf("Expected key change in front of the last part:\n%s", out)
+	}
+}
+
+func TestAppendPartialToMediaPlaylist(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.AppendPartial("seg0.0.mp4", 1, true)
+	p.AppendPartial("seg0.1.mp4", 1, false)
+	if len(p.PendingParts) != 2 {
+		t.Fatalf("Expected 2 pending parts, got %d", len(p.PendingParts))
+	}
+	p.Append("seg0.mp4", 2, "")
+	if len(p.PendingParts) != 0 || len(p.Segments[0].Parts) != 2 {
+		t.Fatalf("Parts must be moved to the completed segment")
+	}
+	p.AppendPartialSegment(&PartialSegment{URI: "seg1.0.mp4", Duration: 1, Gap: true})
+	expected := `#EXT-X-PART:DURATION=1,URI="seg0.0.mp4",INDEPENDENT=YES
+#EXT-X-PART:DURATION=1,URI="seg0.1.mp4"
+#EXTINF:2.000,
+seg0.mp4
+#EXT-X-PART:DURATION=1,URI="seg1.0.mp4",GAP=YES
+`
+	if out := p.String(); !strings.HasSuffix(out, expected) {
+		t.Errorf("Expected suffix:\n%s\ngot:\n%s", expected, out)
+	}
+	p.Close()
+	if err := p.AppendPartial("seg1.1.mp4", 1, false); err == nil {
+		t.Error("Expected error on appending parts to closed playlist")
+	}
+}
+
+func TestSetServerControl(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	if err := p.SetServerControl(&ServerControl{CanSkipDateRanges: true}); err == nil {
+		t.Error("Expected error for CAN-SKIP-DATERANGES without CAN-SKIP-UNTIL")
+	}
+	err := p.SetServerControl(&ServerControl{
+		CanSkipUntil:      36,
+		CanSkipDateRanges: true,
+		HoldBack:          12,
+		PartHoldBack:      1.5,
+		CanBlockReload:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != 10 {
+		t.Errorf("Expected version 10, got %d", p.Version())
+	}
+	expected := "#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=36,CAN-SKIP-DATERANGES=YES,HOLD-BACK=12,PART-HOLD-BACK=1.5,CAN-BLOCK-RELOAD=YES\n"
+	if !strings.Contains(p.String(), expected) {
+		t.Errorf("Expected %q in:\n%s", expected, p)
+	}
+}
+
+func TestEncodeMasterPlaylistWithExtraAttributes(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.SetVersion(6)
+	vp := VariantParams{Bandwidth: 1500000}
+	vp.SetExtraAttribute("X-CDN-HINT", "edge \"a\"")
+	vp.SetExtraAttribute("X-WEIGHT", "10")
+	vp.SetExtraAttribute("bad name", "skipped")
+	vp.SetQuotedExtraAttribute("X-ID", "123")
+	m.Append("low.m3u8", nil, vp)
+	expected := "#EXT-X-STREAM-INF:BANDWIDTH=1500000,X-CDN-HINT=\"edge a\",X-ID=\"123\",X-WEIGHT=10\nlow.m3u8\n"
+	if !strings.HasSuffix(m.String(), expected) {
+		t.Errorf("Expected suffix %q, got:\n%s", expected, m)
+	}
+}
+
+func TestPartTargetCalculation(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.AppendPartial("seg0.0.mp4", 0.5, true)
+	p.AppendPartial("seg0.1.mp4", 0.75, false)
+	p.Append("seg0.mp4", 1.25, "")
+	if p.PartTarget != 0.75 {
+		t.Errorf("Expected PART-TARGET 0.75, got %v", p.PartTarget)
+	}
+	p.AppendSegment(&MediaSegment{URI: "seg1.mp4", Duration: 1, Parts: []*PartialSegment{{URI: "seg1.0.mp4", Duration: 1}}})
+	if p.PartTarget != 1 {
+		t.Errorf("Expected PART-TARGET 1, got %v", p.PartTarget)
+	}
+	if !strings.Contains(p.String(), "#EXT-X-PART-INF:PART-TARGET=1\n") {
+		t.Errorf("EXT-X-PART-INF not found in:\n%s", p)
+	}
+}
+
+func TestSetRenditionReports(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.Append("seg0.mp4", 4, "")
+	three, zero := 3, 0
+	p.SetRenditionReports([]RenditionReport{
+		{URI: "../hi/index.m3u8", LastMSN: 12, LastPart: &three},
+		{URI: "../mid/index.m3u8", LastMSN: 12, LastPart: &zero},
+		{URI: "../lo/index.m3u8", LastMSN: 12},
+	})
+	expected := "seg0.mp4\n" +
+		"#EXT-X-RENDITION-REPORT:URI=\"../hi/index.m3u8\",LAST-MSN=12,LAST-PART=3\n" +
+		"#EXT-X-RENDITION-REPORT:URI=\"../mid/index.m3u8\",LAST-MSN=12,LAST-PART=0\n" +
+		"#EXT-X-RENDITION-REPORT:URI=\"../lo/index.m3u8\",LAST-MSN=12\n"
+	if !strings.HasSuffix(p.String(), expected) {
+		t.Errorf("Expected suffix:\n%s\ngot:\n%s", expected, p)
+	}
+}
+
+func TestEncodeDelta(t *testing.T) {
+	p, _ := NewMediaPlaylist(6, 6)
+	for i := 0; i < 6; i++ {
+		p.Append(fmt.Sprintf("seg%d.ts", i), 4, "")
+	}
+	if _, err := p.EncodeDelta(12); err == nil {
+		t.Error("Expected error for playlist without CAN-SKIP-UNTIL")
+	}
+	p.SetServerControl(&ServerControl{CanSkipUntil: 12})
+	if _, err := p.EncodeDelta(8); err == nil {
+		t.Error("Expected error for skip boundary less than CAN-SKIP-UNTIL")
+	}
+	full := p.String()
+	delta, err := p.EncodeDelta(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := delta.String()
+	if !strings.Contains(out, "#EXT-X-SKIP:SKIPPED-SEGMENTS=3\n#EXTINF:4.000,\nseg3.ts\n") {
+		t.Errorf("Expected first 3 segments to be skipped:\n%s", out)
+	}
+	if strings.Contains(out, "seg2.ts") || !strings.Contains(out, "#EXT-X-VERSION:9\n") {
+		t.Errorf("Unexpected delta update:\n%s", out)
+	}
+	if p.String() != full || strings.Contains(full, "#EXT-X-SKIP") {
+		t.Errorf("Delta encoding must not affect the cached playlist:\n%s", full)
+	}
+}
+
+func TestEncodeDeltaDateRanges(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, _ := NewMediaPlaylist(6, 6)
+	for i := 0; i < 6; i++ {
+		p.Append(fmt.Sprintf("seg%d.ts", i), 4, "")
+		if i == 0 {
+			p.SetProgramDateTime(start)
+			p.SetDateRange(&DateRange{ID: "ad-1", StartDate: start, Duration: 30})
+		}
+	}
+	p.SetServerControl(&ServerControl{CanSkipUntil: 12})
+	delta, err := p.EncodeDelta(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "#EXT-X-SKIP:SKIPPED-SEGMENTS=3\n#EXT-X-DATERANGE:ID=\"ad-1\",START-DATE=\"2020-01-01T00:00:00Z\",DURATION=30\n#EXTINF:4.000,\nseg3.ts\n"
+	if out := delta.String(); !strings.Contains(out, expected) {
+		t.Errorf("Expected date range of skipped segment kept:\n%s\ngot:\n%s", expected, out)
+	}
+
+	// the client reconstructs the playlist without duplicate date ranges
+	d, _ := NewMediaPlaylist(0, 8)
+	if err = d.Decode(*delta, true); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.ApplyDelta(p); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(d.String(), "#EXT-X-DATERANGE") != 1 {
+		t.Errorf("Expected one date range in reconstructed playlist:\n%s", d)
+	}
+
+	p.SetServerControl(&ServerControl{CanSkipUntil: 12, CanSkipDateRanges: true})
+	p.RecentlyRemoved = []string{"ad-0", "ad-x"}
+	if delta, err = p.EncodeDelta(12); err != nil {
+		t.Fatal(err)
+	}
+	expected = "#EXT-X-SKIP:SKIPPED-SEGMENTS=3,RECENTLY-REMOVED-DATERANGES=\"ad-0\tad-x\"\n#EXTINF:4.000,\nseg3.ts\n"
+	if out := delta.String(); !strings.Contains(out, expected) || strings.Contains(out, "#EXT-X-DATERANGE") {
+		t.Errorf("Expected date ranges skipped:\n%s\ngot:\n%s", expected, out)
+	}
+	if err = d.Decode(*delta, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.RecentlyRemoved) != 2 || d.RecentlyRemoved[1] != "ad-x" {
+		t.Errorf("Unexpected recently removed date ranges %v", d.RecentlyRemoved)
+	}
+}
+
+func TestSetGap(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	if err := p.SetGap(); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("seg0.ts", 4, "")
+	p.Append("seg1.ts", 4, "")
+	if err := p.SetGap(); err != nil {
+		t.Fatal(err)
+	}
+	out := p.String()
+	if !strings.Contains(out, "#EXT-X-GAP\n#EXTINF:4.000,\nseg1.ts\n") || strings.Count(out, "#EXT-X-GAP") != 1 {
+		t.Errorf("Expected only the last segment marked as gap:\n%s", out)
+	}
+	if p.Version() < 8 {
+		t.Errorf("EXT-X-GAP requires version 8, got %d", p.Version())
+	}
+}
+
+func TestSetTiles(t *testing.T) {
+	p, _ := NewMediaPlaylist(2, 2)
+	if err := p.SetTiles(&Tiles{}); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("tiles0.jpg", 10, "")
+	if err := p.SetTiles(&Tiles{Resolution: "312x180", Layout: "5x2", Duration: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if out := p.String(); !strings.Contains(out, "#EXT-X-TILES:RESOLUTION=312x180,LAYOUT=5x2,DURATION=1.000\n#EXTINF:10.000,\ntiles0.jpg\n") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+}
+
+func TestEncodeMasterPlaylistWithAllowedCPC(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, AllowedCPC: map[string][]string{
+		"com.microsoft.playready":        {"SL3000"},
+		"com.apple.streamingkeydelivery": {"AppleMain", "Baseline"},
+	}})
+	expected := `#EXT-X-STREAM-INF:BANDWIDTH=3000000,PROGRAM-ID=0,ALLOWED-CPC="com.apple.streamingkeydelivery:AppleMain/Baseline,com.microsoft.playready:SL3000"`
+	if out := m.String(); !strings.Contains(out, expected+"\nhi.m3u8\n") {
+		t.Errorf("Expected %s in playlist:\n%s", expected, out)
+	}
+}
+
+func TestEncodeMasterPlaylistWithoutProgramID(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, ProgramId: 1})
+	m.Append("hi-iframe.m3u8", nil, VariantParams{Bandwidth: 300000, Iframe: true})
+	if out := m.String(); strings.Count(out, "PROGRAM-ID=") != 2 {
+		t.Errorf("Expected PROGRAM-ID for version %d:\n%s", m.Version(), out)
+	}
+	m.OmitProgramID(true)
+	if out := m.String(); strings.Contains(out, "PROGRAM-ID") {
+		t.Errorf("Unexpected PROGRAM-ID:\n%s", out)
+	}
+}
+
+func TestSetFidelity(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MAP:BYTERANGE="600@0",URI="init.mp4"
+#EXT-X-KEY:IV=0x1,URI="key1",KEYFORMAT="identity",METHOD=AES-128
+#EXTINF:4.000,
+seg0.mp4
+#EXT-X-KEY:METHOD=AES-128,KEYFORMAT="identity",URI="key2"
+#EXTINF:4.000,
+seg1.mp4
+`
+	p, _ := NewMediaPlaylist(0, 2)
+	if err := p.DecodeFrom(strings.NewReader(playlist), true); err != nil {
+		t.Fatal(err)
+	}
+	out := p.String()
+	if !strings.Contains(out, "#EXT-X-KEY:METHOD=AES-128,URI=\"key1\",IV=0x1,KEYFORMAT=\"identity\"\n") ||
+		!strings.Contains(out, "#EXT-X-MAP:URI=\"init.mp4\",BYTERANGE=600@0\n") {
+		t.Errorf("Expected attributes in spec order:\n%s", out)
+	}
+	p.SetFidelity(true)
+	out = p.String()
+	for _, line := range []string{
+		"#EXT-X-MAP:BYTERANGE=600@0,URI=\"init.mp4\"\n",
+		"#EXT-X-KEY:IV=0x1,URI=\"key1\",KEYFORMAT=\"identity\",METHOD=AES-128\n",
+		"#EXT-X-KEY:METHOD=AES-128,KEYFORMAT=\"identity\",URI=\"key2\"\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in playlist:\n%s", line, out)
+		}
+	}
+}
+
+func TestEncodeMasterPlaylistWithReqVideoLayout(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("stereo.m3u8", nil, VariantParams{Bandwidth: 20000000, Codecs: "mvc1.64002a", ReqVideoLayout: "CH-STEREO"})
+	if m.Version() != 12 {
+		t.Errorf("REQ-VIDEO-LAYOUT requires version 12, got %d", m.Version())
+	}
+	if out := m.String(); !strings.Contains(out, "#EXT-X-STREAM-INF:BANDWIDTH=20000000,CODECS=\"mvc1.64002a\",REQ-VIDEO-LAYOUT=\"CH-STEREO\"\nstereo.m3u8\n") {
+		t.Errorf("Unexpected playlist:\n%s", out)
+	}
+}
+
+func TestEncodeMasterPlaylistWithHiResAudio(t *testing.T) {
+	alt := &Alternative{Type: "AUDIO", GroupId: "flac", Name: "Lossless", Channels: "2", BitDepth: 24, SampleRate: 96000, URI: "flac.m3u8"}
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 5000000, Audio: "flac", Alternatives: []*Alternative{alt}})
+	expected := `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="flac",NAME="Lossless",DEFAULT=NO,CHANNELS="2",BIT-DEPTH=24,SAMPLE-RATE=96000,URI="flac.m3u8"`
+	if out := m.String(); !strings.Contains(out, expected+"\n") {
+		t.Errorf("Expected %s in playlist:\n%s", expected, out)
+	}
+}
+
+func TestAppendSCTE35(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 2)
+	if err := p.AppendSCTE35(&SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_End}); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("seg0.ts", 10, "")
+	p.SetSCTE35(&SCTE{Syntax: SCTE35_OATCLS, CueType: SCTE35Cue_End})
+	if err := p.AppendSCTE35(&SCTE{Syntax: SCTE35_67_2014, Cue: "cue", ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Segments[0].SCTE) != 2 {
+		t.Fatalf("Expected 2 cues, got %d", len(p.Segments[0].SCTE))
+	}
+	p.SetSCTE35(&SCTE{Syntax: SCTE35_67_2014, Cue: "other"})
+	if len(p.Segments[0].SCTE) != 1 || p.Segments[0].SCTE35().Cue != "other" {
+		t.Errorf("SetSCTE35 must replace the cues, got %+v", p.Segments[0].SCTE)
+	}
+}
+
+// Encoded EXT-X-MEDIA-SEQUENCE must be the number of the first written
+// segment after any interleaving of Append, Remove and Slide.
+func TestEncodeMediaSequenceOfWindow(t *testing.T) {
+	tests := []struct {
+		name  string
+		ops   string // a - Append, r - Remove, s - Slide, c - Close
+		first int
+		count int
+	}{
+		{"appends beyond window", "aaaaa", 0, 3},
+		{"removes", "aaaarr", 2, 2},
+		{"slides", "aasssss", 4, 3},
+		{"remove all and append", "aarra", 2, 1},
+		{"slides and removes", "asarsrs", 2, 3},
+		{"removes from closed playlist", "aaaacrr", 2, 2},
+	}
+	for _, tc := range tests {
+		p, _ := NewMediaPlaylist(3, 8)
+		n := 0
+		for _, op := range tc.ops {
+			switch op {
+			case 'a':
+				p.Append(fmt.Sprintf("seg%d.ts", n), 4, "")
+				n++
+			case 's':
+				p.Slide(fmt.Sprintf("seg%d.ts", n), 4, "")
+				n++
+			case 'r':
+				p.Remove()
+			case 'c':
+				p.Close()
+			}
+		}
+		out := p.String()
+		if !strings.Contains(out, fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d\n", tc.first)) {
+			t.Errorf("%s: expected MEDIA-SEQUENCE %d:\n%s", tc.name, tc.first, out)
+		}
+		if !strings.Contains(out, fmt.Sprintf("#EXTINF:4.000,\nseg%d.ts\n", tc.first)) {
+			t.Errorf("%s: expected seg%d.ts first:\n%s", tc.name, tc.first, out)
+		}
+		if c := strings.Count(out, "#EXTINF"); c != tc.count {
+			t.Errorf("%s: expected %d segments, got %d", tc.name, tc.count, c)
+		}
+		// each segment keeps its number, decoding must restore them
+		d, _ := NewMediaPlaylist(3, 8)
+		if err := d.DecodeFrom(strings.NewReader(out), true); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for i := uint(0); i < d.Count(); i++ {
+			seg := d.segmentAt(i)
+			if seg.URI != fmt.Sprintf("seg%d.ts", seg.SeqId) {
+				t.Errorf("%s: segment %s decoded with number %d", tc.name, seg.URI, seg.SeqId)
+			}
+		}
+	}
+}
+
+func TestAddKey(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	if err := p.AddKey("SAMPLE-AES", "skd://key1", "", "com.apple.streamingkeydelivery", "1"); err == nil {
+		t.Error("Expected error for empty playlist")
+	}
+	p.Append("seg0.ts", 4, "")
+	p.SetKey("SAMPLE-AES", "skd://key1", "", "com.apple.streamingkeydelivery", "1")
+	p.AddKey("SAMPLE-AES", "https://pr.example.com", "", "com.microsoft.playready", "1")
+	p.Append("seg1.ts", 4, "")
+	p.SetKey("SAMPLE-AES", "skd://key2", "", "com.apple.streamingkeydelivery", "1")
+	expected := `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="https://pr.example.com",KEYFORMAT="com.microsoft.playready",KEYFORMATVERSIONS="1"
+#EXTINF:4.000,
+seg0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key2",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXTINF:4.000,
+seg1.ts
+`
+	if out := p.String(); !strings.HasSuffix(out, expected) {
+		t.Errorf("Expected keys of both key systems:\n%s", out)
+	}
+}
+
+func TestEncodeMediaPlaylistWithEncryptedMap(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	if err := p.Append("seg0.mp4", 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetMapKey("AES-128", "https://example.com/init.key", "0x1", "", ""); err == nil {
+		t.Error("Expected error for segment without map")
+	}
+	p.SetMap("init.mp4", 0, 0)
+	if err := p.SetMapKey("AES-128", "https://example.com/init.key", "", "", ""); err == nil {
+		t.Error("Expected error for map key without IV")
+	}
+	p.SetMapKey("AES-128", "https://example.com/init.key", "0x1", "", "")
+	p.SetKey("AES-128", "https://example.com/seg.key", "0x2", "", "")
+	p.Append("seg1.mp4", 4, "")
+	expected := `#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/init.key",IV=0x1
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/seg.key",IV=0x2
+#EXTINF:4.000,
+seg0.mp4
+#EXTINF:4.000,
+seg1.mp4
+`
+	if out := p.String(); !strings.HasSuffix(out, expected) {
+		t.Errorf("Expected the key of the map in front of it:\n%s", out)
+	}
+}
+
+func TestEncodeMasterPlaylistWithStart(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("v.m3u8", nil, VariantParams{Bandwidth: 1000000})
+	m.SetStart(-12.5, true)
+	if out := m.String(); !strings.Contains(out, "#EXT-X-START:TIME-OFFSET=-12.5,PRECISE=YES\n") {
+		t.Errorf("Expected EXT-X-START in playlist:\n%s", out)
+	}
+}
+
+func TestEncodeMediaPlaylistAllowCache(t *testing.T) {
+	p, _ := NewMediaPlaylist(1, 1)
+	p.Append("seg0.ts", 4, "")
+	if strings.Contains(p.String(), "#EXT-X-ALLOW-CACHE") {
+		t.Error("EXT-X-ALLOW-CACHE must not be written unless set")
+	}
+	p.AllowCache = AllowCacheNo
+	p.ResetCache()
+	if !strings.Contains(p.String(), "#EXT-X-ALLOW-CACHE:NO\n") {
+		t.Errorf("Expected EXT-X-ALLOW-CACHE:NO:\n%s", p)
+	}
+	p.MediaType = EVENT
+	p.AllowCache = AllowCacheYes
+	p.ResetCache()
+	if out := p.String(); !strings.Contains(out, "#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-ALLOW-CACHE:YES\n") {
+		t.Errorf("Expected EXT-X-ALLOW-CACHE:YES for EVENT playlist:\n%s", out)
+	}
+}
+
+func TestEncodeCacheOfMediaPlaylist(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.Append("test01.ts", 5.0, "")
+	p.Append("test02.ts", 5.0, "")
+	out := p.String()
+
+	// fields changed directly are not encoded until the cache is reset
+	p.TargetDuration = 10
+	if p.String() != out {
+		t.Error("Expected the cached playlist")
+	}
+	p.ResetCache()
+	if !strings.Contains(p.String(), "#EXT-X-TARGETDURATION:10\n") {
+		t.Error("Expected the playlist encoded again after ResetCache")
+	}
+
+	for _, v := range []struct {
+		name   string
+		change func()
+	}{
+		{"SetDiscontinuity", func() { p.SetDiscontinuity() }},
+		{"SetKey", func() { p.SetKey("AES-128", "key", "", "", "") }},
+		{"SetMap", func() { p.SetMap("init.mp4", 0, 0) }},
+		{"SetRange", func() { p.SetRange(100, 0) }},
+		{"SetProgramDateTime", func() { p.SetProgramDateTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) }},
+		{"SetSCTE35", func() { p.SetSCTE35(&SCTE{Syntax: SCTE35_67_2014, Cue: "cue"}) }},
+		{"SetDefaultMap", func() { p.SetDefaultMap("default.mp4", 0, 0) }},
+		{"SetIframeOnly", func() { p.SetIframeOnly() }},
+	} {
+		out := p.String()
+		v.change()
+		if p.String() == out {
+			t.Errorf("%s must reset the playlist cache", v.name)
+		}
+	}
+}
+
+func TestEncodeCacheOfMasterPlaylist(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("video.m3u8", nil, VariantParams{Bandwidth: 1000000})
+	out := m.String()
+	m.SetIndependentSegments(true)
+	if m.String() == out {
+		t.Error("SetIndependentSegments must reset the playlist cache")
+	}
+	out = m.String()
+	m.SetVersion(7)
+	if m.String() == out {
+		t.Error("SetVersion must reset the playlist cache")
+	}
+}
+
+func TestEncodeTo(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 5)
+	p.Append("test01.ts", 5.0, "")
+	m := NewMasterPlaylist()
+	m.Append("chunklist.m3u8", p, VariantParams{Bandwidth: 1500000})
+
+	var buf bytes.Buffer
+	p.EncodeTo(&buf)
+	if buf.String() != p.String() {
+		t.Errorf("Unexpected media playlist:\n%s", buf.String())
+	}
+	// cached output is copied
+	buf.Reset()
+	p.EncodeTo(&buf)
+	if buf.String() != p.String() {
+		t.Errorf("Unexpected cached media playlist:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	m.EncodeTo(&buf)
+	if buf.String() != m.String() {
+		t.Errorf("Unexpected master playlist:\n%s", buf.String())
+	}
+}
+
+func TestGetSegment(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	for i := 0; i < 5; i++ {
+		p.Slide(fmt.Sprintf("t%02d.ts", i), 10, "")
+	}
+	// the ring buffer wrapped around, t02.ts is the oldest segment
+	for i := uint(0); i < p.Count(); i++ {
+		seg, ok := p.GetSegment(i)
+		if !ok || seg.URI != fmt.Sprintf("t%02d.ts", i+2) {
+			t.Errorf("Unexpected segment %d: %+v", i, seg)
+		}
+	}
+	if seg, ok := p.GetSegment(p.Count()); ok {
+		t.Errorf("Expected no segment after the last one, got %+v", seg)
+	}
+}
+
+func TestSegmentIter(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	for i := 0; i < 5; i++ {
+		p.Slide(fmt.Sprintf("seg%d.ts", i), 4, "")
+	}
+	var uris []string
+	for it := p.SegmentIter(); it.Next(); {
+		uris = append(uris, it.Segment().URI)
+	}
+	if strings.Join(uris, " ") != "seg2.ts seg3.ts seg4.ts" {
+		t.Errorf("Unexpected segments %v", uris)
+	}
+}
+
+func TestGetSegmentBySeqId(t *testing.T) {
+	p, _ := NewMediaPlaylist(3, 3)
+	p.SeqNo = 100
+	for i := 0; i < 5; i++ {
+		p.Slide(fmt.Sprintf("t%02d.ts", i), 10, "")
+	}
+	for seq, uri := range map[uint64]string{102: "t02.ts", 103: "t03.ts", 104: "t04.ts"} {
+		seg, ok := p.GetSegmentBySeqId(seq)
+		if !ok || seg.URI != uri || seg.SeqId != seq {
+			t.Errorf("Unexpected segment %d: %+v", seq, seg)
+		}
+	}
+	for _, seq := range []uint64{0, 101, 105} {
+		if seg, ok := p.GetSegmentBySeqId(seq); ok {
+			t.Errorf("Expected no segment %d, got %+v", seq, seg)
+		}
+	}
+}
+
+func TestEncodeMasterPlaylistWithImageStream(t *testing.T) {
+	m := NewMasterPlaylist()
+	m.Append("hi.m3u8", nil, VariantParams{Bandwidth: 3000000, Resolution: "1920x1080"})
+	m.Append("thumbs/index.m3u8", nil, VariantParams{Bandwidth: 10000, Resolution: "312x180", Codecs: "jpeg", Image: true})
+	expected := "#EXT-X-IMAGE-STREAM-INF:BANDWIDTH=10000,RESOLUTION=312x180,CODECS=\"jpeg\",URI=\"thumbs/index.m3u8\"\n"
+	out := m.String()
+	if !strings.HasSuffix(out, expected) {
+		t.Fatalf("Expected suffix:\n%s\ngot:\n%s", expected, out)
+	}
+	decoded := NewMasterPlaylist()
+	if err := decoded.DecodeFrom(strings.NewReader(out), true); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Variants) != 2 || !reflect.DeepEqual(decoded.Variants[1].VariantParams, m.Variants[1].VariantParams) || decoded.Variants[1].URI != "thumbs/index.m3u8" {
+		t.Errorf("Unexpected decoded image variant %+v", decoded.Variants[1])
+	}
+}
+
 /******************************
  *  Code generation examples  *
  ******************************/
@@ -1100,6 +1922,7 @@ func BenchmarkEncodeMasterPlaylist(b *testing.B) {
 	if err := p.DecodeFrom(bufio.NewReader(f), true); err != nil {
 		b.Fatal(err)
 	}
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		p.ResetCache()
 		_ = p.Encode() // disregard output
@@ -1118,8 +1941,57 @@ func BenchmarkEncodeMediaPlaylist(b *testing.B) {
 	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
 		b.Fatal(err)
 	}
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		p.ResetCache()
 		_ = p.Encode() // disregard output
 	}
 }
+
+func BenchmarkEncodeToMasterPlaylist(b *testing.B) {
+	f, err := os.Open("sample-playlists/master.m3u8")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p := NewMasterPlaylist()
+	if err := p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		b.Fatal(err)
+	}
+	benchmarkEncodeTo(b, p)
+}
+
+func BenchmarkEncodeToMediaPlaylist(b *testing.B) {
+	f, err := os.Open("sample-playlists/media-playlist-large.m3u8")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := NewMediaPlaylist(50000, 50000)
+	if err != nil {
+		b.Fatalf("Create media playlist failed: %s", err)
+	}
+	if err = p.DecodeFrom(bufio.NewReader(f), true); err != nil {
+		b.Fatal(err)
+	}
+	benchmarkEncodeTo(b, p)
+}
+
+// benchmarkEncodeTo compares encoding to a new buffer for every request
+// with encoding to buffers reused from a pool.
+func benchmarkEncodeTo(b *testing.B, p interface{ EncodeTo(*bytes.Buffer) }) {
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.EncodeTo(new(bytes.Buffer))
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		pool := sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := pool.Get().(*bytes.Buffer)
+			buf.Reset()
+			p.EncodeTo(buf)
+			pool.Put(buf)
+		}
+	})
+}